@@ -1,30 +1,74 @@
 package bruteforce
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
 	"net/http"
+	"r3/cache"
 	"r3/config"
+	"r3/db"
+	"r3/log"
+	"r3/tools"
 	"r3/types"
+	"strings"
 	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 var (
-	access_mx sync.RWMutex
-	attempts  int  = 100   // max allowed failed attempts before block
-	enabled   bool = false // enable bruteforce protection
+	access_mx    sync.RWMutex
+	attempts     int   = 100   // max allowed failed attempts before block
+	blockMinutes int64 = 0     // duration of host block, 0 = block does not expire
+	enabled      bool  = false // enable bruteforce protection
+	ipv6Prefix   int   = 64    // IPv6 addresses are grouped by prefix (a single client usually controls a whole subnet)
+
+	proxiesTrusted = make([]*net.IPNet, 0) // proxies that are trusted to forward client addresses
 
 	hostMapTracked = make(map[string]int)
-	hostMapBlocked = make(map[string]types.Void)
+	hostMapBlocked = make(map[string]int64) // value: unix time of block expiry, 0 = never expires
 )
 
 func SetConfig() {
 	access_mx.Lock()
 	attempts = int(config.GetUint64("bruteforceAttempts"))
+	blockMinutes = int64(config.GetUint64("bruteforceBlockMinutes"))
 	enabled = config.GetUint64("bruteforceProtection") == 1
+	ipv6Prefix = int(config.GetUint64("bruteforceIpv6Prefix"))
+
+	if ipv6Prefix <= 0 || ipv6Prefix > 128 {
+		ipv6Prefix = 128
+	}
+
+	proxiesTrusted = make([]*net.IPNet, 0)
+	for _, entry := range strings.Split(config.GetString("proxiesTrusted"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		// single addresses are handled as networks with full mask
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry = fmt.Sprintf("%s/32", entry)
+			} else {
+				entry = fmt.Sprintf("%s/128", entry)
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Warning("server", fmt.Sprintf("ignoring invalid trusted proxy address '%s'", entry), err)
+			continue
+		}
+		proxiesTrusted = append(proxiesTrusted, ipNet)
+	}
 	access_mx.Unlock()
 
 	if !enabled {
-		ClearHostMap()
+		clearTracked()
 	}
 }
 
@@ -32,13 +76,48 @@ func SetConfig() {
 func GetCounts() (int, int) {
 	access_mx.RLock()
 	defer access_mx.RUnlock()
-	return len(hostMapTracked), len(hostMapBlocked)
+
+	now := tools.GetTimeUnix()
+	blocked := 0
+	for _, dateExpiry := range hostMapBlocked {
+		if dateExpiry == 0 || dateExpiry > now {
+			blocked++
+		}
+	}
+	return len(hostMapTracked), blocked
+}
+
+// returns all currently blocked hosts, as stored in the database (shared by all cluster nodes)
+func GetBlocked() ([]types.BruteforceBlock, error) {
+	blocks := make([]types.BruteforceBlock, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT b.host, b.date_blocked, b.date_expiry, COALESCE(n.name, '-')
+		FROM instance.bruteforce_block AS b
+		LEFT JOIN instance_cluster.node AS n ON n.id = b.node_id
+		WHERE b.date_expiry IS NULL
+		OR    b.date_expiry > $1
+		ORDER BY b.date_blocked DESC
+	`, tools.GetTimeUnix())
+	if err != nil {
+		return blocks, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b types.BruteforceBlock
+		if err := rows.Scan(&b.Host, &b.DateBlocked, &b.DateExpiry, &b.NodeName); err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks, nil
 }
 
 // returns if request should be blocked due to assumed bruteforce attempt
 func Check(r *http.Request) bool {
 
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	host, err := GetHost(r)
 	if err != nil {
 		return true
 	}
@@ -54,8 +133,8 @@ func CheckByHost(host string) bool {
 		return false
 	}
 
-	_, exists := hostMapBlocked[host]
-	return exists
+	dateExpiry, exists := hostMapBlocked[getHostKey(host)]
+	return exists && (dateExpiry == 0 || dateExpiry > tools.GetTimeUnix())
 }
 
 // store bad authentication attempt
@@ -63,7 +142,7 @@ func CheckByHost(host string) bool {
 // uses host part of source address to identify source
 func BadAttempt(r *http.Request) {
 
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	host, err := GetHost(r)
 	if err != nil {
 		// logging error case could flood the logs
 		return
@@ -74,35 +153,161 @@ func BadAttempt(r *http.Request) {
 func BadAttemptByHost(host string) {
 
 	// ignore local access
-	if host == "::1" || host == "localhost" || host == "127.0.0.1" {
+	if host == "localhost" {
+		return
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
 		return
 	}
 
+	blocked := false
+	var dateExpiry int64 = 0
+
+	access_mx.Lock()
+	if !enabled {
+		access_mx.Unlock()
+		return
+	}
+	key := getHostKey(host)
+
+	if _, exists := hostMapTracked[key]; !exists {
+		// host not known yet, track from now on
+		hostMapTracked[key] = 1
+
+	} else if hostMapTracked[key] > attempts {
+		// max allowed attempts reached, block host
+		if blockMinutes != 0 {
+			dateExpiry = tools.GetTimeUnix() + (blockMinutes * 60)
+		}
+		delete(hostMapTracked, key)
+		hostMapBlocked[key] = dateExpiry
+		blocked = true
+
+	} else {
+		// host is known but not yet blocked
+		hostMapTracked[key]++
+	}
+	access_mx.Unlock()
+
+	if blocked {
+		log.Warning("server", "bruteforce protection",
+			fmt.Errorf("host '%s' has been blocked after too many failed attempts", key))
+
+		if err := storeBlock(key, dateExpiry); err != nil {
+			log.Error("server", fmt.Sprintf("failed to store bruteforce block for host '%s'", key), err)
+		}
+	}
+}
+
+// applies host block from another cluster node
+func Block(host string, dateExpiry int64) {
 	access_mx.Lock()
 	defer access_mx.Unlock()
 
-	// host not known yet, track from now on
-	if _, exists := hostMapTracked[host]; !exists {
-		hostMapTracked[host] = 1
-		return
+	delete(hostMapTracked, host)
+	hostMapBlocked[host] = dateExpiry
+}
+
+// removes host block locally, host is the blocked key (IP address or IPv6 prefix)
+func Unblock(host string) {
+	access_mx.Lock()
+	defer access_mx.Unlock()
+
+	delete(hostMapTracked, host)
+	delete(hostMapBlocked, host)
+}
+func Unblock_tx(tx pgx.Tx, host string) error {
+	_, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.bruteforce_block
+		WHERE host = $1
+	`, host)
+	return err
+}
+
+// loads non-expired host blocks from the database
+func LoadBlocked() error {
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT host, COALESCE(date_expiry, 0)
+		FROM instance.bruteforce_block
+		WHERE date_expiry IS NULL
+		OR    date_expiry > $1
+	`, tools.GetTimeUnix())
+	if err != nil {
+		return err
 	}
+	defer rows.Close()
 
-	// max allowed attempts reached, block host
-	if hostMapTracked[host] > attempts {
-		delete(hostMapTracked, host)
-		hostMapBlocked[host] = types.Void{}
-		return
+	blocked := make(map[string]int64)
+	for rows.Next() {
+		var host string
+		var dateExpiry int64
+		if err := rows.Scan(&host, &dateExpiry); err != nil {
+			return err
+		}
+		blocked[host] = dateExpiry
+	}
+
+	access_mx.Lock()
+	hostMapBlocked = blocked
+	access_mx.Unlock()
+	return nil
+}
+
+// clears tracked hosts and removes expired host blocks
+func Cleanup() error {
+	now := tools.GetTimeUnix()
+
+	access_mx.Lock()
+	hostMapTracked = make(map[string]int)
+	for host, dateExpiry := range hostMapBlocked {
+		if dateExpiry != 0 && dateExpiry <= now {
+			delete(hostMapBlocked, host)
+		}
 	}
+	access_mx.Unlock()
 
-	// host is known but not yet blocked
-	hostMapTracked[host]++
+	_, err := db.Pool.Exec(db.Ctx, `
+		DELETE FROM instance.bruteforce_block
+		WHERE date_expiry IS NOT NULL
+		AND   date_expiry <= $1
+	`, now)
+	return err
 }
 
-func ClearHostMap() error {
+func clearTracked() {
 	access_mx.Lock()
 	defer access_mx.Unlock()
 
 	hostMapTracked = make(map[string]int)
-	hostMapBlocked = make(map[string]types.Void)
-	return nil
+}
+
+// stores host block in the database and informs other cluster nodes
+// cluster events are created directly as the cluster package depends on this one
+func storeBlock(host string, dateExpiry int64) error {
+	dateExpiryNull := pgtype.Int8{Int64: dateExpiry, Valid: dateExpiry != 0}
+
+	if _, err := db.Pool.Exec(db.Ctx, `
+		INSERT INTO instance.bruteforce_block (host, node_id, date_blocked, date_expiry)
+		VALUES ($1,$2,$3,$4)
+		ON CONFLICT (host) DO UPDATE
+			SET node_id = $2, date_blocked = $3, date_expiry = $4
+	`, host, cache.GetNodeId(), tools.GetTimeUnix(), dateExpiryNull); err != nil {
+		return err
+	}
+
+	payloadJson, err := json.Marshal(types.ClusterEventBruteforce{
+		Host:       host,
+		DateExpiry: dateExpiry,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Pool.Exec(db.Ctx, `
+		INSERT INTO instance_cluster.node_event (node_id,content,payload)
+		SELECT id,'bruteforceBlocked',$1
+		FROM instance_cluster.node
+		WHERE id <> $2
+	`, payloadJson, cache.GetNodeId())
+	return err
 }
@@ -0,0 +1,107 @@
+package bruteforce
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// returns client address of request
+// if request comes from a trusted proxy, forwarded client address is used instead
+func GetHost(r *http.Request) (string, error) {
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "", err
+	}
+
+	access_mx.RLock()
+	defer access_mx.RUnlock()
+
+	if !isTrustedProxy(host) {
+		return host, nil
+	}
+
+	// walk forwarding chain from closest to furthest hop
+	// first address that is not a trusted proxy is the client
+	hosts := getForwardedHosts(r)
+	for i := len(hosts) - 1; i >= 0; i-- {
+		if !isTrustedProxy(hosts[i]) {
+			return hosts[i], nil
+		}
+	}
+
+	// all forwarded addresses are trusted proxies, use furthest hop
+	if len(hosts) != 0 {
+		return hosts[0], nil
+	}
+	return host, nil
+}
+
+// returns forwarded client addresses, furthest hop first
+// standardized 'Forwarded' header takes precedence over 'X-Forwarded-For'
+func getForwardedHosts(r *http.Request) []string {
+	hosts := make([]string, 0)
+
+	for _, header := range r.Header.Values("Forwarded") {
+		for _, element := range strings.Split(header, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, found := strings.Cut(strings.TrimSpace(pair), "=")
+				if !found || !strings.EqualFold(key, "for") {
+					continue
+				}
+
+				value = strings.Trim(value, `"`)
+				if strings.HasPrefix(value, "[") {
+					// IPv6 address, optionally with port: [2001:db8::1]:4711
+					value, _, _ = strings.Cut(strings.TrimPrefix(value, "["), "]")
+				} else if strings.Count(value, ":") == 1 {
+					// IPv4 address with port
+					value, _, _ = strings.Cut(value, ":")
+				}
+
+				// obfuscated identifiers or 'unknown' cannot be used
+				if net.ParseIP(value) != nil {
+					hosts = append(hosts, value)
+				}
+			}
+		}
+	}
+	if len(hosts) != 0 {
+		return hosts
+	}
+
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, value := range strings.Split(header, ",") {
+			value = strings.TrimSpace(value)
+			if net.ParseIP(value) != nil {
+				hosts = append(hosts, value)
+			}
+		}
+	}
+	return hosts
+}
+
+// returns key by which host is tracked and blocked
+// IPv6 addresses are grouped by their network prefix
+func getHostKey(host string) string {
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() != nil || ipv6Prefix == 128 {
+		return host
+	}
+	return fmt.Sprintf("%s/%d", ip.Mask(net.CIDRMask(ipv6Prefix, 128)).String(), ipv6Prefix)
+}
+
+func isTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range proxiesTrusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
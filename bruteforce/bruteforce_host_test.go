@@ -0,0 +1,29 @@
+package bruteforce
+
+import "testing"
+
+func TestGetHostKey(t *testing.T) {
+	ipv6PrefixEx := ipv6Prefix
+	defer func() { ipv6Prefix = ipv6PrefixEx }()
+
+	tests := []struct {
+		prefix int
+		host   string
+		want   string
+	}{
+		{64, "192.168.1.10", "192.168.1.10"},
+		{64, "::ffff:192.168.1.10", "::ffff:192.168.1.10"},
+		{64, "2001:db8:1:2:3:4:5:6", "2001:db8:1:2::/64"},
+		{64, "2001:db8:1:2:ffff::1", "2001:db8:1:2::/64"},
+		{48, "2001:db8:1:2:3:4:5:6", "2001:db8:1::/48"},
+		{128, "2001:db8:1:2:3:4:5:6", "2001:db8:1:2:3:4:5:6"},
+		{64, "not-an-address", "not-an-address"},
+		{64, "", ""},
+	}
+	for _, test := range tests {
+		ipv6Prefix = test.prefix
+		if got := getHostKey(test.host); got != test.want {
+			t.Errorf("getHostKey(%q) with prefix %d = %q, want %q", test.host, test.prefix, got, test.want)
+		}
+	}
+}
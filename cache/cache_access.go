@@ -1,40 +1,98 @@
 package cache
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net"
 	"r3/db"
+	"r3/log"
 	"r3/types"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/gofrs/uuid"
 )
 
+// role assigned to or delegated to login, valid in given time range (0 = unlimited)
+type roleAssignment struct {
+	roleId   uuid.UUID
+	dateFrom int64
+	dateTo   int64
+}
+
+type contextKey int
+
+const contextKeyAddress contextKey = iota
+
 var (
 	access_mx        sync.RWMutex
-	loginIdMapAccess = make(map[int64]types.LoginAccess) // access permissions by login ID
+	loginIdMapAccess = make(map[int64][]types.LoginAccess) // effective access permissions by login ID, one per set of granted roles
+	loginIdMapRoles  = make(map[int64][]roleAssignment)    // assigned & delegated roles (excl. inherited) by login ID
+	roleIdMapAccess  = make(map[uuid.UUID]types.Role)      // copy of schema roles, updated on schema change
+	location_mx      sync.Mutex                            // timezone locations are loaded on demand
+	locationNameMap  = make(map[string]*time.Location)     // timezone locations by name
 )
 
+// returns context carrying the client address of a request
+// network conditions of roles are checked against this address
+func SetContextAddress(ctx context.Context, address string) context.Context {
+	return context.WithValue(ctx, contextKeyAddress, address)
+}
+
+// returns client address of request context, empty string if unknown
+func GetContextAddress(ctx context.Context) string {
+	address, _ := ctx.Value(contextKeyAddress).(string)
+	return address
+}
+
 // get effective access for specified login
-func GetAccessById(loginId int64) (types.LoginAccess, error) {
+// roles with access conditions (networks, time windows) are only included while their conditions are met
+// network conditions are checked against the client address of the given request context
+func GetAccessById(ctx context.Context, loginId int64) (types.LoginAccess, error) {
 
 	if loginId == 0 {
 		return types.LoginAccess{}, errors.New("invalid login ID 0")
 	}
+	address := GetContextAddress(ctx)
 
+	// granted roles can change without role assignments changing (time passes, client addresses differ)
+	// schema lock must not be taken here, as callers often hold it already
 	access_mx.RLock()
-	defer access_mx.RUnlock()
+	_, exists := loginIdMapRoles[loginId]
+	if exists {
+		roleIds := getRoleIdsGranted(loginId, address, time.Now())
+		for _, access := range loginIdMapAccess[loginId] {
+			if slices.Equal(access.RoleIds, roleIds) {
+				access_mx.RUnlock()
+				return access, nil
+			}
+		}
+	}
+	access_mx.RUnlock()
+
+	access_mx.Lock()
+	defer access_mx.Unlock()
 
-	if _, exists := loginIdMapAccess[loginId]; !exists {
+	if _, exists := loginIdMapRoles[loginId]; !exists {
 		if err := load(loginId); err != nil {
 			return types.LoginAccess{}, err
 		}
 	}
-	return loginIdMapAccess[loginId], nil
+	return apply(loginId, address), nil
 }
 
 // renew permissions for all cached logins
 func RenewAccessAll() error {
-	for loginId, _ := range loginIdMapAccess {
+	access_mx.RLock()
+	loginIds := make([]int64, 0, len(loginIdMapRoles))
+	for loginId, _ := range loginIdMapRoles {
+		loginIds = append(loginIds, loginId)
+	}
+	access_mx.RUnlock()
+
+	for _, loginId := range loginIds {
 		if err := RenewAccessById(loginId); err != nil {
 			return err
 		}
@@ -47,23 +105,72 @@ func RenewAccessById(loginId int64) error {
 	access_mx.Lock()
 	defer access_mx.Unlock()
 
-	if _, exists := loginIdMapAccess[loginId]; !exists {
+	if _, exists := loginIdMapRoles[loginId]; !exists {
 		return nil
 	}
 	return load(loginId)
 }
 
-// load access permissions for login ID into cache
-func load(loginId int64) error {
-	Schema_mx.RLock()
-	defer Schema_mx.RUnlock()
+// returns access denial reason if role is not granted from given client address right now, empty string if granted
+func GetRoleDenial(roleId uuid.UUID, address string) string {
+	access_mx.RLock()
+	defer access_mx.RUnlock()
+
+	role, exists := roleIdMapAccess[roleId]
+	if !exists {
+		return ""
+	}
+	return getRoleDenial(role, address, time.Now())
+}
+
+// store copy of roles for resolving access, called with schema cache locked
+// role values are replaced, not changed, on schema updates and can be shared
+func setAccessRoles(roleIdMap map[uuid.UUID]types.Role) {
+	access_mx.Lock()
+	defer access_mx.Unlock()
 
-	roleIds, err := loadRoleIds(loginId)
+	roleIdMapAccess = make(map[uuid.UUID]types.Role)
+	for id, role := range roleIdMap {
+		roleIdMapAccess[id] = role
+	}
+}
+
+// load role assignments for login ID into cache, resets cached access permissions
+func load(loginId int64) error {
+	roles, err := loadRoles(loginId)
 	if err != nil {
 		return err
 	}
+	loginIdMapRoles[loginId] = roles
+	loginIdMapAccess[loginId] = make([]types.LoginAccess, 0)
+	return nil
+}
+
+// returns access permissions of roles currently granted to login from given client address
+// access permissions are cached for each set of granted roles
+func apply(loginId int64, address string) types.LoginAccess {
+	now := time.Now()
+	roleIds := getRoleIdsGranted(loginId, address, now)
+
+	for _, access := range loginIdMapAccess[loginId] {
+		if slices.Equal(access.RoleIds, roleIds) {
+			return access
+		}
+	}
 
-	loginIdMapAccess[loginId] = types.LoginAccess{
+	// denied roles are logged once, when a new set of granted roles occurs
+	for _, a := range loginIdMapRoles[loginId] {
+		role, exists := roleIdMapAccess[a.roleId]
+		if !exists || !a.isValidAt(now.Unix()) {
+			continue
+		}
+		if reason := getRoleDenial(role, address, now); reason != "" {
+			log.Info("server", fmt.Sprintf("role '%s' is not granted to login %d, %s",
+				role.Name, loginId, reason))
+		}
+	}
+
+	access := types.LoginAccess{
 		RoleIds:    roleIds,
 		Api:        make(map[uuid.UUID]int),
 		Attribute:  make(map[uuid.UUID]int),
@@ -73,96 +180,179 @@ func load(loginId int64) error {
 	}
 
 	for _, roleId := range roleIds {
-		role, _ := RoleIdMap[roleId]
+		role, _ := roleIdMapAccess[roleId]
 
 		// because access rights work cumulatively, apply highest right only
-		for id, access := range role.AccessApis {
-			if _, exists := loginIdMapAccess[loginId].Api[id]; !exists ||
-				loginIdMapAccess[loginId].Api[id] < access {
-
-				loginIdMapAccess[loginId].Api[id] = access
+		for id, level := range role.AccessApis {
+			if _, exists := access.Api[id]; !exists || access.Api[id] < level {
+				access.Api[id] = level
 			}
 		}
-		for id, access := range role.AccessAttributes {
-			if _, exists := loginIdMapAccess[loginId].Attribute[id]; !exists ||
-				loginIdMapAccess[loginId].Attribute[id] < access {
-
-				loginIdMapAccess[loginId].Attribute[id] = access
+		for id, level := range role.AccessAttributes {
+			if _, exists := access.Attribute[id]; !exists || access.Attribute[id] < level {
+				access.Attribute[id] = level
 			}
 		}
-		for id, access := range role.AccessCollections {
-			if _, exists := loginIdMapAccess[loginId].Collection[id]; !exists ||
-				loginIdMapAccess[loginId].Collection[id] < access {
-
-				loginIdMapAccess[loginId].Collection[id] = access
+		for id, level := range role.AccessCollections {
+			if _, exists := access.Collection[id]; !exists || access.Collection[id] < level {
+				access.Collection[id] = level
 			}
 		}
-		for id, access := range role.AccessMenus {
-			if _, exists := loginIdMapAccess[loginId].Menu[id]; !exists ||
-				loginIdMapAccess[loginId].Menu[id] < access {
-
-				loginIdMapAccess[loginId].Menu[id] = access
+		for id, level := range role.AccessMenus {
+			if _, exists := access.Menu[id]; !exists || access.Menu[id] < level {
+				access.Menu[id] = level
 			}
 		}
-		for id, access := range role.AccessRelations {
-			if _, exists := loginIdMapAccess[loginId].Relation[id]; !exists ||
-				loginIdMapAccess[loginId].Relation[id] < access {
-
-				loginIdMapAccess[loginId].Relation[id] = access
+		for id, level := range role.AccessRelations {
+			if _, exists := access.Relation[id]; !exists || access.Relation[id] < level {
+				access.Relation[id] = level
 			}
 		}
 	}
-	return nil
+	loginIdMapAccess[loginId] = append(loginIdMapAccess[loginId], access)
+	return access
 }
 
-func loadRoleIds(loginId int64) ([]uuid.UUID, error) {
+// returns currently granted roles of login, including inherited roles
+// children of roles that are not granted are not inherited
+func getRoleIdsGranted(loginId int64, address string, now time.Time) []uuid.UUID {
 	roleIds := make([]uuid.UUID, 0)
 
+	var addRole func(roleId uuid.UUID)
+	addRole = func(roleId uuid.UUID) {
+		if slices.Contains(roleIds, roleId) {
+			return
+		}
+		role, exists := roleIdMapAccess[roleId]
+		if !exists || getRoleDenial(role, address, now) != "" {
+			return
+		}
+		roleIds = append(roleIds, roleId)
+
+		for _, childId := range role.ChildrenIds {
+			addRole(childId)
+		}
+	}
+	for _, a := range loginIdMapRoles[loginId] {
+		if a.isValidAt(now.Unix()) {
+			addRole(a.roleId)
+		}
+	}
+	return roleIds
+}
+
+func (a roleAssignment) isValidAt(unixTime int64) bool {
+	return (a.dateFrom == 0 || unixTime >= a.dateFrom) && (a.dateTo == 0 || unixTime < a.dateTo)
+}
+
+// returns reason why role is not granted from client address at given time, empty string if granted
+func getRoleDenial(role types.Role, address string, now time.Time) string {
+
+	if len(role.AccessNetworks) != 0 {
+		if address == "" {
+			return "client address is unknown"
+		}
+		if !isAddressInNetworks(address, role.AccessNetworks) {
+			return fmt.Sprintf("client address %s is outside of allowed networks", address)
+		}
+	}
+
+	if len(role.AccessWindows) != 0 {
+		location, err := getLocation(role.AccessTimezone)
+		if err != nil {
+			return fmt.Sprintf("invalid timezone '%s'", role.AccessTimezone)
+		}
+		now = now.In(location)
+		seconds := now.Hour()*3600 + now.Minute()*60 + now.Second()
+
+		inWindow := false
+		for _, w := range role.AccessWindows {
+			if slices.Contains(w.Weekdays, int(now.Weekday())) &&
+				seconds >= w.TimeFrom && seconds < w.TimeTo {
+
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			return "outside of allowed access windows"
+		}
+	}
+	return ""
+}
+
+func getLocation(name string) (*time.Location, error) {
+	location_mx.Lock()
+	defer location_mx.Unlock()
+
+	if location, exists := locationNameMap[name]; exists {
+		return location, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locationNameMap[name] = location
+	return location, nil
+}
+
+func isAddressInNetworks(address string, networks []string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadRoles(loginId int64) ([]roleAssignment, error) {
+	roles := make([]roleAssignment, 0)
+
+	// inherited roles are resolved from schema cache, see getRoleIdsGranted()
+	// delegated roles are only valid while the assignment of the delegating login is
 	rows, err := db.Pool.Query(db.Ctx, `
-		-- get nested children of assigned roles
-		WITH RECURSIVE child_ids AS (
-			SELECT role_id_child
-			FROM app.role_child
-			WHERE role_id IN (
-				SELECT role_id
-				FROM instance.login_role
-				WHERE login_id = $1
-			)
-			
-			UNION
-			
-			SELECT c.role_id_child
-			FROM app.role_child AS c
-			INNER JOIN child_ids AS r ON c.role_id = r.role_id_child
-		)
-		SELECT *
-		FROM child_ids
-		
-		UNION
-		
 		-- get assigned roles
-		SELECT role_id
+		SELECT role_id, COALESCE(date_from,0), COALESCE(date_to,0)
 		FROM instance.login_role
-		WHERE login_id = $2
+		WHERE login_id = $1
+		
+		UNION ALL
+		
+		-- get roles delegated to login
+		SELECT d.role_id, GREATEST(d.date_from, COALESCE(lr.date_from,0)),
+			LEAST(d.date_to, COALESCE(lr.date_to,d.date_to))
+		FROM instance.login_role_delegation AS d
+		INNER JOIN instance.login_role AS lr
+			ON  lr.login_id = d.login_id
+			AND lr.role_id  = d.role_id
+		INNER JOIN instance.login AS l ON l.id = d.login_id
+		WHERE d.login_id_substitute = $1
+		AND   d.date_ended IS NULL
+		AND   l.active
 		
-		UNION
+		UNION ALL
 		
 		-- get 'everyone' roles from all modules
-		SELECT id
+		SELECT id, 0, 0
 		FROM app.role
 		WHERE content = 'everyone'
-	`, loginId, loginId)
+	`, loginId)
 	if err != nil {
-		return roleIds, err
+		return roles, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var id uuid.UUID
-		if err := rows.Scan(&id); err != nil {
-			return roleIds, err
+		var a roleAssignment
+		if err := rows.Scan(&a.roleId, &a.dateFrom, &a.dateTo); err != nil {
+			return roles, err
 		}
-		roleIds = append(roleIds, id)
+		roles = append(roles, a)
 	}
-	return roleIds, nil
+	return roles, nil
 }
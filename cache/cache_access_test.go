@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"r3/types"
+	"testing"
+	"time"
+)
+
+func TestRoleAssignmentIsValidAt(t *testing.T) {
+	tests := []struct {
+		assignment roleAssignment
+		unixTime   int64
+		want       bool
+	}{
+		{roleAssignment{dateFrom: 0, dateTo: 0}, 1000, true},
+		{roleAssignment{dateFrom: 1000, dateTo: 0}, 999, false},
+		{roleAssignment{dateFrom: 1000, dateTo: 0}, 1000, true},
+		{roleAssignment{dateFrom: 0, dateTo: 2000}, 1999, true},
+		{roleAssignment{dateFrom: 0, dateTo: 2000}, 2000, false},
+		{roleAssignment{dateFrom: 1000, dateTo: 2000}, 1500, true},
+		{roleAssignment{dateFrom: 1000, dateTo: 2000}, 2500, false},
+	}
+	for _, test := range tests {
+		if got := test.assignment.isValidAt(test.unixTime); got != test.want {
+			t.Errorf("%+v.isValidAt(%d) = %v, want %v", test.assignment, test.unixTime, got, test.want)
+		}
+	}
+}
+
+func TestGetRoleDenial(t *testing.T) {
+	// monday, 10:30 UTC
+	now := time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)
+
+	windowMorning := types.RoleWindow{Weekdays: []int{1, 2, 3, 4, 5}, TimeFrom: 8 * 3600, TimeTo: 12 * 3600}
+	windowWeekend := types.RoleWindow{Weekdays: []int{0, 6}, TimeFrom: 0, TimeTo: 24 * 3600}
+	windowEnded := types.RoleWindow{Weekdays: []int{1}, TimeFrom: 8 * 3600, TimeTo: 10*3600 + 30*60}
+
+	tests := []struct {
+		name    string
+		role    types.Role
+		address string
+		granted bool
+	}{
+		{"no conditions", types.Role{}, "", true},
+		{"address in network", types.Role{AccessNetworks: []string{"10.0.0.0/8"}}, "10.1.2.3", true},
+		{"address in second network", types.Role{AccessNetworks: []string{"10.0.0.0/8", "2001:db8::/32"}}, "2001:db8::1", true},
+		{"address outside network", types.Role{AccessNetworks: []string{"10.0.0.0/8"}}, "192.168.1.1", false},
+		{"address unknown", types.Role{AccessNetworks: []string{"10.0.0.0/8"}}, "", false},
+		{"address invalid", types.Role{AccessNetworks: []string{"10.0.0.0/8"}}, "localhost", false},
+		{"inside window", types.Role{AccessTimezone: "UTC", AccessWindows: []types.RoleWindow{windowMorning}}, "", true},
+		{"wrong weekday", types.Role{AccessTimezone: "UTC", AccessWindows: []types.RoleWindow{windowWeekend}}, "", false},
+		{"any window", types.Role{AccessTimezone: "UTC", AccessWindows: []types.RoleWindow{windowWeekend, windowMorning}}, "", true},
+		{"window end exclusive", types.Role{AccessTimezone: "UTC", AccessWindows: []types.RoleWindow{windowEnded}}, "", false},
+		{"invalid timezone", types.Role{AccessTimezone: "Invalid/Zone", AccessWindows: []types.RoleWindow{windowMorning}}, "", false},
+		{"network and window", types.Role{AccessNetworks: []string{"10.0.0.0/8"}, AccessTimezone: "UTC",
+			AccessWindows: []types.RoleWindow{windowMorning}}, "10.1.2.3", true},
+		{"network but not window", types.Role{AccessNetworks: []string{"10.0.0.0/8"}, AccessTimezone: "UTC",
+			AccessWindows: []types.RoleWindow{windowWeekend}}, "10.1.2.3", false},
+	}
+	for _, test := range tests {
+		reason := getRoleDenial(test.role, test.address, now)
+		if (reason == "") != test.granted {
+			t.Errorf("getRoleDenial(%s) = %q, want granted %v", test.name, reason, test.granted)
+		}
+	}
+}
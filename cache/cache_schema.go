@@ -28,6 +28,7 @@ import (
 	"r3/schema/role"
 	"r3/tools"
 	"r3/types"
+	"regexp"
 	"sync"
 
 	"github.com/gofrs/uuid"
@@ -45,13 +46,14 @@ var (
 	Schema_mx sync.RWMutex
 
 	// cached entities for regular use during normal operation
-	ModuleIdMap        map[uuid.UUID]types.Module      // all modules by ID
-	ModuleApiNameMapId map[string]map[string]uuid.UUID // all API IDs by module+API name
-	RelationIdMap      map[uuid.UUID]types.Relation    // all relations by ID
-	AttributeIdMap     map[uuid.UUID]types.Attribute   // all attributes by ID
-	RoleIdMap          map[uuid.UUID]types.Role        // all roles by ID
-	PgFunctionIdMap    map[uuid.UUID]types.PgFunction  // all PG functions by ID
-	ApiIdMap           map[uuid.UUID]types.Api         // all APIs by ID
+	ModuleIdMap         map[uuid.UUID]types.Module      // all modules by ID
+	ModuleApiNameMapId  map[string]map[string]uuid.UUID // all API IDs by module+API name
+	RelationIdMap       map[uuid.UUID]types.Relation    // all relations by ID
+	AttributeIdMap      map[uuid.UUID]types.Attribute   // all attributes by ID
+	AttributeIdMapRegex map[uuid.UUID]*regexp.Regexp    // compiled validation regular expressions by attribute ID
+	RoleIdMap           map[uuid.UUID]types.Role        // all roles by ID
+	PgFunctionIdMap     map[uuid.UUID]types.PgFunction  // all PG functions by ID
+	ApiIdMap            map[uuid.UUID]types.Api         // all APIs by ID
 
 	// schema cache
 	moduleIdsOrdered []uuid.UUID     // all module IDs in desired order
@@ -138,6 +140,7 @@ func updateSchemaCache(moduleIdsUpdateOnly []uuid.UUID) error {
 		ModuleApiNameMapId = make(map[string]map[string]uuid.UUID)
 		RelationIdMap = make(map[uuid.UUID]types.Relation)
 		AttributeIdMap = make(map[uuid.UUID]types.Attribute)
+		AttributeIdMapRegex = make(map[uuid.UUID]*regexp.Regexp)
 		RoleIdMap = make(map[uuid.UUID]types.Role)
 		PgFunctionIdMap = make(map[uuid.UUID]types.PgFunction)
 		ApiIdMap = make(map[uuid.UUID]types.Api)
@@ -197,6 +200,16 @@ func updateSchemaCache(moduleIdsUpdateOnly []uuid.UUID) error {
 			// store & backfill attribute to relation
 			for _, atr := range atrs {
 				AttributeIdMap[atr.Id] = atr
+
+				// compile validation regular expressions once, values are checked against them on every set
+				if atr.Validation.Regex.Valid {
+					AttributeIdMapRegex[atr.Id], err = regexp.Compile(atr.Validation.Regex.String)
+					if err != nil {
+						return fmt.Errorf("failed to compile validation regular expression of attribute %s, %s", atr.Id, err)
+					}
+				} else {
+					delete(AttributeIdMapRegex, atr.Id)
+				}
 				rel.Attributes = append(rel.Attributes, atr)
 			}
 
@@ -310,5 +323,8 @@ func updateSchemaCache(moduleIdsUpdateOnly []uuid.UUID) error {
 		// update cache map with parsed module
 		ModuleIdMap[mod.Id] = mod
 	}
+
+	// access cache resolves roles from its own copy, as it is used while schema lock is held
+	setAccessRoles(RoleIdMap)
 	return nil
 }
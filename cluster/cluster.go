@@ -93,6 +93,14 @@ func StartNode() error {
 		`, nodeId); err != nil {
 			return err
 		}
+
+		// clients of previous run are gone, remove their record presence
+		if _, err := db.Pool.Exec(db.Ctx, `
+			DELETE FROM instance.record_presence
+			WHERE node_id = $1
+		`, nodeId); err != nil {
+			return err
+		}
 	}
 
 	// store node details
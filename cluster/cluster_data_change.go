@@ -0,0 +1,113 @@
+package cluster
+
+import (
+	"fmt"
+	"r3/db"
+	"r3/log"
+	"r3/types"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// data changes are notified by the database via triggers on opted-in relations (LISTEN/NOTIFY)
+// this includes changes by the application as well as direct SQL changes
+// notifications are delivered on transaction commit to all listening cluster nodes
+var (
+	dataChangeChannel  = "r3_data_change"
+	dataChangeInterval = time.Millisecond * 500 // collect changes before sending them to clients
+	dataChangeRetry    = time.Second * 5        // wait time before reconnecting listener after failure
+
+	dataChange_mx sync.Mutex
+	dataChanges   = make(map[uuid.UUID]map[int64]bool) // changed records, key: relation ID
+)
+
+// listens for data change notifications, reconnects if connection is lost
+func ListenDataChanges() {
+	go sendDataChanges()
+
+	for {
+		if err := listenDataChanges(); err != nil {
+			log.Error("cluster", "data change listener failed, reconnecting", err)
+		}
+		time.Sleep(dataChangeRetry)
+	}
+}
+
+func listenDataChanges() error {
+	poolCon, err := db.Pool.Acquire(db.Ctx)
+	if err != nil {
+		return err
+	}
+
+	// connection is used exclusively for listening, remove it from pool
+	con := poolCon.Hijack()
+	defer con.Close(db.Ctx)
+
+	if _, err := con.Exec(db.Ctx, fmt.Sprintf(`LISTEN "%s"`, dataChangeChannel)); err != nil {
+		return err
+	}
+	log.Info("cluster", "started listening for data changes")
+
+	for {
+		n, err := con.WaitForNotification(db.Ctx)
+		if err != nil {
+			return err
+		}
+
+		// payload: relation ID,record ID
+		parts := strings.Split(n.Payload, ",")
+		if len(parts) != 2 {
+			continue
+		}
+		relationId, err := uuid.FromString(parts[0])
+		if err != nil {
+			continue
+		}
+		recordId, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		dataChange_mx.Lock()
+		if _, exists := dataChanges[relationId]; !exists {
+			dataChanges[relationId] = make(map[int64]bool)
+		}
+		dataChanges[relationId][recordId] = true
+		dataChange_mx.Unlock()
+	}
+}
+
+// sends collected data changes to websocket clients in regular intervals
+func sendDataChanges() {
+	for {
+		time.Sleep(dataChangeInterval)
+
+		dataChange_mx.Lock()
+		if len(dataChanges) == 0 {
+			dataChange_mx.Unlock()
+			continue
+		}
+		changes := make([]types.DataChange, 0)
+		for relationId, recordIdMap := range dataChanges {
+			recordIds := make([]int64, 0)
+			for recordId, _ := range recordIdMap {
+				recordIds = append(recordIds, recordId)
+			}
+			changes = append(changes, types.DataChange{
+				RelationId: relationId,
+				RecordIds:  recordIds,
+			})
+		}
+		dataChanges = make(map[uuid.UUID]map[int64]bool)
+		dataChange_mx.Unlock()
+
+		WebsocketClientEvents <- types.ClusterWebsocketClientEvent{
+			LoginId:     0,
+			DataChanged: changes,
+		}
+	}
+}
@@ -5,8 +5,10 @@ import (
 	"r3/bruteforce"
 	"r3/cache"
 	"r3/config"
+	"r3/data"
 	"r3/db"
 	"r3/log"
+	"r3/login/login_cert"
 	"r3/tools"
 	"r3/types"
 	"runtime"
@@ -56,6 +58,22 @@ func CheckInNode() error {
 }
 
 // events relevant to all cluster nodes
+func BruteforceBlocked(host string, dateExpiry int64) error {
+	// host blocks are stored and distributed by the node that blocked the host
+	bruteforce.Block(host, dateExpiry)
+	return nil
+}
+func BruteforceUnblocked(updateNodes bool, host string) error {
+	if updateNodes {
+		if err := createEventsForOtherNodes("bruteforceUnblocked", types.ClusterEventBruteforce{
+			Host: host,
+		}); err != nil {
+			return err
+		}
+	}
+	bruteforce.Unblock(host)
+	return nil
+}
 func CollectionUpdated(collectionId uuid.UUID, loginIds []int64) error {
 
 	if len(loginIds) == 0 {
@@ -94,6 +112,7 @@ func ConfigChanged(updateNodes bool, loadConfigFromDb bool, switchToMaintenance
 
 	// apply config to other areas
 	bruteforce.SetConfig()
+	login_cert.SetConfig()
 	config.ActivateLicense()
 	config.SetLogLevels()
 	return nil
@@ -153,7 +172,9 @@ func LoginDisabled(updateNodes bool, loginId int64) error {
 		}
 	}
 	WebsocketClientEvents <- types.ClusterWebsocketClientEvent{LoginId: loginId, Kick: true}
-	return nil
+
+	// roles delegated by disabled login are no longer valid
+	return informDelegationSubstitutes(loginId)
 }
 func LoginReauthorized(updateNodes bool, loginId int64) error {
 	if updateNodes {
@@ -171,6 +192,26 @@ func LoginReauthorized(updateNodes bool, loginId int64) error {
 
 	// inform client to retrieve new access cache
 	WebsocketClientEvents <- types.ClusterWebsocketClientEvent{LoginId: loginId, Renew: true}
+
+	// delegated roles depend on role assignments of delegating login
+	return informDelegationSubstitutes(loginId)
+}
+func LoginRoleDelegationChanged(updateNodes bool, loginId int64) error {
+	if updateNodes {
+		if err := createEventsForOtherNodes("loginRoleDelegationChanged", types.ClusterEventLogin{
+			LoginId: loginId,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// renew access cache
+	if err := cache.RenewAccessById(loginId); err != nil {
+		return err
+	}
+
+	// inform client about changed delegation, client retrieves new access cache
+	WebsocketClientEvents <- types.ClusterWebsocketClientEvent{LoginId: loginId, RoleDelegationChanged: true}
 	return nil
 }
 func LoginReauthorizedAll(updateNodes bool) error {
@@ -197,6 +238,30 @@ func MasterAssigned(state bool) error {
 	SchedulerRestart <- true
 	return nil
 }
+func RecordPresenceChanged(updateNodes bool, relationId uuid.UUID, recordId int64) error {
+	if updateNodes {
+		if err := createEventsForOtherNodes("recordPresenceChanged", types.ClusterEventRecordPresence{
+			RelationId: relationId,
+			RecordId:   recordId,
+		}); err != nil {
+			return err
+		}
+	}
+
+	presence, err := data.GetPresence(relationId, recordId)
+	if err != nil {
+		return err
+	}
+	WebsocketClientEvents <- types.ClusterWebsocketClientEvent{
+		LoginId: 0,
+		RecordPresence: &types.DataPresenceRecord{
+			RelationId: relationId,
+			RecordId:   recordId,
+			Presence:   presence,
+		},
+	}
+	return nil
+}
 func SchemaChangedAll(updateNodes bool, newVersion bool) error {
 	return SchemaChanged(updateNodes, newVersion, make([]uuid.UUID, 0))
 }
@@ -247,3 +312,25 @@ func TasksChanged(updateNodes bool) error {
 	SchedulerRestart <- true
 	return nil
 }
+
+// renews access of substitutes with ongoing role delegations from login
+// only local, other nodes do the same when handling the event of the delegating login
+func informDelegationSubstitutes(loginId int64) error {
+	loginIds := make([]int64, 0)
+	if err := db.Pool.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT DISTINCT login_id_substitute
+			FROM instance.login_role_delegation
+			WHERE login_id = $1
+			AND   date_ended IS NULL
+		)
+	`, loginId).Scan(&loginIds); err != nil {
+		return err
+	}
+	for _, id := range loginIds {
+		if err := LoginRoleDelegationChanged(false, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
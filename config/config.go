@@ -121,6 +121,7 @@ func SetLogLevels() {
 	log.SetLogLevel("mail", int(GetUint64("logMail")))
 	log.SetLogLevel("module", int(GetUint64("logModule")))
 	log.SetLogLevel("scheduler", int(GetUint64("logScheduler")))
+	log.SetLogLevel("scim", int(GetUint64("logScim")))
 	log.SetLogLevel("server", int(GetUint64("logServer")))
 	log.SetLogLevel("transfer", int(GetUint64("logTransfer")))
 	log.SetLogLevel("websocket", int(GetUint64("logWebsocket")))
@@ -16,25 +16,31 @@ var (
 	storeString map[string]string = make(map[string]string)
 
 	NamesString = []string{"appName", "appNameShort", "backupDir",
-		"companyColorHeader", "companyColorLogin", "companyLogo",
+		"clientCertCaBundle", "clientCertLoginMap", "companyColorHeader", "companyColorLogin", "companyLogo",
 		"companyLogoUrl", "companyName", "companyWelcome", "css",
 		"dbVersionCut", "exportPrivateKey", "iconPwa1", "iconPwa2",
-		"instanceId", "licenseFile", "publicHostName", "repoPass",
-		"repoPublicKeys", "repoUrl", "repoUser", "tokenSecret",
+		"instanceId", "licenseFile", "mfaMailBody", "mfaMailSubject",
+		"proxiesTrusted", "publicHostName", "registrationMailBody",
+		"registrationMailSubject", "registrationNotifyMail", "repoPass",
+		"repoPublicKeys", "repoUrl", "repoUser", "scimTokenHash", "tokenSecret",
 		"updateCheckUrl", "updateCheckVersion"}
 
 	NamesUint64 = []string{"backupDaily", "backupMonthly", "backupWeekly",
 		"backupCountDaily", "backupCountMonthly", "backupCountWeekly",
-		"bruteforceAttempts", "bruteforceProtection", "builderMode",
+		"bruteforceAttempts", "bruteforceBlockMinutes", "bruteforceIpv6Prefix",
+		"bruteforceProtection", "builderMode", "clientCertAuth",
 		"clusterNodeMissingAfter", "dbTimeoutCsv", "dbTimeoutDataRest",
 		"dbTimeoutDataWs", "dbTimeoutIcs", "filesKeepDaysDeleted",
 		"fileVersionsKeepCount", "fileVersionsKeepDays", "icsDaysPost",
 		"icsDaysPre", "icsDownload", "imagerThumbWidth", "logApi", "logBackup",
 		"logCache", "logCluster", "logCsv", "logImager", "logLdap", "logMail",
-		"logModule", "logServer", "logScheduler", "logTransfer", "logWebsocket",
-		"logsKeepDays", "mailTrafficKeepDays", "productionMode", "pwForceDigit",
+		"logModule", "logServer", "logScheduler", "logScim", "logTransfer", "logWebsocket",
+		"logsKeepDays", "mailTrafficKeepDays", "mfaMail", "mfaMailAccountId",
+		"mfaMailCodeMinutes", "mfaRequired", "productionMode", "pwForceDigit",
 		"pwForceLower", "pwForceSpecial", "pwForceUpper", "pwLengthMin",
+		"recycleBinKeepDays", "registrationActive", "registrationLoginTemplateId", "registrationMailAccountId",
 		"schemaTimestamp", "repoChecked", "repoFeedback", "repoSkipVerify",
+		"scimLoginTemplateId", "slowQueryExplainPercent", "slowQueryKeepDays", "slowQueryThresholdMs",
 		"tokenExpiryHours"}
 )
 
@@ -2,8 +2,10 @@ package data
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"r3/cache"
+	"r3/db"
 	"r3/handler"
 	"r3/schema"
 	"r3/types"
@@ -17,9 +19,9 @@ import (
 
 // check whether access to attribute is authorized
 // cases: getting or setting attribute values
-func authorizedAttribute(loginId int64, attributeId uuid.UUID, requestedAccess int) bool {
+func authorizedAttribute(ctx context.Context, loginId int64, attributeId uuid.UUID, requestedAccess int) bool {
 
-	access, err := cache.GetAccessById(loginId)
+	access, err := cache.GetAccessById(ctx, loginId)
 	if err != nil {
 		return false
 	}
@@ -43,9 +45,9 @@ func authorizedAttribute(loginId int64, attributeId uuid.UUID, requestedAccess i
 
 // check whether access to relation is authorized
 // cases: creating or deleting relation tupels
-func authorizedRelation(loginId int64, relationId uuid.UUID, requestedAccess int) bool {
+func authorizedRelation(ctx context.Context, loginId int64, relationId uuid.UUID, requestedAccess int) bool {
 
-	access, err := cache.GetAccessById(loginId)
+	access, err := cache.GetAccessById(ctx, loginId)
 	if err != nil {
 		return false
 	}
@@ -56,6 +58,29 @@ func authorizedRelation(loginId int64, relationId uuid.UUID, requestedAccess int
 	return false
 }
 
+// check whether access to form is authorized
+// forms are opened within their module, which is only reachable if any of its menus is accessible
+func authorizedForm(ctx context.Context, loginId int64, formId uuid.UUID) bool {
+
+	access, err := cache.GetAccessById(ctx, loginId)
+	if err != nil {
+		return false
+	}
+
+	for _, mod := range cache.ModuleIdMap {
+		if !slices.ContainsFunc(mod.Forms, func(f types.Form) bool { return f.Id == formId }) {
+			continue
+		}
+		for _, menu := range mod.Menus {
+			if access.Menu[menu.Id] == 1 {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
 // check whether a relation uses logging
 func relationUsesLogging(retentionCount pgtype.Int4, retentionDays pgtype.Int4) bool {
 	return retentionCount.Valid || retentionDays.Valid
@@ -63,9 +88,10 @@ func relationUsesLogging(retentionCount pgtype.Int4, retentionDays pgtype.Int4)
 
 // get the names of policy blacklist & whitelist functions (empty strings if no functions are available)
 // functions are available if a relation policy fits the given logins role memberships for the given action
-func getPolicyFunctionNames(loginId int64, policies []types.RelationPolicy, action string) (string, string, error) {
+func getPolicyFunctionNames(ctx context.Context, loginId int64, policies []types.RelationPolicy,
+	action string) (string, string, error) {
 
-	access, err := cache.GetAccessById(loginId)
+	access, err := cache.GetAccessById(ctx, loginId)
 	if err != nil {
 		return "", "", err
 	}
@@ -138,7 +164,7 @@ func getPolicyValues_tx(ctx context.Context, tx pgx.Tx, loginId int64,
 		return idsBlacklist, idsWhitelist, false, nil
 	}
 
-	fncNameBlacklist, fncNameWhitelist, err := getPolicyFunctionNames(loginId, rel.Policies, action)
+	fncNameBlacklist, fncNameWhitelist, err := getPolicyFunctionNames(ctx, loginId, rel.Policies, action)
 	if err != nil {
 		return idsBlacklist, idsWhitelist, false, err
 	}
@@ -157,7 +183,7 @@ func getPolicyValues_tx(ctx context.Context, tx pgx.Tx, loginId int64,
 }
 
 // get applicable policy filter (e. g. WHERE clause) for data call
-func getPolicyFilter(loginId int64, action string, tableAlias string,
+func getPolicyFilter(ctx context.Context, loginId int64, action string, tableAlias string,
 	policies []types.RelationPolicy) (string, error) {
 
 	if len(policies) == 0 {
@@ -166,7 +192,7 @@ func getPolicyFilter(loginId int64, action string, tableAlias string,
 
 	clauses := []string{}
 
-	fncNameBlacklist, fncNameWhitelist, err := getPolicyFunctionNames(loginId, policies, action)
+	fncNameBlacklist, fncNameWhitelist, err := getPolicyFunctionNames(ctx, loginId, policies, action)
 	if err != nil {
 		return "", err
 	}
@@ -188,6 +214,105 @@ func getPolicyFilter(loginId int64, action string, tableAlias string,
 	return fmt.Sprintf("\nAND %s", strings.Join(clauses, "\nAND ")), nil
 }
 
+// returns filter to hide records that were moved to the recycle bin, if relation uses soft delete
+func getSoftDeleteFilter(tableAlias string, rel types.Relation) string {
+	if !rel.SoftDelete {
+		return ""
+	}
+	return fmt.Sprintf("\nAND \"%s\".\"%s\" IS NULL", tableAlias, schema.SoftDeleteName)
+}
+
+// checks whether record exists and passes the relation policies for all given actions
+// records in the recycle bin are treated as not existing
+func authorizedRecord_tx(ctx context.Context, tx pgx.Tx, loginId int64,
+	relationId uuid.UUID, recordId int64, actions []string) (bool, error) {
+
+	cache.Schema_mx.RLock()
+	rel, exists := cache.RelationIdMap[relationId]
+	if !exists {
+		cache.Schema_mx.RUnlock()
+		return false, handler.ErrSchemaUnknownRelation(relationId)
+	}
+	mod, exists := cache.ModuleIdMap[rel.ModuleId]
+	if !exists {
+		cache.Schema_mx.RUnlock()
+		return false, handler.ErrSchemaUnknownModule(rel.ModuleId)
+	}
+
+	tableAlias := "t"
+	policyFilters := make([]string, 0)
+	for _, action := range actions {
+		policyFilter, err := getPolicyFilter(ctx, loginId, action, tableAlias, rel.Policies)
+		if err != nil {
+			cache.Schema_mx.RUnlock()
+			return false, err
+		}
+		policyFilters = append(policyFilters, policyFilter)
+	}
+	cache.Schema_mx.RUnlock()
+
+	var authorized bool
+	err := tx.QueryRow(ctx, getAuthorizedRecordQuery(mod.Name, rel, tableAlias,
+		policyFilters), recordId).Scan(&authorized)
+
+	return authorized, err
+}
+
+// returns IDs of records the login may select, filtered by relation policies
+// without a filtering policy IDs are returned as is, otherwise IDs of records that do not exist (anymore) are removed
+func GetRecordIdsSelectable(ctx context.Context, loginId int64, relationId uuid.UUID,
+	recordIds []int64) ([]int64, error) {
+
+	cache.Schema_mx.RLock()
+	rel, exists := cache.RelationIdMap[relationId]
+	if !exists {
+		cache.Schema_mx.RUnlock()
+		return recordIds, handler.ErrSchemaUnknownRelation(relationId)
+	}
+	mod, exists := cache.ModuleIdMap[rel.ModuleId]
+	if !exists {
+		cache.Schema_mx.RUnlock()
+		return recordIds, handler.ErrSchemaUnknownModule(rel.ModuleId)
+	}
+
+	tableAlias := "t"
+	policyFilter, err := getPolicyFilter(ctx, loginId, "select", tableAlias, rel.Policies)
+	cache.Schema_mx.RUnlock()
+
+	if err != nil || policyFilter == "" || len(recordIds) == 0 {
+		return recordIds, err
+	}
+
+	recordIdsSelectable := make([]int64, 0)
+	err = db.Pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT ARRAY(
+			SELECT "%s"."%s"
+			FROM "%s"."%s" AS "%s"
+			WHERE "%s"."%s" = ANY($1)
+			%s
+		)
+	`, tableAlias, schema.PkName, mod.Name, rel.Name, tableAlias,
+		tableAlias, schema.PkName, policyFilter), recordIds).Scan(&recordIdsSelectable)
+
+	return recordIdsSelectable, err
+}
+
+// returns query checking whether record exists with the given policy filters applied
+func getAuthorizedRecordQuery(moduleName string, rel types.Relation, tableAlias string,
+	policyFilters []string) string {
+
+	return fmt.Sprintf(`
+		SELECT EXISTS(
+			SELECT 1
+			FROM "%s"."%s" AS "%s"
+			WHERE "%s"."%s" = $1
+			%s
+			%s
+		)
+	`, moduleName, rel.Name, tableAlias, tableAlias, schema.PkName,
+		strings.Join(policyFilters, ""), getSoftDeleteFilter(tableAlias, rel))
+}
+
 func getFunctionName(pgFunctionId uuid.UUID) (string, error) {
 	fnc, exists := cache.PgFunctionIdMap[pgFunctionId]
 	if !exists {
@@ -199,3 +324,15 @@ func getFunctionName(pgFunctionId uuid.UUID) (string, error) {
 	}
 	return fmt.Sprintf(`"%s"."%s"`, mod.Name, fnc.Name), nil
 }
+
+// returns value in the same form as values decoded from JSON (change logs, requests)
+// allows for comparison of values from different sources
+func getValueComparable(value interface{}) (interface{}, error) {
+	var out interface{}
+	valueJson, err := json.Marshal(value)
+	if err != nil {
+		return out, err
+	}
+	err = json.Unmarshal(valueJson, &out)
+	return out, err
+}
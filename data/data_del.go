@@ -16,7 +16,7 @@ func Del_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 	recordId int64, loginId int64) error {
 
 	// check for authorized access, DELETE(3) for DEL
-	if !authorizedRelation(loginId, relationId, 3) {
+	if !authorizedRelation(ctx, loginId, relationId, 3) {
 		return errors.New(handler.ErrUnauthorized)
 	}
 
@@ -28,6 +28,11 @@ func Del_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 		return handler.ErrSchemaUnknownRelation(relationId)
 	}
 
+	// check for exclusive edit lock by other login
+	if err := checkPresenceLock_tx(ctx, tx, relationId, recordId, loginId); err != nil {
+		return err
+	}
+
 	// check for protected preset record
 	for _, preset := range rel.Presets {
 		if preset.Protected && cache.GetPresetRecordId(preset.Id) == recordId {
@@ -42,17 +47,33 @@ func Del_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 
 	// get policy filter if applicable
 	tableAlias := "t"
-	policyFilter, err := getPolicyFilter(loginId, "delete", tableAlias, rel.Policies)
+	policyFilter, err := getPolicyFilter(ctx, loginId, "delete", tableAlias, rel.Policies)
+	if err != nil {
+		return err
+	}
+
+	// soft delete, record is kept in recycle bin
+	if rel.SoftDelete {
+		return delSoft_tx(ctx, tx, mod, rel, recordId, loginId, tableAlias, policyFilter)
+	}
+
+	// collect last known values of record and its cascaded records for data change logs
+	logs, err := collectValuesDeleted_tx(ctx, tx, rel.Id, []int64{recordId},
+		make(map[uuid.UUID][]int64))
+
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(ctx, fmt.Sprintf(`
+	res, err := tx.Exec(ctx, fmt.Sprintf(`
 		DELETE FROM "%s"."%s" AS "%s"
 		WHERE "%s"."%s" = $1
 		%s
 	`, mod.Name, rel.Name, tableAlias, tableAlias,
 		schema.PkName, policyFilter), recordId)
 
-	return err
+	if err != nil || res.RowsAffected() == 0 {
+		return err
+	}
+	return setLogsDeleted_tx(ctx, tx, logs, loginId)
 }
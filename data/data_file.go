@@ -32,7 +32,7 @@ var (
 	regexRenameSchema = regexp.MustCompile(`_\((\d+)\)`)
 )
 
-func MayAccessFile(loginId int64, attributeId uuid.UUID) error {
+func MayAccessFile(ctx context.Context, loginId int64, attributeId uuid.UUID) error {
 	cache.Schema_mx.RLock()
 	defer cache.Schema_mx.RUnlock()
 
@@ -42,7 +42,7 @@ func MayAccessFile(loginId int64, attributeId uuid.UUID) error {
 	}
 
 	// check for authorized access, READ(1) for GET
-	if !authorizedAttribute(loginId, attributeId, 1) {
+	if !authorizedAttribute(ctx, loginId, attributeId, 1) {
 		return errors.New(handler.ErrUnauthorized)
 	}
 	return nil
@@ -62,7 +62,7 @@ func GetFilePathVersion(fileId uuid.UUID, version int64) string {
 }
 
 // attempts to store file upload
-func SetFile(loginId int64, attributeId uuid.UUID, fileId uuid.UUID,
+func SetFile(ctx context.Context, loginId int64, attributeId uuid.UUID, fileId uuid.UUID,
 	part *multipart.Part, isNewFile bool) error {
 
 	var err error
@@ -76,7 +76,7 @@ func SetFile(loginId int64, attributeId uuid.UUID, fileId uuid.UUID,
 	cache.Schema_mx.RUnlock()
 
 	// check for authorized access, WRITE(2) for SET
-	if !authorizedAttribute(loginId, attributeId, 2) {
+	if !authorizedAttribute(ctx, loginId, attributeId, 2) {
 		return errors.New(handler.ErrUnauthorized)
 	}
 
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"context"
 	"fmt"
 	"r3/db"
 	"r3/schema"
@@ -10,16 +11,16 @@ import (
 	"github.com/gofrs/uuid"
 )
 
-func CopyFiles(loginId int64, srcAttributeId uuid.UUID, srcFileIds []uuid.UUID,
-	srcRecordId int64, dstAttributeId uuid.UUID) ([]types.DataGetValueFile, error) {
+func CopyFiles(ctx context.Context, loginId int64, srcAttributeId uuid.UUID,
+	srcFileIds []uuid.UUID, srcRecordId int64, dstAttributeId uuid.UUID) ([]types.DataGetValueFile, error) {
 
 	files := make([]types.DataGetValueFile, 0)
 
 	// check access to source/destination attribute
-	if err := MayAccessFile(loginId, srcAttributeId); err != nil {
+	if err := MayAccessFile(ctx, loginId, srcAttributeId); err != nil {
 		return files, err
 	}
-	if err := MayAccessFile(loginId, dstAttributeId); err != nil {
+	if err := MayAccessFile(ctx, loginId, dstAttributeId); err != nil {
 		return files, err
 	}
 
@@ -0,0 +1,166 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"r3/handler"
+	"r3/types"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// geo points are stored as native point (x = longitude, y = latitude)
+// values are exchanged as GeoJSON point geometries
+
+// mean earth radius in meters, must match radius used by instance.geo_distance()
+const geoEarthRadius float64 = 6371008.8
+
+// meters per degree of latitude, used to narrow down radius filters via bounding box
+const geoMetersPerDegree float64 = geoEarthRadius * math.Pi / 180
+
+// returns GeoJSON point for retrieved point value
+func getGeoPoint(p pgtype.Point) interface{} {
+	if !p.Valid {
+		return nil
+	}
+	return types.DataGeoPoint{
+		Type:        "Point",
+		Coordinates: [2]float64{p.P.X, p.P.Y},
+	}
+}
+
+// returns point to store and GeoJSON point for geo point attribute value
+// accepts GeoJSON point geometries, [longitude,latitude] arrays and 'longitude,latitude' strings
+func getValuePoint(atr types.Attribute, value interface{}) (interface{}, interface{}, error) {
+	if value == nil || value == "" {
+		return nil, nil, nil
+	}
+	if v, ok := value.(map[string]interface{}); ok {
+		value = v["coordinates"]
+	}
+	coords, err := getGeoNumbers(value, 2)
+	if err != nil || !isGeoCoordinateValid(coords[0], coords[1]) {
+		return nil, nil, handler.CreateErrCodeWithArgs("APP", handler.ErrCodeAppInvalidGeoPoint,
+			map[string]string{"ATR_ID": atr.Id.String()})
+	}
+	return pgtype.Point{P: pgtype.Vec2{X: coords[0], Y: coords[1]}, Valid: true},
+		types.DataGeoPoint{Type: "Point", Coordinates: [2]float64{coords[0], coords[1]}}, nil
+}
+
+// returns fixed number of float values from array or comma separated string
+// filter values for geo operators are given this way:
+// WITHIN RADIUS: longitude, latitude, radius in meters
+// WITHIN BOX:    min. longitude, min. latitude, max. longitude, max. latitude
+func getGeoNumbers(value interface{}, count int) ([]float64, error) {
+	numbers := make([]float64, 0)
+
+	switch v := value.(type) {
+	case pgtype.Text:
+		return getGeoNumbers(v.String, count)
+	case string:
+		if strings.HasPrefix(strings.TrimSpace(v), "[") {
+			var values []interface{}
+			if err := json.Unmarshal([]byte(v), &values); err != nil {
+				return numbers, err
+			}
+			return getGeoNumbers(values, count)
+		}
+		for _, s := range strings.Split(v, ",") {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return numbers, err
+			}
+			numbers = append(numbers, f)
+		}
+	case []interface{}:
+		for _, n := range v {
+			f, ok := n.(float64)
+			if !ok {
+				return numbers, fmt.Errorf("invalid geo value '%v'", n)
+			}
+			numbers = append(numbers, f)
+		}
+	case []float64:
+		numbers = v
+	}
+
+	if len(numbers) != count {
+		return numbers, fmt.Errorf("geo value requires %d numbers, got %d", count, len(numbers))
+	}
+	for _, n := range numbers {
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return numbers, fmt.Errorf("invalid geo value '%v'", n)
+		}
+	}
+	return numbers, nil
+}
+
+// returns SQL code for geo operator filter on point attribute code
+// filter values are added as query arguments
+func getGeoFilterCode(code string, operator string, value interface{},
+	queryArgs *[]interface{}, queryCountArgs *[]interface{}) (string, error) {
+
+	var addArgs = func(values ...float64) []string {
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			*queryArgs = append(*queryArgs, v)
+			if queryCountArgs != nil {
+				*queryCountArgs = append(*queryCountArgs, v)
+			}
+			placeholders[i] = fmt.Sprintf("$%d::DOUBLE PRECISION", len(*queryArgs))
+		}
+		return placeholders
+	}
+
+	switch operator {
+	case "WITHIN BOX":
+		n, err := getGeoNumbers(value, 4)
+		if err != nil {
+			return "", err
+		}
+		if !isGeoCoordinateValid(n[0], n[1]) || !isGeoCoordinateValid(n[2], n[3]) {
+			return "", fmt.Errorf("invalid geo bounding box")
+		}
+		a := addArgs(n...)
+		return fmt.Sprintf("%s <@ BOX(POINT(%s,%s),POINT(%s,%s))",
+			code, a[0], a[1], a[2], a[3]), nil
+
+	case "WITHIN RADIUS":
+		n, err := getGeoNumbers(value, 3)
+		if err != nil {
+			return "", err
+		}
+		if !isGeoCoordinateValid(n[0], n[1]) || n[2] < 0 {
+			return "", fmt.Errorf("invalid geo radius")
+		}
+		a := addArgs(n...)
+		distance := fmt.Sprintf("instance.geo_distance(%s,POINT(%s,%s)) <= %s",
+			code, a[0], a[1], a[2])
+
+		// narrow down results via bounding box first, can use index on point attribute
+		// not possible close to poles or if box would cross the antimeridian
+		deltaLat := n[2] / geoMetersPerDegree
+		cosLat := math.Cos((math.Abs(n[1]) + deltaLat) * math.Pi / 180)
+		if n[1]-deltaLat < -90 || n[1]+deltaLat > 90 || cosLat <= 0.01 {
+			return fmt.Sprintf("(%s)", distance), nil
+		}
+		deltaLon := deltaLat / cosLat
+		if n[0]-deltaLon < -180 || n[0]+deltaLon > 180 {
+			return fmt.Sprintf("(%s)", distance), nil
+		}
+		b := addArgs(n[0]-deltaLon, n[1]-deltaLat, n[0]+deltaLon, n[1]+deltaLat)
+		return fmt.Sprintf("(%s <@ BOX(POINT(%s,%s),POINT(%s,%s)) AND %s)",
+			code, b[0], b[1], b[2], b[3], distance), nil
+	}
+	return "", fmt.Errorf("unknown geo operator '%s'", operator)
+}
+
+func isGeoCoordinateValid(lon float64, lat float64) bool {
+	return lon >= -180 && lon <= 180 && lat >= -90 && lat <= 90
+}
+func isGeoOperator(operator string) bool {
+	return operator == "WITHIN BOX" || operator == "WITHIN RADIUS"
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestGetGeoNumbers(t *testing.T) {
+	tests := []struct {
+		value   interface{}
+		count   int
+		want    []float64
+		wantErr bool
+	}{
+		{"8.5,47.3", 2, []float64{8.5, 47.3}, false},
+		{" 8.5 , 47.3 , 1000 ", 3, []float64{8.5, 47.3, 1000}, false},
+		{"[8.5,47.3,9,48]", 4, []float64{8.5, 47.3, 9, 48}, false},
+		{pgtype.Text{String: "8.5,47.3", Valid: true}, 2, []float64{8.5, 47.3}, false},
+		{[]interface{}{8.5, 47.3}, 2, []float64{8.5, 47.3}, false},
+		{[]float64{8.5, 47.3}, 2, []float64{8.5, 47.3}, false},
+		{"8.5,47.3", 3, nil, true},
+		{"8.5,abc", 2, nil, true},
+		{"[8.5,\"47.3\"]", 2, nil, true},
+		{"[8.5,47.3", 2, nil, true},
+		{"NaN,47.3", 2, nil, true},
+		{"8.5,+Inf", 2, nil, true},
+		{12, 2, nil, true},
+	}
+	for _, test := range tests {
+		got, err := getGeoNumbers(test.value, test.count)
+		if (err != nil) != test.wantErr {
+			t.Errorf("getGeoNumbers(%v, %d) error = %v, want error %v", test.value, test.count, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("getGeoNumbers(%v, %d) = %v, want %v", test.value, test.count, got, test.want)
+		}
+	}
+}
+
+func TestGetGeoFilterCode(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    interface{}
+		wantBox  bool // bounding box is used to narrow down results
+		wantArgs int
+		wantErr  bool
+	}{
+		{"WITHIN BOX", "8,47,9,48", true, 4, false},
+		{"WITHIN BOX", "8,47,9,95", false, 0, true},
+		{"WITHIN BOX", "8,47,9", false, 0, true},
+		{"WITHIN RADIUS", "8.5,47.3,1000", true, 7, false},
+		{"WITHIN RADIUS", "8.5,47.3,0", true, 7, false},
+		{"WITHIN RADIUS", "0,89.999,1000", false, 3, false},    // box would cross pole
+		{"WITHIN RADIUS", "179.999,0,1000", false, 3, false},   // box would cross antimeridian
+		{"WITHIN RADIUS", "-179.999,10,1000", false, 3, false}, // box would cross antimeridian
+		{"WITHIN RADIUS", "8.5,47.3,-1", false, 0, true},
+		{"WITHIN RADIUS", "200,47.3,1000", false, 0, true},
+		{"WITHIN", "8.5,47.3,1000", false, 0, true},
+	}
+	for _, test := range tests {
+		queryArgs := make([]interface{}, 0)
+		queryCountArgs := make([]interface{}, 0)
+		code, err := getGeoFilterCode(`"r0"."point"`, test.operator, test.value, &queryArgs, &queryCountArgs)
+		if (err != nil) != test.wantErr {
+			t.Errorf("getGeoFilterCode(%s, %v) error = %v, want error %v", test.operator, test.value, err, test.wantErr)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if len(queryArgs) != test.wantArgs || !reflect.DeepEqual(queryArgs, queryCountArgs) {
+			t.Errorf("getGeoFilterCode(%s, %v) args = %v, count args = %v, want %d args",
+				test.operator, test.value, queryArgs, queryCountArgs, test.wantArgs)
+		}
+		if strings.Contains(code, "<@ BOX(") != test.wantBox {
+			t.Errorf("getGeoFilterCode(%s, %v) = %s, want bounding box %v", test.operator, test.value, code, test.wantBox)
+		}
+	}
+}
+
+func TestGetGeoFilterCodeRadiusBox(t *testing.T) {
+	// bounding box must include all points within radius, as calculated by instance.geo_distance()
+	lon, lat, radius := 8.5, 47.3, 5000.0
+
+	queryArgs := make([]interface{}, 0)
+	if _, err := getGeoFilterCode(`"r0"."point"`, "WITHIN RADIUS",
+		[]float64{lon, lat, radius}, &queryArgs, nil); err != nil {
+
+		t.Fatal(err)
+	}
+	if len(queryArgs) != 7 {
+		t.Fatalf("expected 7 arguments, got %d", len(queryArgs))
+	}
+	lonMin, latMin := queryArgs[3].(float64), queryArgs[4].(float64)
+	lonMax, latMax := queryArgs[5].(float64), queryArgs[6].(float64)
+
+	// points just inside the radius, due north/south/east/west
+	deltaLat := radius * 0.9999 / geoEarthRadius * 180 / math.Pi
+	if lat+deltaLat > latMax || lat-deltaLat < latMin {
+		t.Errorf("latitude range %v-%v excludes points %v within radius", latMin, latMax, deltaLat)
+	}
+	deltaLon := 2 * math.Asin(math.Sin(radius*0.9999/geoEarthRadius/2)/math.Cos(lat*math.Pi/180)) * 180 / math.Pi
+	if lon+deltaLon > lonMax || lon-deltaLon < lonMin {
+		t.Errorf("longitude range %v-%v excludes points %v within radius", lonMin, lonMax, deltaLon)
+	}
+}
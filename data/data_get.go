@@ -14,13 +14,15 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-var regexRelId = regexp.MustCompile(`^\_r(\d+)id`) // finds: _r3id
+var regexRelId = regexp.MustCompile(`^\_r(\d+)id`)      // finds: _r3id
+var regexRelVersion = regexp.MustCompile(`^\_r(\d+)v$`) // finds: _r3v
 
 // get data
 // updates SQL query pointer value (for error logging), returns data rows + total count
@@ -39,7 +41,7 @@ func Get_tx(ctx context.Context, tx pgx.Tx, data types.DataGet, loginId int64,
 	queryCountArgs := make([]interface{}, 0)    // SQL query arguments for count (potentially less, no expressions besides COUNT)
 
 	// prepare SQL query for data GET request
-	*query, queryCount, err = prepareQuery(data, indexRelationIds,
+	*query, queryCount, err = prepareQuery(ctx, data, indexRelationIds,
 		&queryArgs, &queryCountArgs, loginId, 0)
 
 	if err != nil {
@@ -47,6 +49,7 @@ func Get_tx(ctx context.Context, tx pgx.Tx, data types.DataGet, loginId int64,
 	}
 
 	// execute SQL query
+	timeStart := time.Now()
 	rows, err := tx.Query(ctx, *query, queryArgs...)
 	if err != nil {
 		return results, 0, err
@@ -61,11 +64,20 @@ func Get_tx(ctx context.Context, tx pgx.Tx, data types.DataGet, loginId int64,
 
 		indexRecordIds := make(map[int]interface{}) // ID for each relation tupel by index
 		indexRecordEncKeys := make(map[int]string)  // encrypted key for each relation tupel by index
+		indexRecordVersions := make(map[int]int64)  // version for each relation tupel by index
 		values := make([]interface{}, 0)            // final values for selected attributes
 
 		// collect values for expressions
+		// geo points are returned as GeoJSON, intervals as number of seconds
 		for i := 0; i < len(data.Expressions); i++ {
-			values = append(values, valuesAll[i])
+			switch v := valuesAll[i].(type) {
+			case pgtype.Point:
+				values = append(values, getGeoPoint(v))
+			case pgtype.Interval:
+				values = append(values, getIntervalSeconds(v))
+			default:
+				values = append(values, v)
+			}
 		}
 
 		// collect relation tupel IDs
@@ -82,21 +94,36 @@ func Get_tx(ctx context.Context, tx pgx.Tx, data types.DataGet, loginId int64,
 					return results, 0, err
 				}
 				indexRecordIds[relIndex] = valuesAll[i]
+				continue
+			}
+
+			matches = regexRelVersion.FindStringSubmatch(string(columns[i].Name))
+
+			if len(matches) == 2 && valuesAll[i] != nil {
+
+				// column provides relation record version
+				relIndex, err := strconv.Atoi(matches[1])
+				if err != nil {
+					return results, 0, err
+				}
+				indexRecordVersions[relIndex] = valuesAll[i].(int64)
 			}
 		}
 
 		results = append(results, types.DataGetResult{
-			IndexRecordIds:     indexRecordIds,
-			IndexRecordEncKeys: indexRecordEncKeys,
-			IndexesPermNoDel:   make([]int, 0),
-			IndexesPermNoSet:   make([]int, 0),
-			Values:             values,
+			IndexRecordIds:      indexRecordIds,
+			IndexRecordEncKeys:  indexRecordEncKeys,
+			IndexRecordVersions: indexRecordVersions,
+			IndexesPermNoDel:    make([]int, 0),
+			IndexesPermNoSet:    make([]int, 0),
+			Values:              values,
 		})
 	}
 	if err := rows.Err(); err != nil {
 		return results, 0, err
 	}
 	rows.Close()
+	captureSlowQuery(data, loginId, *query, queryArgs, time.Since(timeStart))
 
 	// resolve relation policy access permissions for retrieved result records
 	// DEL/SET actions only; records not allowed to GET are not retrieved as results
@@ -261,9 +288,11 @@ func Get_tx(ctx context.Context, tx pgx.Tx, data types.DataGet, loginId int64,
 
 	if data.Limit != 0 && (count >= data.Limit || data.Offset != 0) {
 		// defined limit has been reached or offset was used, get total count
+		timeStart = time.Now()
 		if err := tx.QueryRow(ctx, queryCount, queryCountArgs...).Scan(&count); err != nil {
 			return results, 0, err
 		}
+		captureSlowQuery(data, loginId, queryCount, queryCountArgs, time.Since(timeStart))
 	}
 	return results, count, nil
 }
@@ -271,14 +300,14 @@ func Get_tx(ctx context.Context, tx pgx.Tx, data types.DataGet, loginId int64,
 // build SQL call from data GET request
 // also used for sub queries, a nesting level is included for separation (0 = main query)
 // returns data + count SQL query strings
-func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
+func prepareQuery(ctx context.Context, data types.DataGet, indexRelationIds map[int]uuid.UUID,
 	queryArgs *[]interface{}, queryCountArgs *[]interface{}, loginId int64,
 	nestingLevel int) (string, string, error) {
 
 	// check for authorized access, READ(1) for GET
 	for _, expr := range data.Expressions {
 		if expr.AttributeId.Valid &&
-			!authorizedAttribute(loginId, expr.AttributeId.Bytes, 1) {
+			!authorizedAttribute(ctx, loginId, expr.AttributeId.Bytes, 1) {
 
 			return "", "", errors.New(handler.ErrUnauthorized)
 		}
@@ -312,7 +341,7 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 			continue
 		}
 
-		if err := addJoin(indexRelationIds, join, &inJoin, loginId, nestingLevel); err != nil {
+		if err := addJoin(ctx, indexRelationIds, join, &inJoin, loginId, nestingLevel); err != nil {
 			return "", "", err
 		}
 	}
@@ -333,7 +362,7 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 			filter.Side1.Brackets++
 		}
 
-		if err := addWhere(filter, queryArgs, queryCountArgs,
+		if err := addWhere(ctx, filter, queryArgs, queryCountArgs,
 			loginId, &inWhere, nestingLevel); err != nil {
 
 			return "", "", err
@@ -341,7 +370,7 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 	}
 
 	// add filter for base relation policy if applicable
-	policyFilter, err := getPolicyFilter(loginId, "select",
+	policyFilter, err := getPolicyFilter(ctx, loginId, "select",
 		getRelationCode(data.IndexSource, nestingLevel), rel.Policies)
 
 	if err != nil {
@@ -351,6 +380,11 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 		inWhere = append(inWhere, policyFilter)
 	}
 
+	// hide deleted records of base relation
+	if softDeleteFilter := getSoftDeleteFilter(relCode, rel); softDeleteFilter != "" {
+		inWhere = append(inWhere, softDeleteFilter)
+	}
+
 	// add filters to query, replacing first AND with WHERE
 	queryWhere := strings.Replace(strings.Join(inWhere, ""), "AND", "WHERE", 1)
 
@@ -362,7 +396,7 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 		// option: return NULL
 		if expr.ReturnNull {
 			inSelect = append(inSelect, data_sql.GetExpression(
-				expr, "null", data_sql.GetExpressionAlias(pos)))
+				expr, "null", data_sql.GetExpressionAlias(pos), ""))
 
 			continue
 		}
@@ -378,7 +412,7 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 			}
 			indexRelationIdsSub := make(map[int]uuid.UUID)
 
-			subQuery, _, err := prepareQuery(expr.Query, indexRelationIdsSub,
+			subQuery, _, err := prepareQuery(ctx, expr.Query, indexRelationIdsSub,
 				queryArgs, queryCountArgsOptional, loginId, nestingLevel+1)
 
 			if err != nil {
@@ -386,7 +420,7 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 			}
 
 			inSelect = append(inSelect, data_sql.GetExpression(
-				expr, subQuery, data_sql.GetExpressionAlias(pos)))
+				expr, subQuery, data_sql.GetExpressionAlias(pos), ""))
 
 			continue
 		}
@@ -419,6 +453,13 @@ func prepareQuery(data types.DataGet, indexRelationIds map[int]uuid.UUID,
 				getRelationCode(index, nestingLevel),
 				schema.PkName,
 				getTupelIdCode(index, nestingLevel)))
+
+			// record version, changes with every update of the record (not available with aggregation)
+			if data.GetVersion && len(mapIndex_agg) == 0 {
+				inSelect = append(inSelect, fmt.Sprintf(`"%s".xmin::TEXT::BIGINT AS %s`,
+					getRelationCode(index, nestingLevel),
+					getTupelVersionCode(index, nestingLevel)))
+			}
 		}
 	}
 
@@ -538,9 +579,29 @@ func addSelect(exprPos int, expr types.DataGetExpression,
 
 	if !expr.OutsideIn {
 		// attribute is from index relation
-		*inSelect = append(*inSelect, data_sql.GetExpression(
-			expr, getAttributeCode(relCode, atr.Name), alias))
+		code := getAttributeCode(relCode, atr.Name)
 
+		if expr.JsonPath != "" {
+			if !schema.IsContentJson(atr.Content) {
+				return fmt.Errorf("JSON path requires JSONB attribute")
+			}
+			var err error
+			code, err = data_sql.GetJsonPathCode(code, expr.JsonPath, false)
+			if err != nil {
+				return err
+			}
+		}
+		if len(expr.GeoDistanceTo) != 0 {
+			if !schema.IsContentPoint(atr.Content) {
+				return fmt.Errorf("geo distance requires geo point attribute")
+			}
+			var err error
+			code, err = data_sql.GetGeoDistanceCode(code, expr.GeoDistanceTo)
+			if err != nil {
+				return err
+			}
+		}
+		*inSelect = append(*inSelect, data_sql.GetExpression(expr, code, alias, atr.Content))
 		return nil
 	}
 
@@ -563,15 +624,8 @@ func addSelect(exprPos int, expr types.DataGetExpression,
 		}
 
 		// from other relation, collect tupel IDs in relationship with given index tupel
-		*inSelect = append(*inSelect, fmt.Sprintf(`(
-			SELECT %s
-			FROM "%s"."%s"
-			WHERE "%s"."%s" = "%s"."%s"
-		) AS %s`,
-			selectExpr,
-			shipMod.Name, shipRel.Name,
-			shipRel.Name, atr.Name, relCode, schema.PkName,
-			alias))
+		*inSelect = append(*inSelect, getOutsideInSelect(selectExpr, shipMod.Name,
+			shipRel, atr.Name, relCode, alias))
 
 	} else {
 		shipAtrNm, exists := cache.AttributeIdMap[expr.AttributeIdNm.Bytes]
@@ -580,20 +634,30 @@ func addSelect(exprPos int, expr types.DataGetExpression,
 		}
 
 		// from other relation, collect tupel IDs from n:m relationship attribute
-		*inSelect = append(*inSelect, fmt.Sprintf(`(
-			SELECT JSON_AGG("%s")
-			FROM "%s"."%s"
-			WHERE "%s"."%s" = "%s"."%s"
-		) AS %s`,
-			shipAtrNm.Name,
-			shipMod.Name, shipRel.Name,
-			shipRel.Name, atr.Name, relCode, schema.PkName,
-			alias))
+		*inSelect = append(*inSelect, getOutsideInSelect(fmt.Sprintf(`JSON_AGG("%s")`, shipAtrNm.Name),
+			shipMod.Name, shipRel, atr.Name, relCode, alias))
 	}
 	return nil
 }
 
-func addJoin(indexRelationIds map[int]uuid.UUID, join types.DataGetJoin,
+// returns sub query collecting values from other relation, which references index tupel via relationship attribute
+// records of other relation in the recycle bin are not included
+func getOutsideInSelect(selectExpr string, shipModName string, shipRel types.Relation,
+	shipAtrName string, relCode string, alias string) string {
+
+	return fmt.Sprintf(`(
+			SELECT %s
+			FROM "%s"."%s"
+			WHERE "%s"."%s" = "%s"."%s"%s
+		) AS %s`,
+		selectExpr,
+		shipModName, shipRel.Name,
+		shipRel.Name, shipAtrName, relCode, schema.PkName,
+		getSoftDeleteFilter(shipRel.Name, shipRel),
+		alias)
+}
+
+func addJoin(ctx context.Context, indexRelationIds map[int]uuid.UUID, join types.DataGetJoin,
 	inJoin *[]string, loginId int64, nestingLevel int) error {
 
 	// check join attribute
@@ -640,24 +704,24 @@ func addJoin(indexRelationIds map[int]uuid.UUID, join types.DataGetJoin,
 	}
 
 	// apply filter policy to JOIN if applicable
-	policyFilter, err := getPolicyFilter(loginId, "select",
+	policyFilter, err := getPolicyFilter(ctx, loginId, "select",
 		getRelationCode(join.Index, nestingLevel), relTarget.Policies)
 
 	if err != nil {
 		return err
 	}
 
-	*inJoin = append(*inJoin, fmt.Sprintf("\n"+`%s JOIN "%s"."%s" AS "%s" ON "%s"."%s" = "%s"."%s" %s`,
+	*inJoin = append(*inJoin, fmt.Sprintf("\n"+`%s JOIN "%s"."%s" AS "%s" ON "%s"."%s" = "%s"."%s" %s %s`,
 		join.Connector, modTarget.Name, relTarget.Name, relCodeTarget,
 		relCodeFrom, atr.Name,
 		relCodeTo, schema.PkName,
-		policyFilter))
+		policyFilter, getSoftDeleteFilter(relCodeTarget, relTarget)))
 
 	return nil
 }
 
 // parses filters to generate query lines and arguments
-func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
+func addWhere(ctx context.Context, filter types.DataGetFilter, queryArgs *[]interface{},
 	queryCountArgs *[]interface{}, loginId int64, inWhere *[]string,
 	nestingLevel int) error {
 
@@ -672,15 +736,24 @@ func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
 	ftsActive := filter.Side0.FtsDict.Valid || filter.Side1.FtsDict.Valid
 	isNullOp := isNullOperator(filter.Operator)
 
+	// check for money comparison, currencies are compared if known on both sides
+	var isMoneySide = func(s types.DataGetFilterSide) bool {
+		atr, exists := cache.AttributeIdMap[s.AttributeId.Bytes]
+		return s.AttributeId.Valid && exists && schema.IsContentMoney(atr.Content) && s.JsonPath == ""
+	}
+	isMoneyComp := !ftsActive && !isNullOp && !isLikeOperator(filter.Operator) &&
+		!isArrayOperator(filter.Operator) && (isMoneySide(filter.Side0) || isMoneySide(filter.Side1))
+
 	// define comparisons
-	var getComp = func(s types.DataGetFilterSide, comp *string) error {
+	// currency is filled for money comparisons with money attribute or value with currency
+	var getComp = func(s types.DataGetFilterSide, comp *string, currency *string) error {
 		var isQuery = s.Query.RelationId != uuid.Nil
 
 		// sub query filter
 		if isQuery {
 			indexRelationIdsSub := make(map[int]uuid.UUID)
 
-			subQuery, _, err := prepareQuery(s.Query, indexRelationIdsSub,
+			subQuery, _, err := prepareQuery(ctx, s.Query, indexRelationIdsSub,
 				queryArgs, queryCountArgs, loginId, nestingLevel+1)
 
 			if err != nil {
@@ -699,6 +772,18 @@ func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
 			}
 			*comp = getAttributeCode(getRelationCode(s.AttributeIndex, s.AttributeNested), atr.Name)
 
+			// compare value inside JSONB attribute
+			if s.JsonPath != "" {
+				if !schema.IsContentJson(atr.Content) {
+					return fmt.Errorf("JSON path requires JSONB attribute")
+				}
+				var err error
+				*comp, err = data_sql.GetJsonPathCode(*comp, s.JsonPath, isJsonOperator(filter.Operator))
+				if err != nil {
+					return err
+				}
+			}
+
 			if ftsActive {
 				ftsDict := "'simple'"
 
@@ -724,8 +809,16 @@ func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
 				// special cases
 				// (I)LIKE comparison needs attribute cast as TEXT (relevant for integers/floats/etc.)
 				// REGCONFIG attributes must be cast as TEXT
+				// money attributes are compared by amount, intervals by number of seconds (same as retrieved)
 				if isLikeOperator(filter.Operator) || atr.Content == "regconfig" {
 					*comp = fmt.Sprintf("%s::TEXT", *comp)
+				} else if schema.IsContentMoney(atr.Content) {
+					if isMoneyComp {
+						*currency = fmt.Sprintf("(%s).currency", *comp)
+					}
+					*comp = fmt.Sprintf("(%s).amount", *comp)
+				} else if schema.IsContentInterval(atr.Content) {
+					*comp = fmt.Sprintf("EXTRACT(EPOCH FROM %s)", *comp)
 				}
 			}
 			return nil
@@ -758,6 +851,12 @@ func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
 			s.Value = fmt.Sprintf("%%%s%%", s.Value)
 		}
 
+		// money value with currency, amount is compared as value, currency separately
+		var currencyValue string
+		if isMoneyComp {
+			s.Value, currencyValue = getMoneyFilterValue(s.Value)
+		}
+
 		// PGX fix: cannot use proper true/false values in SQL parameters
 		// no good solution found so far, error: 'cannot convert (true|false) to Text'
 		if fmt.Sprintf("%T", s.Value) == "bool" {
@@ -788,16 +887,46 @@ func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
 		} else {
 			*comp = fmt.Sprintf("$%d", len(*queryArgs))
 		}
+
+		if currencyValue != "" {
+			*queryArgs = append(*queryArgs, currencyValue)
+			if queryCountArgs != nil {
+				*queryCountArgs = append(*queryCountArgs, currencyValue)
+			}
+			*currency = fmt.Sprintf("$%d", len(*queryArgs))
+		}
 		return nil
 	}
 
 	// build left/right comparison sides (ignore right side, if NULL operator)
 	comp0, comp1 := "", ""
-	if err := getComp(filter.Side0, &comp0); err != nil {
+	currency0, currency1 := "", ""
+	if err := getComp(filter.Side0, &comp0, &currency0); err != nil {
 		return err
 	}
+
+	// geo operator, left side is geo point attribute, right side are fixed values
+	if isGeoOperator(filter.Operator) {
+		atr, exists := cache.AttributeIdMap[filter.Side0.AttributeId.Bytes]
+		if !filter.Side0.AttributeId.Valid || !exists || !schema.IsContentPoint(atr.Content) {
+			return fmt.Errorf("operator '%s' requires geo point attribute", filter.Operator)
+		}
+		code, err := getGeoFilterCode(comp0, filter.Operator, filter.Side1.Value,
+			queryArgs, queryCountArgs)
+
+		if err != nil {
+			return err
+		}
+		*inWhere = append(*inWhere, fmt.Sprintf("\n%s %s%s%s",
+			filter.Connector,
+			getBrackets(filter.Side0.Brackets, false),
+			code,
+			getBrackets(filter.Side1.Brackets, true)))
+
+		return nil
+	}
 	if !isNullOp {
-		if err := getComp(filter.Side1, &comp1); err != nil {
+		if err := getComp(filter.Side1, &comp1, &currency1); err != nil {
 			return err
 		}
 
@@ -808,15 +937,28 @@ func addWhere(filter types.DataGetFilter, queryArgs *[]interface{},
 	}
 
 	// generate WHERE line from parsed filter definition
-	*inWhere = append(*inWhere, fmt.Sprintf("\n%s %s%s %s %s%s",
+	*inWhere = append(*inWhere, fmt.Sprintf("\n%s %s%s%s",
 		filter.Connector,
 		getBrackets(filter.Side0.Brackets, false),
-		comp0, filter.Operator, comp1,
+		getComparison(comp0, filter.Operator, comp1, currency0, currency1),
 		getBrackets(filter.Side1.Brackets, true)))
 
 	return nil
 }
 
+// returns comparison of both filter sides
+// money amounts are only equal, lower or greater if both sides have the same currency
+// if the currency of one side is unknown (like a plain number), only amounts are compared
+func getComparison(comp0 string, operator string, comp1 string, currency0 string, currency1 string) string {
+	if currency0 == "" || currency1 == "" {
+		return fmt.Sprintf("%s %s %s", comp0, operator, comp1)
+	}
+	if operator == "<>" {
+		return fmt.Sprintf("(%s <> %s OR %s <> %s)", comp0, comp1, currency0, currency1)
+	}
+	return fmt.Sprintf("(%s %s %s AND %s = %s)", comp0, operator, comp1, currency0, currency1)
+}
+
 func addOrderBy(data types.DataGet, nestingLevel int) (string, error) {
 
 	if len(data.Orders) == 0 {
@@ -854,6 +996,19 @@ func addOrderBy(data types.DataGet, nestingLevel int) (string, error) {
 				alias = getAttributeCode(getRelationCode(int(ord.Index.Int32), nestingLevel), atr.Name)
 			}
 
+			// order by distance to given location
+			if len(ord.GeoDistanceTo) != 0 && expressionPosAlias == -1 {
+				atr := cache.AttributeIdMap[ord.AttributeId.Bytes]
+				if !schema.IsContentPoint(atr.Content) {
+					return "", fmt.Errorf("geo distance requires geo point attribute")
+				}
+				var err error
+				alias, err = data_sql.GetGeoDistanceCode(alias, ord.GeoDistanceTo)
+				if err != nil {
+					return "", err
+				}
+			}
+
 		} else if ord.ExpressionPos.Valid {
 			// order by chosen expression (by position in array)
 			alias = data_sql.GetExpressionAlias(int(ord.ExpressionPos.Int32))
@@ -888,6 +1043,11 @@ func getTupelIdCode(relationIndex int, nestingLevel int) string {
 	return fmt.Sprintf("%sid", getRelationCode(relationIndex, nestingLevel))
 }
 
+// tupel versions are uniquely identified by the relation code + the fixed string 'v'
+func getTupelVersionCode(relationIndex int, nestingLevel int) string {
+	return fmt.Sprintf("%sv", getRelationCode(relationIndex, nestingLevel))
+}
+
 // an attribute is referenced by the relation code + the attribute name
 // due to the relation code, this will always uniquely identify an attribute from a specific index
 // example: _r3.surname maps to person.surname from index 3
@@ -917,6 +1077,9 @@ func getBrackets(count int, right bool) string {
 func isArrayOperator(operator string) bool {
 	return slices.Contains([]string{"= ANY", "<> ALL"}, operator)
 }
+func isJsonOperator(operator string) bool {
+	return slices.Contains([]string{"@>", "<@", "?"}, operator)
+}
 func isLikeOperator(operator string) bool {
 	return slices.Contains([]string{"LIKE", "ILIKE", "NOT LIKE", "NOT ILIKE"}, operator)
 }
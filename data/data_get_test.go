@@ -0,0 +1,98 @@
+package data
+
+import (
+	"context"
+	"r3/cache"
+	"r3/types"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestGetOutsideInSelect(t *testing.T) {
+	tests := []struct {
+		name       string
+		selectExpr string
+		rel        types.Relation
+		want       string
+	}{
+		{"1:n", `JSON_AGG("id")`, types.Relation{Name: "task"},
+			`( SELECT JSON_AGG("id") FROM "app"."task" WHERE "task"."project" = "_r0"."id" ) AS "_a1"`},
+		{"1:n soft delete", `JSON_AGG("id")`, types.Relation{Name: "task", SoftDelete: true},
+			`( SELECT JSON_AGG("id") FROM "app"."task" WHERE "task"."project" = "_r0"."id" AND "task"."_deleted" IS NULL ) AS "_a1"`},
+		{"1:1 soft delete", `"id"`, types.Relation{Name: "task", SoftDelete: true},
+			`( SELECT "id" FROM "app"."task" WHERE "task"."project" = "_r0"."id" AND "task"."_deleted" IS NULL ) AS "_a1"`},
+	}
+	for _, test := range tests {
+		got := strings.Join(strings.Fields(getOutsideInSelect(test.selectExpr, "app",
+			test.rel, "project", "_r0", `"_a1"`)), " ")
+
+		if got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAddWhereMoney(t *testing.T) {
+	atrPrice := types.Attribute{Id: uuid.Must(uuid.NewV4()), Name: "price", Content: "money"}
+	atrBudget := types.Attribute{Id: uuid.Must(uuid.NewV4()), Name: "budget", Content: "money"}
+
+	cache.Schema_mx.Lock()
+	if cache.AttributeIdMap == nil {
+		cache.AttributeIdMap = make(map[uuid.UUID]types.Attribute)
+	}
+	cache.AttributeIdMap[atrPrice.Id] = atrPrice
+	cache.AttributeIdMap[atrBudget.Id] = atrBudget
+	cache.Schema_mx.Unlock()
+
+	sideAtr := func(atr types.Attribute, index int) types.DataGetFilterSide {
+		return types.DataGetFilterSide{AttributeId: pgtype.UUID{Bytes: atr.Id, Valid: true}, AttributeIndex: index}
+	}
+	sideValue := func(value interface{}) types.DataGetFilterSide {
+		return types.DataGetFilterSide{Value: value}
+	}
+
+	tests := []struct {
+		name     string
+		side0    types.DataGetFilterSide
+		operator string
+		side1    types.DataGetFilterSide
+		want     string
+		wantArgs []interface{}
+	}{
+		{"amount only", sideAtr(atrPrice, 0), "=", sideValue(10),
+			`AND ("_r0"."price").amount = $1`, []interface{}{10}},
+		{"value with currency", sideAtr(atrPrice, 0), "=", sideValue("10 EUR"),
+			`AND (("_r0"."price").amount = $1 AND ("_r0"."price").currency = $2)`, []interface{}{"10.00", "EUR"}},
+		{"value object with currency", sideAtr(atrPrice, 0), ">=",
+			sideValue(map[string]interface{}{"amount": "10", "currency": "usd"}),
+			`AND (("_r0"."price").amount >= $1 AND ("_r0"."price").currency = $2)`, []interface{}{"10.00", "USD"}},
+		{"value with currency not equal", sideAtr(atrPrice, 0), "<>", sideValue("10 EUR"),
+			`AND (("_r0"."price").amount <> $1 OR ("_r0"."price").currency <> $2)`, []interface{}{"10.00", "EUR"}},
+		{"attributes", sideAtr(atrPrice, 0), "<", sideAtr(atrBudget, 1),
+			`AND (("_r0"."price").amount < ("_r1"."budget").amount AND ("_r0"."price").currency = ("_r1"."budget").currency)`, []interface{}{}},
+		{"null", sideAtr(atrPrice, 0), "IS NULL", sideValue(nil),
+			`AND ("_r0"."price").amount IS NULL`, []interface{}{}},
+	}
+	for _, test := range tests {
+		args := make([]interface{}, 0)
+		inWhere := make([]string, 0)
+		filter := types.DataGetFilter{Connector: "AND", Operator: test.operator, Side0: test.side0, Side1: test.side1}
+
+		cache.Schema_mx.RLock()
+		err := addWhere(context.Background(), filter, &args, nil, 0, &inWhere, 0)
+		cache.Schema_mx.RUnlock()
+
+		if err != nil {
+			t.Errorf("%s: error = %v", test.name, err)
+			continue
+		}
+		got := strings.Join(strings.Fields(strings.Join(inWhere, "")), " ")
+		if got != test.want || !reflect.DeepEqual(args, test.wantArgs) {
+			t.Errorf("%s: got %s %v, want %s %v", test.name, got, args, test.want, test.wantArgs)
+		}
+	}
+}
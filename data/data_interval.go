@@ -0,0 +1,80 @@
+package data
+
+import (
+	"encoding/json"
+	"math"
+	"r3/handler"
+	"r3/types"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// durations are stored as native interval
+// values are exchanged as number of seconds
+
+// returns number of seconds for retrieved interval value
+// months and days are converted with fixed lengths (30 days, 24 hours), same as EXTRACT(EPOCH FROM interval)
+func getIntervalSeconds(i pgtype.Interval) interface{} {
+	if !i.Valid {
+		return nil
+	}
+	days := int64(i.Months)*30 + int64(i.Days)
+	return days*86400 + int64(math.Round(float64(i.Microseconds)/1000000))
+}
+
+// returns interval to store and number of seconds for interval attribute value
+// accepts numbers of seconds and duration texts ('[-]hours:minutes[:seconds]')
+func getValueInterval(atr types.Attribute, value interface{}) (interface{}, interface{}, error) {
+	if value == nil || value == "" {
+		return nil, nil, nil
+	}
+
+	var seconds int64
+	var err error
+	switch v := value.(type) {
+	case float64:
+		seconds = int64(math.Round(v))
+	case int64:
+		seconds = v
+	case json.Number:
+		seconds, err = v.Int64()
+	case string:
+		seconds, err = getIntervalSecondsFromText(v)
+	default:
+		err = strconv.ErrSyntax
+	}
+	if err != nil {
+		return nil, nil, handler.CreateErrCodeWithArgs("APP", handler.ErrCodeAppInvalidInterval,
+			map[string]string{"ATR_ID": atr.Id.String()})
+	}
+	return pgtype.Interval{Microseconds: seconds * 1000000, Valid: true}, seconds, nil
+}
+
+// parses number of seconds or duration text ('[-]hours:minutes[:seconds]')
+func getIntervalSecondsFromText(text string) (int64, error) {
+	text = strings.TrimSpace(text)
+	if !strings.Contains(text, ":") {
+		return strconv.ParseInt(text, 10, 64)
+	}
+
+	negative := strings.HasPrefix(text, "-")
+	parts := strings.Split(strings.TrimPrefix(text, "-"), ":")
+	if len(parts) > 3 {
+		return 0, strconv.ErrSyntax
+	}
+
+	var seconds int64
+	for i, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil || (i != 0 && n > 59) {
+			return 0, strconv.ErrSyntax
+		}
+		seconds += int64(n) * int64(math.Pow(60, float64(2-i)))
+	}
+	if negative {
+		seconds = -seconds
+	}
+	return seconds, nil
+}
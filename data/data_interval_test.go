@@ -0,0 +1,37 @@
+package data
+
+import "testing"
+
+func TestGetIntervalSecondsFromText(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    int64
+		wantErr bool
+	}{
+		{"90", 90, false},
+		{" -90 ", -90, false},
+		{"1:30", 5400, false},
+		{"01:30:15", 5415, false},
+		{"-0:45", -2700, false},
+		{"100:00", 360000, false},
+		{"0:00:59", 59, false},
+		{"1:60", 0, true},
+		{"1:30:60", 0, true},
+		{"1:2:3:4", 0, true},
+		{"1:-30", 0, true},
+		{"1:", 0, true},
+		{"a:30", 0, true},
+		{"1.5", 0, true},
+		{"", 0, true},
+	}
+	for _, test := range tests {
+		got, err := getIntervalSecondsFromText(test.text)
+		if (err != nil) != test.wantErr {
+			t.Errorf("getIntervalSecondsFromText(%q) error = %v, want error %v", test.text, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("getIntervalSecondsFromText(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"r3/cache"
 	"r3/db"
 	"r3/handler"
+	"r3/schema"
 	"r3/tools"
 	"r3/types"
 	"slices"
+	"strings"
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
@@ -78,13 +81,13 @@ func GetLogs_tx(ctx context.Context, tx pgx.Tx, recordId int64,
 
 	// check for authorized access, READ(1) for GET
 	for _, attributeId := range attributeIds {
-		if !authorizedAttribute(loginId, attributeId, 1) {
+		if !authorizedAttribute(ctx, loginId, attributeId, 1) {
 			return logs, errors.New(handler.ErrUnauthorized)
 		}
 	}
 
 	rows, err := tx.Query(ctx, `
-		SELECT d.id, d.relation_id, l.name, d.date_change
+		SELECT d.id, d.relation_id, l.name, d.date_change, d.deleted
 		FROM instance.data_log as d
 		LEFT JOIN instance.login AS l ON l.id = d.login_id_wofk
 		WHERE d.record_id_wofk = $1
@@ -103,7 +106,7 @@ func GetLogs_tx(ctx context.Context, tx pgx.Tx, recordId int64,
 		var l types.DataLog
 		var name pgtype.Text
 
-		if err := rows.Scan(&l.Id, &l.RelationId, &name, &l.DateChange); err != nil {
+		if err := rows.Scan(&l.Id, &l.RelationId, &name, &l.DateChange, &l.Deleted); err != nil {
 			return logs, err
 		}
 		l.RecordId = recordId
@@ -156,7 +159,7 @@ func setLog_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 
 	// new record, apply logs for record and its attribute values
 	if wasCreated {
-		logId, err := setLogRecord_tx(ctx, tx, relationId, loginId, recordId)
+		logId, err := setLogRecord_tx(ctx, tx, relationId, loginId, recordId, false)
 		if err != nil {
 			return err
 		}
@@ -228,7 +231,7 @@ func setLog_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 		return nil
 	}
 
-	logId, err := setLogRecord_tx(ctx, tx, relationId, loginId, recordId)
+	logId, err := setLogRecord_tx(ctx, tx, relationId, loginId, recordId, false)
 	if err != nil {
 		return err
 	}
@@ -242,7 +245,7 @@ func setLog_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 	return nil
 }
 func setLogRecord_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
-	loginId int64, recordId int64) (uuid.UUID, error) {
+	loginId int64, recordId int64, deleted bool) (uuid.UUID, error) {
 
 	logId, err := uuid.NewV4()
 	if err != nil {
@@ -251,13 +254,213 @@ func setLogRecord_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
 
 	if _, err := tx.Exec(ctx, `
 		INSERT INTO instance.data_log (id, relation_id,
-			login_id_wofk, record_id_wofk, date_change)
-		VALUES ($1,$2,$3,$4,$5)
-	`, logId, relationId, loginId, recordId, tools.GetTimeUnix()); err != nil {
+			login_id_wofk, record_id_wofk, date_change, deleted)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`, logId, relationId, loginId, recordId, tools.GetTimeUnix(), deleted); err != nil {
 		return logId, err
 	}
 	return logId, nil
 }
+
+// last known attribute values of a record that is about to be deleted
+type logDeleted struct {
+	relationId uuid.UUID
+	recordId   int64
+	attributes []types.DataSetAttribute
+}
+
+// collect last known attribute values of records that are about to be deleted
+// records deleted via cascading relationships are followed as well, if any relation along the way uses logging
+// collected values must be written with setLogsDeleted_tx after the deletion succeeded
+func collectValuesDeleted_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
+	recordIds []int64, recordIdsSeen map[uuid.UUID][]int64) ([]logDeleted, error) {
+
+	logs := make([]logDeleted, 0)
+
+	// skip records that were already visited (circular relationships)
+	recordIdsNew := make([]int64, 0)
+	for _, id := range recordIds {
+		if !slices.Contains(recordIdsSeen[relationId], id) {
+			recordIdsNew = append(recordIdsNew, id)
+		}
+	}
+	if len(recordIdsNew) == 0 || !relationLogsDeletions(relationId, make(map[uuid.UUID]bool)) {
+		return logs, nil
+	}
+	recordIdsSeen[relationId] = append(recordIdsSeen[relationId], recordIdsNew...)
+
+	rel, exists := cache.RelationIdMap[relationId]
+	if !exists {
+		return logs, handler.ErrSchemaUnknownRelation(relationId)
+	}
+	mod, exists := cache.ModuleIdMap[rel.ModuleId]
+	if !exists {
+		return logs, handler.ErrSchemaUnknownModule(rel.ModuleId)
+	}
+
+	logs, err := collectValuesDeletedForRelation_tx(ctx, tx, mod, rel, recordIdsNew)
+	if err != nil {
+		return logs, err
+	}
+
+	// follow cascading relationships
+	for _, atr := range cache.AttributeIdMap {
+		if !atr.RelationshipId.Valid || atr.RelationshipId.Bytes != relationId || atr.OnDelete != "CASCADE" {
+			continue
+		}
+
+		relChild, exists := cache.RelationIdMap[atr.RelationId]
+		if !exists {
+			return logs, handler.ErrSchemaUnknownRelation(atr.RelationId)
+		}
+		modChild, exists := cache.ModuleIdMap[relChild.ModuleId]
+		if !exists {
+			return logs, handler.ErrSchemaUnknownModule(relChild.ModuleId)
+		}
+		if !relationLogsDeletions(relChild.Id, make(map[uuid.UUID]bool)) {
+			continue
+		}
+
+		recordIdsChild := make([]int64, 0)
+		if err := tx.QueryRow(ctx, fmt.Sprintf(`
+			SELECT ARRAY(
+				SELECT "%s"
+				FROM "%s"."%s"
+				WHERE "%s" = ANY($1)
+			)
+		`, schema.PkName, modChild.Name, relChild.Name, atr.Name),
+			recordIdsNew).Scan(&recordIdsChild); err != nil {
+
+			return logs, err
+		}
+
+		logsChild, err := collectValuesDeleted_tx(ctx, tx, relChild.Id, recordIdsChild, recordIdsSeen)
+		if err != nil {
+			return logs, err
+		}
+		logs = append(logs, logsChild...)
+	}
+	return logs, nil
+}
+
+// collect last known attribute values of records of a single relation, if relation uses logging
+func collectValuesDeletedForRelation_tx(ctx context.Context, tx pgx.Tx, mod types.Module,
+	rel types.Relation, recordIds []int64) ([]logDeleted, error) {
+
+	logs := make([]logDeleted, 0)
+	if !relationUsesLogging(rel.RetentionCount, rel.RetentionDays) || len(recordIds) == 0 {
+		return logs, nil
+	}
+
+	// file attributes are not stored in the relation, their files are kept with their own retention
+	attributeIds := make([]uuid.UUID, 0)
+	columns := make([]string, 0)
+	for _, atr := range rel.Attributes {
+		if atr.Name == schema.PkName || schema.IsContentFiles(atr.Content) {
+			continue
+		}
+		attributeIds = append(attributeIds, atr.Id)
+
+		// values are logged in the same format as they are retrieved
+		switch {
+		case schema.IsContentInterval(atr.Content):
+			columns = append(columns, fmt.Sprintf(`TO_JSON(EXTRACT(EPOCH FROM "%s")::BIGINT)::TEXT`, atr.Name))
+		case schema.IsContentMoney(atr.Content):
+			columns = append(columns, fmt.Sprintf(`instance.money_to_json("%s")::TEXT`, atr.Name))
+		case schema.IsContentPoint(atr.Content):
+			columns = append(columns, fmt.Sprintf(`CASE WHEN "%s" IS NULL THEN NULL ELSE JSON_BUILD_OBJECT(
+				'type','Point','coordinates',JSON_BUILD_ARRAY("%s"[0],"%s"[1]))::TEXT END`,
+				atr.Name, atr.Name, atr.Name))
+		default:
+			columns = append(columns, fmt.Sprintf(`TO_JSON("%s")::TEXT`, atr.Name))
+		}
+	}
+
+	if len(columns) == 0 {
+		return logs, nil
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT "%s", %s
+		FROM "%s"."%s"
+		WHERE "%s" = ANY($1)
+	`, schema.PkName, strings.Join(columns, ", "), mod.Name, rel.Name,
+		schema.PkName), recordIds)
+	if err != nil {
+		return logs, err
+	}
+
+	for rows.Next() {
+		var recordId int64
+		values := make([]pgtype.Text, len(columns))
+		valuePtrs := []interface{}{&recordId}
+		for i := range values {
+			valuePtrs = append(valuePtrs, &values[i])
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			rows.Close()
+			return logs, err
+		}
+
+		l := logDeleted{
+			relationId: rel.Id,
+			recordId:   recordId,
+			attributes: make([]types.DataSetAttribute, 0),
+		}
+		for i, v := range values {
+			a := types.DataSetAttribute{AttributeId: attributeIds[i]}
+			if v.Valid {
+				a.Value = json.RawMessage(v.String)
+			}
+			l.attributes = append(l.attributes, a)
+		}
+		logs = append(logs, l)
+	}
+	rows.Close()
+	return logs, nil
+}
+
+// returns whether deletions from relation need to be logged
+// either relation itself uses logging or any relation it cascades deletions into
+func relationLogsDeletions(relationId uuid.UUID, relationIdsChecked map[uuid.UUID]bool) bool {
+	if relationIdsChecked[relationId] {
+		return false
+	}
+	relationIdsChecked[relationId] = true
+
+	rel, exists := cache.RelationIdMap[relationId]
+	if !exists {
+		return false
+	}
+	if relationUsesLogging(rel.RetentionCount, rel.RetentionDays) {
+		return true
+	}
+	for _, atr := range cache.AttributeIdMap {
+		if atr.RelationshipId.Valid && atr.RelationshipId.Bytes == relationId &&
+			atr.OnDelete == "CASCADE" && relationLogsDeletions(atr.RelationId, relationIdsChecked) {
+
+			return true
+		}
+	}
+	return false
+}
+
+// set data change logs for deleted records with their last known attribute values
+func setLogsDeleted_tx(ctx context.Context, tx pgx.Tx, logs []logDeleted, loginId int64) error {
+	for _, l := range logs {
+		logId, err := setLogRecord_tx(ctx, tx, l.relationId, loginId, l.recordId, true)
+		if err != nil {
+			return err
+		}
+		for _, atr := range l.attributes {
+			if err := setLogValue_tx(ctx, tx, logId, atr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func setLogValue_tx(ctx context.Context, tx pgx.Tx, logId uuid.UUID, atr types.DataSetAttribute) error {
 
 	valueJson, err := json.Marshal(atr.Value)
@@ -0,0 +1,430 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"r3/cache"
+	"r3/data/data_image"
+	"r3/handler"
+	"r3/schema"
+	"r3/tools"
+	"r3/types"
+	"reflect"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// restores records to their state at a data change log entry
+// the state of an attribute is its latest logged value up to the log entry
+// attributes without logged values (logs outside of retention) are kept as they are
+
+// get changes required to restore record to its state at log entry
+// only attributes the login may update are included
+func GetLogRestore_tx(ctx context.Context, tx pgx.Tx, logId uuid.UUID, loginId int64) (types.DataLogRestore, error) {
+
+	res := types.DataLogRestore{
+		LogId:      logId,
+		Attributes: make([]types.DataLogRestoreAttribute, 0),
+		Files:      make([]types.DataLogRestoreFile, 0),
+	}
+
+	err := tx.QueryRow(ctx, `
+		SELECT relation_id, record_id_wofk, date_change
+		FROM instance.data_log
+		WHERE id = $1
+	`, logId).Scan(&res.RelationId, &res.RecordId, &res.DateChange)
+
+	if err == pgx.ErrNoRows {
+		return res, fmt.Errorf("data change log %s does not exist", logId)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	rel, exists := cache.RelationIdMap[res.RelationId]
+	if !exists {
+		return res, handler.ErrSchemaUnknownRelation(res.RelationId)
+	}
+
+	// get latest logged values of regular attributes up to log entry
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT ON (v.attribute_id, v.attribute_id_nm, v.outside_in)
+			v.attribute_id, v.attribute_id_nm, v.outside_in, v.value
+		FROM instance.data_log_value AS v
+		JOIN instance.data_log       AS d ON d.id = v.data_log_id
+		JOIN app.attribute           AS a ON a.id = v.attribute_id
+		WHERE d.relation_id    =  $1
+		AND   d.record_id_wofk =  $2
+		AND   d.date_change    <= $3
+		AND   a.content        <> 'files'
+		ORDER BY v.attribute_id, v.attribute_id_nm, v.outside_in, d.date_change DESC
+	`, res.RelationId, res.RecordId, res.DateChange)
+	if err != nil {
+		return res, err
+	}
+
+	attributes := make([]types.DataSetAttribute, 0)
+	for rows.Next() {
+		var a types.DataSetAttribute
+		var value pgtype.Text
+		if err := rows.Scan(&a.AttributeId, &a.AttributeIdNm, &a.OutsideIn, &value); err != nil {
+			rows.Close()
+			return res, err
+		}
+		if !authorizedAttribute(ctx, loginId, a.AttributeId, 2) {
+			continue
+		}
+		if value.Valid {
+			if err := json.Unmarshal([]byte(value.String), &a.Value); err != nil {
+				rows.Close()
+				return res, err
+			}
+		}
+		attributes = append(attributes, a)
+	}
+	rows.Close()
+
+	// compare to current values
+	if len(attributes) != 0 {
+		current, err := collectCurrentValuesForLog_tx(ctx, tx, res.RelationId,
+			attributes, []int{}, res.RecordId, loginId)
+
+		if err != nil {
+			return res, err
+		}
+
+		for i, a := range attributes {
+			valueCurrent, err := getValueComparable(current.Values[i])
+			if err != nil {
+				return res, err
+			}
+			if reflect.DeepEqual(valueCurrent, a.Value) {
+				continue
+			}
+			res.Attributes = append(res.Attributes, types.DataLogRestoreAttribute{
+				AttributeId:   a.AttributeId,
+				AttributeIdNm: a.AttributeIdNm,
+				OutsideIn:     a.OutsideIn,
+				ValueCurrent:  valueCurrent,
+				ValueRestored: a.Value,
+			})
+		}
+	}
+
+	// compare files of file attributes
+	for _, atr := range rel.Attributes {
+		if !schema.IsContentFiles(atr.Content) || !authorizedAttribute(ctx, loginId, atr.Id, 2) {
+			continue
+		}
+		files, err := getLogRestoreFiles_tx(ctx, tx, atr.Id, res.RelationId, res.RecordId, res.DateChange)
+		if err != nil {
+			return res, err
+		}
+		res.Files = append(res.Files, files...)
+	}
+	return res, nil
+}
+
+// restores record to its state at log entry
+// attribute values are applied as regular data change, access checks, triggers and change logs apply
+func SetLogRestore_tx(ctx context.Context, tx pgx.Tx, logId uuid.UUID, loginId int64) error {
+
+	res, err := GetLogRestore_tx(ctx, tx, logId, loginId)
+	if err != nil {
+		return err
+	}
+	if len(res.Attributes) == 0 && len(res.Files) == 0 {
+		return errors.New("record is already in the state of this change")
+	}
+
+	// file changes are applied directly, check access like regular data changes do
+	// login must be able to update record (incl. policies) and each file attribute
+	if len(res.Files) != 0 {
+		if !authorizedRelation(ctx, loginId, res.RelationId, 2) {
+			return errors.New(handler.ErrUnauthorized)
+		}
+		authorized, err := authorizedRecord_tx(ctx, tx, loginId, res.RelationId, res.RecordId, []string{"update"})
+		if err != nil {
+			return err
+		}
+		if !authorized {
+			return errors.New(handler.ErrUnauthorized)
+		}
+
+		cache.Schema_mx.RLock()
+		for _, f := range res.Files {
+			if !authorizedAttribute(ctx, loginId, f.AttributeId, 2) {
+				cache.Schema_mx.RUnlock()
+				return errors.New(handler.ErrUnauthorized)
+			}
+		}
+		cache.Schema_mx.RUnlock()
+	}
+
+	if len(res.Attributes) != 0 {
+		dataSet := types.DataSet{
+			RelationId:  res.RelationId,
+			AttributeId: uuid.Nil,
+			IndexFrom:   -1,
+			RecordId:    res.RecordId,
+			Attributes:  make([]types.DataSetAttribute, 0),
+			EncKeysSet:  make([]types.DataSetEncKeys, 0),
+		}
+		for _, a := range res.Attributes {
+			dataSet.Attributes = append(dataSet.Attributes, types.DataSetAttribute{
+				AttributeId:   a.AttributeId,
+				AttributeIdNm: a.AttributeIdNm,
+				OutsideIn:     a.OutsideIn,
+				Value:         a.ValueRestored,
+			})
+		}
+		if _, err := Set_tx(ctx, tx, map[int]types.DataSet{0: dataSet}, loginId); err != nil {
+			return err
+		}
+	}
+
+	// apply file changes, file versions are restored as new versions
+	attributeIdMapChanges := make(map[uuid.UUID]map[uuid.UUID]types.DataSetFileChange)
+	for _, f := range res.Files {
+		if _, exists := attributeIdMapChanges[f.AttributeId]; !exists {
+			attributeIdMapChanges[f.AttributeId] = make(map[uuid.UUID]types.DataSetFileChange)
+		}
+
+		switch f.Action {
+		case "assign":
+			if _, err := tx.Exec(ctx, fmt.Sprintf(`
+				UPDATE instance_file."%s"
+				SET date_delete = NULL
+				WHERE record_id = $1
+				AND   file_id   = $2
+			`, schema.GetFilesTableName(f.AttributeId)), res.RecordId, f.FileId); err != nil {
+				return err
+			}
+			attributeIdMapChanges[f.AttributeId][f.FileId] = types.DataSetFileChange{
+				Action: "create", Name: f.Name, Version: -1}
+
+		case "delete":
+			attributeIdMapChanges[f.AttributeId][f.FileId] = types.DataSetFileChange{
+				Action: "delete", Name: f.NameCurrent, Version: -1}
+
+		case "rename":
+			attributeIdMapChanges[f.AttributeId][f.FileId] = types.DataSetFileChange{
+				Action: "rename", Name: f.Name, Version: -1}
+
+		case "version":
+			if err := setLogRestoreFileVersion_tx(ctx, tx, f, res.RelationId, res.RecordId, loginId); err != nil {
+				return err
+			}
+		}
+	}
+
+	cache.Schema_mx.RLock()
+	rel, exists := cache.RelationIdMap[res.RelationId]
+	cache.Schema_mx.RUnlock()
+	if !exists {
+		return handler.ErrSchemaUnknownRelation(res.RelationId)
+	}
+
+	for attributeId, changes := range attributeIdMapChanges {
+		if len(changes) == 0 {
+			continue
+		}
+
+		// restored files are assigned again, only name changes must be applied
+		changesApply := make(map[uuid.UUID]types.DataSetFileChange)
+		for fileId, c := range changes {
+			if c.Action == "create" {
+				c.Action = "rename"
+			}
+			changesApply[fileId] = c
+		}
+		if err := FilesApplyAttributChanges_tx(ctx, tx, res.RecordId, attributeId, changesApply); err != nil {
+			return err
+		}
+
+		if relationUsesLogging(rel.RetentionCount, rel.RetentionDays) {
+			if err := setLog_tx(ctx, tx, res.RelationId, []types.DataSetAttribute{{
+				AttributeId: attributeId,
+				Value:       types.DataSetFileChanges{FileIdMapChange: changes},
+			}}, []int{0}, false, []interface{}{nil}, res.RecordId, loginId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// get file changes required to restore file attribute of record to its state at given date
+// only files that occur in change logs are considered, others are kept as they are
+func getLogRestoreFiles_tx(ctx context.Context, tx pgx.Tx, attributeId uuid.UUID,
+	relationId uuid.UUID, recordId int64, dateChange int64) ([]types.DataLogRestoreFile, error) {
+
+	files := make([]types.DataLogRestoreFile, 0)
+
+	type fileState struct {
+		known   bool   // file state at date is known (file change was logged before date)
+		present bool   // file was assigned to record at date
+		name    string // file name at date, empty if not known
+	}
+	fileIdMapState := make(map[uuid.UUID]fileState)
+
+	rows, err := tx.Query(ctx, `
+		SELECT v.value, d.date_change
+		FROM instance.data_log_value AS v
+		JOIN instance.data_log       AS d ON d.id = v.data_log_id
+		WHERE d.relation_id    = $1
+		AND   d.record_id_wofk = $2
+		AND   v.attribute_id   = $3
+		AND   v.value IS NOT NULL
+		ORDER BY d.date_change ASC
+	`, relationId, recordId, attributeId)
+	if err != nil {
+		return files, err
+	}
+
+	for rows.Next() {
+		var value string
+		var date int64
+		if err := rows.Scan(&value, &date); err != nil {
+			rows.Close()
+			return files, err
+		}
+
+		var changes types.DataSetFileChanges
+		if err := json.Unmarshal([]byte(value), &changes); err != nil {
+			rows.Close()
+			return files, err
+		}
+
+		for fileId, c := range changes.FileIdMapChange {
+			s := fileIdMapState[fileId]
+
+			if date > dateChange {
+				// first change after date decides whether file existed at date
+				if !s.known {
+					s.known = true
+					s.present = c.Action != "create"
+				}
+				fileIdMapState[fileId] = s
+				continue
+			}
+
+			s.known = true
+			switch c.Action {
+			case "create", "rename":
+				s.present = true
+				s.name = c.Name
+			case "delete":
+				s.present = false
+			case "update":
+				s.present = true
+			}
+			fileIdMapState[fileId] = s
+		}
+	}
+	rows.Close()
+
+	for fileId, s := range fileIdMapState {
+		var nameCurrent string
+		var assigned bool
+		err := tx.QueryRow(ctx, fmt.Sprintf(`
+			SELECT name, date_delete IS NULL
+			FROM instance_file."%s"
+			WHERE record_id = $1
+			AND   file_id   = $2
+		`, schema.GetFilesTableName(attributeId)), recordId, fileId).Scan(&nameCurrent, &assigned)
+
+		if err == pgx.ErrNoRows {
+			// file reference was removed entirely, cannot be restored
+			continue
+		}
+		if err != nil {
+			return files, err
+		}
+
+		f := types.DataLogRestoreFile{
+			AttributeId: attributeId,
+			FileId:      fileId,
+			Name:        s.name,
+			NameCurrent: nameCurrent,
+		}
+		if f.Name == "" {
+			f.Name = nameCurrent
+		}
+
+		if !s.present {
+			if assigned {
+				f.Action = "delete"
+				files = append(files, f)
+			}
+			continue
+		}
+
+		if !assigned {
+			f.Action = "assign"
+			f.NameCurrent = ""
+			files = append(files, f)
+		} else if f.Name != nameCurrent {
+			f.Action = "rename"
+			files = append(files, f)
+		}
+
+		// check for file version at date
+		var version pgtype.Int8
+		if err := tx.QueryRow(ctx, `
+			SELECT MAX(version), (
+				SELECT MAX(version)
+				FROM instance.file_version
+				WHERE file_id = $1
+			)
+			FROM instance.file_version
+			WHERE file_id     =  $1
+			AND   date_change <= $2
+		`, fileId, dateChange).Scan(&version, &f.VersionCurrent); err != nil {
+			return files, err
+		}
+		if version.Valid && version.Int64 != f.VersionCurrent {
+			f.Action = "version"
+			f.Version = version.Int64
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// restores file version by storing its content as new latest version
+func setLogRestoreFileVersion_tx(ctx context.Context, tx pgx.Tx, f types.DataLogRestoreFile,
+	relationId uuid.UUID, recordId int64, loginId int64) error {
+
+	var hash string
+	var sizeKb int64
+	if err := tx.QueryRow(ctx, `
+		SELECT hash, size_kb
+		FROM instance.file_version
+		WHERE file_id = $1
+		AND   version = $2
+	`, f.FileId, f.Version).Scan(&hash, &sizeKb); err != nil {
+		return err
+	}
+
+	versionNew := f.VersionCurrent + 1
+	filePath := GetFilePathVersion(f.FileId, versionNew)
+	if err := tools.FileCopy(GetFilePathVersion(f.FileId, f.Version), filePath, false); err != nil {
+		return err
+	}
+
+	// create/update thumbnail - failure should not block progress
+	data_image.CreateThumbnail(f.FileId, filepath.Ext(f.Name), filePath,
+		GetFilePathThumb(f.FileId), false)
+
+	return FileApplyVersion_tx(ctx, tx, false, f.AttributeId, relationId, f.FileId,
+		hash, f.Name, sizeKb, versionNew, []int64{recordId}, loginId)
+}
@@ -0,0 +1,112 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"r3/handler"
+	"r3/types"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// money values are stored as composite type instance.money_value (amount, currency)
+// values are exchanged as objects with amount as text, to keep exact precision
+
+var (
+	regexMoneyAmount   = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+	regexMoneyCurrency = regexp.MustCompile(`^[A-Z]{3}$`)
+
+	// ISO 4217 currencies with other than 2 minor units
+	// must match instance.currency_precision()
+	currencyPrecisionDefault = 2
+	currencyPrecision        = map[string]int{
+		"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+		"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+		"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+		"CLF": 4, "UYW": 4,
+	}
+)
+
+func getCurrencyPrecision(currency string) int {
+	if p, exists := currencyPrecision[currency]; exists {
+		return p
+	}
+	return currencyPrecisionDefault
+}
+
+// returns composite value to store and normalized money value for money attribute value
+// accepts objects (amount, currency) and strings ('12.50 EUR' or 'EUR 12.50')
+// amounts may not have more decimals than the currency allows, they are padded to its precision
+func getValueMoney(atr types.Attribute, value interface{}) (interface{}, interface{}, error) {
+	if value == nil || value == "" {
+		return nil, nil, nil
+	}
+
+	var errInvalid = handler.CreateErrCodeWithArgs("APP", handler.ErrCodeAppInvalidMoney,
+		map[string]string{"ATR_ID": atr.Id.String()})
+
+	var m types.DataMoney
+	switch v := value.(type) {
+	case map[string]interface{}:
+		switch a := v["amount"].(type) {
+		case string:
+			m.Amount = a
+		case float64:
+			m.Amount = strconv.FormatFloat(a, 'f', -1, 64)
+		case json.Number:
+			m.Amount = a.String()
+		default:
+			return nil, nil, errInvalid
+		}
+		c, ok := v["currency"].(string)
+		if !ok {
+			return nil, nil, errInvalid
+		}
+		m.Currency = c
+	case string:
+		parts := strings.Fields(v)
+		if len(parts) != 2 {
+			return nil, nil, errInvalid
+		}
+		if regexMoneyCurrency.MatchString(strings.ToUpper(parts[0])) {
+			m.Currency, m.Amount = parts[0], parts[1]
+		} else {
+			m.Amount, m.Currency = parts[0], parts[1]
+		}
+	case types.DataMoney:
+		m = v
+	default:
+		return nil, nil, errInvalid
+	}
+
+	m.Amount = strings.TrimSpace(m.Amount)
+	m.Currency = strings.ToUpper(strings.TrimSpace(m.Currency))
+
+	if !regexMoneyAmount.MatchString(m.Amount) || !regexMoneyCurrency.MatchString(m.Currency) {
+		return nil, nil, errInvalid
+	}
+
+	// check and pad decimals to currency precision
+	precision := getCurrencyPrecision(m.Currency)
+	integer, decimals, _ := strings.Cut(m.Amount, ".")
+	if len(decimals) > precision {
+		return nil, nil, errInvalid
+	}
+	m.Amount = integer
+	if precision != 0 {
+		m.Amount = fmt.Sprintf("%s.%s%s", integer, decimals,
+			strings.Repeat("0", precision-len(decimals)))
+	}
+	return fmt.Sprintf("(%s,%s)", m.Amount, m.Currency), m, nil
+}
+
+// returns amount and currency of money filter value, if value includes a currency
+// other values (like plain numbers to compare amounts with) are returned as is
+func getMoneyFilterValue(value interface{}) (interface{}, string) {
+	_, v, err := getValueMoney(types.Attribute{}, value)
+	if m, ok := v.(types.DataMoney); ok && err == nil {
+		return m.Amount, m.Currency
+	}
+	return value, ""
+}
@@ -0,0 +1,46 @@
+package data
+
+import (
+	"r3/types"
+	"testing"
+)
+
+func TestGetValueMoney(t *testing.T) {
+	tests := []struct {
+		value     interface{}
+		wantStore interface{}
+		wantErr   bool
+	}{
+		{nil, nil, false},
+		{"", nil, false},
+		{"12.5 EUR", "(12.50,EUR)", false},
+		{"EUR 12.5", "(12.50,EUR)", false},
+		{"eur 12", "(12.00,EUR)", false},
+		{"-3.99 USD", "(-3.99,USD)", false},
+		{"1200 JPY", "(1200,JPY)", false},
+		{"1.5 KWD", "(1.500,KWD)", false},
+		{map[string]interface{}{"amount": "7.1", "currency": "chf"}, "(7.10,CHF)", false},
+		{map[string]interface{}{"amount": 7.25, "currency": "CHF"}, "(7.25,CHF)", false},
+		{types.DataMoney{Amount: "3", Currency: "GBP"}, "(3.00,GBP)", false},
+		{"12.505 EUR", nil, true},
+		{"12.5 JPY", nil, true},
+		{"12,50 EUR", nil, true},
+		{"1e3 EUR", nil, true},
+		{"12.5 EURO", nil, true},
+		{"12.5", nil, true},
+		{"12.5 EUR extra", nil, true},
+		{map[string]interface{}{"amount": true, "currency": "EUR"}, nil, true},
+		{map[string]interface{}{"amount": "1"}, nil, true},
+		{12.5, nil, true},
+	}
+	for _, test := range tests {
+		store, _, err := getValueMoney(types.Attribute{}, test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("getValueMoney(%v) error = %v, want error %v", test.value, err, test.wantErr)
+			continue
+		}
+		if store != test.wantStore {
+			t.Errorf("getValueMoney(%v) = %v, want %v", test.value, store, test.wantStore)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"r3/db"
+	"r3/handler"
+	"r3/tools"
+	"r3/types"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// record presence, which logins have which records open (and are editing or locking them)
+// presence is stored in the shared database to be available to all cluster nodes
+// clients must renew their presence regularly, otherwise it expires (incl. locks)
+
+var PresenceExpirySeconds int64 = 60
+
+// get current presence for record
+func GetPresence(relationId uuid.UUID, recordId int64) ([]types.DataPresence, error) {
+	presence := make([]types.DataPresence, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT p.login_id, l.name, BOOL_OR(p.edit), BOOL_OR(p.lock), MAX(p.date_expiry)
+		FROM instance.record_presence AS p
+		JOIN instance.login           AS l ON l.id = p.login_id
+		WHERE p.relation_id =  $1
+		AND   p.record_id   =  $2
+		AND   p.date_expiry >= $3
+		GROUP BY p.login_id, l.name
+		ORDER BY l.name
+	`, relationId, recordId, tools.GetTimeUnix())
+	if err != nil {
+		return presence, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p types.DataPresence
+		if err := rows.Scan(&p.LoginId, &p.LoginName, &p.Edit, &p.Lock, &p.DateExpiry); err != nil {
+			return presence, err
+		}
+		presence = append(presence, p)
+	}
+	return presence, nil
+}
+
+// sets or renews presence of client on record
+// returns whether presence changed (new, edit or lock state changed), renewals do not count as change
+func SetPresence(ctx context.Context, clientId uuid.UUID, nodeId uuid.UUID, loginId int64,
+	relationId uuid.UUID, recordId int64, edit bool, lock bool) (bool, error) {
+
+	// check for authorized access, READ(1) for presence, UPDATE(2) for lock as it blocks changes by others
+	requestedAccess := 1
+	if lock {
+		requestedAccess = 2
+	}
+	if !authorizedRelation(ctx, loginId, relationId, requestedAccess) {
+		return false, errors.New(handler.ErrUnauthorized)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	// record must exist and be visible to login, locks must also be allowed by update policies
+	// otherwise any record ID could be locked, blocking changes by others
+	actions := []string{"select"}
+	if lock {
+		actions = append(actions, "update")
+	}
+	authorized, err := authorizedRecord_tx(ctx, tx, loginId, relationId, recordId, actions)
+	if err != nil {
+		return false, err
+	}
+	if !authorized {
+		return false, errors.New(handler.ErrUnauthorized)
+	}
+
+	now := tools.GetTimeUnix()
+
+	// remove expired presence, releases expired locks
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM instance.record_presence
+		WHERE date_expiry < $1
+	`, now); err != nil {
+		return false, err
+	}
+
+	// lock can only be held by one login at a time, serialize concurrent lock requests for record
+	if lock {
+		if _, err := tx.Exec(ctx, `
+			SELECT pg_advisory_xact_lock(HASHTEXTEXTENDED($1::TEXT || '_' || $2::TEXT, 0))
+		`, relationId, recordId); err != nil {
+			return false, err
+		}
+		if err := checkPresenceLock_tx(ctx, tx, relationId, recordId, loginId); err != nil {
+			return false, err
+		}
+	}
+
+	var editOld, lockOld bool
+	err = tx.QueryRow(ctx, `
+		SELECT edit, lock
+		FROM instance.record_presence
+		WHERE client_id   = $1
+		AND   relation_id = $2
+		AND   record_id   = $3
+	`, clientId, relationId, recordId).Scan(&editOld, &lockOld)
+
+	if err != nil && err != pgx.ErrNoRows {
+		return false, err
+	}
+	changed := err == pgx.ErrNoRows || editOld != edit || lockOld != lock
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO instance.record_presence (client_id, node_id, login_id,
+			relation_id, record_id, edit, lock, date_expiry)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+		ON CONFLICT (client_id, relation_id, record_id) DO UPDATE
+		SET edit = $6, lock = $7, date_expiry = $8
+	`, clientId, nodeId, loginId, relationId, recordId, edit, lock,
+		now+PresenceExpirySeconds); err != nil {
+
+		return false, err
+	}
+	return changed, tx.Commit(ctx)
+}
+
+// removes presence of client on record
+func DelPresence(clientId uuid.UUID, relationId uuid.UUID, recordId int64) error {
+	_, err := db.Pool.Exec(db.Ctx, `
+		DELETE FROM instance.record_presence
+		WHERE client_id   = $1
+		AND   relation_id = $2
+		AND   record_id   = $3
+	`, clientId, relationId, recordId)
+	return err
+}
+
+// removes all presence of client, returns records the client was present on
+func DelPresenceByClient(clientId uuid.UUID) ([]types.ClusterEventRecordPresence, error) {
+	records := make([]types.ClusterEventRecordPresence, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		DELETE FROM instance.record_presence
+		WHERE client_id = $1
+		RETURNING relation_id, record_id
+	`, clientId)
+	if err != nil {
+		return records, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.ClusterEventRecordPresence
+		if err := rows.Scan(&r.RelationId, &r.RecordId); err != nil {
+			return records, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// checks whether record is locked by another login
+func checkPresenceLock_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
+	recordId int64, loginId int64) error {
+
+	var loginName string
+	err := tx.QueryRow(ctx, `
+		SELECT l.name
+		FROM instance.record_presence AS p
+		JOIN instance.login           AS l ON l.id = p.login_id
+		WHERE p.relation_id =  $1
+		AND   p.record_id   =  $2
+		AND   p.login_id    <> $3
+		AND   p.lock
+		AND   p.date_expiry >= $4
+		LIMIT 1
+	`, relationId, recordId, loginId, tools.GetTimeUnix()).Scan(&loginName)
+
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return handler.CreateErrCodeWithArgs("APP", handler.ErrCodeAppRecordLocked,
+		map[string]string{"LOGIN": loginName})
+}
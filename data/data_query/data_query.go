@@ -1,6 +1,7 @@
 package data_query
 
 import (
+	"context"
 	"r3/cache"
 	"r3/types"
 	"slices"
@@ -9,7 +10,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func ConvertColumnToExpression(column types.Column, loginId int64, languageCode string) types.DataGetExpression {
+func ConvertColumnToExpression(ctx context.Context, column types.Column, loginId int64, languageCode string) types.DataGetExpression {
 
 	expr := types.DataGetExpression{
 		AttributeId: pgtype.UUID{Bytes: column.AttributeId, Valid: true},
@@ -28,14 +29,14 @@ func ConvertColumnToExpression(column types.Column, loginId int64, languageCode
 			RelationId:  column.Query.RelationId.Bytes,
 			Joins:       ConvertQueryToDataJoins(column.Query.Joins),
 			Expressions: []types.DataGetExpression{expr},
-			Filters:     ConvertQueryToDataFilter(column.Query.Filters, loginId, languageCode),
+			Filters:     ConvertQueryToDataFilter(ctx, column.Query.Filters, loginId, languageCode),
 			Orders:      ConvertQueryToDataOrders(column.Query.Orders),
 			Limit:       column.Query.FixedLimit,
 		},
 	}
 }
 
-func ConvertSubQueryToDataGet(query types.Query, queryAggregator pgtype.Text,
+func ConvertSubQueryToDataGet(ctx context.Context, query types.Query, queryAggregator pgtype.Text,
 	attributeId pgtype.UUID, attributeIndex int, loginId int64,
 	languageCode string) types.DataGet {
 
@@ -50,13 +51,13 @@ func ConvertSubQueryToDataGet(query types.Query, queryAggregator pgtype.Text,
 				Index:         attributeIndex,
 			},
 		},
-		Filters: ConvertQueryToDataFilter(query.Filters, loginId, languageCode),
+		Filters: ConvertQueryToDataFilter(ctx, query.Filters, loginId, languageCode),
 		Orders:  ConvertQueryToDataOrders(query.Orders),
 		Limit:   query.FixedLimit,
 	}
 }
 
-func ConvertQueryToDataFilter(filters []types.QueryFilter,
+func ConvertQueryToDataFilter(ctx context.Context, filters []types.QueryFilter,
 	loginId int64, languageCode string) []types.DataGetFilter {
 
 	filtersOut := make([]types.DataGetFilter, len(filters))
@@ -76,7 +77,7 @@ func ConvertQueryToDataFilter(filters []types.QueryFilter,
 		case "preset":
 			sideOut.Value = cache.GetPresetRecordId(side.PresetId.Bytes)
 		case "subQuery":
-			sideOut.Query = ConvertSubQueryToDataGet(side.Query, side.QueryAggregator,
+			sideOut.Query = ConvertSubQueryToDataGet(ctx, side.Query, side.QueryAggregator,
 				side.AttributeId, side.AttributeIndex, loginId, languageCode)
 		case "true":
 			sideOut.Value = true
@@ -99,7 +100,7 @@ func ConvertQueryToDataFilter(filters []types.QueryFilter,
 		case "login":
 			sideOut.Value = loginId
 		case "role":
-			access, err := cache.GetAccessById(loginId)
+			access, err := cache.GetAccessById(ctx, loginId)
 			if err == nil {
 				sideOut.Value = slices.Contains(access.RoleIds, side.RoleId.Bytes)
 			} else {
@@ -0,0 +1,291 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"r3/cache"
+	"r3/config"
+	"r3/db"
+	"r3/handler"
+	"r3/log"
+	"r3/schema"
+	"r3/tools"
+	"r3/types"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// get recycle bin entries, deleted by login or by anyone (admin only)
+func GetRecycled_tx(ctx context.Context, tx pgx.Tx, loginId int64, all bool) ([]types.DataRecycled, error) {
+	entries := make([]types.DataRecycled, 0)
+
+	rows, err := tx.Query(ctx, `
+		SELECT r.id, r.relation_id, r.record_id, r.record_count,
+			r.login_id, l.name, r.date_deleted
+		FROM instance.recycle_bin AS r
+		LEFT JOIN instance.login AS l ON l.id = r.login_id
+		WHERE $1 OR r.login_id = $2
+		ORDER BY r.date_deleted DESC, r.id DESC
+	`, all, loginId)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e types.DataRecycled
+		if err := rows.Scan(&e.Id, &e.RelationId, &e.RecordId, &e.RecordCount,
+			&e.LoginId, &e.LoginName, &e.DateDeleted); err != nil {
+
+			return entries, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// restores deleted record together with its cascaded records
+// logins can restore their own deletions if they are still allowed to delete from the relation
+func RestoreRecycled_tx(ctx context.Context, tx pgx.Tx, id int32, loginId int64, isAdmin bool) error {
+
+	var relationId uuid.UUID
+	var loginIdDeleted int64
+	err := tx.QueryRow(ctx, `
+		SELECT relation_id, COALESCE(login_id,0)
+		FROM instance.recycle_bin
+		WHERE id = $1
+	`, id).Scan(&relationId, &loginIdDeleted)
+
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("recycle bin entry %d does not exist", id)
+	}
+	if err != nil {
+		return err
+	}
+
+	// check for authorized access, DELETE(3) for DEL
+	if !isAdmin && (loginIdDeleted != loginId || !authorizedRelation(ctx, loginId, relationId, 3)) {
+		return errors.New(handler.ErrUnauthorized)
+	}
+
+	// deleted records reference their recycle bin entry, removing it restores them (ON DELETE SET NULL)
+	_, err = tx.Exec(ctx, `
+		DELETE FROM instance.recycle_bin
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// permanently deletes record of recycle bin entry, cascaded records are deleted by their foreign keys
+func PurgeRecycled_tx(ctx context.Context, tx pgx.Tx, id int32) error {
+
+	var relationId uuid.UUID
+	var recordId int64
+	if err := tx.QueryRow(ctx, `
+		SELECT relation_id, record_id
+		FROM instance.recycle_bin
+		WHERE id = $1
+	`, id).Scan(&relationId, &recordId); err != nil {
+		return err
+	}
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	rel, exists := cache.RelationIdMap[relationId]
+	if !exists {
+		return handler.ErrSchemaUnknownRelation(relationId)
+	}
+	mod, exists := cache.ModuleIdMap[rel.ModuleId]
+	if !exists {
+		return handler.ErrSchemaUnknownModule(rel.ModuleId)
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM "%s"."%s"
+		WHERE "%s" = $1
+		AND   "%s" = $2
+	`, mod.Name, rel.Name, schema.PkName, schema.SoftDeleteName), recordId, id); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(ctx, `
+		DELETE FROM instance.recycle_bin
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// permanently deletes records that were kept in the recycle bin for longer than the retention period
+func PurgeRecycledExpired() error {
+
+	ids := make([]int32, 0)
+	if err := db.Pool.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT id
+			FROM instance.recycle_bin
+			WHERE date_deleted < $1
+		)
+	`, tools.GetTimeUnix()-(int64(config.GetUint64("recycleBinKeepDays"))*86400)).Scan(&ids); err != nil {
+		return err
+	}
+
+	// purge entries separately, records that cannot be deleted (blocking references) are kept
+	for _, id := range ids {
+		tx, err := db.Pool.Begin(db.Ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := PurgeRecycled_tx(db.Ctx, tx, id); err != nil {
+			log.Error("server", fmt.Sprintf("failed to purge recycle bin entry %d", id), err)
+			tx.Rollback(db.Ctx)
+			continue
+		}
+		if err := tx.Commit(db.Ctx); err != nil {
+			return err
+		}
+	}
+	if len(ids) != 0 {
+		log.Info("server", fmt.Sprintf("purged %d recycle bin entries", len(ids)))
+	}
+	return nil
+}
+
+// marks record as deleted by referencing a new recycle bin entry
+// records of soft delete relations, that would be deleted via cascading relationships, are marked as well
+func delSoft_tx(ctx context.Context, tx pgx.Tx, mod types.Module, rel types.Relation,
+	recordId int64, loginId int64, tableAlias string, policyFilter string) error {
+
+	var id int32
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO instance.recycle_bin (relation_id, record_id,
+			record_count, login_id, date_deleted)
+		VALUES ($1,$2,1,NULLIF($3,0),$4)
+		RETURNING id
+	`, rel.Id, recordId, loginId, tools.GetTimeUnix()).Scan(&id); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(ctx, fmt.Sprintf(`
+		UPDATE "%s"."%s" AS "%s"
+		SET "%s" = $1
+		WHERE "%s"."%s" = $2
+		%s
+		%s
+	`, mod.Name, rel.Name, tableAlias, schema.SoftDeleteName, tableAlias,
+		schema.PkName, policyFilter, getSoftDeleteFilter(tableAlias, rel)), id, recordId)
+
+	if err != nil {
+		return err
+	}
+
+	// nothing to delete, same as regular DELETE without affected record
+	if res.RowsAffected() == 0 {
+		_, err := tx.Exec(ctx, `DELETE FROM instance.recycle_bin WHERE id = $1`, id)
+		return err
+	}
+
+	// log last known values of marked records, records deleted on purge are not logged again
+	logs, err := collectValuesDeletedForRelation_tx(ctx, tx, mod, rel, []int64{recordId})
+	if err != nil {
+		return err
+	}
+
+	count := 1
+	if err := delSoftCascade_tx(ctx, tx, rel.Id, []int64{recordId}, id, &count, &logs); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE instance.recycle_bin
+		SET record_count = $1
+		WHERE id = $2
+	`, count, id); err != nil {
+		return err
+	}
+	return setLogsDeleted_tx(ctx, tx, logs, loginId)
+}
+
+// follows relationships pointing to deleted records of relation
+// records of cascading relationships are marked deleted if their relation supports soft delete,
+// otherwise they are kept until the recycle bin entry is purged
+// restricting relationships block the deletion as a regular DELETE would
+func delSoftCascade_tx(ctx context.Context, tx pgx.Tx, relationId uuid.UUID,
+	recordIds []int64, recycleId int32, count *int, logs *[]logDeleted) error {
+
+	for _, atr := range cache.AttributeIdMap {
+		if !atr.RelationshipId.Valid || atr.RelationshipId.Bytes != relationId {
+			continue
+		}
+
+		rel, exists := cache.RelationIdMap[atr.RelationId]
+		if !exists {
+			return handler.ErrSchemaUnknownRelation(atr.RelationId)
+		}
+		mod, exists := cache.ModuleIdMap[rel.ModuleId]
+		if !exists {
+			return handler.ErrSchemaUnknownModule(rel.ModuleId)
+		}
+
+		switch atr.OnDelete {
+		case "CASCADE":
+			if !rel.SoftDelete {
+				continue
+			}
+
+			recordIdsChild := make([]int64, 0)
+			if err := tx.QueryRow(ctx, fmt.Sprintf(`
+				WITH deleted AS (
+					UPDATE "%s"."%s"
+					SET "%s" = $1
+					WHERE "%s" = ANY($2)
+					AND   "%s" IS NULL
+					RETURNING "%s"
+				)
+				SELECT ARRAY(SELECT "%s" FROM deleted)
+			`, mod.Name, rel.Name, schema.SoftDeleteName, atr.Name,
+				schema.SoftDeleteName, schema.PkName, schema.PkName),
+				recycleId, recordIds).Scan(&recordIdsChild); err != nil {
+
+				return err
+			}
+
+			if len(recordIdsChild) == 0 {
+				continue
+			}
+			*count += len(recordIdsChild)
+
+			logsChild, err := collectValuesDeletedForRelation_tx(ctx, tx, mod, rel, recordIdsChild)
+			if err != nil {
+				return err
+			}
+			*logs = append(*logs, logsChild...)
+
+			if err := delSoftCascade_tx(ctx, tx, rel.Id, recordIdsChild, recycleId, count, logs); err != nil {
+				return err
+			}
+
+		case "NO ACTION", "RESTRICT":
+			var referenced bool
+			if err := tx.QueryRow(ctx, fmt.Sprintf(`
+				SELECT EXISTS(
+					SELECT 1
+					FROM "%s"."%s" AS "t"
+					WHERE "t"."%s" = ANY($1)
+					%s
+				)
+			`, mod.Name, rel.Name, atr.Name, getSoftDeleteFilter("t", rel)),
+				recordIds).Scan(&referenced); err != nil {
+
+				return err
+			}
+			if referenced {
+				return handler.CreateErrCode("DBS", handler.ErrCodeDbsConstraintFk)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"r3/cache"
+	"r3/handler"
+	"r3/schema"
+	"r3/types"
+	"sort"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	searchLimitDefault = 50
+	searchLimitMax     = 200
+
+	// highlighted matches are returned as <mark></mark>, clients must escape everything else
+	searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=10, MaxFragments=2"
+)
+
+// global full text search over all relations with a search form
+// searches text attributes with full text (GIN) indexes, using their dictionary attributes if defined
+// results are ranked per relation, then merged and limited
+func Search_tx(ctx context.Context, tx pgx.Tx, search types.DataSearch,
+	loginId int64) ([]types.DataSearchResult, error) {
+
+	results := make([]types.DataSearchResult, 0)
+
+	search.Text = strings.TrimSpace(search.Text)
+	if search.Text == "" {
+		return results, nil
+	}
+	if !cache.GetSearchDictionaryIsValid(search.Dictionary) {
+		search.Dictionary = "simple"
+	}
+	if search.Limit <= 0 {
+		search.Limit = searchLimitDefault
+	}
+	if search.Limit > searchLimitMax {
+		search.Limit = searchLimitMax
+	}
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	for _, rel := range cache.RelationIdMap {
+
+		// check for authorized access, READ(1) for GET
+		// skip relations whose search form cannot be opened by the login
+		if !rel.SearchFormId.Valid || !authorizedRelation(ctx, loginId, rel.Id, 1) ||
+			!authorizedForm(ctx, loginId, rel.SearchFormId.Bytes) {
+
+			continue
+		}
+
+		resultsRel, err := searchRelation_tx(ctx, tx, search, rel, loginId)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, resultsRel...)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+
+	if len(results) > search.Limit {
+		results = results[:search.Limit]
+	}
+	return results, nil
+}
+
+func searchRelation_tx(ctx context.Context, tx pgx.Tx, search types.DataSearch,
+	rel types.Relation, loginId int64) ([]types.DataSearchResult, error) {
+
+	results := make([]types.DataSearchResult, 0)
+	tableAlias := "t"
+
+	mod, exists := cache.ModuleIdMap[rel.ModuleId]
+	if !exists {
+		return results, handler.ErrSchemaUnknownModule(rel.ModuleId)
+	}
+
+	// collect searchable attributes, text attributes with full text index
+	attributeIds := make([]uuid.UUID, 0)
+	configs := make([]string, 0)
+	vectors := make([]string, 0)
+	columns := make([]string, 0)
+	for _, ind := range rel.Indexes {
+		if ind.Method != "GIN" || len(ind.Attributes) != 1 {
+			continue
+		}
+
+		atr, exists := cache.AttributeIdMap[ind.Attributes[0].AttributeId]
+		if !exists || !schema.IsContentText(atr.Content) || atr.Encrypted ||
+			!authorizedAttribute(ctx, loginId, atr.Id, 1) {
+
+			continue
+		}
+
+		// same dictionary definition as used by the index
+		config := "'simple'::REGCONFIG"
+		if ind.AttributeIdDict.Valid {
+			atrDict, exists := cache.AttributeIdMap[ind.AttributeIdDict.Bytes]
+			if !exists {
+				continue
+			}
+			config = fmt.Sprintf(`CASE WHEN "%s"."%s" IS NULL THEN 'simple'::REGCONFIG ELSE "%s"."%s" END`,
+				tableAlias, atrDict.Name, tableAlias, atrDict.Name)
+		}
+		column := fmt.Sprintf(`"%s"."%s"`, tableAlias, atr.Name)
+
+		attributeIds = append(attributeIds, atr.Id)
+		configs = append(configs, config)
+		columns = append(columns, column)
+		vectors = append(vectors, fmt.Sprintf("TO_TSVECTOR(%s,%s)", config, column))
+	}
+
+	if len(attributeIds) == 0 {
+		return results, nil
+	}
+
+	policyFilter, err := getPolicyFilter(ctx, loginId, "select", tableAlias, rel.Policies)
+	if err != nil {
+		return results, err
+	}
+
+	// match on any searchable attribute, rank by best match
+	// snippet is taken from the first matching attribute
+	tsQuery := fmt.Sprintf("WEBSEARCH_TO_TSQUERY('%s',$1)", search.Dictionary)
+	matches := make([]string, 0)
+	ranks := make([]string, 0)
+	indexCases := make([]string, 0)
+	snippetCases := make([]string, 0)
+	for i, vector := range vectors {
+		match := fmt.Sprintf("%s @@ %s", vector, tsQuery)
+
+		matches = append(matches, match)
+		ranks = append(ranks, fmt.Sprintf("TS_RANK(%s,%s)", vector, tsQuery))
+		indexCases = append(indexCases, fmt.Sprintf("WHEN %s THEN %d", match, i))
+		snippetCases = append(snippetCases, fmt.Sprintf("WHEN %s THEN TS_HEADLINE(%s,%s,%s,'%s')",
+			match, configs[i], columns[i], tsQuery, searchHeadlineOptions))
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf(`
+		SELECT "%s"."%s", GREATEST(%s),
+			CASE %s END,
+			CASE %s END
+		FROM "%s"."%s" AS "%s"
+		WHERE (%s)
+		%s
+		%s
+		ORDER BY 2 DESC
+		LIMIT $2
+	`, tableAlias, schema.PkName, strings.Join(ranks, ","),
+		strings.Join(indexCases, " "),
+		strings.Join(snippetCases, " "),
+		mod.Name, rel.Name, tableAlias,
+		strings.Join(matches, " OR "),
+		policyFilter, getSoftDeleteFilter(tableAlias, rel)), search.Text, search.Limit)
+
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.DataSearchResult
+		var index int
+		if err := rows.Scan(&r.RecordId, &r.Rank, &index, &r.Snippet); err != nil {
+			return results, err
+		}
+		r.AttributeId = attributeIds[index]
+		r.FormId = rel.SearchFormId.Bytes
+		r.RelationId = rel.Id
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -10,6 +10,7 @@ import (
 	"r3/handler"
 	"r3/schema"
 	"r3/types"
+	"reflect"
 	"slices"
 	"sort"
 	"strings"
@@ -55,17 +56,22 @@ func Set_tx(ctx context.Context, tx pgx.Tx, dataSetsByIndex map[int]types.DataSe
 		}
 
 		// check write access for tupel creation
-		if isNewRecord && !authorizedRelation(loginId, dataSet.RelationId, 2) {
+		if isNewRecord && !authorizedRelation(ctx, loginId, dataSet.RelationId, 2) {
 			return indexRecordIds, errors.New(handler.ErrUnauthorized)
 		}
 
 		// check write access for updating attribute values
 		for _, attribute := range dataSet.Attributes {
 
-			if !authorizedAttribute(loginId, attribute.AttributeId, 2) {
+			if !authorizedAttribute(ctx, loginId, attribute.AttributeId, 2) {
 				return indexRecordIds, errors.New(handler.ErrUnauthorized)
 			}
 
+			// computed attribute values are generated by the database
+			if atr, exists := cache.AttributeIdMap[attribute.AttributeId]; exists && atr.Expression != "" {
+				return indexRecordIds, fmt.Errorf("cannot set value of computed attribute '%s'", atr.Name)
+			}
+
 			// check for protected preset record values
 			for _, preset := range rel.Presets {
 
@@ -88,6 +94,20 @@ func Set_tx(ctx context.Context, tx pgx.Tx, dataSetsByIndex map[int]types.DataSe
 			}
 		}
 
+		// check for exclusive edit lock by other login
+		if !isNewRecord {
+			if err := checkPresenceLock_tx(ctx, tx, rel.Id, dataSet.RecordId, loginId); err != nil {
+				return indexRecordIds, err
+			}
+		}
+
+		// check for conflicting changes since record was retrieved
+		if !isNewRecord && dataSet.Version != 0 {
+			if err := checkVersion_tx(ctx, tx, rel, dataSet, loginId); err != nil {
+				return indexRecordIds, err
+			}
+		}
+
 		// set data for record of given relation index
 
 		// log data changes if retention is enabled
@@ -226,6 +246,50 @@ func setForIndex_tx(ctx context.Context, tx pgx.Tx, index int,
 			continue
 		}
 
+		// JSON values are validated and stored as JSON text
+		if schema.IsContentJson(atr.Content) {
+			v, err := getValueJson(atr, attribute.Value)
+			if err != nil {
+				return err
+			}
+			attribute.Value = v
+			dataSet.Attributes[ai].Value = v
+		}
+
+		// geo point values are stored as points, GeoJSON is kept for logs and comparisons
+		if schema.IsContentPoint(atr.Content) {
+			p, g, err := getValuePoint(atr, attribute.Value)
+			if err != nil {
+				return err
+			}
+			attribute.Value = p
+			dataSet.Attributes[ai].Value = g
+		}
+
+		// money values are stored as composite type, durations as interval
+		// normalized values (money with currency precision, seconds) are kept for logs and comparisons
+		if schema.IsContentMoney(atr.Content) {
+			m, n, err := getValueMoney(atr, attribute.Value)
+			if err != nil {
+				return err
+			}
+			attribute.Value = m
+			dataSet.Attributes[ai].Value = n
+		}
+		if schema.IsContentInterval(atr.Content) {
+			i, s, err := getValueInterval(atr, attribute.Value)
+			if err != nil {
+				return err
+			}
+			attribute.Value = i
+			dataSet.Attributes[ai].Value = s
+		}
+
+		// check normalized value against attribute validation rules
+		if err := checkValueValidation(atr, dataSet.Attributes[ai].Value); err != nil {
+			return err
+		}
+
 		// process attribute values for this relation tupel
 		values = append(values, attribute.Value)
 
@@ -243,7 +307,7 @@ func setForIndex_tx(ctx context.Context, tx pgx.Tx, index int,
 
 		// get policy filter if applicable
 		tableAlias := "t"
-		policyFilter, err := getPolicyFilter(loginId, "update", tableAlias, rel.Policies)
+		policyFilter, err := getPolicyFilter(ctx, loginId, "update", tableAlias, rel.Policies)
 		if err != nil {
 			return err
 		}
@@ -253,8 +317,10 @@ func setForIndex_tx(ctx context.Context, tx pgx.Tx, index int,
 			UPDATE "%s"."%s" AS "%s" SET %s
 			WHERE "%s"."%s" = %s
 			%s
+			%s
 		`, mod.Name, rel.Name, tableAlias, strings.Join(params, `, `), tableAlias,
-			schema.PkName, fmt.Sprintf("$%d", len(values)), policyFilter),
+			schema.PkName, fmt.Sprintf("$%d", len(values)), policyFilter,
+			getSoftDeleteFilter(tableAlias, rel)),
 			values...); err != nil {
 
 			return err
@@ -556,3 +622,132 @@ func collectCurrentValuesForLog_tx(ctx context.Context, tx pgx.Tx,
 	}
 	return results[0], nil
 }
+
+// checks whether record was changed by others since it was retrieved (optimistic locking)
+// record is locked for the rest of the transaction, so it cannot change between check and update
+// if the version changed, values to set are compared with their originally retrieved values
+// changes to other attributes do not conflict and are kept
+func checkVersion_tx(ctx context.Context, tx pgx.Tx, rel types.Relation,
+	dataSet types.DataSet, loginId int64) error {
+
+	mod, exists := cache.ModuleIdMap[rel.ModuleId]
+	if !exists {
+		return handler.ErrSchemaUnknownModule(rel.ModuleId)
+	}
+
+	var version int64
+	err := tx.QueryRow(ctx, fmt.Sprintf(`
+		SELECT xmin::TEXT::BIGINT
+		FROM "%s"."%s"
+		WHERE "%s" = $1
+		FOR UPDATE
+	`, mod.Name, rel.Name, schema.PkName), dataSet.RecordId).Scan(&version)
+
+	if err == pgx.ErrNoRows || (err == nil && version == dataSet.Version) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// file attribute values are changes to apply, not states to compare
+	attributes := make([]types.DataSetAttribute, 0)
+	for _, a := range dataSet.Attributes {
+		atr, exists := cache.AttributeIdMap[a.AttributeId]
+		if !exists {
+			return handler.ErrSchemaUnknownAttribute(a.AttributeId)
+		}
+		if !schema.IsContentFiles(atr.Content) {
+			attributes = append(attributes, a)
+		}
+	}
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	current, err := collectCurrentValuesForLog_tx(ctx, tx, rel.Id,
+		attributes, []int{}, dataSet.RecordId, loginId)
+
+	if err != nil {
+		return err
+	}
+
+	attributeIds := make([]string, 0)
+	loginNames := make([]string, 0)
+	for i, a := range attributes {
+
+		// encrypted values cannot be compared, any change is a conflict
+		if !cache.AttributeIdMap[a.AttributeId].Encrypted {
+			valueCurrent, err := getValueComparable(current.Values[i])
+			if err != nil {
+				return err
+			}
+			valueOrg, err := getValueComparable(a.ValueOrg)
+			if err != nil {
+				return err
+			}
+			valueNew, err := getValueComparable(a.Value)
+			if err != nil {
+				return err
+			}
+
+			// unchanged since retrieval or already changed to the same value
+			if reflect.DeepEqual(valueCurrent, valueOrg) || reflect.DeepEqual(valueCurrent, valueNew) {
+				continue
+			}
+		}
+		attributeIds = append(attributeIds, a.AttributeId.String())
+
+		// get login that changed attribute last, if change was logged
+		var loginName string
+		if err := tx.QueryRow(ctx, `
+			SELECT COALESCE((
+				SELECT l.name
+				FROM instance.data_log_value AS v
+				JOIN instance.data_log       AS d ON d.id = v.data_log_id
+				JOIN instance.login          AS l ON l.id = d.login_id_wofk
+				WHERE d.relation_id    = $1
+				AND   d.record_id_wofk = $2
+				AND   v.attribute_id   = $3
+				ORDER BY d.date_change DESC
+				LIMIT 1
+			),'')
+		`, rel.Id, dataSet.RecordId, a.AttributeId).Scan(&loginName); err != nil {
+			return err
+		}
+
+		// remove characters used to separate error arguments
+		loginName = strings.NewReplacer(",", "", "[", "", "]", "").Replace(loginName)
+		if loginName != "" && !slices.Contains(loginNames, loginName) {
+			loginNames = append(loginNames, loginName)
+		}
+	}
+
+	if len(attributeIds) == 0 {
+		return nil
+	}
+	return handler.CreateErrCodeWithArgs("APP", handler.ErrCodeAppRecordChanged, map[string]string{
+		"ATR_IDS":   strings.Join(attributeIds, ","),
+		"LOGINS":    strings.Join(loginNames, ","),
+		"RECORD_ID": fmt.Sprintf("%d", dataSet.RecordId),
+	})
+}
+
+// returns JSON text for JSONB attribute value
+// strings must contain valid JSON text, other values (objects, arrays, numbers, booleans) are converted
+func getValueJson(atr types.Attribute, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		if !json.Valid([]byte(v)) {
+			return nil, handler.CreateErrCodeWithArgs("APP", handler.ErrCodeAppInvalidJson,
+				map[string]string{"ATR_ID": atr.Id.String()})
+		}
+		return json.RawMessage(v), nil
+	}
+	return json.Marshal(value)
+}
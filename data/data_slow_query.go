@@ -0,0 +1,202 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"r3/config"
+	"r3/db"
+	"r3/log"
+	"r3/tools"
+	"r3/types"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofrs/uuid"
+)
+
+// limits concurrent EXPLAIN plans, sampled plans are skipped while limit is reached
+var slowQueryExplainSlots = make(chan struct{}, 2)
+
+// captures data GET queries that exceed the configured threshold
+// arguments are redacted as they can contain sensitive data (filter values, login IDs, etc.)
+// queries are stored without LIMIT/OFFSET, to aggregate all pages of the same query
+// EXPLAIN plans are sampled or requested by admins, as they execute the query again (ANALYZE)
+// plans run asynchronously, outside of the request transaction and with their own timeout
+// failing to capture a query must not fail the data request, errors are only logged
+func captureSlowQuery(data types.DataGet, loginId int64, query string,
+	queryArgs []interface{}, duration time.Duration) {
+
+	threshold := config.GetUint64("slowQueryThresholdMs")
+	if threshold == 0 || duration.Milliseconds() < int64(threshold) {
+		return
+	}
+
+	attributeIdsFilter := make([]uuid.UUID, 0)
+	attributeIdsJoin := make([]uuid.UUID, 0)
+	collectSlowQueryAttributeIds(data, &attributeIdsFilter, &attributeIdsJoin)
+
+	querySql := getSlowQueryNormalized(query, data.Limit, data.Offset)
+
+	// store outside of request transaction, slow queries are also relevant if the request fails later
+	// form and field are sent by the client, they are only stored if they use the queried relation
+	var id int64
+	if err := db.Pool.QueryRow(db.Ctx, `
+		INSERT INTO instance.slow_query (login_id, relation_id, api_id, form_id,
+			field_id, date, duration_ms, query_sql, query_args,
+			attribute_ids_filter, attribute_ids_join)
+		VALUES (NULLIF($1,0),$2,$3,COALESCE((
+			SELECT form_id
+			FROM app.query
+			WHERE form_id     = $4
+			AND   relation_id = $2
+		),(
+			SELECT f.form_id
+			FROM app.field AS f
+			JOIN app.query AS q ON q.field_id = f.id
+			WHERE f.id          = $5
+			AND   q.relation_id = $2
+		)),(
+			SELECT field_id
+			FROM app.query
+			WHERE field_id    = $5
+			AND   relation_id = $2
+		),$6,$7,$8,$9,$10,$11)
+		RETURNING id
+	`, loginId, data.RelationId, data.ApiId, data.FormId, data.FieldId,
+		tools.GetTimeUnix(), duration.Milliseconds(), querySql,
+		getSlowQueryArgsRedacted(queryArgs), attributeIdsFilter,
+		attributeIdsJoin).Scan(&id); err != nil {
+
+		log.Error("server", "failed to capture slow query", err)
+		return
+	}
+
+	// plan is captured if requested for this query, otherwise it is sampled
+	tag, err := db.Pool.Exec(db.Ctx, `
+		DELETE FROM instance.slow_query_explain
+		WHERE query_sql = $1
+	`, querySql)
+	if err != nil {
+		log.Error("server", "failed to check plan request of slow query", err)
+		return
+	}
+	if tag.RowsAffected() == 0 && rand.Intn(100) >= int(config.GetUint64("slowQueryExplainPercent")) {
+		return
+	}
+	select {
+	case slowQueryExplainSlots <- struct{}{}:
+	default:
+		log.Info("server", "skipped plan of slow query, too many plans are running")
+		return
+	}
+
+	go func() {
+		defer func() { <-slowQueryExplainSlots }()
+
+		// plan takes at least as long as the query, timeout allows for some margin
+		ctx, ctxCancel := context.WithTimeout(db.Ctx, 2*duration+10*time.Second)
+		defer ctxCancel()
+
+		plan, err := getSlowQueryExplain(ctx, loginId, query, queryArgs)
+		if err != nil {
+			log.Warning("server", "failed to get plan of slow query", err)
+			return
+		}
+		if _, err := db.Pool.Exec(db.Ctx, `
+			UPDATE instance.slow_query
+			SET explain = $1
+			WHERE id = $2
+		`, plan, id); err != nil {
+			log.Error("server", "failed to store plan of slow query", err)
+		}
+	}()
+}
+
+// returns query without LIMIT/OFFSET of the main query, as added by data GET
+func getSlowQueryNormalized(query string, limit int, offset int) string {
+	if offset != 0 {
+		query = strings.TrimSuffix(query, fmt.Sprintf("\nOFFSET %d", offset))
+	}
+	if limit != 0 {
+		query = strings.TrimSuffix(query, fmt.Sprintf("\nLIMIT %d", limit))
+	}
+	return query
+}
+
+// collects attributes used in filters and joins, incl. sub queries
+func collectSlowQueryAttributeIds(data types.DataGet, attributeIdsFilter *[]uuid.UUID,
+	attributeIdsJoin *[]uuid.UUID) {
+
+	for _, j := range data.Joins {
+		if !slices.Contains(*attributeIdsJoin, j.AttributeId) {
+			*attributeIdsJoin = append(*attributeIdsJoin, j.AttributeId)
+		}
+	}
+
+	for _, f := range data.Filters {
+		for _, s := range []types.DataGetFilterSide{f.Side0, f.Side1} {
+			if s.AttributeId.Valid && !slices.Contains(*attributeIdsFilter, uuid.UUID(s.AttributeId.Bytes)) {
+				*attributeIdsFilter = append(*attributeIdsFilter, s.AttributeId.Bytes)
+			}
+			if s.Query.RelationId != uuid.Nil {
+				collectSlowQueryAttributeIds(s.Query, attributeIdsFilter, attributeIdsJoin)
+			}
+		}
+	}
+}
+
+func getSlowQueryArgsRedacted(queryArgs []interface{}) []string {
+	args := make([]string, 0)
+	for _, arg := range queryArgs {
+		switch v := arg.(type) {
+		case nil:
+			args = append(args, "NULL")
+		case string:
+			args = append(args, fmt.Sprintf("text(%d)", utf8.RuneCountInString(v)))
+		default:
+			args = append(args, fmt.Sprintf("%T", v))
+		}
+	}
+	return args
+}
+
+// plan is executed in its own transaction, which is always rolled back
+// login context is set like for the original request, as it is used by policy functions
+func getSlowQueryExplain(ctx context.Context, loginId int64, query string,
+	queryArgs []interface{}) (string, error) {
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	if _, err := tx.Exec(ctx, `
+		SELECT SET_CONFIG('r3.login_id',$1,TRUE)
+	`, strconv.FormatInt(loginId, 10)); err != nil {
+		return "", err
+	}
+
+	rows, err := tx.Query(ctx, fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS) %s", query), queryArgs...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	lines := make([]string, 0)
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n"), nil
+}
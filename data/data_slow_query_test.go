@@ -0,0 +1,30 @@
+package data
+
+import "testing"
+
+func TestGetSlowQueryNormalized(t *testing.T) {
+	query := "SELECT \"_r0\".\"id\"\nFROM \"app\".\"task\" AS \"_r0\" \nWHERE \"_r0\".\"id\" IN (\n" +
+		"SELECT \"id\" FROM \"app\".\"project\"\nLIMIT 5\n)\nORDER BY \"_r0\".\"id\" ASC"
+
+	tests := []struct {
+		query  string
+		limit  int
+		offset int
+	}{
+		{query, 0, 0},
+		{query + "\nLIMIT 50", 50, 0},
+		{query + "\nLIMIT 50\nOFFSET 100", 50, 100},
+		{query + "\nOFFSET 100", 0, 100},
+	}
+	for _, test := range tests {
+		if got := getSlowQueryNormalized(test.query, test.limit, test.offset); got != query {
+			t.Errorf("getSlowQueryNormalized(%q, %d, %d) = %q, want %q",
+				test.query, test.limit, test.offset, got, query)
+		}
+	}
+
+	// limit of sub query is kept, as it is part of the query definition
+	if got := getSlowQueryNormalized(query, 5, 0); got != query {
+		t.Errorf("getSlowQueryNormalized() removed sub query limit: %q", got)
+	}
+}
@@ -2,16 +2,46 @@ package data_sql
 
 import (
 	"fmt"
+	"math"
 	"r3/types"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
+var regexJsonPath = regexp.MustCompile(`^[\w\-]+(\.[\w\-]+)*$`)
+
 // alias for SELECT expression
 // set for all expressions, needed for grouped/aggregated/sub query expressions
 func GetExpressionAlias(expressionPosition int) string {
 	return fmt.Sprintf(`"_e%d"`, expressionPosition)
 }
 
-func GetExpression(expr types.DataGetExpression, code string, alias string) string {
+// code to extract value at path from JSONB attribute, as text or as JSONB (for JSONB operators like @> or ?)
+// path elements (object keys or array indexes) are separated by dots: 'address.city', 'items.0.name'
+func GetJsonPathCode(code string, path string, asJson bool) (string, error) {
+	if !regexJsonPath.MatchString(path) {
+		return "", fmt.Errorf("invalid JSON path '%s'", path)
+	}
+	operator := "#>>"
+	if asJson {
+		operator = "#>"
+	}
+	return fmt.Sprintf(`(%s %s '{%s}')`, code, operator, strings.ReplaceAll(path, ".", ",")), nil
+}
+
+// code to calculate distance in meters between geo point attribute and longitude/latitude
+func GetGeoDistanceCode(code string, lonLat []float64) (string, error) {
+	if len(lonLat) != 2 || math.Abs(lonLat[0]) > 180 || math.Abs(lonLat[1]) > 90 {
+		return "", fmt.Errorf("invalid geo distance location %v", lonLat)
+	}
+	return fmt.Sprintf("instance.geo_distance(%s,POINT(%s,%s))", code,
+		strconv.FormatFloat(lonLat[0], 'f', -1, 64),
+		strconv.FormatFloat(lonLat[1], 'f', -1, 64)), nil
+}
+
+// returns SELECT expression, attribute content is required for special cases (money, interval)
+func GetExpression(expr types.DataGetExpression, code string, alias string, content string) string {
 	var distinct = ""
 	if expr.Distincted {
 		distinct = "DISTINCT "
@@ -19,6 +49,21 @@ func GetExpression(expr types.DataGetExpression, code string, alias string) stri
 	aggregated := expr.Aggregator.Valid
 	subQuery := !expr.AttributeId.Valid
 
+	// money values are returned as JSON (amount as text to keep precision)
+	// numeric aggregations are calculated per currency, results are lists of money values
+	if content == "money" && !subQuery {
+		switch expr.Aggregator.String {
+		case "avg", "max", "min", "sum":
+			return fmt.Sprintf("instance.money_%s(%s%s) AS %s", expr.Aggregator.String, distinct, code, alias)
+		case "count":
+			// count is not affected
+		case "list":
+			code = fmt.Sprintf("((%s).amount::TEXT || ' ' || (%s).currency)", code, code)
+		default:
+			code = fmt.Sprintf("instance.money_to_json(%s)", code)
+		}
+	}
+
 	if aggregated {
 		// build aggregation syntax
 		var prefix string
@@ -37,6 +82,9 @@ func GetExpression(expr types.DataGetExpression, code string, alias string) stri
 		case "array":
 			return fmt.Sprintf("%sARRAY_AGG(%s%s)%s AS %s", prefix, distinct, code, postfix, alias)
 		case "avg":
+			if content == "interval" {
+				return fmt.Sprintf("%sAVG(%s%s)%s AS %s", prefix, distinct, code, postfix, alias)
+			}
 			return fmt.Sprintf("%sAVG(%s%s)::NUMERIC(20,2)%s AS %s", prefix, distinct, code, postfix, alias)
 		case "count":
 			return fmt.Sprintf("%sCOUNT(%s%s)%s AS %s", prefix, distinct, code, postfix, alias)
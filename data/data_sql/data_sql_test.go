@@ -0,0 +1,35 @@
+package data_sql
+
+import "testing"
+
+func TestGetJsonPathCode(t *testing.T) {
+	tests := []struct {
+		path    string
+		asJson  bool
+		want    string
+		wantErr bool
+	}{
+		{"city", false, `("r0"."data" #>> '{city}')`, false},
+		{"address.city", false, `("r0"."data" #>> '{address,city}')`, false},
+		{"items.0.name", false, `("r0"."data" #>> '{items,0,name}')`, false},
+		{"first-name", false, `("r0"."data" #>> '{first-name}')`, false},
+		{"tags", true, `("r0"."data" #> '{tags}')`, false},
+		{"address.tags", true, `("r0"."data" #> '{address,tags}')`, false},
+		{"", false, "", true},
+		{"address.", false, "", true},
+		{".city", false, "", true},
+		{"address..city", false, "", true},
+		{"city'}') OR (1=1", false, "", true},
+		{"address city", false, "", true},
+	}
+	for _, test := range tests {
+		got, err := GetJsonPathCode(`"r0"."data"`, test.path, test.asJson)
+		if (err != nil) != test.wantErr {
+			t.Errorf("GetJsonPathCode(%q, %v) error = %v, want error %v", test.path, test.asJson, err, test.wantErr)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("GetJsonPathCode(%q, %v) = %s, want %s", test.path, test.asJson, got, test.want)
+		}
+	}
+}
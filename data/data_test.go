@@ -0,0 +1,34 @@
+package data
+
+import (
+	"r3/types"
+	"strings"
+	"testing"
+)
+
+func TestGetAuthorizedRecordQuery(t *testing.T) {
+	policyUpdate := "\nAND \"t\".\"id\" = ANY(\"app\".\"policy_update\"())"
+	policySelect := "\nAND \"t\".\"id\" <> ALL(\"app\".\"policy_select\"())"
+
+	tests := []struct {
+		name          string
+		rel           types.Relation
+		policyFilters []string
+		want          string
+	}{
+		{"no filters", types.Relation{Name: "task"}, []string{""},
+			`SELECT EXISTS( SELECT 1 FROM "app"."task" AS "t" WHERE "t"."id" = $1 )`},
+		{"update policy", types.Relation{Name: "task"}, []string{policyUpdate},
+			`SELECT EXISTS( SELECT 1 FROM "app"."task" AS "t" WHERE "t"."id" = $1 AND "t"."id" = ANY("app"."policy_update"()) )`},
+		{"select and update policies", types.Relation{Name: "task"}, []string{policySelect, policyUpdate},
+			`SELECT EXISTS( SELECT 1 FROM "app"."task" AS "t" WHERE "t"."id" = $1 AND "t"."id" <> ALL("app"."policy_select"()) AND "t"."id" = ANY("app"."policy_update"()) )`},
+		{"soft delete", types.Relation{Name: "task", SoftDelete: true}, []string{policyUpdate},
+			`SELECT EXISTS( SELECT 1 FROM "app"."task" AS "t" WHERE "t"."id" = $1 AND "t"."id" = ANY("app"."policy_update"()) AND "t"."_deleted" IS NULL )`},
+	}
+	for _, test := range tests {
+		got := strings.Join(strings.Fields(getAuthorizedRecordQuery("app", test.rel, "t", test.policyFilters)), " ")
+		if got != test.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", test.name, got, test.want)
+		}
+	}
+}
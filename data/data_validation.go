@@ -0,0 +1,122 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"r3/cache"
+	"r3/handler"
+	"r3/schema"
+	"r3/types"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// attribute validation rules, checked for values to set
+// texts: regular expression, min./max. length, allowed values
+// numbers: min./max. value, allowed values
+// durations: min./max. value in seconds
+// NULL values are not checked, required values are handled by NOT NULL constraints
+// validation expressions are check constraints, enforced by the database
+// requires schema cache read lock, for compiled regular expressions
+func checkValueValidation(atr types.Attribute, value interface{}) error {
+	v := atr.Validation
+
+	if value == nil || atr.Encrypted {
+		return nil
+	}
+	if !v.Regex.Valid && !v.Min.Valid && !v.Max.Valid && len(v.Values) == 0 {
+		return nil
+	}
+
+	getErr := func(number int, limit interface{}) error {
+		args := map[string]string{"ATR_ID": atr.Id.String()}
+		if limit != nil {
+			args["LIMIT"] = fmt.Sprintf("%v", limit)
+		}
+		return handler.CreateErrCodeWithArgs("APP", number, args)
+	}
+
+	if schema.IsContentText(atr.Content) {
+		text, ok := value.(string)
+		if !ok {
+			text = fmt.Sprintf("%v", value)
+		}
+
+		if v.Regex.Valid {
+			// compiled when the schema cache is loaded
+			rx, exists := cache.AttributeIdMapRegex[atr.Id]
+			if !exists {
+				return handler.ErrSchemaUnknownAttribute(atr.Id)
+			}
+			if !rx.MatchString(text) {
+				return getErr(handler.ErrCodeAppValidationRegex, nil)
+			}
+		}
+
+		length := float64(utf8.RuneCountInString(text))
+		if v.Min.Valid {
+			min, _ := v.Min.Float64Value()
+			if length < min.Float64 {
+				return getErr(handler.ErrCodeAppValidationLengthMin, min.Float64)
+			}
+		}
+		if v.Max.Valid {
+			max, _ := v.Max.Float64Value()
+			if length > max.Float64 {
+				return getErr(handler.ErrCodeAppValidationLengthMax, max.Float64)
+			}
+		}
+		if len(v.Values) != 0 && !slices.Contains(v.Values, text) {
+			return getErr(handler.ErrCodeAppValidationValues, nil)
+		}
+		return nil
+	}
+
+	if schema.IsContentNumber(atr.Content) || schema.IsContentInterval(atr.Content) {
+		var number float64
+		var err error
+		switch n := value.(type) {
+		case float64:
+			number = n
+		case int64:
+			number = float64(n)
+		case json.Number:
+			number, err = n.Float64()
+		case string:
+			number, err = strconv.ParseFloat(n, 64)
+		default:
+			err = strconv.ErrSyntax
+		}
+		if err != nil {
+			// invalid numbers are rejected by the database
+			return nil
+		}
+
+		if v.Min.Valid {
+			min, _ := v.Min.Float64Value()
+			if number < min.Float64 {
+				return getErr(handler.ErrCodeAppValidationValueMin, min.Float64)
+			}
+		}
+		if v.Max.Valid {
+			max, _ := v.Max.Float64Value()
+			if number > max.Float64 {
+				return getErr(handler.ErrCodeAppValidationValueMax, max.Float64)
+			}
+		}
+		if len(v.Values) != 0 {
+			allowed := false
+			for _, valueAllowed := range v.Values {
+				if n, err := strconv.ParseFloat(valueAllowed, 64); err == nil && n == number {
+					allowed = true
+					break
+				}
+			}
+			if !allowed {
+				return getErr(handler.ErrCodeAppValidationValues, nil)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"r3/cache"
+	"r3/handler"
+	"r3/types"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCheckValueValidation(t *testing.T) {
+	var number = func(n int64) pgtype.Numeric {
+		return pgtype.Numeric{Int: big.NewInt(n), Valid: true}
+	}
+	var regex = func(s string) pgtype.Text {
+		return pgtype.Text{String: s, Valid: true}
+	}
+
+	tests := []struct {
+		content    string
+		encrypted  bool
+		validation types.AttributeValidation
+		value      interface{}
+		wantCode   int // 0 = valid
+	}{
+		// texts
+		{"text", false, types.AttributeValidation{}, "anything", 0},
+		{"text", false, types.AttributeValidation{Regex: regex(`^[A-Z]{2}\d+$`)}, "AB12", 0},
+		{"text", false, types.AttributeValidation{Regex: regex(`^[A-Z]{2}\d+$`)}, "ab12", handler.ErrCodeAppValidationRegex},
+		{"varchar", false, types.AttributeValidation{Min: number(3)}, "äöü", 0},
+		{"varchar", false, types.AttributeValidation{Min: number(3)}, "ab", handler.ErrCodeAppValidationLengthMin},
+		{"varchar", false, types.AttributeValidation{Max: number(3)}, "abcd", handler.ErrCodeAppValidationLengthMax},
+		{"text", false, types.AttributeValidation{Values: []string{"open", "closed"}}, "open", 0},
+		{"text", false, types.AttributeValidation{Values: []string{"open", "closed"}}, "Open", handler.ErrCodeAppValidationValues},
+		{"text", false, types.AttributeValidation{Min: number(3)}, nil, 0},
+		{"text", true, types.AttributeValidation{Min: number(3)}, "a", 0},
+
+		// numbers
+		{"integer", false, types.AttributeValidation{Min: number(1), Max: number(10)}, float64(5), 0},
+		{"integer", false, types.AttributeValidation{Min: number(1), Max: number(10)}, float64(0), handler.ErrCodeAppValidationValueMin},
+		{"integer", false, types.AttributeValidation{Min: number(1), Max: number(10)}, int64(11), handler.ErrCodeAppValidationValueMax},
+		{"numeric", false, types.AttributeValidation{Max: number(10)}, json.Number("10.5"), handler.ErrCodeAppValidationValueMax},
+		{"numeric", false, types.AttributeValidation{Max: number(10)}, "9.5", 0},
+		{"bigint", false, types.AttributeValidation{Values: []string{"1", "2.0"}}, float64(2), 0},
+		{"bigint", false, types.AttributeValidation{Values: []string{"1", "2.0"}}, float64(3), handler.ErrCodeAppValidationValues},
+		{"integer", false, types.AttributeValidation{Max: number(10)}, "abc", 0}, // rejected by database
+
+		// durations, in seconds
+		{"interval", false, types.AttributeValidation{Max: number(3600)}, int64(3600), 0},
+		{"interval", false, types.AttributeValidation{Max: number(3600)}, int64(3601), handler.ErrCodeAppValidationValueMax},
+
+		// other contents are not checked
+		{"boolean", false, types.AttributeValidation{Values: []string{"true"}}, false, 0},
+	}
+	cache.AttributeIdMapRegex = make(map[uuid.UUID]*regexp.Regexp)
+	for i, test := range tests {
+		atr := types.Attribute{Id: uuid.FromStringOrNil(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1)),
+			Content: test.content, Encrypted: test.encrypted, Validation: test.validation}
+
+		// regular expressions are compiled when the schema cache is loaded
+		if test.validation.Regex.Valid {
+			cache.AttributeIdMapRegex[atr.Id] = regexp.MustCompile(test.validation.Regex.String)
+		}
+		err := checkValueValidation(atr, test.value)
+
+		if test.wantCode == 0 {
+			if err != nil {
+				t.Errorf("checkValueValidation(%s, %v) error = %v, want valid", test.content, test.value, err)
+			}
+			continue
+		}
+		wantPrefix := fmt.Sprintf("{ERR_APP_%03d}", test.wantCode)
+		if err == nil || !strings.HasPrefix(err.Error(), wantPrefix) {
+			t.Errorf("checkValueValidation(%s, %v) error = %v, want %s", test.content, test.value, err, wantPrefix)
+		}
+	}
+}
+
+func TestCheckValueValidationUncachedRegex(t *testing.T) {
+	cache.AttributeIdMapRegex = make(map[uuid.UUID]*regexp.Regexp)
+	atr := types.Attribute{Id: uuid.Must(uuid.NewV4()), Content: "text", Validation: types.AttributeValidation{
+		Regex: pgtype.Text{String: "^a", Valid: true},
+	}}
+	if err := checkValueValidation(atr, "abc"); err == nil {
+		t.Error("checkValueValidation() without compiled regular expression returned no error")
+	}
+}
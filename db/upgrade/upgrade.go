@@ -256,6 +256,842 @@ var upgradeFunctions = map[string]func(tx pgx.Tx) (string, error){
 				);
 			END;
 			$BODY$;
+			
+			-- persistent bruteforce host blocks, shared between cluster nodes
+			CREATE TABLE instance.bruteforce_block (
+				host text NOT NULL,
+				node_id uuid,
+				date_blocked bigint NOT NULL,
+				date_expiry bigint,
+			    CONSTRAINT bruteforce_block_pkey PRIMARY KEY (host),
+			    CONSTRAINT bruteforce_block_node_id_fkey FOREIGN KEY (node_id)
+			        REFERENCES instance_cluster.node (id) MATCH SIMPLE
+			        ON UPDATE SET NULL
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_bruteforce_block_node_id_fkey ON instance.bruteforce_block USING btree (node_id ASC NULLS LAST);
+			CREATE INDEX ind_bruteforce_block_date_expiry  ON instance.bruteforce_block USING btree (date_expiry ASC NULLS LAST);
+			
+			ALTER TYPE instance_cluster.node_event_content ADD VALUE 'bruteforceBlocked';
+			ALTER TYPE instance_cluster.node_event_content ADD VALUE 'bruteforceUnblocked';
+			
+			INSERT INTO instance.config (name,value) VALUES
+				('bruteforceBlockMinutes','60'),
+				('bruteforceIpv6Prefix','64'),
+				('proxiesTrusted','');
+			
+			-- admin impersonation sessions with audit trail
+			CREATE TABLE instance.login_impersonation (
+				id serial NOT NULL,
+				login_id integer NOT NULL,
+				login_id_admin integer,
+				reason text NOT NULL,
+				date_start bigint NOT NULL,
+				date_end bigint NOT NULL,
+			    CONSTRAINT login_impersonation_pkey PRIMARY KEY (id),
+			    CONSTRAINT login_impersonation_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_impersonation_login_id_admin_fkey FOREIGN KEY (login_id_admin)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_login_impersonation_login_id_fkey       ON instance.login_impersonation USING btree (login_id ASC NULLS LAST);
+			CREATE INDEX fki_login_impersonation_login_id_admin_fkey ON instance.login_impersonation USING btree (login_id_admin ASC NULLS LAST);
+			CREATE INDEX ind_login_impersonation_date_start          ON instance.login_impersonation USING btree (date_start DESC NULLS LAST);
+			
+			CREATE TABLE instance.login_impersonation_log (
+				login_impersonation_id integer NOT NULL,
+				date_milli bigint NOT NULL,
+				ressource text NOT NULL,
+				action text NOT NULL,
+				payload text NOT NULL,
+			    CONSTRAINT login_impersonation_log_login_impersonation_id_fkey FOREIGN KEY (login_impersonation_id)
+			        REFERENCES instance.login_impersonation (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_login_impersonation_log_login_impersonation_id_fkey
+				ON instance.login_impersonation_log USING btree (login_impersonation_id ASC NULLS LAST);
+			
+			-- SCIM provisioning
+			ALTER TYPE instance.log_context ADD VALUE 'scim';
+			INSERT INTO instance.config (name,value) VALUES ('logScim','2');
+			INSERT INTO instance.config (name,value) VALUES ('scimLoginTemplateId','0');
+			INSERT INTO instance.config (name,value) VALUES ('scimTokenHash','');
+			
+			CREATE TABLE instance.scim_login (
+				login_id integer NOT NULL,
+				external_id text,
+				name_given text,
+				name_family text,
+				date_created bigint NOT NULL,
+				date_changed bigint NOT NULL,
+			    CONSTRAINT scim_login_pkey PRIMARY KEY (login_id),
+			    CONSTRAINT scim_login_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE TABLE instance.scim_group (
+				id uuid NOT NULL,
+				name text NOT NULL,
+				external_id text,
+				date_created bigint NOT NULL,
+				date_changed bigint NOT NULL,
+			    CONSTRAINT scim_group_pkey PRIMARY KEY (id),
+			    CONSTRAINT scim_group_name_key UNIQUE (name)
+			);
+			
+			CREATE TABLE instance.scim_group_login (
+				scim_group_id uuid NOT NULL,
+				login_id integer NOT NULL,
+			    CONSTRAINT scim_group_login_pkey PRIMARY KEY (scim_group_id, login_id),
+			    CONSTRAINT scim_group_login_scim_group_id_fkey FOREIGN KEY (scim_group_id)
+			        REFERENCES instance.scim_group (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT scim_group_login_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_scim_group_login_login_id_fkey ON instance.scim_group_login USING btree (login_id ASC NULLS LAST);
+			
+			CREATE TABLE instance.scim_group_role (
+				scim_group_id uuid NOT NULL,
+				role_id uuid NOT NULL,
+			    CONSTRAINT scim_group_role_pkey PRIMARY KEY (scim_group_id, role_id),
+			    CONSTRAINT scim_group_role_scim_group_id_fkey FOREIGN KEY (scim_group_id)
+			        REFERENCES instance.scim_group (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT scim_group_role_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_scim_group_role_role_id_fkey ON instance.scim_group_role USING btree (role_id ASC NULLS LAST);
+			
+			-- roles assigned via SCIM group mappings, only these are replaced when group memberships change
+			ALTER TABLE instance.login_role ADD COLUMN scim boolean NOT NULL DEFAULT false;
+			
+			-- LDAP attribute mapping into login records
+			ALTER TABLE instance.ldap ADD COLUMN login_record_attribute_id uuid;
+			ALTER TABLE instance.ldap ADD CONSTRAINT ldap_login_record_attribute_id_fkey FOREIGN KEY (login_record_attribute_id)
+				REFERENCES app.attribute (id) MATCH SIMPLE
+				ON UPDATE CASCADE
+				ON DELETE SET NULL
+				DEFERRABLE INITIALLY DEFERRED;
+			
+			CREATE INDEX fki_ldap_login_record_attribute_id_fkey ON instance.ldap USING btree (login_record_attribute_id ASC NULLS LAST);
+			
+			CREATE TABLE instance.ldap_record_attribute (
+				ldap_id integer NOT NULL,
+				attribute_id uuid NOT NULL,
+				ldap_attribute text NOT NULL,
+			    CONSTRAINT ldap_record_attribute_pkey PRIMARY KEY (ldap_id, attribute_id),
+			    CONSTRAINT ldap_record_attribute_ldap_id_fkey FOREIGN KEY (ldap_id)
+			        REFERENCES instance.ldap (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT ldap_record_attribute_attribute_id_fkey FOREIGN KEY (attribute_id)
+			        REFERENCES app.attribute (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_ldap_record_attribute_attribute_id_fkey ON instance.ldap_record_attribute USING btree (attribute_id ASC NULLS LAST);
+			
+			-- incremental LDAP sync
+			ALTER TABLE instance.ldap ADD COLUMN delta_sync boolean NOT NULL DEFAULT false;
+			ALTER TABLE instance.ldap ADD COLUMN full_sync_interval integer NOT NULL DEFAULT 24;
+			ALTER TABLE instance.ldap ADD COLUMN sync_marker text;
+			ALTER TABLE instance.ldap ADD COLUMN date_sync_full bigint;
+			
+			CREATE TABLE instance.ldap_run (
+				id serial NOT NULL,
+				ldap_id integer NOT NULL,
+				date_start bigint NOT NULL,
+				duration_ms bigint NOT NULL,
+				full_sync boolean NOT NULL,
+				count_created integer NOT NULL,
+				count_updated integer NOT NULL,
+				count_deactivated integer NOT NULL,
+				count_failed integer NOT NULL,
+				error text,
+			    CONSTRAINT ldap_run_pkey PRIMARY KEY (id),
+			    CONSTRAINT ldap_run_ldap_id_fkey FOREIGN KEY (ldap_id)
+			        REFERENCES instance.ldap (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_ldap_run_ldap_id_fkey ON instance.ldap_run USING btree (ldap_id ASC NULLS LAST);
+			CREATE INDEX ind_ldap_run_date_start ON instance.ldap_run USING btree (date_start DESC NULLS LAST);
+			
+			-- LDAP nested groups for generic LDAP servers & failover hosts
+			ALTER TABLE instance.ldap ADD COLUMN nested_groups boolean NOT NULL DEFAULT false;
+			ALTER TABLE instance.ldap ADD COLUMN hosts_failover text[] NOT NULL DEFAULT '{}';
+			ALTER TABLE instance.ldap ADD COLUMN sync_host text;
+			
+			-- client certificate authentication
+			INSERT INTO instance.config (name,value) VALUES ('clientCertAuth','0');
+			INSERT INTO instance.config (name,value) VALUES ('clientCertCaBundle','');
+			INSERT INTO instance.config (name,value) VALUES ('clientCertLoginMap','subjectCn');
+			
+			ALTER TABLE instance.login ADD COLUMN cert_required boolean NOT NULL DEFAULT false;
+			
+			CREATE TABLE instance.client_cert_revoked (
+				serial text NOT NULL,
+				comment text,
+				date_revoked bigint NOT NULL,
+			    CONSTRAINT client_cert_revoked_pkey PRIMARY KEY (serial)
+			);
+			
+			-- role access conditions
+			ALTER TABLE app.role ADD COLUMN access_networks text[] NOT NULL DEFAULT '{}';
+			ALTER TABLE app.role ADD COLUMN access_timezone text NOT NULL DEFAULT 'UTC';
+			
+			CREATE TABLE app.role_window (
+				role_id uuid NOT NULL,
+				"position" smallint NOT NULL,
+				weekdays integer[] NOT NULL,
+				time_from integer NOT NULL,
+				time_to integer NOT NULL,
+			    CONSTRAINT role_window_pkey PRIMARY KEY (role_id, "position"),
+			    CONSTRAINT role_window_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			-- time-limited role assignments
+			ALTER TABLE instance.login_role ADD COLUMN date_from bigint;
+			ALTER TABLE instance.login_role ADD COLUMN date_to bigint;
+			
+			CREATE INDEX ind_login_role_date_to ON instance.login_role USING btree (date_to ASC NULLS LAST);
+			
+			-- role delegations to substitute logins
+			CREATE TABLE instance.login_role_delegation (
+				id serial NOT NULL,
+				login_id integer NOT NULL,
+				login_id_substitute integer NOT NULL,
+				role_id uuid NOT NULL,
+				date_from bigint NOT NULL,
+				date_to bigint NOT NULL,
+				date_created bigint NOT NULL,
+				date_ended bigint,
+				revoked boolean NOT NULL,
+			    CONSTRAINT login_role_delegation_pkey PRIMARY KEY (id),
+			    CONSTRAINT login_role_delegation_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_role_delegation_login_id_substitute_fkey FOREIGN KEY (login_id_substitute)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_role_delegation_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_login_role_delegation_login_id_fkey            ON instance.login_role_delegation USING btree (login_id ASC NULLS LAST);
+			CREATE INDEX fki_login_role_delegation_login_id_substitute_fkey ON instance.login_role_delegation USING btree (login_id_substitute ASC NULLS LAST);
+			CREATE INDEX fki_login_role_delegation_role_id_fkey             ON instance.login_role_delegation USING btree (role_id ASC NULLS LAST);
+			CREATE INDEX ind_login_role_delegation_date_to                  ON instance.login_role_delegation USING btree (date_to ASC NULLS LAST);
+			
+			-- audit log for role assignment changes
+			CREATE TYPE instance.login_role_log_action AS ENUM (
+				'assignmentExpired','delegationCreated','delegationExpired','delegationRevoked'
+			);
+			
+			CREATE TABLE instance.login_role_log (
+				id serial NOT NULL,
+				login_id integer NOT NULL,
+				login_id_actor integer,
+				login_role_delegation_id integer,
+				role_id uuid NOT NULL,
+				action instance.login_role_log_action NOT NULL,
+				date bigint NOT NULL,
+			    CONSTRAINT login_role_log_pkey PRIMARY KEY (id),
+			    CONSTRAINT login_role_log_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_role_log_login_id_actor_fkey FOREIGN KEY (login_id_actor)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_role_log_login_role_delegation_id_fkey FOREIGN KEY (login_role_delegation_id)
+			        REFERENCES instance.login_role_delegation (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_role_log_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_login_role_log_login_id_fkey                 ON instance.login_role_log USING btree (login_id ASC NULLS LAST);
+			CREATE INDEX fki_login_role_log_login_id_actor_fkey           ON instance.login_role_log USING btree (login_id_actor ASC NULLS LAST);
+			CREATE INDEX fki_login_role_log_login_role_delegation_id_fkey ON instance.login_role_log USING btree (login_role_delegation_id ASC NULLS LAST);
+			CREATE INDEX fki_login_role_log_role_id_fkey                  ON instance.login_role_log USING btree (role_id ASC NULLS LAST);
+			CREATE INDEX ind_login_role_log_date                          ON instance.login_role_log USING btree (date DESC NULLS LAST);
+			
+			ALTER TYPE instance_cluster.node_event_content ADD VALUE 'loginRoleDelegationChanged';
+			
+			-- regular expiry of role assignments & delegations
+			INSERT INTO instance.task (
+				name,interval_seconds,cluster_master_only,
+				embedded_only,active_only,active
+			) VALUES ('expireLoginRoles',60,true,false,false,true);
+			
+			INSERT INTO instance.schedule (task_name,date_attempt,date_success)
+			VALUES ('expireLoginRoles',0,0);
+			
+			-- only currently valid role assignments & delegations are returned
+			CREATE OR REPLACE FUNCTION instance.get_role_ids(login_id INTEGER, inherited BOOLEAN DEFAULT FALSE)
+				RETURNS UUID[]
+				LANGUAGE 'plpgsql'
+				STABLE
+			AS $BODY$
+			DECLARE
+				login INTEGER := login_id;
+				date_now BIGINT := EXTRACT(EPOCH FROM NOW())::BIGINT;
+				role_ids UUID[];
+			BEGIN
+				SELECT ARRAY(
+					SELECT lr.role_id
+					FROM instance.login_role AS lr
+					WHERE lr.login_id = login
+					AND  (lr.date_from IS NULL OR lr.date_from <= date_now)
+					AND  (lr.date_to   IS NULL OR lr.date_to   >  date_now)
+					UNION
+					SELECT d.role_id
+					FROM instance.login_role_delegation AS d
+					INNER JOIN instance.login_role AS lr
+						ON  lr.login_id = d.login_id
+						AND lr.role_id  = d.role_id
+					INNER JOIN instance.login AS l ON l.id = d.login_id
+					WHERE d.login_id_substitute = login
+					AND   d.date_ended IS NULL
+					AND   d.date_from <= date_now
+					AND   d.date_to   >  date_now
+					AND  (lr.date_from IS NULL OR lr.date_from <= date_now)
+					AND  (lr.date_to   IS NULL OR lr.date_to   >  date_now)
+					AND   l.active
+				) INTO role_ids;
+				
+				IF inherited THEN
+					SELECT ARRAY(
+						WITH RECURSIVE child_ids AS (
+							SELECT role_id_child
+							FROM app.role_child
+							WHERE role_id = ANY(role_ids)
+							UNION
+								SELECT c.role_id_child
+								FROM app.role_child AS c
+								INNER JOIN child_ids AS r ON c.role_id = r.role_id_child
+						)
+						SELECT *
+						FROM child_ids
+						UNION
+						SELECT UNNEST(role_ids)
+					) INTO role_ids;
+				END IF;
+				
+				RETURN role_ids;
+			END;
+			$BODY$;
+			
+			-- segregation of duties, roles that must not be granted to the same login
+			CREATE TABLE app.role_conflict (
+				role_id uuid NOT NULL,
+				role_id_conflict uuid NOT NULL,
+			    CONSTRAINT role_conflict_pkey PRIMARY KEY (role_id, role_id_conflict),
+			    CONSTRAINT role_conflict_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT role_conflict_role_id_conflict_fkey FOREIGN KEY (role_id_conflict)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT role_conflict_role_id_check CHECK (role_id <> role_id_conflict)
+			);
+			
+			CREATE INDEX fki_role_conflict_role_id_fkey          ON app.role_conflict USING btree (role_id ASC NULLS LAST);
+			CREATE INDEX fki_role_conflict_role_id_conflict_fkey ON app.role_conflict USING btree (role_id_conflict ASC NULLS LAST);
+			
+			-- MFA via codes sent by mail & forced MFA
+			INSERT INTO instance.config (name,value) VALUES
+				('mfaMail','0'),
+				('mfaMailAccountId','0'),
+				('mfaMailBody','Your login code is: {CODE}<br /><br />It is valid for {MINUTES} minutes. If you did not try to log in, please inform your administrator.'),
+				('mfaMailCodeMinutes','5'),
+				('mfaMailSubject','Your login code'),
+				('mfaRequired','0');
+			
+			ALTER TABLE instance.login ADD COLUMN mfa_mail text;
+			
+			CREATE TABLE instance.login_mfa_code (
+				login_id integer NOT NULL,
+				code_hash text NOT NULL,
+				date_created bigint NOT NULL,
+				date_expiry bigint NOT NULL,
+				attempts smallint NOT NULL,
+			    CONSTRAINT login_mfa_code_pkey PRIMARY KEY (login_id),
+			    CONSTRAINT login_mfa_code_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE TABLE instance.role_mfa (
+				role_id uuid NOT NULL,
+			    CONSTRAINT role_mfa_pkey PRIMARY KEY (role_id),
+			    CONSTRAINT role_mfa_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			-- self-registration with admin approval
+			INSERT INTO instance.config (name,value) VALUES
+				('registrationActive','0'),
+				('registrationLoginTemplateId','0'),
+				('registrationMailAccountId','0'),
+				('registrationMailBody','Your registration code is: {CODE}<br /><br />It is valid for {MINUTES} minutes. If you did not register, you can ignore this message.'),
+				('registrationMailSubject','Your registration code'),
+				('registrationNotifyMail','');
+			
+			CREATE TABLE instance.registration_rule (
+				id serial NOT NULL,
+				domain text NOT NULL,
+				approve_auto boolean NOT NULL,
+			    CONSTRAINT registration_rule_pkey PRIMARY KEY (id),
+			    CONSTRAINT registration_rule_domain_key UNIQUE (domain)
+			);
+			
+			CREATE TABLE instance.registration_rule_role (
+				registration_rule_id integer NOT NULL,
+				role_id uuid NOT NULL,
+			    CONSTRAINT registration_rule_role_pkey PRIMARY KEY (registration_rule_id, role_id),
+			    CONSTRAINT registration_rule_role_registration_rule_id_fkey FOREIGN KEY (registration_rule_id)
+			        REFERENCES instance.registration_rule (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT registration_rule_role_role_id_fkey FOREIGN KEY (role_id)
+			        REFERENCES app.role (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_registration_rule_role_role_id_fkey ON instance.registration_rule_role USING btree (role_id ASC NULLS LAST);
+			
+			CREATE TYPE instance.login_registration_state AS ENUM ('unverified','pending');
+			
+			CREATE TABLE instance.login_registration (
+				login_id integer NOT NULL,
+				registration_rule_id integer,
+				mail text NOT NULL,
+				state instance.login_registration_state NOT NULL,
+				code_hash text NOT NULL,
+				code_expiry bigint NOT NULL,
+				attempts smallint NOT NULL,
+				date_created bigint NOT NULL,
+			    CONSTRAINT login_registration_pkey PRIMARY KEY (login_id),
+			    CONSTRAINT login_registration_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT login_registration_registration_rule_id_fkey FOREIGN KEY (registration_rule_id)
+			        REFERENCES instance.registration_rule (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_login_registration_registration_rule_id_fkey ON instance.login_registration USING btree (registration_rule_id ASC NULLS LAST);
+			
+			INSERT INTO instance.task (
+				name,interval_seconds,cluster_master_only,
+				embedded_only,active_only,active
+			) VALUES ('cleanupRegistrations',3600,true,false,false,true);
+			
+			INSERT INTO instance.schedule (task_name,date_attempt,date_success)
+			VALUES ('cleanupRegistrations',0,0);
+			
+			-- soft delete & recycle bin
+			ALTER TABLE app.relation ADD COLUMN soft_delete boolean NOT NULL DEFAULT false;
+			ALTER TABLE app.relation ALTER COLUMN soft_delete DROP DEFAULT;
+			
+			INSERT INTO instance.config (name,value) VALUES ('recycleBinKeepDays','30');
+			
+			CREATE TABLE instance.recycle_bin (
+				id serial NOT NULL,
+				relation_id uuid NOT NULL,
+				record_id bigint NOT NULL,
+				record_count integer NOT NULL,
+				login_id integer,
+				date_deleted bigint NOT NULL,
+			    CONSTRAINT recycle_bin_pkey PRIMARY KEY (id),
+			    CONSTRAINT recycle_bin_relation_id_fkey FOREIGN KEY (relation_id)
+			        REFERENCES app.relation (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT recycle_bin_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_recycle_bin_relation_id_fkey ON instance.recycle_bin USING btree (relation_id ASC NULLS LAST);
+			CREATE INDEX fki_recycle_bin_login_id_fkey    ON instance.recycle_bin USING btree (login_id ASC NULLS LAST);
+			CREATE INDEX ind_recycle_bin_date_deleted     ON instance.recycle_bin USING btree (date_deleted ASC NULLS LAST);
+			
+			INSERT INTO instance.task (
+				name,interval_seconds,cluster_master_only,
+				embedded_only,active_only,active
+			) VALUES ('cleanupRecycleBin',86400,true,false,false,true);
+			
+			INSERT INTO instance.schedule (task_name,date_attempt,date_success)
+			VALUES ('cleanupRecycleBin',0,0);
+			
+			-- data change logs for deleted records
+			ALTER TABLE instance.data_log ADD COLUMN deleted BOOLEAN NOT NULL DEFAULT FALSE;
+			ALTER TABLE instance.data_log ALTER COLUMN deleted DROP DEFAULT;
+			
+			-- record presence & soft locks
+			ALTER TYPE instance_cluster.node_event_content ADD VALUE 'recordPresenceChanged';
+			
+			CREATE UNLOGGED TABLE instance.record_presence (
+				client_id uuid NOT NULL,
+				node_id uuid NOT NULL,
+				login_id integer NOT NULL,
+				relation_id uuid NOT NULL,
+				record_id bigint NOT NULL,
+				edit boolean NOT NULL,
+				lock boolean NOT NULL,
+				date_expiry bigint NOT NULL,
+			    CONSTRAINT record_presence_pkey PRIMARY KEY (client_id, relation_id, record_id),
+			    CONSTRAINT record_presence_node_id_fkey FOREIGN KEY (node_id)
+			        REFERENCES instance_cluster.node (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT record_presence_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT record_presence_relation_id_fkey FOREIGN KEY (relation_id)
+			        REFERENCES app.relation (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_record_presence_node_id_fkey  ON instance.record_presence USING btree (node_id ASC NULLS LAST);
+			CREATE INDEX fki_record_presence_login_id_fkey ON instance.record_presence USING btree (login_id ASC NULLS LAST);
+			CREATE INDEX ind_record_presence_record        ON instance.record_presence USING btree (relation_id ASC NULLS LAST, record_id ASC NULLS LAST);
+			
+			-- data change notifications for subscribed clients, only for relations that opt in
+			ALTER TABLE app.relation ADD COLUMN data_change_notify boolean NOT NULL DEFAULT false;
+			ALTER TABLE app.relation ALTER COLUMN data_change_notify DROP DEFAULT;
+			
+			CREATE FUNCTION instance.trg_data_change_notify()
+			    RETURNS trigger
+			    LANGUAGE 'plpgsql'
+			AS $BODY$
+			DECLARE
+				record_id BIGINT;
+			BEGIN
+				IF TG_OP = 'DELETE' THEN
+					record_id := OLD.id;
+				ELSE
+					record_id := NEW.id;
+				END IF;
+				
+				-- payload: relation ID,record ID, identical notifications are merged within transaction
+				PERFORM pg_notify('r3_data_change', CONCAT(TG_ARGV[0], ',', record_id));
+				RETURN NULL;
+			END;
+			$BODY$;
+			
+			-- JSONB attributes
+			ALTER TYPE app.attribute_content ADD VALUE 'jsonb';
+			ALTER TYPE app.condition_operator ADD VALUE '?';
+			
+			-- geo point attributes
+			ALTER TYPE app.attribute_content ADD VALUE 'point';
+			ALTER TYPE app.condition_operator ADD VALUE 'WITHIN RADIUS';
+			ALTER TYPE app.condition_operator ADD VALUE 'WITHIN BOX';
+			ALTER TYPE app.pg_index_method ADD VALUE 'GIST';
+			
+			CREATE FUNCTION instance.geo_distance(p0 POINT, p1 POINT)
+			    RETURNS DOUBLE PRECISION
+			    LANGUAGE 'sql'
+			    IMMUTABLE PARALLEL SAFE
+			AS $BODY$
+				-- great-circle distance in meters (haversine), points are longitude/latitude
+				SELECT 2 * 6371008.8 * ASIN(SQRT(
+					POWER(SIN(RADIANS(p1[1] - p0[1]) / 2), 2) +
+					COS(RADIANS(p0[1])) * COS(RADIANS(p1[1])) *
+					POWER(SIN(RADIANS(p1[0] - p0[0]) / 2), 2)
+				));
+			$BODY$;
+			
+			-- map fields
+			ALTER TYPE app.field_content ADD VALUE 'map';
+			
+			CREATE TABLE IF NOT EXISTS app.field_map (
+			    field_id uuid NOT NULL,
+				attribute_id_geo uuid NOT NULL,
+				attribute_id_color uuid,
+				index_geo smallint NOT NULL,
+				index_color smallint,
+			    CONSTRAINT field_map_pkey PRIMARY KEY (field_id),
+			    CONSTRAINT field_map_field_id_fkey FOREIGN KEY (field_id)
+			        REFERENCES app.field (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT field_map_attribute_id_geo_fkey FOREIGN KEY (attribute_id_geo)
+			        REFERENCES app.attribute (id) MATCH SIMPLE
+			        ON UPDATE NO ACTION
+			        ON DELETE NO ACTION
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT field_map_attribute_id_color_fkey FOREIGN KEY (attribute_id_color)
+			        REFERENCES app.attribute (id) MATCH SIMPLE
+			        ON UPDATE NO ACTION
+			        ON DELETE NO ACTION
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_field_map_attribute_id_geo_fkey
+				ON app.field_map USING btree (attribute_id_geo ASC NULLS LAST);
+			CREATE INDEX fki_field_map_attribute_id_color_fkey
+				ON app.field_map USING btree (attribute_id_color ASC NULLS LAST);
+			
+			-- money and interval attributes
+			ALTER TYPE app.attribute_content ADD VALUE 'money';
+			ALTER TYPE app.attribute_content ADD VALUE 'interval';
+			
+			CREATE TYPE instance.money_value AS (
+				amount NUMERIC,
+				currency CHAR(3)
+			);
+			
+			CREATE FUNCTION instance.currency_precision(currency TEXT)
+			    RETURNS INTEGER
+			    LANGUAGE 'sql'
+			    IMMUTABLE PARALLEL SAFE
+			AS $BODY$
+				-- ISO 4217 minor units, must match currency precision in data package
+				SELECT CASE
+					WHEN currency IN ('BIF','CLP','DJF','GNF','ISK','JPY','KMF','KRW','PYG',
+						'RWF','UGX','UYI','VND','VUV','XAF','XOF','XPF') THEN 0
+					WHEN currency IN ('BHD','IQD','JOD','KWD','LYD','OMR','TND') THEN 3
+					WHEN currency IN ('CLF','UYW') THEN 4
+					ELSE 2
+				END;
+			$BODY$;
+			
+			CREATE FUNCTION instance.money_to_json(v instance.money_value)
+			    RETURNS JSONB
+			    LANGUAGE 'sql'
+			    IMMUTABLE PARALLEL SAFE
+			AS $BODY$
+				-- amount as text to keep precision
+				SELECT CASE
+					WHEN (v).amount IS NULL OR (v).currency IS NULL THEN NULL
+					ELSE JSONB_BUILD_OBJECT('amount',(v).amount::TEXT,'currency',(v).currency)
+				END;
+			$BODY$;
+			
+			-- money aggregation per currency
+			-- state: {"EUR":{"sum":1,"count":1,"min":1,"max":1}, ...}
+			-- results: list of money values, one per currency
+			CREATE FUNCTION instance.money_agg_state(state JSONB, v instance.money_value)
+			    RETURNS JSONB
+			    LANGUAGE 'sql'
+			    IMMUTABLE PARALLEL SAFE
+			AS $BODY$
+				SELECT CASE
+					WHEN (v).amount IS NULL OR (v).currency IS NULL THEN state
+					ELSE state || JSONB_BUILD_OBJECT((v).currency::TEXT, JSONB_BUILD_OBJECT(
+						'sum',   COALESCE((state->(v).currency::TEXT->>'sum')::NUMERIC,0) + (v).amount,
+						'count', COALESCE((state->(v).currency::TEXT->>'count')::BIGINT,0) + 1,
+						'min',   LEAST((state->(v).currency::TEXT->>'min')::NUMERIC,(v).amount),
+						'max',   GREATEST((state->(v).currency::TEXT->>'max')::NUMERIC,(v).amount)
+					))
+				END;
+			$BODY$;
+			
+			CREATE FUNCTION instance.money_agg_result(state JSONB, aggregator TEXT)
+			    RETURNS JSONB
+			    LANGUAGE 'sql'
+			    IMMUTABLE PARALLEL SAFE
+			AS $BODY$
+				SELECT JSONB_AGG(JSONB_BUILD_OBJECT(
+					'amount', (CASE aggregator
+						WHEN 'avg' THEN ROUND((s.value->>'sum')::NUMERIC / (s.value->>'count')::NUMERIC,
+							instance.currency_precision(s.key))
+						ELSE (s.value->>aggregator)::NUMERIC
+					END)::TEXT,
+					'currency', s.key
+				) ORDER BY s.key)
+				FROM JSONB_EACH(state) AS s;
+			$BODY$;
+			
+			CREATE FUNCTION instance.money_agg_avg(state JSONB) RETURNS JSONB LANGUAGE 'sql' IMMUTABLE PARALLEL SAFE
+				AS $BODY$ SELECT instance.money_agg_result(state,'avg'); $BODY$;
+			CREATE FUNCTION instance.money_agg_max(state JSONB) RETURNS JSONB LANGUAGE 'sql' IMMUTABLE PARALLEL SAFE
+				AS $BODY$ SELECT instance.money_agg_result(state,'max'); $BODY$;
+			CREATE FUNCTION instance.money_agg_min(state JSONB) RETURNS JSONB LANGUAGE 'sql' IMMUTABLE PARALLEL SAFE
+				AS $BODY$ SELECT instance.money_agg_result(state,'min'); $BODY$;
+			CREATE FUNCTION instance.money_agg_sum(state JSONB) RETURNS JSONB LANGUAGE 'sql' IMMUTABLE PARALLEL SAFE
+				AS $BODY$ SELECT instance.money_agg_result(state,'sum'); $BODY$;
+			
+			CREATE AGGREGATE instance.money_avg(instance.money_value) (
+				SFUNC = instance.money_agg_state, STYPE = JSONB, INITCOND = '{}', FINALFUNC = instance.money_agg_avg);
+			CREATE AGGREGATE instance.money_max(instance.money_value) (
+				SFUNC = instance.money_agg_state, STYPE = JSONB, INITCOND = '{}', FINALFUNC = instance.money_agg_max);
+			CREATE AGGREGATE instance.money_min(instance.money_value) (
+				SFUNC = instance.money_agg_state, STYPE = JSONB, INITCOND = '{}', FINALFUNC = instance.money_agg_min);
+			CREATE AGGREGATE instance.money_sum(instance.money_value) (
+				SFUNC = instance.money_agg_state, STYPE = JSONB, INITCOND = '{}', FINALFUNC = instance.money_agg_sum);
+			
+			-- computed attributes
+			ALTER TABLE app.attribute ADD COLUMN expression TEXT;
+			
+			CREATE TABLE IF NOT EXISTS app.attribute_depends (
+			    attribute_id uuid NOT NULL,
+				attribute_id_on uuid NOT NULL,
+			    CONSTRAINT attribute_depends_pkey PRIMARY KEY (attribute_id, attribute_id_on),
+			    CONSTRAINT attribute_depends_attribute_id_fkey FOREIGN KEY (attribute_id)
+			        REFERENCES app.attribute (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE CASCADE
+			        DEFERRABLE INITIALLY DEFERRED,
+			    CONSTRAINT attribute_depends_attribute_id_on_fkey FOREIGN KEY (attribute_id_on)
+			        REFERENCES app.attribute (id) MATCH SIMPLE
+			        ON UPDATE NO ACTION
+			        ON DELETE NO ACTION
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			
+			CREATE INDEX fki_attribute_depends_attribute_id_fkey
+				ON app.attribute_depends USING btree (attribute_id ASC NULLS LAST);
+			CREATE INDEX fki_attribute_depends_attribute_id_on_fkey
+				ON app.attribute_depends USING btree (attribute_id_on ASC NULLS LAST);
+			
+			-- attribute validation rules
+			ALTER TABLE app.attribute ADD COLUMN validation_regex TEXT;
+			ALTER TABLE app.attribute ADD COLUMN validation_min NUMERIC;
+			ALTER TABLE app.attribute ADD COLUMN validation_max NUMERIC;
+			ALTER TABLE app.attribute ADD COLUMN validation_values TEXT[] NOT NULL DEFAULT '{}';
+			ALTER TABLE app.attribute ALTER COLUMN validation_values DROP DEFAULT;
+			ALTER TABLE app.attribute ADD COLUMN validation_expression TEXT;
+			
+			-- global search
+			ALTER TABLE app.relation ADD COLUMN search_form_id uuid;
+			ALTER TABLE app.relation ADD CONSTRAINT relation_search_form_id_fkey FOREIGN KEY (search_form_id)
+				REFERENCES app.form (id) MATCH SIMPLE
+				ON UPDATE NO ACTION
+				ON DELETE SET NULL
+				DEFERRABLE INITIALLY DEFERRED;
+			CREATE INDEX fki_relation_search_form_id
+				ON app.relation USING btree (search_form_id ASC NULLS LAST);
+			
+			-- slow query capture
+			INSERT INTO instance.config (name,value) VALUES
+				('slowQueryExplainPercent','0'),
+				('slowQueryKeepDays','7'),
+				('slowQueryThresholdMs','0');
+			
+			CREATE TABLE instance.slow_query (
+				id serial NOT NULL,
+				login_id integer,
+				relation_id uuid NOT NULL,
+				api_id uuid,
+				form_id uuid,
+				field_id uuid,
+				date bigint NOT NULL,
+				duration_ms integer NOT NULL,
+				query_sql text NOT NULL,
+				query_args text[] NOT NULL,
+				attribute_ids_filter uuid[] NOT NULL,
+				attribute_ids_join uuid[] NOT NULL,
+				explain text,
+			    CONSTRAINT slow_query_pkey PRIMARY KEY (id),
+			    CONSTRAINT slow_query_login_id_fkey FOREIGN KEY (login_id)
+			        REFERENCES instance.login (id) MATCH SIMPLE
+			        ON UPDATE CASCADE
+			        ON DELETE SET NULL
+			        DEFERRABLE INITIALLY DEFERRED
+			);
+			CREATE INDEX fki_slow_query_login_id
+				ON instance.slow_query USING btree (login_id ASC NULLS LAST);
+			CREATE INDEX ind_slow_query_date
+				ON instance.slow_query USING btree (date DESC NULLS LAST);
+			
+			-- plans requested by admins, captured on the next slow run of the query
+			CREATE TABLE instance.slow_query_explain (
+				id serial NOT NULL,
+				query_sql text NOT NULL,
+				date bigint NOT NULL,
+			    CONSTRAINT slow_query_explain_pkey PRIMARY KEY (id)
+			);
+			
+			INSERT INTO instance.task (
+				name,interval_seconds,cluster_master_only,
+				embedded_only,active_only,active
+			) VALUES ('cleanupSlowQueries',86400,true,false,false,true);
+			
+			INSERT INTO instance.schedule (task_name,date_attempt,date_success)
+			VALUES ('cleanupSlowQueries',0,0);
 		`)
 		return "3.5", err
 	},
@@ -16,6 +16,7 @@ import (
 	"r3/handler"
 	"r3/log"
 	"r3/login/login_auth"
+	"r3/login/login_impersonate"
 	"r3/schema"
 	"r3/types"
 	"regexp"
@@ -46,16 +47,33 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		handler.AbortRequestWithCode(w, "api", httpCode, errToLog, errMsgUser)
 	}
 
-	// check token
+	// check token, client certificate can be used instead if no token is given
 	var loginId int64
 	var admin bool
 	var noAuth bool
-	if _, err := login_auth.Token(token, &loginId, &admin, &noAuth); err != nil {
+	var impersonationId int64
+	if token == "" && r.TLS != nil && len(r.TLS.PeerCertificates) != 0 {
+		address := cache.GetContextAddress(r.Context())
+		if _, _, err := login_auth.Cert(r.TLS.PeerCertificates, address, &loginId, &admin, &noAuth); err != nil {
+			abort(http.StatusUnauthorized, err, handler.ErrUnauthorized)
+			bruteforce.BadAttempt(r)
+			return
+		}
+	} else if _, err := login_auth.TokenImpersonated(token, &loginId, &admin, &noAuth, &impersonationId); err != nil {
 		abort(http.StatusUnauthorized, err, handler.ErrUnauthorized)
 		bruteforce.BadAttempt(r)
 		return
 	}
 
+	if impersonationId != 0 {
+		if err := login_impersonate.Log(impersonationId, "api", r.Method, json.RawMessage(
+			strconv.Quote(r.URL.RequestURI()))); err != nil {
+
+			abort(http.StatusServiceUnavailable, err, handler.ErrGeneral)
+			return
+		}
+	}
+
 	var isDelete, isGet, isPost bool
 	switch r.Method {
 	case "DELETE":
@@ -141,7 +159,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// check role access
-	access, err := cache.GetAccessById(loginId)
+	access, err := cache.GetAccessById(r.Context(), loginId)
 	if err != nil {
 		abort(http.StatusServiceUnavailable, err, handler.ErrGeneral)
 		return
@@ -197,7 +215,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// execute request
-	ctx, ctxCancel := context.WithTimeout(context.Background(),
+	ctx, ctxCancel := context.WithTimeout(r.Context(),
 		time.Duration(int64(config.GetUint64("dbTimeoutDataRest")))*time.Second)
 
 	defer ctxCancel()
@@ -295,6 +313,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			IndexSource: 0,
 			Limit:       getters.limit,
 			Offset:      getters.offset,
+			ApiId:       pgtype.UUID{Bytes: api.Id, Valid: true},
 		}
 
 		// abort if requested limit exceeds max limit
@@ -320,11 +339,11 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		// build expressions from columns
 		for _, column := range api.Columns {
 			dataGet.Expressions = append(dataGet.Expressions,
-				data_query.ConvertColumnToExpression(column, loginId, languageCode))
+				data_query.ConvertColumnToExpression(ctx, column, loginId, languageCode))
 		}
 
 		// apply query filters
-		dataGet.Filters = data_query.ConvertQueryToDataFilter(
+		dataGet.Filters = data_query.ConvertQueryToDataFilter(ctx,
 			api.Query.Filters, loginId, languageCode)
 
 		// add record filter
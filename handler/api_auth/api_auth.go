@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"net/http"
 	"r3/bruteforce"
+	"r3/cache"
 	"r3/handler"
 	"r3/login/login_auth"
+	"r3/types"
 
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -46,9 +48,21 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	var loginId int64
 	var isAdmin bool
 	var noAuth bool
-
-	token, _, mfaTokens, err := login_auth.User(req.Username, req.Password,
-		pgtype.Int4{}, pgtype.Text{}, &loginId, &isAdmin, &noAuth)
+	var token string
+	var mfaTokens []types.LoginMfaToken
+	var mfaSetupSecret string
+	var err error
+
+	// client address is checked against network conditions of roles
+	address := cache.GetContextAddress(r.Context())
+
+	if req.Username == "" && r.TLS != nil && len(r.TLS.PeerCertificates) != 0 {
+		// no credentials given, authenticate via client certificate
+		token, _, err = login_auth.Cert(r.TLS.PeerCertificates, address, &loginId, &isAdmin, &noAuth)
+	} else {
+		token, _, mfaTokens, mfaSetupSecret, err = login_auth.User(req.Username, req.Password, address,
+			pgtype.Int4{}, pgtype.Text{}, false, pgtype.Text{}, pgtype.Text{}, &loginId, &isAdmin, &noAuth)
+	}
 
 	if err != nil {
 		handler.AbortRequestWithCode(w, context, http.StatusUnauthorized,
@@ -58,7 +72,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(mfaTokens) != 0 {
+	if len(mfaTokens) != 0 || mfaSetupSecret != "" {
 		handler.AbortRequestWithCode(w, context, http.StatusBadRequest,
 			nil, "failed to authenticate, MFA is currently not supported")
 
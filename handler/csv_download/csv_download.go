@@ -19,6 +19,7 @@ import (
 	"r3/tools"
 	"r3/types"
 	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 
@@ -255,7 +256,8 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// store attribute content use for each column
+	// store attribute content and content use for each column
+	columnAttributeContent := make([]string, len(columns))
 	columnAttributeContentUse := make([]string, len(columns))
 	for i, column := range columns {
 		atr, exists := cache.AttributeIdMap[column.AttributeId]
@@ -266,11 +268,16 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		columnAttributeContentUse[i] = atr.ContentUse
+
+		// counted values are plain numbers
+		if column.Aggregator.String != "count" {
+			columnAttributeContent[i] = atr.Content
+		}
 	}
 
 	for {
-		total, err := dataToCsv(writer, get, locUser, boolTrue, boolFalse,
-			dateFormat, columnAttributeContentUse, loginId)
+		total, err := dataToCsv(r.Context(), writer, get, locUser, boolTrue, boolFalse,
+			dateFormat, columnAttributeContent, columnAttributeContentUse, loginId)
 
 		if err != nil {
 			handler.AbortRequest(w, handlerContext, err, handler.ErrGeneral)
@@ -295,11 +302,11 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	os.Remove(filePath)
 }
 
-func dataToCsv(writer *csv.Writer, get types.DataGet, locUser *time.Location,
+func dataToCsv(ctx context.Context, writer *csv.Writer, get types.DataGet, locUser *time.Location,
 	boolTrue string, boolFalse string, dateFormat string,
-	columnAttributeContentUse []string, loginId int64) (int, error) {
+	columnAttributeContent []string, columnAttributeContentUse []string, loginId int64) (int, error) {
 
-	ctx, ctxCancel := context.WithTimeout(context.Background(),
+	ctx, ctxCancel := context.WithTimeout(ctx,
 		time.Duration(int64(config.GetUint64("dbTimeoutCsv")))*time.Second)
 
 	defer ctxCancel()
@@ -352,10 +359,52 @@ func dataToCsv(writer *csv.Writer, get types.DataGet, locUser *time.Location,
 		return fmt.Sprintf("%v", value)
 	}
 
+	// durations as '[-]hours:minutes:seconds', same as accepted by CSV import
+	parseIntervalValue := func(value int64) string {
+		sign := ""
+		if value < 0 {
+			sign = "-"
+			value = -value
+		}
+		return fmt.Sprintf("%s%d:%02d:%02d", sign, value/3600, value%3600/60, value%60)
+	}
+
+	// money values as 'amount currency', same as accepted by CSV import
+	// aggregated money values (per currency) are separated by comma
+	parseMoneyValue := func(value interface{}) string {
+		getMoney := func(v interface{}) string {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				return fmt.Sprintf("%v", v)
+			}
+			return fmt.Sprintf("%v %v", m["amount"], m["currency"])
+		}
+		if values, ok := value.([]interface{}); ok {
+			out := make([]string, 0)
+			for _, v := range values {
+				out = append(out, getMoney(v))
+			}
+			return strings.Join(out, ", ")
+		}
+		return getMoney(value)
+	}
+
 	for i, j := 0, len(rows); i < j; i++ {
 
 		stringValues := make([]string, len(rows[i].Values))
 		for pos, value := range rows[i].Values {
+			if value != nil && columnAttributeContent[pos] == "money" {
+				stringValues[pos] = parseMoneyValue(value)
+				continue
+			}
+			if value != nil && columnAttributeContent[pos] == "jsonb" {
+				stringValues[pos], err = getJsonValue(value)
+				if err != nil {
+					return 0, err
+				}
+				continue
+			}
+
 			switch v := value.(type) {
 			case nil:
 				stringValues[pos] = ""
@@ -370,9 +419,25 @@ func dataToCsv(writer *csv.Writer, get types.DataGet, locUser *time.Location,
 			case int32:
 				stringValues[pos] = parseIntegerValues(columnAttributeContentUse[pos], int64(v))
 			case int64:
+				if columnAttributeContent[pos] == "interval" {
+					stringValues[pos] = parseIntervalValue(v)
+					continue
+				}
 				stringValues[pos] = parseIntegerValues(columnAttributeContentUse[pos], v)
 			case pgtype.Numeric:
 				stringValues[pos] = tools.PgxNumericToString(v)
+			case types.DataGeoPoint:
+				// geo points as 'longitude,latitude', same as accepted by CSV import
+				stringValues[pos] = fmt.Sprintf("%s,%s",
+					strconv.FormatFloat(v.Coordinates[0], 'f', -1, 64),
+					strconv.FormatFloat(v.Coordinates[1], 'f', -1, 64))
+			case map[string]interface{}, []interface{}:
+				// JSON values
+				b, err := json.Marshal(v)
+				if err != nil {
+					return 0, err
+				}
+				stringValues[pos] = string(b)
 			default:
 				stringValues[pos] = fmt.Sprintf("%v", value)
 			}
@@ -396,3 +461,10 @@ func getCaption(captionMap map[string]map[string]string, contentName string, lan
 	}
 	return value
 }
+
+// returns JSON value as JSON text, same as accepted by CSV import
+// scalar values are encoded as well, a JSON string must stay quoted to be imported as the same value
+func getJsonValue(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	return string(b), err
+}
@@ -0,0 +1,61 @@
+package csv_download
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// JSON values must survive CSV export and import unchanged
+// import accepts valid JSON text only and stores it as is, see data.getValueJson()
+func TestGetJsonValueRoundTrip(t *testing.T) {
+	values := []interface{}{
+		"text",
+		"123",
+		"true",
+		`text with "quotes", commas and
+line breaks`,
+		float64(12.5),
+		true,
+		map[string]interface{}{"name": "value", "list": []interface{}{float64(1), "2"}},
+		[]interface{}{"a", float64(3), false},
+	}
+	// export values as CSV row
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	row := make([]string, len(values))
+	for i, value := range values {
+		text, err := getJsonValue(value)
+		if err != nil {
+			t.Fatalf("getJsonValue(%#v) error = %v", value, err)
+		}
+		row[i] = text
+	}
+	if err := writer.Write(row); err != nil {
+		t.Fatal(err)
+	}
+	writer.Flush()
+
+	// import CSV row again
+	rowImported, err := csv.NewReader(&buf).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, value := range values {
+		text := rowImported[i]
+		if !json.Valid([]byte(text)) {
+			t.Errorf("exported value %#v as %q, not valid JSON text", value, text)
+			continue
+		}
+		var imported interface{}
+		if err := json.Unmarshal([]byte(text), &imported); err != nil {
+			t.Errorf("import of %q error = %v", text, err)
+			continue
+		}
+		if !reflect.DeepEqual(imported, value) {
+			t.Errorf("round trip of %#v returned %#v", value, imported)
+		}
+	}
+}
@@ -174,7 +174,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// read file
-		res.Count, err = importFromCsv(filePath, loginId, boolTrue, dateFormat,
+		res.Count, err = importFromCsv(r.Context(), filePath, loginId, boolTrue, dateFormat,
 			timezone, commaChar, ignoreHeader, columns, joins, lookups)
 
 		if err != nil {
@@ -197,7 +197,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 // import all lines from CSV, optionally skipping a header line
 // returns to which line it got
-func importFromCsv(filePath string, loginId int64, boolTrue string,
+func importFromCsv(ctx context.Context, filePath string, loginId int64, boolTrue string,
 	dateFormat string, timezone string, commaChar string, ignoreHeader bool,
 	columns []types.Column, joins []types.QueryJoin,
 	lookups []types.QueryLookup) (int, error) {
@@ -210,7 +210,7 @@ func importFromCsv(filePath string, loginId int64, boolTrue string,
 	}
 	defer file.Close()
 
-	ctx, ctxCancel := context.WithTimeout(context.Background(),
+	ctx, ctxCancel := context.WithTimeout(ctx,
 		time.Duration(int64(config.GetUint64("dbTimeoutCsv")))*time.Second)
 
 	defer ctxCancel()
@@ -371,6 +371,18 @@ func importLine_tx(ctx context.Context, tx pgx.Tx, loginId int64,
 		case "numeric", "text", "uuid", "varchar":
 			valuesIn[i] = valuesString[i]
 
+		// JSON text is validated on import
+		case "jsonb":
+			valuesIn[i] = valuesString[i]
+
+		// geo points as 'longitude,latitude', validated on import
+		case "point":
+			valuesIn[i] = valuesString[i]
+
+		// money as 'amount currency', durations as '[-]hours:minutes:seconds' or seconds, validated on import
+		case "money", "interval":
+			valuesIn[i] = valuesString[i]
+
 		case "boolean":
 			valuesIn[i] = valuesString[i] == boolTrue
 
@@ -12,6 +12,7 @@ import (
 	"r3/handler"
 	"r3/log"
 	"r3/login/login_auth"
+	"r3/login/login_impersonate"
 	"r3/request"
 	"slices"
 	"time"
@@ -62,14 +63,15 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	var loginId int64
 	var isAdmin bool
 	var noAuth bool
-	if _, err := login_auth.Token(req.Token, &loginId, &isAdmin, &noAuth); err != nil {
+	var impersonationId int64
+	if _, err := login_auth.TokenImpersonated(req.Token, &loginId, &isAdmin, &noAuth, &impersonationId); err != nil {
 		handler.AbortRequest(w, handlerContext, err, handler.ErrAuthFailed)
 		bruteforce.BadAttempt(r)
 		return
 	}
 
 	// execute request
-	ctx, ctxCancel := context.WithTimeout(context.Background(),
+	ctx, ctxCancel := context.WithTimeout(r.Context(),
 		time.Duration(int64(config.GetUint64("dbTimeoutDataRest")))*time.Second)
 
 	defer ctxCancel()
@@ -83,7 +85,15 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 	log.Info("server", fmt.Sprintf("DIRECT ACCESS, %s data, payload: %s", req.Action, req.Request))
 
-	res, err := request.Exec_tx(ctx, tx, loginId, isAdmin, noAuth, "data", req.Action, req.Request)
+	if impersonationId != 0 {
+		if err := login_impersonate.Log(impersonationId, "data", req.Action, req.Request); err != nil {
+			handler.AbortRequest(w, handlerContext, err, handler.ErrGeneral)
+			return
+		}
+	}
+
+	res, err := request.Exec_tx(ctx, tx, loginId, isAdmin, noAuth,
+		impersonationId, "data", req.Action, req.Request)
 	if err != nil {
 		handler.AbortRequest(w, handlerContext, err, handler.ErrGeneral)
 		return
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"r3/bruteforce"
+	"r3/cache"
 	"r3/handler"
 	"r3/login/login_auth"
 
@@ -47,13 +48,22 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	var mfaTokenId = pgtype.Int4{}
 	var mfaTokenPin = pgtype.Text{}
 
-	token, _, _, err := login_auth.User(req.Username, req.Password,
-		mfaTokenId, mfaTokenPin, &loginId, &isAdmin, &noAuth)
+	// client address is checked against network conditions of roles
+	address := cache.GetContextAddress(r.Context())
+
+	token, _, mfaTokens, mfaSetupSecret, err := login_auth.User(req.Username, req.Password, address,
+		mfaTokenId, mfaTokenPin, false, pgtype.Text{}, pgtype.Text{}, &loginId, &isAdmin, &noAuth)
 
 	if err != nil {
 		handler.AbortRequest(w, context, err, handler.ErrAuthFailed)
 		bruteforce.BadAttempt(r)
 		return
 	}
+
+	// MFA cannot be completed here, login without session must not get an empty token
+	if len(mfaTokens) != 0 || mfaSetupSecret != "" {
+		handler.AbortRequest(w, context, nil, "failed to authenticate, MFA is currently not supported")
+		return
+	}
 	w.Write([]byte(fmt.Sprintf(`{"token": "%s"}`, token)))
 }
@@ -57,7 +57,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// check file access privilege
-	if err := data.MayAccessFile(loginId, attributeId); err != nil {
+	if err := data.MayAccessFile(r.Context(), loginId, attributeId); err != nil {
 		handler.AbortRequest(w, context, err, handler.ErrUnauthorized)
 		return
 	}
@@ -97,7 +97,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		if err := data.SetFile(loginId, attributeId, fileId, part, isNewFile); err != nil {
+		if err := data.SetFile(r.Context(), loginId, attributeId, fileId, part, isNewFile); err != nil {
 			handler.AbortRequest(w, context, err, handler.ErrGeneral)
 			return
 		}
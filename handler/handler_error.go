@@ -32,6 +32,18 @@ const (
 	ErrCodeAppUnknownModule         int = 7
 	ErrCodeAppUnknownRelation       int = 8
 	ErrCodeAppUnknownAttribute      int = 9
+	ErrCodeAppRecordChanged         int = 10
+	ErrCodeAppRecordLocked          int = 11
+	ErrCodeAppInvalidJson           int = 12
+	ErrCodeAppInvalidGeoPoint       int = 13
+	ErrCodeAppInvalidMoney          int = 14
+	ErrCodeAppInvalidInterval       int = 15
+	ErrCodeAppValidationRegex       int = 16
+	ErrCodeAppValidationLengthMin   int = 17
+	ErrCodeAppValidationLengthMax   int = 18
+	ErrCodeAppValidationValueMin    int = 19
+	ErrCodeAppValidationValueMax    int = 20
+	ErrCodeAppValidationValues      int = 21
 	ErrCodeCsvParseInt              int = 1
 	ErrCodeCsvParseFloat            int = 2
 	ErrCodeCsvParseDateTime         int = 3
@@ -45,6 +57,7 @@ const (
 	ErrCodeDbsConstraintNotNull     int = 5
 	ErrCodeDbsIndexFailUnique       int = 6
 	ErrCodeDbsInvalidTypeSyntax     int = 7
+	ErrCodeDbsConstraintCheck       int = 8
 	ErrCodeLicValidityExpired       int = 1
 	ErrCodeLicLoginsReached         int = 2
 	ErrCodeSecUnauthorized          int = 1
@@ -123,6 +136,17 @@ var (
 			},
 			matchRx: regexp.MustCompile(`^ERROR\: .+ on table \".+\" violates foreign key constraint \"fk_.{36}\"`),
 		},
+		errExpected{ // check constraint violation, attribute validation expression
+			convertFn: func(err error) error {
+				matches := regexp.MustCompile(`^ERROR\: .+ violates check constraint \"chk_(.{36})\"`).FindStringSubmatch(err.Error())
+				if len(matches) != 2 {
+					return CreateErrCode("DBS", ErrCodeDbsConstraintCheck)
+				}
+				return CreateErrCodeWithArgs("DBS", ErrCodeDbsConstraintCheck,
+					map[string]string{"ATR_ID": matches[1]})
+			},
+			matchRx: regexp.MustCompile(`^ERROR\: .+ violates check constraint \"chk_.{36}\"`),
+		},
 		errExpected{ // NOT NULL constraint violation
 			convertFn: func(err error) error {
 				matches := regexp.MustCompile(`^ERROR\: null value in column \"(.+)\" violates not-null constraint \(SQLSTATE 23502\)`).FindStringSubmatch(err.Error())
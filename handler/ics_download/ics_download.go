@@ -79,6 +79,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	dataGet := types.DataGet{
 		RelationId:  f.Query.RelationId.Bytes,
 		IndexSource: 0,
+		FieldId:     pgtype.UUID{Bytes: fieldId, Valid: true},
 	}
 
 	// join relations
@@ -97,7 +98,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 
 	// apply field filters
 	// some filters are not compatible with backend requests (field value, open form record ID, ...)
-	dataGet.Filters = data_query.ConvertQueryToDataFilter(f.Query.Filters, loginId, languageCode)
+	dataGet.Filters = data_query.ConvertQueryToDataFilter(r.Context(), f.Query.Filters, loginId, languageCode)
 
 	// define ICS event range, if defined
 	dateRange0 := f.DateRange0
@@ -186,11 +187,11 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		dataGet.Expressions = append(dataGet.Expressions,
-			data_query.ConvertColumnToExpression(column, loginId, languageCode))
+			data_query.ConvertColumnToExpression(r.Context(), column, loginId, languageCode))
 	}
 
 	// get data
-	ctx, ctxCancel := context.WithTimeout(context.Background(),
+	ctx, ctxCancel := context.WithTimeout(r.Context(),
 		time.Duration(int64(config.GetUint64("dbTimeoutIcs")))*time.Second)
 
 	defer ctxCancel()
@@ -0,0 +1,271 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"r3/bruteforce"
+	"r3/config"
+	"r3/handler"
+	"r3/log"
+	"r3/scim"
+	"strconv"
+	"strings"
+)
+
+var (
+	context       = "scim"
+	countDefault  = 100
+	countMax      = 1000
+	requestMaxLen = int64(1024 * 1024 * 2) // 2 MiB
+)
+
+/*
+	SCIM 2.0 endpoint (RFC 7643/7644), used by identity providers to push users and groups
+	Users are mapped to logins, groups to role assignments (defined by admin)
+
+	Supported:
+	GET/POST              /scim/v2/Users
+	GET/PUT/PATCH/DELETE  /scim/v2/Users/ID
+	GET/POST              /scim/v2/Groups
+	GET/PUT/PATCH/DELETE  /scim/v2/Groups/ID
+	GET                   /scim/v2/ServiceProviderConfig
+*/
+func Handler(w http.ResponseWriter, r *http.Request) {
+
+	if blocked := bruteforce.Check(r); blocked {
+		handler.AbortRequestNoLog(w, handler.ErrBruteforceBlock)
+		return
+	}
+
+	if !config.GetLicenseActive() {
+		abort(w, &scim.Error{Status: http.StatusForbidden, Detail: "no valid license"})
+		return
+	}
+
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !scim.CheckToken(token) {
+		log.Warning(context, "failed to authenticate SCIM client", errors.New("invalid bearer token"))
+		abort(w, &scim.Error{Status: http.StatusUnauthorized, Detail: handler.ErrUnauthorized})
+		bruteforce.BadAttempt(r)
+		return
+	}
+
+	// process path elements
+	// 0 = empty, 1 = "scim", 2 = "v2", 3 = resource type, 4 = resource ID (some cases)
+	elements := strings.Split(strings.TrimSuffix(r.URL.Path, "/"), "/")
+	if len(elements) < 4 || len(elements) > 5 {
+		abort(w, &scim.Error{Status: http.StatusNotFound, Detail: "invalid URL, expected: /scim/v2/RESOURCE_TYPE[/ID]"})
+		return
+	}
+	resourceType := elements[3]
+	resourceId := ""
+	if len(elements) == 5 {
+		resourceId = elements[4]
+	}
+
+	log.Info(context, fmt.Sprintf("%s %s (ID: '%s')", r.Method, resourceType, resourceId))
+
+	// read body of writing requests
+	var body json.RawMessage
+	if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(r.Body, requestMaxLen))
+		if err != nil {
+			abort(w, &scim.Error{Status: http.StatusBadRequest, ScimType: "invalidSyntax", Detail: "could not read request body"})
+			return
+		}
+	}
+
+	// parse list parameters
+	query := r.URL.Query()
+	filter := query.Get("filter")
+	excludeMembers := strings.Contains(strings.ToLower(query.Get("excludedAttributes")), "members")
+
+	startIndex, err := strconv.Atoi(query.Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(query.Get("count"))
+	if err != nil || count < 0 {
+		count = countDefault
+	}
+	if count > countMax {
+		count = countMax
+	}
+
+	var res interface{}
+	statusCode := http.StatusOK
+	location := ""
+
+	switch resourceType {
+	case "Users":
+		if resourceId == "" {
+			switch r.Method {
+			case "GET":
+				res, err = scim.GetUsers(filter, startIndex, count)
+			case "POST":
+				var u scim.User
+				u, err = scim.CreateUser(body)
+				location = fmt.Sprintf("%s/%s", getBaseUrl(r, resourceType), u.Id)
+				statusCode = http.StatusCreated
+				res = u
+			default:
+				err = errMethod(r.Method)
+			}
+			break
+		}
+		switch r.Method {
+		case "GET":
+			res, err = scim.GetUser(resourceId)
+		case "PUT":
+			res, err = scim.ReplaceUser(resourceId, body)
+		case "PATCH":
+			res, err = scim.PatchUser(resourceId, body)
+		case "DELETE":
+			err = scim.DelUser(resourceId)
+			statusCode = http.StatusNoContent
+		default:
+			err = errMethod(r.Method)
+		}
+
+	case "Groups":
+		if resourceId == "" {
+			switch r.Method {
+			case "GET":
+				res, err = scim.GetGroups(filter, startIndex, count, excludeMembers)
+			case "POST":
+				var g scim.Group
+				g, err = scim.CreateGroup(body)
+				location = fmt.Sprintf("%s/%s", getBaseUrl(r, resourceType), g.Id)
+				statusCode = http.StatusCreated
+				res = g
+			default:
+				err = errMethod(r.Method)
+			}
+			break
+		}
+		switch r.Method {
+		case "GET":
+			res, err = scim.GetGroup(resourceId, excludeMembers)
+		case "PUT":
+			res, err = scim.ReplaceGroup(resourceId, body)
+		case "PATCH":
+			res, err = scim.PatchGroup(resourceId, body)
+		case "DELETE":
+			err = scim.DelGroup(resourceId)
+			statusCode = http.StatusNoContent
+		default:
+			err = errMethod(r.Method)
+		}
+
+	case "ServiceProviderConfig":
+		res = getServiceProviderConfig()
+
+	default:
+		err = &scim.Error{Status: http.StatusNotFound, Detail: fmt.Sprintf("unknown resource type '%s'", resourceType)}
+	}
+
+	if err != nil {
+		abort(w, err)
+		return
+	}
+
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	resJson, err := json.Marshal(res)
+	if err != nil {
+		abort(w, err)
+		return
+	}
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(statusCode)
+	w.Write(resJson)
+}
+
+// responds with SCIM error, unknown errors are logged and not revealed to the client
+func abort(w http.ResponseWriter, err error) {
+
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		log.Error(context, "failed to execute SCIM request", err)
+		scimErr = &scim.Error{Status: http.StatusInternalServerError, Detail: handler.ErrGeneral}
+	} else {
+		log.Warning(context, "aborted SCIM request", err)
+	}
+
+	resJson, _ := json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail"`
+	}{
+		Schemas:  []string{scim.SchemaError},
+		Status:   fmt.Sprintf("%d", scimErr.Status),
+		ScimType: scimErr.ScimType,
+		Detail:   scimErr.Detail,
+	})
+
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(scimErr.Status)
+	w.Write(resJson)
+}
+
+func errMethod(method string) error {
+	return &scim.Error{Status: http.StatusMethodNotAllowed, Detail: fmt.Sprintf("HTTP method '%s' is not supported", method)}
+}
+
+func getBaseUrl(r *http.Request, resourceType string) string {
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/scim/v2/%s", scheme, r.Host, resourceType)
+}
+
+func getServiceProviderConfig() interface{} {
+	type supported struct {
+		Supported bool `json:"supported"`
+	}
+	type supportedMax struct {
+		Supported    bool `json:"supported"`
+		MaxResults   int  `json:"maxResults,omitempty"`
+		MaxOperation int  `json:"maxOperations,omitempty"`
+	}
+	type authScheme struct {
+		Type        string `json:"type"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	return struct {
+		Schemas               []string     `json:"schemas"`
+		Patch                 supported    `json:"patch"`
+		Bulk                  supportedMax `json:"bulk"`
+		Filter                supportedMax `json:"filter"`
+		ChangePassword        supported    `json:"changePassword"`
+		Sort                  supported    `json:"sort"`
+		Etag                  supported    `json:"etag"`
+		AuthenticationSchemes []authScheme `json:"authenticationSchemes"`
+	}{
+		Schemas:        []string{scim.SchemaServiceConfig},
+		Patch:          supported{Supported: true},
+		Bulk:           supportedMax{Supported: false},
+		Filter:         supportedMax{Supported: true, MaxResults: countMax},
+		ChangePassword: supported{Supported: false},
+		Sort:           supported{Supported: false},
+		Etag:           supported{Supported: false},
+		AuthenticationSchemes: []authScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer token",
+			Description: "Authentication via bearer token, as created in the admin UI",
+		}},
+	}
+}
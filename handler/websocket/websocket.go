@@ -2,16 +2,20 @@ package websocket
 
 import (
 	"context"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net"
 	"net/http"
 	"r3/bruteforce"
+	"r3/cache"
 	"r3/cluster"
+	"r3/data"
 	"r3/handler"
 	"r3/log"
 	"r3/request"
 	"r3/types"
+	"slices"
 	"sync"
 
 	"github.com/gofrs/uuid"
@@ -20,17 +24,27 @@ import (
 
 // a websocket client
 type clientType struct {
-	address    string             // IP address, no port
-	admin      bool               // belongs to admin login?
-	ctx        context.Context    // global context for client requests
-	ctxCancel  context.CancelFunc // to abort requests in case of disconnect
-	fixedToken bool               // logged in with fixed token (limited access, only auth and server messages)
-	loginId    int64              // client login ID, 0 = not logged in yet
-	noAuth     bool               // logged in without authentication (public auth, username only)
-	write_mx   sync.Mutex         // to force sequential writes
-	ws         *websocket.Conn    // websocket connection
+	address         string              // IP address, no port
+	admin           bool                // belongs to admin login?
+	certs           []*x509.Certificate // client certificate chain, if provided during TLS handshake
+	ctx             context.Context     // global context for client requests
+	ctxCancel       context.CancelFunc  // to abort requests in case of disconnect
+	fixedToken      bool                // logged in with fixed token (limited access, only auth and server messages)
+	id              uuid.UUID           // client ID, used to track record presence
+	impersonationId int64               // impersonation session ID, if admin is impersonating login
+	loginId         int64               // client login ID, 0 = not logged in yet
+	noAuth          bool                // logged in without authentication (public auth, username only)
+	presence        map[string]bool     // records the client is present on
+	presence_mx     sync.Mutex          // to access record presence map
+	subs            subsType            // data change subscriptions
+	subs_mx         sync.Mutex          // to access data change subscriptions
+	write_mx        sync.Mutex          // to force sequential writes
+	ws              *websocket.Conn     // websocket connection
 }
 
+// data change subscriptions of a client, key: subscription ID
+type subsType map[string]types.DataSubscription
+
 // a hub for all active websocket clients
 type hubType struct {
 	clients map[*clientType]bool
@@ -81,7 +95,7 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// get client host address
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	host, err := bruteforce.GetHost(r)
 	if err != nil {
 		handler.AbortRequest(w, handlerContext, err, handler.ErrGeneral)
 		ws.Close()
@@ -91,18 +105,40 @@ func Handler(w http.ResponseWriter, r *http.Request) {
 	log.Info(handlerContext, fmt.Sprintf("new client connecting from %s", host))
 
 	// create global request context with abort function
-	ctx, ctxCancel := context.WithCancel(context.Background())
+	// client address is included to check network conditions of roles
+	ctx, ctxCancel := context.WithCancel(cache.SetContextAddress(context.Background(), host))
+
+	clientId, err := uuid.NewV4()
+	if err != nil {
+		handler.AbortRequest(w, handlerContext, err, handler.ErrGeneral)
+		ws.Close()
+		ctxCancel()
+		return
+	}
+
+	// client certificates, if requested during TLS handshake
+	certs := make([]*x509.Certificate, 0)
+	if r.TLS != nil {
+		certs = r.TLS.PeerCertificates
+	}
 
 	client := &clientType{
-		address:    host,
-		admin:      false,
-		ctx:        ctx,
-		ctxCancel:  ctxCancel,
-		fixedToken: false,
-		loginId:    0,
-		noAuth:     false,
-		write_mx:   sync.Mutex{},
-		ws:         ws,
+		address:         host,
+		admin:           false,
+		certs:           certs,
+		ctx:             ctx,
+		ctxCancel:       ctxCancel,
+		fixedToken:      false,
+		id:              clientId,
+		impersonationId: 0,
+		loginId:         0,
+		noAuth:          false,
+		presence:        make(map[string]bool),
+		presence_mx:     sync.Mutex{},
+		subs:            make(subsType),
+		subs_mx:         sync.Mutex{},
+		write_mx:        sync.Mutex{},
+		ws:              ws,
 	}
 
 	hub.clientAdd <- client
@@ -120,6 +156,22 @@ func (hub *hubType) start() {
 			client.ctxCancel()
 			delete(hub.clients, client)
 			cluster.SetWebsocketClientCount(len(hub.clients))
+
+			if client.loginId != 0 {
+				// remove record presence of client, inform other clients
+				go func() {
+					records, err := data.DelPresenceByClient(client.id)
+					if err != nil {
+						log.Error(handlerContext, "failed to remove record presence of client", err)
+						return
+					}
+					for _, r := range records {
+						if err := cluster.RecordPresenceChanged(true, r.RelationId, r.RecordId); err != nil {
+							log.Error(handlerContext, "failed to update record presence", err)
+						}
+					}
+				}()
+			}
 		}
 	}
 
@@ -164,9 +216,22 @@ func (hub *hubType) start() {
 						FileName:    event.FileRequestedFileName,
 					})
 				}
+				if event.RecordPresence != nil {
+					jsonMsg, err = prepareUnrequested("record_presence", event.RecordPresence)
+				}
+				if len(event.DataChanged) != 0 {
+					// data changes are filtered and sent per client
+					for client := range hub.clients {
+						go client.writeDataChanges(event.DataChanged)
+					}
+					continue
+				}
 				if event.Renew {
 					jsonMsg, err = prepareUnrequested("reauthorized", nil)
 				}
+				if event.RoleDelegationChanged {
+					jsonMsg, err = prepareUnrequested("role_delegation_changed", nil)
+				}
 				if event.SchemaLoading {
 					jsonMsg, err = prepareUnrequested("schema_loading", nil)
 				}
@@ -179,13 +244,20 @@ func (hub *hubType) start() {
 				}
 			}
 
-			for client, _ := range hub.clients {
+			for client := range hub.clients {
 
 				// login ID 0 affects all
 				if event.LoginId != 0 && event.LoginId != client.loginId {
 					continue
 				}
 
+				// record presence only affects clients with the record open
+				if event.RecordPresence != nil && !client.hasPresence(getPresenceKey(
+					event.RecordPresence.RelationId, event.RecordPresence.RecordId)) {
+
+					continue
+				}
+
 				// non-kick event, send message
 				if !kickEvent {
 					go client.write(jsonMsg)
@@ -251,10 +323,47 @@ func (client *clientType) handleTransaction(reqTransJson json.RawMessage) json.R
 	// take over transaction number for response so client can match it locally
 	resTrans.TransactionNr = reqTrans.TransactionNr
 
-	// client can either authenticate or execute requests
+	// client can either authenticate, update its client state (record presence, data subscriptions) or execute requests
 	authRequest := len(reqTrans.Requests) == 1 && reqTrans.Requests[0].Ressource == "auth"
+	stateRequest := len(reqTrans.Requests) == 1 && (reqTrans.Requests[0].Ressource == "presence" ||
+		reqTrans.Requests[0].Ressource == "subscription")
 
-	if !authRequest {
+	if stateRequest {
+		if client.fixedToken || client.loginId == 0 {
+			log.Warning(handlerContext, "blocked client request",
+				fmt.Errorf("client state requests require authenticated client"))
+
+			return []byte("{}")
+		}
+		resTrans.Responses = make([]types.Response, 0)
+
+		var resPayload interface{}
+		var err error
+
+		switch reqTrans.Requests[0].Ressource {
+		case "presence":
+			resPayload, err = client.handlePresence(reqTrans.Requests[0])
+		case "subscription":
+			resPayload, err = client.handleSubscription(reqTrans.Requests[0])
+		}
+		if err != nil {
+			returnErr, isExpectedErr := handler.ConvertToErrCode(err, !client.admin)
+			if !isExpectedErr {
+				log.Warning(handlerContext, fmt.Sprintf("TRANSACTION %d, request %s %s failure (login ID %d)",
+					reqTrans.TransactionNr, reqTrans.Requests[0].Ressource,
+					reqTrans.Requests[0].Action, client.loginId), err)
+			}
+			resTrans.Error = fmt.Sprintf("%v", returnErr)
+		} else {
+			var res types.Response
+			res.Payload, err = json.Marshal(resPayload)
+			if err != nil {
+				resTrans.Error = handler.ErrGeneral
+			} else {
+				resTrans.Responses = append(resTrans.Responses, res)
+			}
+		}
+	} else if !authRequest {
 		if client.fixedToken {
 			log.Warning(handlerContext, "blocked client request",
 				fmt.Errorf("only authentication allowed for fixed token clients"))
@@ -264,7 +373,7 @@ func (client *clientType) handleTransaction(reqTransJson json.RawMessage) json.R
 
 		// execute non-authentication transaction
 		resTrans = request.ExecTransaction(client.ctx, client.loginId,
-			client.admin, client.noAuth, reqTrans, resTrans)
+			client.admin, client.noAuth, client.impersonationId, reqTrans, resTrans)
 
 	} else {
 		// execute authentication request
@@ -280,16 +389,20 @@ func (client *clientType) handleTransaction(reqTransJson json.RawMessage) json.R
 		var resPayload interface{}
 
 		switch req.Action {
+		case "cert": // authentication via client certificate (mutual TLS)
+			resPayload, err = request.LoginAuthCert(client.certs, client.address,
+				&client.loginId, &client.admin, &client.noAuth)
+
 		case "token": // authentication via JSON web token
 			resPayload, err = request.LoginAuthToken(req.Payload,
-				&client.loginId, &client.admin, &client.noAuth)
+				&client.loginId, &client.admin, &client.noAuth, &client.impersonationId)
 
 		case "tokenFixed": // authentication via fixed token (fat-client)
 			resPayload, err = request.LoginAuthTokenFixed(req.Payload,
 				&client.loginId, &client.fixedToken)
 
 		case "user": // authentication via credentials
-			resPayload, err = request.LoginAuthUser(req.Payload,
+			resPayload, err = request.LoginAuthUser(req.Payload, client.address,
 				&client.loginId, &client.admin, &client.noAuth)
 		}
 
@@ -315,8 +428,8 @@ func (client *clientType) handleTransaction(reqTransJson json.RawMessage) json.R
 		}
 
 		if resTrans.Error == "" {
-			log.Info(handlerContext, fmt.Sprintf("authenticated client (login ID %d, admin: %v)",
-				client.loginId, client.admin))
+			log.Info(handlerContext, fmt.Sprintf("authenticated client (login ID %d, admin: %v, impersonation ID: %d)",
+				client.loginId, client.admin, client.impersonationId))
 		}
 	}
 
@@ -329,6 +442,161 @@ func (client *clientType) handleTransaction(reqTransJson json.RawMessage) json.R
 	return resTransJson
 }
 
+// sets or removes record presence of client, informs other clients if presence changed
+func (client *clientType) handlePresence(reqIn types.Request) (interface{}, error) {
+	var req struct {
+		RelationId uuid.UUID `json:"relationId"`
+		RecordId   int64     `json:"recordId"`
+		Edit       bool      `json:"edit"`
+		Lock       bool      `json:"lock"`
+	}
+	if err := json.Unmarshal(reqIn.Payload, &req); err != nil {
+		return nil, err
+	}
+	key := getPresenceKey(req.RelationId, req.RecordId)
+
+	switch reqIn.Action {
+	case "del":
+		client.presence_mx.Lock()
+		delete(client.presence, key)
+		client.presence_mx.Unlock()
+
+		if err := data.DelPresence(client.id, req.RelationId, req.RecordId); err != nil {
+			return nil, err
+		}
+		return nil, cluster.RecordPresenceChanged(true, req.RelationId, req.RecordId)
+
+	case "set":
+		changed, err := data.SetPresence(client.ctx, client.id, cache.GetNodeId(), client.loginId,
+			req.RelationId, req.RecordId, req.Edit, req.Lock)
+
+		if err != nil {
+			return nil, err
+		}
+
+		client.presence_mx.Lock()
+		client.presence[key] = true
+		client.presence_mx.Unlock()
+
+		if changed {
+			if err := cluster.RecordPresenceChanged(true, req.RelationId, req.RecordId); err != nil {
+				return nil, err
+			}
+		}
+		return data.GetPresence(req.RelationId, req.RecordId)
+	}
+	return nil, fmt.Errorf("unknown action '%s'", reqIn.Action)
+}
+
+// sets or removes data change subscription of client
+func (client *clientType) handleSubscription(reqIn types.Request) (interface{}, error) {
+	var req types.DataSubscription
+	if err := json.Unmarshal(reqIn.Payload, &req); err != nil {
+		return nil, err
+	}
+
+	switch reqIn.Action {
+	case "del":
+		client.subs_mx.Lock()
+		delete(client.subs, req.Id)
+		client.subs_mx.Unlock()
+		return nil, nil
+
+	case "set":
+		if !client.hasAccessToRelation(req.RelationId) {
+			return nil, errors.New(handler.ErrUnauthorized)
+		}
+		if req.RecordIds == nil {
+			req.RecordIds = make([]int64, 0)
+		}
+		client.subs_mx.Lock()
+		client.subs[req.Id] = req
+		client.subs_mx.Unlock()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown action '%s'", reqIn.Action)
+}
+
+// sends data changes to client, filtered by its subscriptions and read access
+// record IDs are filtered by select policies, records the login may not see are not notified
+// subscriptions to entire relations still receive the change, without the IDs of filtered records
+func (client *clientType) writeDataChanges(changes []types.DataChange) {
+	if client.loginId == 0 {
+		return
+	}
+
+	changesClient := make([]types.DataChange, 0)
+	for _, change := range changes {
+
+		// access can change after subscription, check again
+		if !client.isSubscribedTo(change) || !client.hasAccessToRelation(change.RelationId) {
+			continue
+		}
+
+		recordIds, err := data.GetRecordIdsSelectable(client.ctx, client.loginId,
+			change.RelationId, change.RecordIds)
+
+		if err != nil {
+			log.Error(handlerContext, "could not filter data changes by relation policies", err)
+			continue
+		}
+		change.RecordIds = recordIds
+
+		// record subscriptions are checked again against the remaining record IDs
+		if client.isSubscribedTo(change) {
+			changesClient = append(changesClient, change)
+		}
+	}
+	if len(changesClient) == 0 {
+		return
+	}
+
+	jsonMsg, err := prepareUnrequested("data_changed", changesClient)
+	if err != nil {
+		log.Error(handlerContext, "could not prepare unrequested transaction", err)
+		return
+	}
+	client.write(jsonMsg)
+}
+
+// checks whether client subscribed to data change, either to the entire relation or to one of its records
+func (client *clientType) isSubscribedTo(change types.DataChange) bool {
+	client.subs_mx.Lock()
+	defer client.subs_mx.Unlock()
+
+	for _, sub := range client.subs {
+		if sub.RelationId != change.RelationId {
+			continue
+		}
+		if len(sub.RecordIds) == 0 || slices.ContainsFunc(change.RecordIds, func(id int64) bool {
+			return slices.Contains(sub.RecordIds, id)
+		}) {
+			return true
+		}
+	}
+	return false
+}
+
+// checks read access of client login to relation
+func (client *clientType) hasAccessToRelation(relationId uuid.UUID) bool {
+	access, err := cache.GetAccessById(client.ctx, client.loginId)
+	if err != nil {
+		return false
+	}
+	level, exists := access.Relation[relationId]
+	return exists && level >= 1
+}
+
+func (client *clientType) hasPresence(key string) bool {
+	client.presence_mx.Lock()
+	defer client.presence_mx.Unlock()
+	return client.presence[key]
+}
+
+func getPresenceKey(relationId uuid.UUID, recordId int64) string {
+	return fmt.Sprintf("%s_%d", relationId, recordId)
+}
+
 func prepareUnrequested(ressource string, payload interface{}) ([]byte, error) {
 
 	var resTrans types.UnreqResponseTransaction
@@ -1,9 +1,11 @@
 package ldap
 
 import (
+	"fmt"
 	"r3/db"
 	"r3/types"
 
+	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -22,7 +24,8 @@ func Get() ([]types.Ldap, error) {
 		SELECT id, login_template_id, name, host, port, bind_user_dn,
 			bind_user_pw, search_class, search_dn, key_attribute,
 			login_attribute, member_attribute, assign_roles, ms_ad_ext,
-			starttls, tls, tls_verify
+			starttls, tls, tls_verify, login_record_attribute_id, delta_sync,
+			full_sync_interval, hosts_failover, nested_groups
 		FROM instance.ldap
 		ORDER BY name ASC
 	`)
@@ -35,7 +38,9 @@ func Get() ([]types.Ldap, error) {
 		if err := rows.Scan(&l.Id, &l.LoginTemplateId, &l.Name, &l.Host,
 			&l.Port, &l.BindUserDn, &l.BindUserPw, &l.SearchClass, &l.SearchDn,
 			&l.KeyAttribute, &l.LoginAttribute, &l.MemberAttribute,
-			&l.AssignRoles, &l.MsAdExt, &l.Starttls, &l.Tls, &l.TlsVerify); err != nil {
+			&l.AssignRoles, &l.MsAdExt, &l.Starttls, &l.Tls, &l.TlsVerify,
+			&l.LoginRecordAttributeId, &l.DeltaSync, &l.FullSyncInterval,
+			&l.HostsFailover, &l.NestedGroups); err != nil {
 
 			rows.Close()
 			return ldaps, err
@@ -49,41 +54,62 @@ func Get() ([]types.Ldap, error) {
 		if err != nil {
 			return ldaps, err
 		}
+		ldaps[i].RecordAttributes, err = getRecordAttributes(ldaps[i].Id)
+		if err != nil {
+			return ldaps, err
+		}
 	}
 	return ldaps, nil
 }
 
 func Set_tx(tx pgx.Tx, l types.Ldap) error {
 
+	if l.DeltaSync && l.FullSyncInterval < 1 {
+		return fmt.Errorf("full sync interval must be at least 1 hour")
+	}
+	if l.HostsFailover == nil {
+		l.HostsFailover = make([]string, 0)
+	}
+
 	if l.Id == 0 {
 		if err := tx.QueryRow(db.Ctx, `
 			INSERT INTO instance.ldap (
 				login_template_id, name, host, port, bind_user_dn, bind_user_pw,
 				search_class, search_dn, key_attribute, login_attribute,
-				member_attribute, assign_roles, ms_ad_ext, starttls, tls, tls_verify
+				member_attribute, assign_roles, ms_ad_ext, starttls, tls, tls_verify,
+				login_record_attribute_id, delta_sync, full_sync_interval,
+				hosts_failover, nested_groups
 			)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21)
 			RETURNING id
 		`, l.LoginTemplateId, l.Name, l.Host, l.Port, l.BindUserDn, l.BindUserPw,
 			l.SearchClass, l.SearchDn, l.KeyAttribute, l.LoginAttribute,
 			l.MemberAttribute, l.AssignRoles, l.MsAdExt, l.Starttls, l.Tls,
-			l.TlsVerify).Scan(&l.Id); err != nil {
+			l.TlsVerify, l.LoginRecordAttributeId, l.DeltaSync,
+			l.FullSyncInterval, l.HostsFailover, l.NestedGroups).Scan(&l.Id); err != nil {
 
 			return err
 		}
 	} else {
+		// changed connection settings can affect which entries are found
+		// reset sync state to force full sync on next run
 		if _, err := tx.Exec(db.Ctx, `
 			UPDATE instance.ldap
 			SET login_template_id = $1, name = $2, host = $3, port = $4,
 				bind_user_dn = $5, bind_user_pw = $6, search_class = $7,
 				search_dn = $8, key_attribute = $9, login_attribute = $10,
 				member_attribute = $11, assign_roles = $12, ms_ad_ext = $13,
-				starttls = $14, tls = $15, tls_verify = $16
-			WHERE id = $17
+				starttls = $14, tls = $15, tls_verify = $16,
+				login_record_attribute_id = $17, delta_sync = $18,
+				full_sync_interval = $19, hosts_failover = $20,
+				nested_groups = $21, sync_marker = NULL, sync_host = NULL,
+				date_sync_full = NULL
+			WHERE id = $22
 		`, l.LoginTemplateId, l.Name, l.Host, l.Port, l.BindUserDn, l.BindUserPw,
 			l.SearchClass, l.SearchDn, l.KeyAttribute, l.LoginAttribute,
 			l.MemberAttribute, l.AssignRoles, l.MsAdExt, l.Starttls, l.Tls,
-			l.TlsVerify, l.Id); err != nil {
+			l.TlsVerify, l.LoginRecordAttributeId, l.DeltaSync,
+			l.FullSyncInterval, l.HostsFailover, l.NestedGroups, l.Id); err != nil {
 
 			return err
 		}
@@ -105,9 +131,79 @@ func Set_tx(tx pgx.Tx, l types.Ldap) error {
 			return err
 		}
 	}
+
+	// update LDAP attributes for login records
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.ldap_record_attribute
+		WHERE ldap_id = $1
+	`, l.Id); err != nil {
+		return err
+	}
+
+	if !l.LoginRecordAttributeId.Valid {
+		return nil
+	}
+
+	for _, a := range l.RecordAttributes {
+		if err := checkRecordAttribute_tx(tx, l.LoginRecordAttributeId.Bytes, a.AttributeId); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO instance.ldap_record_attribute (ldap_id, attribute_id, ldap_attribute)
+			VALUES ($1,$2,$3)
+		`, l.Id, a.AttributeId, a.LdapAttribute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record attributes must be unencrypted text attributes of the same relation as the login attribute
+func checkRecordAttribute_tx(tx pgx.Tx, attributeIdLogin uuid.UUID, attributeId uuid.UUID) error {
+	valid := false
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT EXISTS(
+			SELECT a.id
+			FROM app.attribute AS a
+			JOIN app.attribute AS l ON l.relation_id = a.relation_id
+			WHERE a.id = $1
+			AND   l.id = $2
+			AND   a.content::TEXT IN ('text','varchar')
+			AND   NOT a.encrypted
+		)
+	`, attributeId, attributeIdLogin).Scan(&valid); err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("attribute %s cannot store LDAP values, unencrypted text attribute of login record relation required", attributeId)
+	}
 	return nil
 }
 
+func getRecordAttributes(ldapId int32) ([]types.LdapRecordAttribute, error) {
+	attributes := make([]types.LdapRecordAttribute, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT attribute_id, ldap_attribute
+		FROM instance.ldap_record_attribute
+		WHERE ldap_id = $1
+	`, ldapId)
+	if err != nil {
+		return attributes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a types.LdapRecordAttribute
+		if err := rows.Scan(&a.AttributeId, &a.LdapAttribute); err != nil {
+			return attributes, err
+		}
+		a.LdapId = ldapId
+		attributes = append(attributes, a)
+	}
+	return attributes, nil
+}
+
 func getRoles(ldapId int32) ([]types.LdapRole, error) {
 	roles := make([]types.LdapRole, 0)
 
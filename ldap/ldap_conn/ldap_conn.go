@@ -3,14 +3,35 @@ package ldap_conn
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
 	"r3/cache"
 	"r3/log"
+	"r3/tools"
 	"r3/types"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
 
 	goldap "github.com/go-ldap/ldap/v3"
 )
 
+type host struct {
+	name string
+	port int
+}
+
+var (
+	access_mx  sync.Mutex
+	hostStates = make(map[int32]map[string]types.LdapHostState) // key: LDAP ID, host address
+
+	dialTimeout         = 5 * time.Second
+	retryUnhealthyAfter = int64(60) // seconds, hosts that recently failed are tried last
+)
+
 // connect to a LDAP profile
+// primary host is tried first, then failover hosts in order, hosts that recently failed are tried last
+// returned LDAP profile contains host and port of the connected host
 func ConnectAndBind(ldapId int32) (*goldap.Conn, types.Ldap, error) {
 
 	ldap, err := cache.GetLdap(ldapId)
@@ -18,42 +39,162 @@ func ConnectAndBind(ldapId int32) (*goldap.Conn, types.Ldap, error) {
 		return nil, ldap, err
 	}
 
+	var errLast error
+	for _, h := range getHostsOrdered(ldap) {
+		address := net.JoinHostPort(h.name, fmt.Sprintf("%d", h.port))
+
+		ldapConn, err := connectAndBind(ldap, h)
+		setHostState(ldap.Id, address, err)
+
+		if err != nil {
+			log.Warning("ldap", fmt.Sprintf("failed to connect to '%s'", address), err)
+			errLast = err
+			continue
+		}
+		ldap.Host = h.name
+		ldap.Port = h.port
+		return ldapConn, ldap, nil
+	}
+	return nil, ldap, errLast
+}
+
+// returns connection states of all hosts of a LDAP profile, in configured order
+func GetHostStates(ldapId int32) ([]types.LdapHostState, error) {
+	states := make([]types.LdapHostState, 0)
+
+	ldap, err := cache.GetLdap(ldapId)
+	if err != nil {
+		return states, err
+	}
+
+	access_mx.Lock()
+	defer access_mx.Unlock()
+
+	for _, h := range getHosts(ldap) {
+		address := net.JoinHostPort(h.name, fmt.Sprintf("%d", h.port))
+
+		s, exists := hostStates[ldap.Id][address]
+		if !exists {
+			s = types.LdapHostState{Host: address, Healthy: true}
+		}
+		states = append(states, s)
+	}
+	return states, nil
+}
+
+func connectAndBind(ldap types.Ldap, h host) (*goldap.Conn, error) {
+
 	// prepare bind string
 	protocol := "ldap"
 	if ldap.Tls {
 		protocol = "ldaps"
 	}
-	bind := fmt.Sprintf("%s://%s:%d", protocol, ldap.Host, ldap.Port)
+	bind := fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(h.name, fmt.Sprintf("%d", h.port)))
 
 	// prepare TLS config
 	tlsConfig := tls.Config{
 		InsecureSkipVerify: !ldap.TlsVerify,
-		ServerName:         ldap.Host,
+		ServerName:         h.name,
 	}
 
 	log.Info("ldap", fmt.Sprintf("connecting to '%s'", bind))
 
+	dialer := goldap.DialWithDialer(&net.Dialer{Timeout: dialTimeout})
+
 	var ldapConn *goldap.Conn
+	var err error
 	if ldap.Tls {
-		ldapConn, err = goldap.DialURL(bind, goldap.DialWithTLSConfig(&tlsConfig))
+		ldapConn, err = goldap.DialURL(bind, dialer, goldap.DialWithTLSConfig(&tlsConfig))
 		if err != nil {
-			return nil, ldap, err
+			return nil, err
 		}
 	} else {
-		ldapConn, err = goldap.DialURL(bind)
+		ldapConn, err = goldap.DialURL(bind, dialer)
 		if err != nil {
-			return nil, ldap, err
+			return nil, err
 		}
 		if ldap.Starttls {
 			if err := ldapConn.StartTLS(&tlsConfig); err != nil {
-				return nil, ldap, err
+				ldapConn.Close()
+				return nil, err
 			}
 		}
 	}
 
 	// bind with reading user
 	if err := ldapConn.Bind(ldap.BindUserDn, ldap.BindUserPw); err != nil {
-		return nil, ldap, err
+		ldapConn.Close()
+		return nil, err
+	}
+	return ldapConn, nil
+}
+
+// returns primary and failover hosts in configured order
+func getHosts(ldap types.Ldap) []host {
+	hosts := []host{{name: ldap.Host, port: ldap.Port}}
+
+	for _, address := range ldap.HostsFailover {
+		name, portStr, err := net.SplitHostPort(address)
+		if err != nil {
+			// no port defined, use port of primary host
+			hosts = append(hosts, host{name: address, port: ldap.Port})
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			port = ldap.Port
+		}
+		hosts = append(hosts, host{name: name, port: port})
+	}
+	return hosts
+}
+
+// returns hosts in configured order, hosts that failed recently are moved to the end
+func getHostsOrdered(ldap types.Ldap) []host {
+	hosts := getHosts(ldap)
+
+	access_mx.Lock()
+	defer access_mx.Unlock()
+
+	now := tools.GetTimeUnix()
+	isAvoided := func(h host) bool {
+		s, exists := hostStates[ldap.Id][net.JoinHostPort(h.name, fmt.Sprintf("%d", h.port))]
+		return exists && !s.Healthy && s.DateFailed > now-retryUnhealthyAfter
+	}
+
+	slices.SortStableFunc(hosts, func(a, b host) int {
+		avoidA, avoidB := isAvoided(a), isAvoided(b)
+		switch {
+		case avoidA && !avoidB:
+			return 1
+		case !avoidA && avoidB:
+			return -1
+		}
+		return 0
+	})
+	return hosts
+}
+
+func setHostState(ldapId int32, address string, err error) {
+	access_mx.Lock()
+	defer access_mx.Unlock()
+
+	if _, exists := hostStates[ldapId]; !exists {
+		hostStates[ldapId] = make(map[string]types.LdapHostState)
+	}
+
+	s := hostStates[ldapId][address]
+	s.Host = address
+	s.Healthy = err == nil
+
+	if err != nil {
+		s.Failures++
+		s.DateFailed = tools.GetTimeUnix()
+		s.LastError = err.Error()
+	} else {
+		s.Failures = 0
+		s.DateOk = tools.GetTimeUnix()
+		s.LastError = ""
 	}
-	return ldapConn, ldap, nil
+	hostStates[ldapId][address] = s
 }
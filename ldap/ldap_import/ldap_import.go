@@ -11,22 +11,33 @@ import (
 	"r3/ldap/ldap_conn"
 	"r3/log"
 	"r3/login"
+	"r3/tools"
 	"r3/types"
 	"slices"
+	"strconv"
+	"time"
 	"unicode/utf8"
 
 	goldap "github.com/go-ldap/ldap/v3"
 	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type loginType struct {
 	active  bool
 	name    string
 	roleIds []uuid.UUID
+
+	// values for login record, key: attribute ID
+	recordValues map[uuid.UUID]string
 }
 
-var msAdExtDisabledAtrFlags = []string{"514", "546", "66050",
-	"66082", "262658", "262690", "328194", "328226"}
+var (
+	msAdExtDisabledAtrFlags = []string{"514", "546", "66050",
+		"66082", "262658", "262690", "328194", "328226"}
+
+	runsKeep = 30 // import runs to keep statistics for, per LDAP connection
+)
 
 func RunAll() error {
 
@@ -38,14 +49,36 @@ func RunAll() error {
 	}
 
 	for _, ldap := range ldapIdMap {
-		if err := Run(ldap.Id); err != nil {
+		if err := Run(ldap.Id, false); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func Run(ldapId int32) error {
+// imports logins from LDAP connection and stores run statistics
+// full sync is executed if forced, if delta sync is disabled or if the full sync interval has passed
+func Run(ldapId int32, forceFull bool) error {
+
+	run := types.LdapRun{
+		LdapId:    ldapId,
+		DateStart: tools.GetTimeUnix(),
+	}
+	timeStart := time.Now()
+
+	err := runSync(ldapId, forceFull, &run)
+	if err != nil {
+		run.Error = pgtype.Text{String: err.Error(), Valid: true}
+	}
+	run.DurationMs = time.Since(timeStart).Milliseconds()
+
+	if errRun := setRun(run, runsKeep); errRun != nil {
+		log.Warning("ldap", "failed to store import run statistics", errRun)
+	}
+	return err
+}
+
+func runSync(ldapId int32, forceFull bool, run *types.LdapRun) error {
 
 	ldapConn, ldap, err := ldap_conn.ConnectAndBind(ldapId)
 	if err != nil {
@@ -53,14 +86,63 @@ func Run(ldapId int32) error {
 	}
 	defer ldapConn.Close()
 
+	// incremental sync
+	// MS AD: uSNChanged (update sequence number, increments with every change on domain controller)
+	// other: modifyTimestamp (operational attribute, generalized time)
+	// group memberships are stored on groups (and as back-link in MS AD), they do not change the user entry
+	// changed memberships as well as deleted or moved users are therefore only applied with full sync
+	// with failover hosts, a host change forces full sync for MS AD as uSNChanged is specific to each domain controller
+	marker, markerHost, dateSyncFull, err := getSyncState(ldap.Id)
+	if err != nil {
+		return err
+	}
+	markerAttribute := "modifyTimestamp"
+	if ldap.MsAdExt {
+		markerAttribute = "uSNChanged"
+	}
+	host := fmt.Sprintf("%s:%d", ldap.Host, ldap.Port)
+
+	run.FullSync = forceFull || !ldap.DeltaSync || marker == "" ||
+		dateSyncFull < run.DateStart-int64(ldap.FullSyncInterval*3600) ||
+		(ldap.MsAdExt && markerHost != host)
+
+	if run.FullSync {
+		// full sync establishes new marker
+		marker = ""
+	}
+
+	filterDelta := ""
+	if !run.FullSync {
+		if ldap.MsAdExt {
+			usn, err := strconv.ParseInt(marker, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid sync marker '%s', %w", marker, err)
+			}
+			filterDelta = fmt.Sprintf("(uSNChanged>=%d)", usn+1)
+		} else {
+			filterDelta = fmt.Sprintf("(modifyTimestamp>=%s)", goldap.EscapeFilter(marker))
+		}
+	}
+
 	// define attributes to lookup and filters to apply
 	attributes := []string{"dn", ldap.KeyAttribute, ldap.LoginAttribute}
 
+	if ldap.DeltaSync {
+		attributes = append(attributes, markerAttribute)
+	}
+
 	// MS AD, add user account control (currently for account (de)activation)
 	if ldap.MsAdExt {
 		attributes = append(attributes, "userAccountControl")
 	}
 
+	// attributes for login records
+	if ldap.LoginRecordAttributeId.Valid {
+		for _, a := range ldap.RecordAttributes {
+			attributes = append(attributes, a.LdapAttribute)
+		}
+	}
+
 	// controls for paged requests
 	pagingControl := goldap.NewControlPaging(30)
 	controls := []goldap.Control{pagingControl}
@@ -76,7 +158,9 @@ func Run(ldapId int32) error {
 
 	// keeping 1 million logins in memory with 3 role IDs each, uses ~300MB RAM
 	// simulation ran: 2020-05-19, go 1.14.2
+	// with delta sync, only changed users are kept
 	logins := make(map[string]loginType) // key: key LDAP attribute
+	markerNew := marker
 
 	// LDAP auto role assignment removes existing roles from user, defining no roles here would remove all access
 	if ldap.AssignRoles && len(ldap.Roles) == 0 {
@@ -93,20 +177,46 @@ func Run(ldapId int32) error {
 	// * query of just users (without we´d loose users that have no defined group DN assigned)
 	ldap.Roles = append(ldap.Roles, types.LdapRole{}) // empty group DN
 
+	// generic LDAP with nested groups: all group DNs per naming context, retrieved once if needed
+	baseDnMapGroupDns := make(map[string]map[string]bool)
+
 	for _, role := range ldap.Roles {
 
-		filters := fmt.Sprintf("(&(objectClass=%s))", ldap.SearchClass)
+		filters := fmt.Sprintf("(&(objectClass=%s)%s)", ldap.SearchClass, filterDelta)
 
 		// set filters to search for group DN if role assignment is active
 		// group DN is empty if just users are queried
 		if ldap.AssignRoles && role.GroupDn != "" {
 
 			if ldap.MsAdExt {
-				filters = fmt.Sprintf("(&(objectClass=%s)(%s:1.2.840.113556.1.4.1941:=%s))",
-					ldap.SearchClass, ldap.MemberAttribute, role.GroupDn)
+				filters = fmt.Sprintf("(&(objectClass=%s)(%s:1.2.840.113556.1.4.1941:=%s)%s)",
+					ldap.SearchClass, ldap.MemberAttribute, role.GroupDn, filterDelta)
+			} else if ldap.NestedGroups {
+				baseDn, err := getBaseDn(role.GroupDn)
+				if err != nil {
+					return err
+				}
+				if _, exists := baseDnMapGroupDns[baseDn]; !exists {
+					baseDnMapGroupDns[baseDn], err = getGroupDns(ldapConn, baseDn)
+					if err != nil {
+						return err
+					}
+				}
+				groupDnsNested, err := getGroupDnsNested(ldapConn, role.GroupDn, baseDnMapGroupDns[baseDn])
+				if err != nil {
+					return err
+				}
+
+				// users are members of the group or any of its nested groups
+				filterMembers := fmt.Sprintf("(%s=%s)", ldap.MemberAttribute, role.GroupDn)
+				for _, dn := range groupDnsNested {
+					filterMembers += fmt.Sprintf("(%s=%s)", ldap.MemberAttribute, goldap.EscapeFilter(dn))
+				}
+				filters = fmt.Sprintf("(&(objectClass=%s)(|%s)%s)",
+					ldap.SearchClass, filterMembers, filterDelta)
 			} else {
-				filters = fmt.Sprintf("(&(objectClass=%s)(%s=%s))",
-					ldap.SearchClass, ldap.MemberAttribute, role.GroupDn)
+				filters = fmt.Sprintf("(&(objectClass=%s)(%s=%s)%s)",
+					ldap.SearchClass, ldap.MemberAttribute, role.GroupDn, filterDelta)
 			}
 		}
 
@@ -144,9 +254,22 @@ func Run(ldapId int32) error {
 					l = loginType{}
 					l.active = true
 					l.roleIds = make([]uuid.UUID, 0)
+					l.recordValues = make(map[uuid.UUID]string)
 				}
 				l.name = entry.GetAttributeValue(ldap.LoginAttribute)
 
+				if ldap.DeltaSync {
+					if m := entry.GetAttributeValue(markerAttribute); isMarkerNewer(ldap.MsAdExt, m, markerNew) {
+						markerNew = m
+					}
+				}
+
+				if ldap.LoginRecordAttributeId.Valid {
+					for _, a := range ldap.RecordAttributes {
+						l.recordValues[a.AttributeId] = entry.GetAttributeValue(a.LdapAttribute)
+					}
+				}
+
 				if ldap.MsAdExt {
 					for _, value := range entry.GetAttributeValues("userAccountControl") {
 						if slices.Contains(msAdExtDisabledAtrFlags, value) {
@@ -178,37 +301,135 @@ func Run(ldapId int32) error {
 
 	// import logins
 	for key, l := range logins {
-		if err := importLogin(l, key, ldap); err != nil {
+		created, changed, err := importLogin(l, key, ldap)
+		if err != nil {
 			log.Warning("ldap", fmt.Sprintf("failed to import login '%s'", l.name), err)
+			run.CountFailed++
 			continue
 		}
+		switch {
+		case created:
+			run.CountCreated++
+		case changed && !l.active:
+			run.CountDeactivated++
+		case changed:
+			run.CountUpdated++
+		}
 	}
 
-	log.Info("ldap", fmt.Sprintf("finished login import for '%s'", ldap.Name))
+	// full sync, deactivate logins that were deleted or moved outside of the search DN
+	if run.FullSync {
+		if len(logins) == 0 {
+			log.Warning("ldap", fmt.Sprintf("skipping deactivation of missing logins for '%s'", ldap.Name),
+				errors.New("no users were found, connection settings might be incorrect"))
+		} else {
+			count, err := deactivateMissing(logins, ldap)
+			if err != nil {
+				return err
+			}
+			run.CountDeactivated += count
+		}
+	}
+
+	if err := setSyncState(ldap.Id, markerNew, host, run.FullSync, run.DateStart); err != nil {
+		return err
+	}
+
+	log.Info("ldap", fmt.Sprintf("finished login import for '%s' (full sync: %v, created: %d, updated: %d, deactivated: %d, failed: %d)",
+		ldap.Name, run.FullSync, run.CountCreated, run.CountUpdated, run.CountDeactivated, run.CountFailed))
+
 	return nil
 }
 
-func importLogin(l loginType, key string, ldap types.Ldap) error {
+func deactivateMissing(logins map[string]loginType, ldap types.Ldap) (int, error) {
+
+	keys := make([]string, 0, len(logins))
+	for key, _ := range logins {
+		keys = append(keys, key)
+	}
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	ids, names, err := login.DeactivateLdapLoginsMissing_tx(tx, ldap.Id, keys)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(db.Ctx); err != nil {
+		return 0, err
+	}
+
+	for i, id := range ids {
+		log.Info("ldap", fmt.Sprintf("user account '%s' does not exist anymore, deactivating login",
+			names[i]))
+
+		cluster.LoginDisabled(true, id)
+	}
+	return len(ids), nil
+}
+
+// returns whether sync marker value is newer than the existing one
+func isMarkerNewer(msAdExt bool, value string, valueEx string) bool {
+	if value == "" {
+		return false
+	}
+	if valueEx == "" {
+		return true
+	}
+	if msAdExt {
+		usn, err1 := strconv.ParseInt(value, 10, 64)
+		usnEx, err2 := strconv.ParseInt(valueEx, 10, 64)
+		return err1 == nil && err2 == nil && usn > usnEx
+	}
+	// generalized time (YYYYMMDDHHMMSS[.f]Z), can be compared as string
+	return value > valueEx
+}
+
+func importLogin(l loginType, key string, ldap types.Ldap) (bool, bool, error) {
 
 	log.Info("ldap", fmt.Sprintf("importing login '%s' (key: %s, roles: %d)",
 		l.name, key, len(l.roleIds)))
 
 	tx, err := db.Pool.Begin(db.Ctx)
 	if err != nil {
-		return err
+		return false, false, err
 	}
 	defer tx.Rollback(db.Ctx)
 
-	loginId, changed, err := login.SetLdapLogin_tx(tx, ldap.Id, key, l.name,
+	loginId, changed, created, err := login.SetLdapLogin_tx(tx, ldap.Id, key, l.name,
 		l.active, l.roleIds, ldap.LoginTemplateId, ldap.AssignRoles)
 
 	if err != nil {
-		return err
+		return false, false, err
+	}
+
+	// update login record, failure must not block login import
+	// (relation might require values that are not provided by LDAP)
+	if ldap.LoginRecordAttributeId.Valid && len(l.recordValues) != 0 {
+		txRecord, err := tx.Begin(db.Ctx)
+		if err != nil {
+			return false, false, err
+		}
+		if err := login.SetRecordValues_tx(txRecord, loginId,
+			ldap.LoginRecordAttributeId.Bytes, l.recordValues); err != nil {
+
+			log.Warning("ldap", fmt.Sprintf("failed to update login record of '%s'", l.name), err)
+			if err := txRecord.Rollback(db.Ctx); err != nil {
+				return false, false, err
+			}
+		} else {
+			if err := txRecord.Commit(db.Ctx); err != nil {
+				return false, false, err
+			}
+		}
 	}
 
 	// commit before renewing access cache (to apply new permissions)
 	if err := tx.Commit(db.Ctx); err != nil {
-		return err
+		return false, false, err
 	}
 
 	if changed {
@@ -224,5 +445,5 @@ func importLogin(l loginType, key string, ldap types.Ldap) error {
 			cluster.LoginDisabled(true, loginId)
 		}
 	}
-	return nil
+	return created, changed, nil
 }
@@ -0,0 +1,129 @@
+package ldap_import
+
+import (
+	"errors"
+	"fmt"
+	"r3/log"
+	"strings"
+
+	goldap "github.com/go-ldap/ldap/v3"
+)
+
+// nested groups on generic LDAP servers (OpenLDAP, 389-DS, etc.)
+// groups list their members (users or other groups) as DNs in member attributes
+// MS AD is not handled here, it resolves nested groups via LDAP_MATCHING_RULE_IN_CHAIN
+var (
+	groupMemberAttributes = []string{"member", "uniqueMember"}
+	groupObjectClasses    = []string{"groupOfNames", "groupOfUniqueNames", "group"}
+	groupNestingMax       = 20 // maximum depth of group nesting to resolve
+)
+
+// returns DNs of all groups within base DN
+// only DNs are retrieved, to identify which members of a group are groups themselves
+func getGroupDns(ldapConn *goldap.Conn, baseDn string) (map[string]bool, error) {
+	groupDns := make(map[string]bool)
+
+	filterClasses := ""
+	for _, class := range groupObjectClasses {
+		filterClasses += fmt.Sprintf("(objectClass=%s)", class)
+	}
+
+	response, err := ldapConn.SearchWithPaging(goldap.NewSearchRequest(
+		baseDn,
+		goldap.ScopeWholeSubtree,
+		goldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf("(|%s)", filterClasses),
+		[]string{"dn"},
+		nil), 100)
+
+	if err != nil {
+		return groupDns, err
+	}
+
+	for _, entry := range response.Entries {
+		groupDns[normalizeDn(entry.DN)] = true
+	}
+	return groupDns, nil
+}
+
+// returns DNs of all groups that are direct or indirect members of the given group
+// groups can be members of each other, already visited groups are skipped to avoid endless loops
+func getGroupDnsNested(ldapConn *goldap.Conn, groupDn string, groupDnsAll map[string]bool) ([]string, error) {
+	groupDnsNested := make([]string, 0)
+	visited := map[string]bool{normalizeDn(groupDn): true}
+	level := []string{groupDn}
+
+	for depth := 0; len(level) != 0; depth++ {
+		if depth == groupNestingMax {
+			log.Warning("ldap", fmt.Sprintf("stopped resolving nested groups of '%s'", groupDn),
+				fmt.Errorf("maximum nesting depth of %d reached", groupNestingMax))
+			break
+		}
+
+		levelNext := make([]string, 0)
+		for _, dn := range level {
+			response, err := ldapConn.Search(goldap.NewSearchRequest(
+				dn,
+				goldap.ScopeBaseObject,
+				goldap.NeverDerefAliases, 0, 0, false,
+				"(objectClass=*)",
+				groupMemberAttributes,
+				nil))
+
+			if err != nil {
+				// nested group might have been deleted since it was listed as member
+				if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+					continue
+				}
+				return groupDnsNested, err
+			}
+			if len(response.Entries) != 1 {
+				continue
+			}
+
+			for _, atr := range groupMemberAttributes {
+				for _, member := range response.Entries[0].GetAttributeValues(atr) {
+					key := normalizeDn(member)
+					if !groupDnsAll[key] || visited[key] {
+						continue
+					}
+					visited[key] = true
+					groupDnsNested = append(groupDnsNested, member)
+					levelNext = append(levelNext, member)
+				}
+			}
+		}
+		level = levelNext
+	}
+	return groupDnsNested, nil
+}
+
+// returns naming context of DN, example: 'CN=admins,OU=groups,DC=test,DC=local' -> 'dc=test,dc=local'
+func getBaseDn(dn string) (string, error) {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return "", err
+	}
+
+	components := make([]string, 0)
+	for _, rdn := range parsed.RDNs {
+		for _, atr := range rdn.Attributes {
+			if strings.EqualFold(atr.Type, "dc") {
+				components = append(components, fmt.Sprintf("dc=%s", atr.Value))
+			}
+		}
+	}
+	if len(components) == 0 {
+		return "", errors.New("cannot resolve nested groups, group DN does not contain domain components")
+	}
+	return strings.Join(components, ","), nil
+}
+
+// DNs are case insensitive and can contain optional spaces, normalize for comparison
+func normalizeDn(dn string) string {
+	parsed, err := goldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(dn)
+	}
+	return strings.ToLower(parsed.String())
+}
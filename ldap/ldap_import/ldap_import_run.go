@@ -0,0 +1,64 @@
+package ldap_import
+
+import (
+	"r3/db"
+	"r3/types"
+)
+
+// store import run, only the latest runs per LDAP connection are kept
+func setRun(r types.LdapRun, keep int) error {
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	if _, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.ldap_run (ldap_id, date_start, duration_ms, full_sync,
+			count_created, count_updated, count_deactivated, count_failed, error)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, r.LdapId, r.DateStart, r.DurationMs, r.FullSync, r.CountCreated,
+		r.CountUpdated, r.CountDeactivated, r.CountFailed, r.Error); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.ldap_run
+		WHERE ldap_id = $1
+		AND id NOT IN (
+			SELECT id
+			FROM instance.ldap_run
+			WHERE ldap_id = $1
+			ORDER BY date_start DESC, id DESC
+			LIMIT $2
+		)
+	`, r.LdapId, keep); err != nil {
+		return err
+	}
+	return tx.Commit(db.Ctx)
+}
+
+// get state of incremental sync
+// marker is the highest change value (uSNChanged or modifyTimestamp) of the last imported entries
+// host is the LDAP host the marker was retrieved from (uSNChanged is specific to each MS AD domain controller)
+func getSyncState(ldapId int32) (string, string, int64, error) {
+	var marker, host string
+	var dateFull int64
+	err := db.Pool.QueryRow(db.Ctx, `
+		SELECT COALESCE(sync_marker,''), COALESCE(sync_host,''),
+			COALESCE(date_sync_full,0)
+		FROM instance.ldap
+		WHERE id = $1
+	`, ldapId).Scan(&marker, &host, &dateFull)
+	return marker, host, dateFull, err
+}
+
+func setSyncState(ldapId int32, marker string, host string, full bool, dateFull int64) error {
+	_, err := db.Pool.Exec(db.Ctx, `
+		UPDATE instance.ldap
+		SET sync_marker = NULLIF($1,''), sync_host = $2,
+			date_sync_full = CASE WHEN $3 THEN $4 ELSE date_sync_full END
+		WHERE id = $5
+	`, marker, host, full, dateFull, ldapId)
+	return err
+}
@@ -0,0 +1,37 @@
+package ldap
+
+import (
+	"r3/db"
+	"r3/types"
+)
+
+// get latest import runs of LDAP connection
+func GetRuns(ldapId int32, limit int) ([]types.LdapRun, error) {
+	runs := make([]types.LdapRun, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT id, date_start, duration_ms, full_sync, count_created,
+			count_updated, count_deactivated, count_failed, error
+		FROM instance.ldap_run
+		WHERE ldap_id = $1
+		ORDER BY date_start DESC, id DESC
+		LIMIT $2
+	`, ldapId, limit)
+	if err != nil {
+		return runs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.LdapRun
+		if err := rows.Scan(&r.Id, &r.DateStart, &r.DurationMs, &r.FullSync,
+			&r.CountCreated, &r.CountUpdated, &r.CountDeactivated,
+			&r.CountFailed, &r.Error); err != nil {
+
+			return runs, err
+		}
+		r.LdapId = ldapId
+		runs = append(runs, r)
+	}
+	return runs, nil
+}
@@ -30,6 +30,7 @@ var (
 		"module":    1,
 		"ldap":      1,
 		"scheduler": 1,
+		"scim":      1,
 		"server":    1,
 		"transfer":  1,
 		"websocket": 1,
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"r3/cache"
+	"r3/config"
 	"r3/db"
 	"r3/handler"
 	"r3/login/login_setting"
@@ -37,7 +38,23 @@ func Get(byId int64, byString string, limit int, offset int,
 	var qb tools.QueryBuilder
 	qb.UseDollarSigns()
 	qb.AddList("SELECT", []string{"l.id", "l.ldap_id", "l.ldap_key",
-		"l.name", "l.admin", "l.no_auth", "l.active"})
+		"l.name", "l.admin", "l.no_auth", "l.cert_required", "l.mfa_mail", "l.active"})
+
+	// MFA is required but no MFA method is set up
+	qb.Add("SELECT", fmt.Sprintf(`(
+		(%t OR EXISTS(
+			SELECT role_id
+			FROM instance.role_mfa
+			WHERE role_id = ANY(instance.get_role_ids(l.id,true))
+		))
+		AND (NOT %t OR COALESCE(l.mfa_mail,'') = '')
+		AND NOT EXISTS(
+			SELECT id
+			FROM instance.login_token_fixed
+			WHERE login_id = l.id
+			AND   context  = 'totp'
+		)
+	)`, config.GetUint64("mfaRequired") == 1, config.GetUint64("mfaMail") == 1))
 
 	qb.Set("FROM", "instance.login AS l")
 
@@ -95,7 +112,8 @@ func Get(byId int64, byString string, limit int, offset int,
 		var records []string
 
 		if err := rows.Scan(&l.Id, &l.LdapId, &l.LdapKey, &l.Name,
-			&l.Admin, &l.NoAuth, &l.Active, &records); err != nil {
+			&l.Admin, &l.NoAuth, &l.CertRequired, &l.MfaMail, &l.Active,
+			&l.MfaMissing, &records); err != nil {
 
 			return logins, 0, err
 		}
@@ -132,6 +150,10 @@ func Get(byId int64, byString string, limit int, offset int,
 		if err != nil {
 			return logins, 0, err
 		}
+		logins[i].RoleDates, err = getRoleDates(l.Id)
+		if err != nil {
+			return logins, 0, err
+		}
 	}
 
 	// get total count
@@ -452,10 +474,10 @@ func ResetTotp_tx(tx pgx.Tx, loginId int64) error {
 // updates internal login backend with logins from LDAP
 // uses unique key value to update login record
 // can optionally update login roles
-// returns login ID and whether login needed to be changed
+// returns login ID, whether login needed to be changed and whether it was created
 func SetLdapLogin_tx(tx pgx.Tx, ldapId int32, ldapKey string, ldapName string,
 	ldapActive bool, ldapRoleIds []uuid.UUID, loginTemplateId pgtype.Int8,
-	updateRoles bool) (int64, bool, error) {
+	updateRoles bool) (int64, bool, bool, error) {
 
 	// existing login details
 	var id int64
@@ -487,7 +509,7 @@ func SetLdapLogin_tx(tx pgx.Tx, ldapId int32, ldapKey string, ldapName string,
 		&admin, &active, &roleIds, &rolesEqual)
 
 	if err != nil && err != pgx.ErrNoRows {
-		return 0, false, err
+		return 0, false, false, err
 	}
 
 	// create if new
@@ -507,13 +529,67 @@ func SetLdapLogin_tx(tx pgx.Tx, ldapId int32, ldapKey string, ldapName string,
 			active = true
 		}
 
-		_, err = Set_tx(tx, id, loginTemplateId, ldapIdSql, ldapKeySql,
+		id, err = Set_tx(tx, id, loginTemplateId, ldapIdSql, ldapKeySql,
 			ldapName, "", admin, false, ldapActive, roleIds,
 			[]types.LoginAdminRecordSet{})
 
-		return id, true, err
+		return id, true, newLogin, err
+	}
+	return id, false, false, nil
+}
+
+// sets whether login must authenticate with client certificate
+func SetCertRequired_tx(tx pgx.Tx, id int64, certRequired bool) error {
+	_, err := tx.Exec(db.Ctx, `
+		UPDATE instance.login
+		SET cert_required = $1
+		WHERE id = $2
+	`, certRequired, id)
+	return err
+}
+
+// sets mail address to which MFA codes are sent, empty to disable MFA via mail for login
+func SetMfaMail_tx(tx pgx.Tx, id int64, mfaMail pgtype.Text) error {
+	if mfaMail.Valid && mfaMail.String != "" && !strings.Contains(mfaMail.String, "@") {
+		return fmt.Errorf("invalid MFA mail address '%s'", mfaMail.String)
+	}
+	_, err := tx.Exec(db.Ctx, `
+		UPDATE instance.login
+		SET mfa_mail = NULLIF($1,'')
+		WHERE id = $2
+	`, mfaMail, id)
+	return err
+}
+
+// deactivates active logins of LDAP connection that were not found during a full LDAP sync
+// returns IDs and names of deactivated logins
+func DeactivateLdapLoginsMissing_tx(tx pgx.Tx, ldapId int32, ldapKeysFound []string) ([]int64, []string, error) {
+	ids := make([]int64, 0)
+	names := make([]string, 0)
+
+	rows, err := tx.Query(db.Ctx, `
+		UPDATE instance.login
+		SET active = false
+		WHERE ldap_id = $1
+		AND active
+		AND ldap_key <> ALL($2)
+		RETURNING id, name
+	`, ldapId, ldapKeysFound)
+	if err != nil {
+		return ids, names, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return ids, names, err
+		}
+		ids = append(ids, id)
+		names = append(names, name)
 	}
-	return id, false, nil
+	return ids, names, rows.Err()
 }
 
 func GenerateSaltHash(pw string) (salt pgtype.Text, hash pgtype.Text) {
@@ -1,15 +1,21 @@
 package login_auth
 
 import (
+	"crypto/x509"
 	"database/sql"
 	"encoding/base32"
 	"errors"
 	"fmt"
+	"r3/cache"
 	"r3/config"
 	"r3/db"
 	"r3/handler"
 	"r3/ldap/ldap_auth"
+	"r3/log"
+	"r3/login/login_cert"
+	"r3/login/login_impersonate"
 	"r3/login/login_license"
+	"r3/login/login_mfa"
 	"r3/tools"
 	"r3/types"
 	"slices"
@@ -17,16 +23,21 @@ import (
 	"time"
 
 	"github.com/gbrlsnchs/jwt/v3"
+	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/xlzd/gotp"
 )
 
+// length of TOTP token set up during authentication, same as for fixed tokens
+var mfaSetupSecretLength = 32
+
 type tokenPayload struct {
 	jwt.Payload
-	Admin   bool  `json:"admin"`   // login belongs to admin user
-	LoginId int64 `json:"loginId"` // login ID
-	NoAuth  bool  `json:"noAuth"`  // login without authentication (username only)
+	Admin           bool  `json:"admin"`           // login belongs to admin user
+	ImpersonationId int64 `json:"impersonationId"` // impersonation session ID, if token was issued to an admin impersonating this login
+	LoginId         int64 `json:"loginId"`         // login ID
+	NoAuth          bool  `json:"noAuth"`          // login without authentication (username only)
 }
 
 // blocks authentication by non-admins if system is not in production mode
@@ -37,6 +48,64 @@ func authCheckSystemMode(admin bool) error {
 	return nil
 }
 
+// checks access conditions of assigned roles for the client address of the authentication request
+// blocks authentication if login has assigned roles but none of them are granted from this address at this time
+// admins are not blocked, as admin access does not depend on roles
+// requests after authentication check conditions against their own client address, see cache.GetAccessById()
+func authCheckRoleConditions(loginId int64, username string, admin bool, address string) error {
+
+	if admin {
+		return nil
+	}
+
+	// assigned and delegated roles, same as used for the access cache
+	roleIds := make([]uuid.UUID, 0)
+	if err := db.Pool.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT role_id
+			FROM instance.login_role
+			WHERE login_id = $1
+			AND  (date_from IS NULL OR date_from <= $2)
+			AND  (date_to   IS NULL OR date_to   >  $2)
+			
+			UNION
+			
+			SELECT d.role_id
+			FROM instance.login_role_delegation AS d
+			INNER JOIN instance.login_role AS lr
+				ON  lr.login_id = d.login_id
+				AND lr.role_id  = d.role_id
+			INNER JOIN instance.login AS l ON l.id = d.login_id
+			WHERE d.login_id_substitute = $1
+			AND   d.date_ended IS NULL
+			AND   d.date_from <= $2
+			AND   d.date_to   >  $2
+			AND  (lr.date_from IS NULL OR lr.date_from <= $2)
+			AND  (lr.date_to   IS NULL OR lr.date_to   >  $2)
+			AND   l.active
+		)
+	`, loginId, tools.GetTimeUnix()).Scan(&roleIds); err != nil {
+		return err
+	}
+
+	reasons := make([]string, 0)
+	for _, roleId := range roleIds {
+		reason := cache.GetRoleDenial(roleId, address)
+		if reason == "" {
+			return nil
+		}
+		reasons = append(reasons, reason)
+	}
+	if len(roleIds) == 0 {
+		return nil
+	}
+
+	log.Warning("server", fmt.Sprintf("denied access for login '%s' from %s", username, address),
+		errors.New(strings.Join(reasons, ", ")))
+
+	return errors.New("access denied, role conditions are not met")
+}
+
 func createToken(loginId int64, username string, admin bool, noAuth bool) (string, error) {
 
 	// token is valid for multiple days, if user decides to stay logged in
@@ -56,6 +125,28 @@ func createToken(loginId int64, username string, admin bool, noAuth bool) (strin
 	}, config.GetTokenSecret())
 	return string(token), err
 }
+
+// stores TOTP token set up during authentication, if the PIN generated from its secret is valid
+func authSetupMfa(loginId int64, name pgtype.Text, secret string, pin string) error {
+	token, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(token) < mfaSetupSecretLength {
+		return errors.New("MFA secret is invalid")
+	}
+	if pin != gotp.NewDefaultTOTP(base32.StdEncoding.WithPadding(
+		base32.NoPadding).EncodeToString(token)).Now() {
+
+		return errors.New("MFA PIN is invalid")
+	}
+	if !name.Valid || name.String == "" {
+		name.String = "TOTP"
+	}
+	_, err = db.Pool.Exec(db.Ctx, `
+		INSERT INTO instance.login_token_fixed (login_id,token,name,context,date_create)
+			VALUES ($1,$2,$3,'totp',$4)
+	`, loginId, string(token), name.String, tools.GetTimeUnix())
+	return err
+}
+
 func storeLastAuthDate(loginId int64) error {
 	_, err := db.Pool.Exec(db.Ctx, `
 		UPDATE instance.login
@@ -67,14 +158,17 @@ func storeLastAuthDate(loginId int64) error {
 }
 
 // performs authentication attempt for user by using username and password
-// returns JWT, KDF salt, MFA token list (if MFA is required)
-func User(username string, password string, mfaTokenId pgtype.Int4,
-	mfaTokenPin pgtype.Text, grantLoginId *int64, grantAdmin *bool,
-	grantNoAuth *bool) (string, string, []types.LoginMfaToken, error) {
+// MFA token ID 0 refers to code sent via mail, which is sent if requested (mfaMailSend)
+// if MFA is required but no method is set up, a TOTP token must be set up before a session is granted
+// the first attempt returns a new TOTP secret, the next attempt sends it back with name and PIN to store it
+// returns JWT, KDF salt, MFA token list (if MFA is required), TOTP secret (if MFA setup is required)
+func User(username string, password string, address string, mfaTokenId pgtype.Int4,
+	mfaTokenPin pgtype.Text, mfaMailSend bool, mfaSetupName pgtype.Text, mfaSetupSecret pgtype.Text,
+	grantLoginId *int64, grantAdmin *bool, grantNoAuth *bool) (string, string, []types.LoginMfaToken, string, error) {
 
 	mfaTokens := make([]types.LoginMfaToken, 0)
 	if username == "" {
-		return "", "", mfaTokens, errors.New("username not given")
+		return "", "", mfaTokens, "", errors.New("username not given")
 	}
 
 	// usernames are case insensitive
@@ -87,49 +181,75 @@ func User(username string, password string, mfaTokenId pgtype.Int4,
 	var saltKdf string
 	var admin bool
 	var noAuth bool
+	var certRequired bool
 
 	err := db.Pool.QueryRow(db.Ctx, `
-		SELECT id, ldap_id, salt, hash, salt_kdf, admin, no_auth
+		SELECT id, ldap_id, salt, hash, salt_kdf, admin, no_auth, cert_required
 		FROM instance.login
 		WHERE active
 		AND name = $1
-	`, username).Scan(&loginId, &ldapId, &salt, &hash, &saltKdf, &admin, &noAuth)
+	`, username).Scan(&loginId, &ldapId, &salt, &hash, &saltKdf, &admin, &noAuth, &certRequired)
 
 	if err != nil && err != pgx.ErrNoRows {
-		return "", "", mfaTokens, err
+		return "", "", mfaTokens, "", err
 	}
 
 	// username not found / user inactive must result in same response as authentication failed
 	// otherwise we can probe the system for valid user names
 	if err == pgx.ErrNoRows {
-		return "", "", mfaTokens, errors.New(handler.ErrAuthFailed)
+		return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
+	}
+
+	// login may only authenticate with client certificate
+	// generic error, to not reveal that the login exists
+	if certRequired {
+		log.Info("server", fmt.Sprintf("login '%s' requires client certificate authentication, password was rejected", username))
+		return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
 	}
 
 	if !noAuth && password == "" {
-		return "", "", mfaTokens, errors.New("password not given")
+		return "", "", mfaTokens, "", errors.New("password not given")
 	}
 
 	if !noAuth {
 		if ldapId.Valid {
 			// authentication against LDAP
 			if err := ldap_auth.Check(ldapId.Int32, username, password); err != nil {
-				return "", "", mfaTokens, errors.New(handler.ErrAuthFailed)
+				return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
 			}
 		} else {
 			// authentication against stored hash
 			if !hash.Valid || !salt.Valid || hash.String != tools.Hash(salt.String+password) {
-				return "", "", mfaTokens, errors.New(handler.ErrAuthFailed)
+				return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
 			}
 		}
 	}
 
 	if err := authCheckSystemMode(admin); err != nil {
-		return "", "", mfaTokens, err
+		return "", "", mfaTokens, "", err
+	}
+	if err := authCheckRoleConditions(loginId, username, admin, address); err != nil {
+		return "", "", mfaTokens, "", err
 	}
 
 	// login ok
+	mfaMail, err := login_mfa.GetMail(loginId)
+	if err != nil {
+		return "", "", mfaTokens, "", err
+	}
+
+	if mfaTokenId.Valid && mfaTokenPin.Valid && mfaTokenId.Int32 == 0 {
+
+		// validate provided MFA mail code
+		if !mfaMail.Valid {
+			return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
+		}
+		if err := login_mfa.CheckMailCode(loginId, mfaTokenPin.String); err != nil {
+			log.Info("server", fmt.Sprintf("MFA mail code of login '%s' was rejected, %s", username, err.Error()))
+			return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
+		}
 
-	if mfaTokenId.Valid && mfaTokenPin.Valid {
+	} else if mfaTokenId.Valid && mfaTokenPin.Valid {
 
 		// validate provided MFA token
 		var mfaToken []byte
@@ -140,13 +260,13 @@ func User(username string, password string, mfaTokenId pgtype.Int4,
 			AND   id       = $2
 			AND   context  = 'totp'
 		`, loginId, mfaTokenId.Int32).Scan(&mfaToken); err != nil {
-			return "", "", mfaTokens, err
+			return "", "", mfaTokens, "", err
 		}
 
 		if mfaTokenPin.String != gotp.NewDefaultTOTP(base32.StdEncoding.WithPadding(
 			base32.NoPadding).EncodeToString(mfaToken)).Now() {
 
-			return "", "", mfaTokens, errors.New(handler.ErrAuthFailed)
+			return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
 		}
 
 	} else {
@@ -158,47 +278,178 @@ func User(username string, password string, mfaTokenId pgtype.Int4,
 			AND   context  = 'totp'
 		`, loginId)
 		if err != nil {
-			return "", "", mfaTokens, err
+			return "", "", mfaTokens, "", err
 		}
 
 		for rows.Next() {
 			var m types.LoginMfaToken
 			if err := rows.Scan(&m.Id, &m.Name); err != nil {
-				return "", "", mfaTokens, err
+				return "", "", mfaTokens, "", err
 			}
+			m.Context = "totp"
 			mfaTokens = append(mfaTokens, m)
 		}
 		rows.Close()
 
+		if mfaMail.Valid {
+			mfaTokens = append(mfaTokens, types.LoginMfaToken{
+				Id:      0,
+				Name:    login_mfa.GetMailMasked(mfaMail.String),
+				Context: "mail",
+			})
+			// failing to send a code (like when requested again too early) does not fail the login attempt
+			// the mail MFA token is still offered, a previously sent code can still be used
+			if mfaMailSend {
+				if err := login_mfa.SendMailCode(loginId, mfaMail.String); err != nil {
+					log.Info("server", fmt.Sprintf("MFA mail code for login '%s' was not sent, %s", username, err.Error()))
+				}
+			}
+		}
+
 		// MFA tokens available, return with list
 		if len(mfaTokens) != 0 {
-			return "", "", mfaTokens, nil
+			return "", "", mfaTokens, "", nil
+		}
+
+		// no MFA tokens available, check whether MFA is required
+		// if so, no session is granted until a TOTP token is set up
+		required, err := login_mfa.GetRequired(loginId)
+		if err != nil {
+			return "", "", mfaTokens, "", err
+		}
+		if required {
+			if !mfaSetupSecret.Valid || !mfaTokenPin.Valid {
+				return "", "", mfaTokens, base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(
+					[]byte(tools.RandStringRunes(mfaSetupSecretLength))), nil
+			}
+			if err := authSetupMfa(loginId, mfaSetupName, mfaSetupSecret.String, mfaTokenPin.String); err != nil {
+				log.Info("server", fmt.Sprintf("MFA setup of login '%s' was rejected, %s", username, err.Error()))
+				return "", "", mfaTokens, "", errors.New(handler.ErrAuthFailed)
+			}
+			log.Info("server", fmt.Sprintf("login '%s' has set up MFA token during authentication", username))
 		}
 	}
 
 	// create session token
 	token, err := createToken(loginId, username, admin, noAuth)
 	if err != nil {
-		return "", "", mfaTokens, err
+		return "", "", mfaTokens, "", err
 	}
 
 	// everything in order, auth successful
 	if err := login_license.RequestConcurrent(loginId, admin); err != nil {
-		return "", "", mfaTokens, err
+		return "", "", mfaTokens, "", err
 	}
 	if err := storeLastAuthDate(loginId); err != nil {
-		return "", "", mfaTokens, err
+		return "", "", mfaTokens, "", err
 	}
 	*grantLoginId = loginId
 	*grantAdmin = admin
 	*grantNoAuth = noAuth
-	return token, saltKdf, mfaTokens, nil
+	return token, saltKdf, mfaTokens, "", nil
+}
+
+// performs authentication attempt for user by using client certificate (mutual TLS)
+// certificate is mapped to login by name, MFA is not applied as certificate is already a separate factor
+// returns JWT and login name
+func Cert(certs []*x509.Certificate, address string, grantLoginId *int64,
+	grantAdmin *bool, grantNoAuth *bool) (string, string, error) {
+
+	username, err := login_cert.GetLoginName(certs)
+	if err != nil {
+		return "", "", err
+	}
+
+	var loginId int64
+	var admin bool
+	err = db.Pool.QueryRow(db.Ctx, `
+		SELECT id, admin
+		FROM instance.login
+		WHERE active
+		AND name = $1
+	`, username).Scan(&loginId, &admin)
+
+	if err != nil && err != pgx.ErrNoRows {
+		return "", "", err
+	}
+	if err == pgx.ErrNoRows {
+		return "", "", errors.New(handler.ErrAuthFailed)
+	}
+
+	if err := authCheckSystemMode(admin); err != nil {
+		return "", "", err
+	}
+	if err := authCheckRoleConditions(loginId, username, admin, address); err != nil {
+		return "", "", err
+	}
+
+	token, err := createToken(loginId, username, admin, false)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := login_license.RequestConcurrent(loginId, admin); err != nil {
+		return "", "", err
+	}
+	if err := storeLastAuthDate(loginId); err != nil {
+		return "", "", err
+	}
+	*grantLoginId = loginId
+	*grantAdmin = admin
+	*grantNoAuth = false
+	return token, username, nil
+}
+
+// creates token for an admin to act as another login
+// token never grants admin access, impersonation session must already exist
+func Impersonate(impersonationId int64, loginId int64, dateEnd int64) (string, error) {
+
+	var username string
+	if err := db.Pool.QueryRow(db.Ctx, `
+		SELECT name
+		FROM instance.login
+		WHERE id = $1
+	`, loginId).Scan(&username); err != nil {
+		return "", err
+	}
+
+	token, err := jwt.Sign(tokenPayload{
+		Payload: jwt.Payload{
+			Issuer:         "r3 application",
+			Subject:        username,
+			ExpirationTime: jwt.NumericDate(time.Unix(dateEnd, 0)),
+			IssuedAt:       jwt.NumericDate(time.Now()),
+		},
+		ImpersonationId: impersonationId,
+		LoginId:         loginId,
+		Admin:           false,
+		NoAuth:          false,
+	}, config.GetTokenSecret())
+	return string(token), err
 }
 
 // performs authentication attempt for user by using existing JWT token, signed by server
+// requests authenticated this way during impersonation are logged by token use only
 // returns username
 func Token(token string, grantLoginId *int64, grantAdmin *bool, grantNoAuth *bool) (string, error) {
 
+	var impersonationId int64
+	name, err := TokenImpersonated(token, grantLoginId, grantAdmin, grantNoAuth, &impersonationId)
+	if err != nil {
+		return "", err
+	}
+	if impersonationId != 0 {
+		if err := login_impersonate.Log(impersonationId, "auth", "token", nil); err != nil {
+			return "", err
+		}
+	}
+	return name, nil
+}
+
+// like Token(), also applies impersonation session ID (0 if token is not used for impersonation)
+func TokenImpersonated(token string, grantLoginId *int64,
+	grantAdmin *bool, grantNoAuth *bool, grantImpersonationId *int64) (string, error) {
+
 	if token == "" {
 		return "", errors.New("empty token")
 	}
@@ -216,6 +467,13 @@ func Token(token string, grantLoginId *int64, grantAdmin *bool, grantNoAuth *boo
 		return "", err
 	}
 
+	// impersonation session can be ended before token expires
+	if tp.ImpersonationId != 0 {
+		if err := login_impersonate.CheckActive(tp.ImpersonationId, tp.LoginId); err != nil {
+			return "", err
+		}
+	}
+
 	// check if login is active
 	active := false
 	name := ""
@@ -235,12 +493,17 @@ func Token(token string, grantLoginId *int64, grantAdmin *bool, grantNoAuth *boo
 	if err := login_license.RequestConcurrent(tp.LoginId, tp.Admin); err != nil {
 		return "", err
 	}
-	if err := storeLastAuthDate(tp.LoginId); err != nil {
-		return "", err
+
+	// impersonating admin does not count as activity of the impersonated login
+	if tp.ImpersonationId == 0 {
+		if err := storeLastAuthDate(tp.LoginId); err != nil {
+			return "", err
+		}
 	}
 	*grantLoginId = tp.LoginId
 	*grantAdmin = tp.Admin
 	*grantNoAuth = tp.NoAuth
+	*grantImpersonationId = tp.ImpersonationId
 	return name, nil
 }
 
@@ -0,0 +1,191 @@
+package login_cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"r3/cache"
+	"r3/config"
+	"r3/db"
+	"r3/log"
+	"r3/tools"
+	"r3/types"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// client certificate (mutual TLS) authentication
+// certificates are requested during TLS handshake and verified against the configured CA bundle
+// only available if TLS is handled by the application (not in HTTP mode or behind TLS terminating proxies)
+
+var (
+	access_mx sync.RWMutex
+	caPool    *x509.CertPool
+	enabled   bool
+	loginMap  string // certificate field used as login name: subjectCn, sanEmail, sanDns
+)
+
+func SetConfig() {
+	access_mx.Lock()
+	defer access_mx.Unlock()
+
+	enabled = config.GetUint64("clientCertAuth") == 1
+	loginMap = config.GetString("clientCertLoginMap")
+	caPool = x509.NewCertPool()
+
+	if enabled && !caPool.AppendCertsFromPEM([]byte(config.GetString("clientCertCaBundle"))) {
+		log.Warning("server", "disabling client certificate authentication",
+			errors.New("CA bundle does not contain any valid certificate"))
+
+		enabled = false
+	}
+}
+
+func GetEnabled() bool {
+	access_mx.RLock()
+	defer access_mx.RUnlock()
+	return enabled
+}
+
+// returns TLS config for new client connection
+// client certificates are requested but not verified during handshake, invalid certificates must not block regular access
+func GetConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	access_mx.RLock()
+	defer access_mx.RUnlock()
+
+	if !enabled {
+		return nil, nil // use default config
+	}
+	return &tls.Config{
+		ClientAuth:     tls.RequestClientCert,
+		ClientCAs:      caPool, // accepted CAs are sent to client, to help with certificate selection
+		GetCertificate: cache.GetCert,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}, nil
+}
+
+// verifies client certificate chain and returns login name from certificate
+func GetLoginName(certs []*x509.Certificate) (string, error) {
+	access_mx.RLock()
+	enabledCopy := enabled
+	pool := caPool
+	loginMapCopy := loginMap
+	access_mx.RUnlock()
+
+	if !enabledCopy {
+		return "", errors.New("client certificate authentication is disabled")
+	}
+	if len(certs) == 0 {
+		return "", errors.New("no client certificate provided")
+	}
+	cert := certs[0]
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Roots:         pool,
+	}); err != nil {
+		return "", err
+	}
+
+	serial := GetSerial(cert)
+	revoked := false
+	if err := db.Pool.QueryRow(db.Ctx, `
+		SELECT EXISTS(
+			SELECT serial
+			FROM instance.client_cert_revoked
+			WHERE serial = $1
+		)
+	`, serial).Scan(&revoked); err != nil {
+		return "", err
+	}
+	if revoked {
+		return "", fmt.Errorf("client certificate with serial '%s' is revoked", serial)
+	}
+
+	var name string
+	switch loginMapCopy {
+	case "sanDns":
+		if len(cert.DNSNames) != 0 {
+			name = cert.DNSNames[0]
+		}
+	case "sanEmail":
+		if len(cert.EmailAddresses) != 0 {
+			name = cert.EmailAddresses[0]
+		}
+	default:
+		name = cert.Subject.CommonName
+	}
+	if name == "" {
+		return "", fmt.Errorf("client certificate with serial '%s' has no value for login mapping '%s'",
+			serial, loginMapCopy)
+	}
+	return strings.ToLower(name), nil // usernames are case insensitive
+}
+
+// returns serial number of certificate as lower case hex, without separators
+func GetSerial(cert *x509.Certificate) string {
+	return strings.ToLower(cert.SerialNumber.Text(16))
+}
+
+// revocation list
+func DelRevoked_tx(tx pgx.Tx, serial string) error {
+	_, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.client_cert_revoked
+		WHERE serial = $1
+	`, serial)
+	return err
+}
+
+func GetRevoked() ([]types.ClientCertRevoked, error) {
+	revoked := make([]types.ClientCertRevoked, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT serial, comment, date_revoked
+		FROM instance.client_cert_revoked
+		ORDER BY date_revoked DESC
+	`)
+	if err != nil {
+		return revoked, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.ClientCertRevoked
+		if err := rows.Scan(&r.Serial, &r.Comment, &r.DateRevoked); err != nil {
+			return revoked, err
+		}
+		revoked = append(revoked, r)
+	}
+	return revoked, nil
+}
+
+// serial is accepted in common notations ('0A:1B:2C', '0a 1b 2c', '0a1b2c')
+func SetRevoked_tx(tx pgx.Tx, serial string, comment string) error {
+
+	serial = strings.ToLower(strings.NewReplacer(":", "", " ", "", "-", "").Replace(serial))
+	serial = strings.TrimLeft(serial, "0")
+	if serial == "" {
+		return errors.New("serial number must not be empty")
+	}
+	for _, r := range serial {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return fmt.Errorf("serial number must be hexadecimal, invalid character '%c'", r)
+		}
+	}
+
+	_, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.client_cert_revoked (serial, comment, date_revoked)
+		VALUES ($1,NULLIF($2,''),$3)
+		ON CONFLICT (serial) DO UPDATE
+		SET comment = NULLIF($2,'')
+	`, serial, comment, tools.GetTimeUnix())
+	return err
+}
@@ -0,0 +1,176 @@
+package login
+
+import (
+	"errors"
+	"fmt"
+	"r3/cluster"
+	"r3/db"
+	"r3/tools"
+	"r3/types"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// returns role delegations given by or received by login
+func GetDelegations(loginId int64) ([]types.LoginRoleDelegation, error) {
+	delegations := make([]types.LoginRoleDelegation, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT d.id, d.login_id, l.name, d.login_id_substitute, ls.name,
+			d.role_id, d.date_from, d.date_to, d.date_created, d.date_ended,
+			d.revoked
+		FROM instance.login_role_delegation AS d
+		INNER JOIN instance.login AS l  ON l.id  = d.login_id
+		INNER JOIN instance.login AS ls ON ls.id = d.login_id_substitute
+		WHERE d.login_id            = $1
+		OR    d.login_id_substitute = $1
+		ORDER BY d.date_from DESC, d.id DESC
+	`, loginId)
+	if err != nil {
+		return delegations, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d types.LoginRoleDelegation
+		if err := rows.Scan(&d.Id, &d.LoginId, &d.LoginName, &d.LoginIdSubstitute,
+			&d.LoginNameSubstitute, &d.RoleId, &d.DateFrom, &d.DateTo,
+			&d.DateCreated, &d.DateEnded, &d.Revoked); err != nil {
+
+			return delegations, err
+		}
+		delegations = append(delegations, d)
+	}
+	return delegations, nil
+}
+
+// delegates roles of login to substitute for given time range
+// substitute is informed after commit
+func SetDelegation(loginId int64, loginIdSubstitute int64, roleIds []uuid.UUID,
+	dateFrom int64, dateTo int64) error {
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	if err := setDelegation_tx(tx, loginId, loginIdSubstitute, roleIds, dateFrom, dateTo); err != nil {
+		return err
+	}
+	if err := tx.Commit(db.Ctx); err != nil {
+		return err
+	}
+	return cluster.LoginRoleDelegationChanged(true, loginIdSubstitute)
+}
+
+// only directly assigned roles can be delegated, the assignment must be valid for the entire range
+func setDelegation_tx(tx pgx.Tx, loginId int64, loginIdSubstitute int64,
+	roleIds []uuid.UUID, dateFrom int64, dateTo int64) error {
+
+	if loginId == loginIdSubstitute {
+		return errors.New("cannot delegate roles to own login")
+	}
+	if len(roleIds) == 0 {
+		return errors.New("no roles to delegate")
+	}
+	if dateFrom >= dateTo {
+		return errors.New("role delegation must start before it ends")
+	}
+	if dateTo <= tools.GetTimeUnix() {
+		return errors.New("role delegation must end in the future")
+	}
+
+	var active bool
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT active
+		FROM instance.login
+		WHERE id = $1
+	`, loginIdSubstitute).Scan(&active); err != nil {
+		return err
+	}
+	if !active {
+		return errors.New("cannot delegate roles to inactive login")
+	}
+
+	for _, roleId := range roleIds {
+		var delegable bool
+		if err := tx.QueryRow(db.Ctx, `
+			SELECT EXISTS(
+				SELECT lr.role_id
+				FROM instance.login_role AS lr
+				INNER JOIN app.role AS r ON r.id = lr.role_id
+				WHERE lr.login_id = $1
+				AND   lr.role_id  = $2
+				AND   r.assignable
+				AND  (lr.date_from IS NULL OR lr.date_from <= $3)
+				AND  (lr.date_to   IS NULL OR lr.date_to   >= $4)
+			)
+		`, loginId, roleId, dateFrom, dateTo).Scan(&delegable); err != nil {
+			return err
+		}
+		if !delegable {
+			return fmt.Errorf("role %s is not assigned to login for the entire delegation period", roleId)
+		}
+
+		var id int64
+		if err := tx.QueryRow(db.Ctx, `
+			INSERT INTO instance.login_role_delegation (login_id, login_id_substitute,
+				role_id, date_from, date_to, date_created, revoked)
+			VALUES ($1,$2,$3,$4,$5,$6,false)
+			RETURNING id
+		`, loginId, loginIdSubstitute, roleId, dateFrom, dateTo, tools.GetTimeUnix()).Scan(&id); err != nil {
+			return err
+		}
+
+		if err := setRoleLog_tx(tx, loginIdSubstitute, pgtype.Int8{Int64: loginId, Valid: true},
+			roleId, pgtype.Int8{Int64: id, Valid: true}, "delegationCreated"); err != nil {
+
+			return err
+		}
+	}
+
+	// delegated roles must not conflict with roles of substitute
+	return checkRoleConflicts_tx(tx, loginIdSubstitute)
+}
+
+// revokes role delegation, only the delegating login can revoke it
+// substitute is informed after commit
+func RevokeDelegation(loginId int64, id int64) error {
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	var loginIdSubstitute int64
+	var roleId uuid.UUID
+	err = tx.QueryRow(db.Ctx, `
+		UPDATE instance.login_role_delegation
+		SET date_ended = $1, revoked = true
+		WHERE id       = $2
+		AND   login_id = $3
+		AND   date_ended IS NULL
+		RETURNING login_id_substitute, role_id
+	`, tools.GetTimeUnix(), id, loginId).Scan(&loginIdSubstitute, &roleId)
+
+	if err == pgx.ErrNoRows {
+		return errors.New("role delegation does not exist or has already ended")
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := setRoleLog_tx(tx, loginIdSubstitute, pgtype.Int8{Int64: loginId, Valid: true},
+		roleId, pgtype.Int8{Int64: id, Valid: true}, "delegationRevoked"); err != nil {
+
+		return err
+	}
+	if err := tx.Commit(db.Ctx); err != nil {
+		return err
+	}
+	return cluster.LoginRoleDelegationChanged(true, loginIdSubstitute)
+}
@@ -0,0 +1,217 @@
+package login_impersonate
+
+import (
+	"encoding/json"
+	"errors"
+	"r3/db"
+	"r3/tools"
+	"r3/types"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// impersonation sessions are kept short, admins can start a new one if required
+var sessionDurationSeconds int64 = 60 * 60
+
+// replaces values of credential fields in logged request payloads
+var payloadRedacted = "[REDACTED]"
+
+// requests that are blocked during impersonation, even for non-admin logins
+// these change credentials or keys of the impersonated login
+var requestsBlocked = map[string][]string{
+	"login":               {"delTokenFixed", "setTokenFixed"},
+	"loginKeys":           {"reset", "store", "storePrivate"},
+	"loginPassword":       {"set"},
+	"loginRoleDelegation": {"del", "set"},
+}
+
+// starts impersonation session of an admin for another login
+// returns impersonation ID and unix time of session expiry
+func Start_tx(tx pgx.Tx, loginIdAdmin int64, loginId int64, reason string) (int64, int64, error) {
+
+	if loginIdAdmin == loginId {
+		return 0, 0, errors.New("cannot impersonate own login")
+	}
+
+	var active bool
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT active
+		FROM instance.login
+		WHERE id = $1
+	`, loginId).Scan(&active); err != nil {
+		return 0, 0, err
+	}
+	if !active {
+		return 0, 0, errors.New("cannot impersonate inactive login")
+	}
+
+	var id int64
+	now := tools.GetTimeUnix()
+	dateEnd := now + sessionDurationSeconds
+
+	err := tx.QueryRow(db.Ctx, `
+		INSERT INTO instance.login_impersonation (
+			login_id_admin, login_id, reason, date_start, date_end)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id
+	`, loginIdAdmin, loginId, reason, now, dateEnd).Scan(&id)
+
+	return id, dateEnd, err
+}
+
+// ends impersonation session prematurely
+func End_tx(tx pgx.Tx, id int64) error {
+	_, err := tx.Exec(db.Ctx, `
+		UPDATE instance.login_impersonation
+		SET date_end = $1
+		WHERE id = $2
+		AND date_end > $1
+	`, tools.GetTimeUnix(), id)
+	return err
+}
+
+// returns impersonation sessions, optionally filtered by impersonated login
+func Get(loginId int64) ([]types.LoginImpersonation, error) {
+	sessions := make([]types.LoginImpersonation, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT i.id, i.login_id, l.name, COALESCE(i.login_id_admin, 0), COALESCE(la.name, '-'),
+			i.reason, i.date_start, i.date_end, (
+				SELECT COUNT(*)
+				FROM instance.login_impersonation_log
+				WHERE login_impersonation_id = i.id
+			)
+		FROM instance.login_impersonation AS i
+		INNER JOIN instance.login AS l  ON l.id  = i.login_id
+		LEFT  JOIN instance.login AS la ON la.id = i.login_id_admin
+		WHERE $1 = 0
+		OR    i.login_id = $1
+		ORDER BY i.date_start DESC
+	`, loginId)
+	if err != nil {
+		return sessions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s types.LoginImpersonation
+		if err := rows.Scan(&s.Id, &s.LoginId, &s.LoginName, &s.LoginIdAdmin,
+			&s.LoginNameAdmin, &s.Reason, &s.DateStart, &s.DateEnd,
+			&s.RequestCount); err != nil {
+
+			return sessions, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, nil
+}
+
+// returns audit trail of impersonation session
+func GetLogs(id int64) ([]types.LoginImpersonationLog, error) {
+	logs := make([]types.LoginImpersonationLog, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT date_milli, ressource, action, payload
+		FROM instance.login_impersonation_log
+		WHERE login_impersonation_id = $1
+		ORDER BY date_milli ASC
+	`, id)
+	if err != nil {
+		return logs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l types.LoginImpersonationLog
+		var payload string
+		if err := rows.Scan(&l.DateMilli, &l.Ressource, &l.Action, &payload); err != nil {
+			return logs, err
+		}
+		l.Payload = json.RawMessage(payload)
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+// checks whether impersonation session is valid for given login
+func CheckActive(id int64, loginId int64) error {
+	var dateEnd int64
+	err := db.Pool.QueryRow(db.Ctx, `
+		SELECT date_end
+		FROM instance.login_impersonation
+		WHERE id       = $1
+		AND   login_id = $2
+	`, id, loginId).Scan(&dateEnd)
+
+	if err == pgx.ErrNoRows || (err == nil && dateEnd <= tools.GetTimeUnix()) {
+		return errors.New("impersonation session ended")
+	}
+	return err
+}
+
+// returns whether request is blocked during impersonation
+func IsBlocked(ressource string, action string) bool {
+	actions, exists := requestsBlocked[ressource]
+	return exists && slices.Contains(actions, action)
+}
+
+// writes request executed during impersonation to audit trail
+// uses its own connection as the audit entry must persist even if the request fails
+// credentials are redacted from the payload
+func Log(id int64, ressource string, action string, payload json.RawMessage) error {
+	_, err := db.Pool.Exec(db.Ctx, `
+		INSERT INTO instance.login_impersonation_log (
+			login_impersonation_id, date_milli, ressource, action, payload)
+		VALUES ($1,$2,$3,$4,$5)
+	`, id, tools.GetTimeUnixMilli(), ressource, action, getPayloadRedacted(payload))
+	return err
+}
+
+// returns payload with values of credential fields (passwords, tokens, keys, codes) replaced
+// payloads that cannot be parsed are not stored
+func getPayloadRedacted(payload json.RawMessage) string {
+	var value interface{}
+	if len(payload) == 0 || json.Unmarshal(payload, &value) != nil {
+		return "null"
+	}
+
+	var redact func(v interface{}) interface{}
+	redact = func(v interface{}) interface{} {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, vSub := range t {
+				if isCredentialField(k) {
+					t[k] = payloadRedacted
+				} else {
+					t[k] = redact(vSub)
+				}
+			}
+		case []interface{}:
+			for i, vSub := range t {
+				t[i] = redact(vSub)
+			}
+		}
+		return v
+	}
+
+	out, err := json.Marshal(redact(value))
+	if err != nil {
+		return "null"
+	}
+	return string(out)
+}
+
+func isCredentialField(name string) bool {
+	name = strings.ToLower(name)
+	if name == "code" || name == "pin" {
+		return true
+	}
+	for _, part := range []string{"pass", "secret", "token", "key"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
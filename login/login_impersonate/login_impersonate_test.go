@@ -0,0 +1,48 @@
+package login_impersonate
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetPayloadRedacted(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{``, `null`},
+		{`null`, `null`},
+		{`not json`, `null`},
+		{`{"id":12,"name":"max"}`, `{"id":12,"name":"max"}`},
+		{`{"pwNew0":"x","pass":"secret1","passwordNew":"secret2"}`,
+			`{"pass":"[REDACTED]","passwordNew":"[REDACTED]","pwNew0":"x"}`},
+		{`{"tokenFixed":"abc","keyPrivateEnc":"def","clientSecret":"ghi","code":"123456"}`,
+			`{"clientSecret":"[REDACTED]","code":"[REDACTED]","keyPrivateEnc":"[REDACTED]","tokenFixed":"[REDACTED]"}`},
+		{`{"records":[{"id":1,"password":"x"},{"nested":{"Token":"y"}}]}`,
+			`{"records":[{"id":1,"password":"[REDACTED]"},{"nested":{"Token":"[REDACTED]"}}]}`},
+		{`[{"pin":"1234"},"text"]`, `[{"pin":"[REDACTED]"},"text"]`},
+	}
+	for _, test := range tests {
+		got := getPayloadRedacted(json.RawMessage(test.payload))
+		if got != test.want {
+			t.Errorf("getPayloadRedacted(%s) = %s, want %s", test.payload, got, test.want)
+		}
+	}
+}
+
+func TestIsBlockedCredentialChanges(t *testing.T) {
+	for ressource, actions := range map[string][]string{
+		"loginPassword": {"set"},
+		"loginKeys":     {"reset", "store", "storePrivate"},
+		"login":         {"setTokenFixed", "delTokenFixed"},
+	} {
+		for _, action := range actions {
+			if !IsBlocked(ressource, action) {
+				t.Errorf("IsBlocked(%s, %s) = false, want true", ressource, action)
+			}
+		}
+	}
+	if IsBlocked("data", "set") {
+		t.Error("IsBlocked(data, set) = true, want false")
+	}
+}
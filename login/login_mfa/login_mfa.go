@@ -0,0 +1,230 @@
+package login_mfa
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"r3/config"
+	"r3/db"
+	"r3/log"
+	"r3/tools"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// multi-factor authentication via mail
+// short numeric codes are sent via the mail spooler to the MFA mail address of a login
+
+var (
+	mailCodeAttemptsMax = 5  // failed attempts after which a code becomes invalid
+	mailCodeDigits      = 6  // length of code
+	mailCodeRateSeconds = 60 // minimum time between codes sent to the same login
+)
+
+// returns whether login must authenticate with a second factor
+// MFA is required globally or by roles granted to login (incl. inherited roles)
+func GetRequired(loginId int64) (bool, error) {
+	if config.GetUint64("mfaRequired") == 1 {
+		return true, nil
+	}
+
+	var required bool
+	err := db.Pool.QueryRow(db.Ctx, `
+		SELECT EXISTS(
+			SELECT role_id
+			FROM instance.role_mfa
+			WHERE role_id = ANY(instance.get_role_ids($1,true))
+		)
+	`, loginId).Scan(&required)
+	return required, err
+}
+
+// returns roles requiring MFA
+func GetRoleIds() ([]uuid.UUID, error) {
+	roleIds := make([]uuid.UUID, 0)
+	err := db.Pool.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT role_id
+			FROM instance.role_mfa
+		)
+	`).Scan(&roleIds)
+	return roleIds, err
+}
+
+func SetRoleIds_tx(tx pgx.Tx, roleIds []uuid.UUID) error {
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.role_mfa
+	`); err != nil {
+		return err
+	}
+	for _, roleId := range roleIds {
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO instance.role_mfa (role_id)
+			VALUES ($1)
+		`, roleId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns MFA mail address of login, if mail codes are enabled and address is set
+func GetMail(loginId int64) (pgtype.Text, error) {
+	var mail pgtype.Text
+	if config.GetUint64("mfaMail") != 1 {
+		return mail, nil
+	}
+	err := db.Pool.QueryRow(db.Ctx, `
+		SELECT mfa_mail
+		FROM instance.login
+		WHERE id = $1
+		AND   mfa_mail <> ''
+	`, loginId).Scan(&mail)
+
+	if err == pgx.ErrNoRows {
+		return mail, nil
+	}
+	return mail, err
+}
+
+// returns mail address with most characters of the local part hidden
+func GetMailMasked(mail string) string {
+	local, domain, found := strings.Cut(mail, "@")
+	if !found || len(local) < 2 {
+		return "***"
+	}
+	return fmt.Sprintf("%c***%c@%s", local[0], local[len(local)-1], domain)
+}
+
+// creates new code for login and spools mail to send it
+// a new code replaces the previous one but can only be requested after a waiting period
+func SendMailCode(loginId int64, mail string) error {
+	now := tools.GetTimeUnix()
+	minutes := config.GetUint64("mfaMailCodeMinutes")
+
+	var dateCreated int64
+	err := db.Pool.QueryRow(db.Ctx, `
+		SELECT date_created
+		FROM instance.login_mfa_code
+		WHERE login_id = $1
+	`, loginId).Scan(&dateCreated)
+
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+	if err == nil && now-dateCreated < int64(mailCodeRateSeconds) {
+		return fmt.Errorf("MFA code was already sent, new code can be requested in %d seconds",
+			int64(mailCodeRateSeconds)-(now-dateCreated))
+	}
+
+	code, err := getMailCodeNew()
+	if err != nil {
+		return err
+	}
+
+	var accountId pgtype.Int4
+	if id := config.GetUint64("mfaMailAccountId"); id != 0 {
+		accountId.Int32 = int32(id)
+		accountId.Valid = true
+	}
+
+	replacer := strings.NewReplacer("{CODE}", code, "{MINUTES}", fmt.Sprintf("%d", minutes))
+	subject := replacer.Replace(config.GetString("mfaMailSubject"))
+	body := replacer.Replace(config.GetString("mfaMailBody"))
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	if _, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.login_mfa_code (login_id, code_hash, date_created, date_expiry, attempts)
+		VALUES ($1,$2,$3,$4,0)
+		ON CONFLICT (login_id) DO UPDATE
+		SET code_hash = $2, date_created = $3, date_expiry = $4, attempts = 0
+	`, loginId, getMailCodeHash(loginId, code), now, now+int64(minutes)*60); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.mail_spool (to_list, cc_list, bcc_list,
+			subject, body, outgoing, date, mail_account_id)
+		VALUES ($1,'','',$2,$3,TRUE,$4,$5)
+	`, mail, subject, body, now, accountId); err != nil {
+		return err
+	}
+
+	log.Info("server", fmt.Sprintf("spooled MFA mail code for login %d", loginId))
+	return tx.Commit(db.Ctx)
+}
+
+// checks code sent to login via mail, a valid code can only be used once
+// code row is locked while checking, parallel attempts cannot exceed the attempt limit
+func CheckMailCode(loginId int64, code string) error {
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	var codeHash string
+	var dateExpiry int64
+	var attempts int
+	err = tx.QueryRow(db.Ctx, `
+		SELECT code_hash, date_expiry, attempts
+		FROM instance.login_mfa_code
+		WHERE login_id = $1
+		FOR UPDATE
+	`, loginId).Scan(&codeHash, &dateExpiry, &attempts)
+
+	if err == pgx.ErrNoRows {
+		return errors.New("no MFA code was sent")
+	}
+	if err != nil {
+		return err
+	}
+	if dateExpiry <= tools.GetTimeUnix() || attempts >= mailCodeAttemptsMax {
+		return errors.New("MFA code has expired")
+	}
+
+	if codeHash != getMailCodeHash(loginId, code) {
+		if _, err := tx.Exec(db.Ctx, `
+			UPDATE instance.login_mfa_code
+			SET attempts = attempts + 1
+			WHERE login_id = $1
+		`, loginId); err != nil {
+			return err
+		}
+		if err := tx.Commit(db.Ctx); err != nil {
+			return err
+		}
+		return errors.New("MFA code is invalid")
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.login_mfa_code
+		WHERE login_id = $1
+	`, loginId); err != nil {
+		return err
+	}
+	return tx.Commit(db.Ctx)
+}
+
+func getMailCodeHash(loginId int64, code string) string {
+	return tools.Hash(fmt.Sprintf("%d_%s", loginId, code))
+}
+
+func getMailCodeNew() (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(mailCodeDigits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", mailCodeDigits, n), nil
+}
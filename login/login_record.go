@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"r3/cache"
 	"r3/db"
+	"r3/handler"
 	"r3/schema"
 	"r3/tools"
 	"r3/types"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // get relation records as login associate
@@ -71,3 +76,85 @@ func GetRecords(attributeIdLookup uuid.UUID, idsExclude []int64,
 	}
 	return records, nil
 }
+
+// updates text attributes of login record (relation record connected to login via login attribute)
+// creates login record if it does not exist yet, empty values are stored as NULL
+func SetRecordValues_tx(tx pgx.Tx, loginId int64, attributeIdLogin uuid.UUID,
+	attributeIdMapValue map[uuid.UUID]string) error {
+
+	if len(attributeIdMapValue) == 0 {
+		return nil
+	}
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	atrLogin, exists := cache.AttributeIdMap[attributeIdLogin]
+	if !exists {
+		return handler.ErrSchemaUnknownAttribute(attributeIdLogin)
+	}
+	rel := cache.RelationIdMap[atrLogin.RelationId]
+	mod := cache.ModuleIdMap[rel.ModuleId]
+
+	names := make([]string, 0)
+	values := make([]interface{}, 0)
+	for atrId, value := range attributeIdMapValue {
+		atr, exists := cache.AttributeIdMap[atrId]
+		if !exists {
+			return handler.ErrSchemaUnknownAttribute(atrId)
+		}
+		if atr.RelationId != rel.Id {
+			return fmt.Errorf("attribute '%s' is not part of login record relation '%s'",
+				atr.Name, rel.Name)
+		}
+
+		// shorten value to fit attribute length
+		if atr.Content == "varchar" && atr.Length != 0 && utf8.RuneCountInString(value) > atr.Length {
+			value = string([]rune(value)[:atr.Length])
+		}
+		names = append(names, fmt.Sprintf(`"%s"`, atr.Name))
+		values = append(values, pgtype.Text{String: value, Valid: value != ""})
+	}
+
+	recordExists := false
+	if err := tx.QueryRow(db.Ctx, fmt.Sprintf(`
+		SELECT EXISTS(
+			SELECT "%s"
+			FROM "%s"."%s"
+			WHERE "%s" = $1
+		)
+	`, schema.PkName, mod.Name, rel.Name, atrLogin.Name), loginId).Scan(&recordExists); err != nil {
+		return err
+	}
+
+	if !recordExists {
+		placeholders := make([]string, 0)
+		for i := 0; i <= len(names); i++ {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", i+1))
+		}
+		_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			INSERT INTO "%s"."%s" ("%s", %s)
+			VALUES (%s)
+		`, mod.Name, rel.Name, atrLogin.Name, strings.Join(names, ", "),
+			strings.Join(placeholders, ",")), append([]interface{}{loginId}, values...)...)
+
+		return err
+	}
+
+	// only update if values changed, to avoid unnecessary record changes
+	sets := make([]string, 0)
+	changes := make([]string, 0)
+	for i, name := range names {
+		sets = append(sets, fmt.Sprintf("%s = $%d", name, i+2))
+		changes = append(changes, fmt.Sprintf("%s IS DISTINCT FROM $%d", name, i+2))
+	}
+	_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+		UPDATE "%s"."%s"
+		SET %s
+		WHERE "%s" = $1
+		AND (%s)
+	`, mod.Name, rel.Name, strings.Join(sets, ", "), atrLogin.Name,
+		strings.Join(changes, " OR ")), append([]interface{}{loginId}, values...)...)
+
+	return err
+}
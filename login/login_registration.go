@@ -0,0 +1,422 @@
+package login
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"r3/config"
+	"r3/db"
+	"r3/log"
+	"r3/login/login_check"
+	"r3/tools"
+	"r3/types"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	registrationCodeAttemptsMax = 5  // failed attempts after which a code becomes invalid
+	registrationCodeDigits      = 8  // length of code
+	registrationCodeMinutes     = 60 // validity of code, unverified registrations are removed afterwards
+	registrationCodeRateSeconds = 60 // minimum time between codes sent to the same mail address
+)
+
+func GetRegistrationActive() bool {
+	return config.GetUint64("registrationActive") == 1
+}
+
+// returns open registrations, unverified or awaiting approval
+func GetRegistrations() ([]types.LoginRegistration, error) {
+	registrations := make([]types.LoginRegistration, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT r.login_id, l.name, r.mail, r.state, r.registration_rule_id, r.date_created
+		FROM instance.login_registration AS r
+		INNER JOIN instance.login AS l ON l.id = r.login_id
+		ORDER BY r.date_created ASC
+	`)
+	if err != nil {
+		return registrations, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.LoginRegistration
+		if err := rows.Scan(&r.LoginId, &r.LoginName, &r.Mail, &r.State,
+			&r.RuleId, &r.DateCreated); err != nil {
+
+			return registrations, err
+		}
+		registrations = append(registrations, r)
+	}
+	return registrations, nil
+}
+
+// registers new, inactive login for mail address and sends verification code to it
+// to not reveal existing logins, registering a taken name is silently ignored
+func Register_tx(tx pgx.Tx, mail string, pass string) error {
+
+	if !GetRegistrationActive() {
+		return errors.New("self-registration is disabled")
+	}
+
+	mail = strings.ToLower(strings.TrimSpace(mail))
+	ruleId, err := getRegistrationRuleId_tx(tx, mail)
+	if err != nil {
+		return err
+	}
+	if err := login_check.PasswordComplexity(pass); err != nil {
+		return err
+	}
+
+	var loginId int64
+	var state string
+	var dateCreated int64
+	err = tx.QueryRow(db.Ctx, `
+		SELECT l.id, COALESCE(r.state::TEXT,''), COALESCE(r.date_created,0)
+		FROM instance.login AS l
+		LEFT JOIN instance.login_registration AS r ON r.login_id = l.id
+		WHERE l.name = $1
+	`, mail).Scan(&loginId, &state, &dateCreated)
+
+	if err != nil && err != pgx.ErrNoRows {
+		return err
+	}
+
+	now := tools.GetTimeUnix()
+
+	if err == nil {
+		// login exists, repeated registration renews code of unverified registration
+		if state != "unverified" || now-dateCreated < int64(registrationCodeRateSeconds) {
+			return nil
+		}
+		salt, hash := GenerateSaltHash(pass)
+		if err := SetSaltHash_tx(tx, salt, hash, loginId); err != nil {
+			return err
+		}
+	} else {
+		templateId := config.GetUint64("registrationLoginTemplateId")
+		loginId, err = Set_tx(tx, 0, pgtype.Int8{Int64: int64(templateId), Valid: templateId != 0},
+			pgtype.Int4{}, pgtype.Text{}, mail, pass, false, false, false, nil, nil)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	code, err := getRegistrationCodeNew()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.login_registration (login_id, registration_rule_id,
+			mail, state, code_hash, code_expiry, attempts, date_created)
+		VALUES ($1,$2,$3,'unverified',$4,$5,0,$6)
+		ON CONFLICT (login_id) DO UPDATE
+		SET registration_rule_id = $2, code_hash = $4, code_expiry = $5,
+			attempts = 0, date_created = $6
+	`, loginId, ruleId, mail, getRegistrationCodeHash(loginId, code),
+		now+int64(registrationCodeMinutes)*60, now); err != nil {
+
+		return err
+	}
+
+	replacer := strings.NewReplacer("{CODE}", code, "{MINUTES}", fmt.Sprintf("%d", registrationCodeMinutes))
+	return spoolRegistrationMail_tx(tx, mail,
+		replacer.Replace(config.GetString("registrationMailSubject")),
+		replacer.Replace(config.GetString("registrationMailBody")))
+}
+
+// verifies mail address of registration with code that was sent to it
+// verified registrations are approved automatically if the matching rule says so, otherwise admins are notified
+// returns false if code was wrong, to keep the count of failed attempts
+// registration is locked while checking, parallel attempts cannot exceed the attempt limit
+func RegisterVerify_tx(tx pgx.Tx, mail string, code string) (bool, error) {
+
+	if !GetRegistrationActive() {
+		return false, errors.New("self-registration is disabled")
+	}
+
+	var loginId int64
+	var codeHash string
+	var codeExpiry int64
+	var attempts int
+	var approveAuto pgtype.Bool
+	err := tx.QueryRow(db.Ctx, `
+		SELECT r.login_id, r.code_hash, r.code_expiry, r.attempts, rr.approve_auto
+		FROM instance.login_registration AS r
+		LEFT JOIN instance.registration_rule AS rr ON rr.id = r.registration_rule_id
+		WHERE r.mail  = $1
+		AND   r.state = 'unverified'
+		FOR UPDATE OF r
+	`, strings.ToLower(strings.TrimSpace(mail))).Scan(&loginId, &codeHash,
+		&codeExpiry, &attempts, &approveAuto)
+
+	if err == pgx.ErrNoRows {
+		return false, errors.New("no registration to verify")
+	}
+	if err != nil {
+		return false, err
+	}
+	if codeExpiry <= tools.GetTimeUnix() || attempts >= registrationCodeAttemptsMax {
+		return false, errors.New("registration code has expired")
+	}
+
+	if codeHash != getRegistrationCodeHash(loginId, code) {
+		_, err := tx.Exec(db.Ctx, `
+			UPDATE instance.login_registration
+			SET attempts = attempts + 1
+			WHERE login_id = $1
+		`, loginId)
+		return false, err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		UPDATE instance.login_registration
+		SET state = 'pending', code_hash = ''
+		WHERE login_id = $1
+	`, loginId); err != nil {
+		return false, err
+	}
+
+	if approveAuto.Valid && approveAuto.Bool {
+		return true, ApproveRegistration_tx(tx, loginId)
+	}
+
+	// inform admins about registration awaiting approval
+	if notify := config.GetString("registrationNotifyMail"); notify != "" {
+		if err := spoolRegistrationMail_tx(tx, notify,
+			fmt.Sprintf("%s: new registration awaiting approval", config.GetString("appName")),
+			fmt.Sprintf("The login '%s' was registered and awaits approval by an administrator.",
+				strings.ToLower(strings.TrimSpace(mail)))); err != nil {
+
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// approves verified registration, activates login and assigns roles of matching registration rule
+func ApproveRegistration_tx(tx pgx.Tx, loginId int64) error {
+
+	mail, ruleId, err := getRegistrationPending_tx(tx, loginId)
+	if err != nil {
+		return err
+	}
+
+	roleIds := make([]uuid.UUID, 0)
+	if ruleId.Valid {
+		if err := tx.QueryRow(db.Ctx, `
+			SELECT ARRAY(
+				SELECT role_id
+				FROM instance.registration_rule_role
+				WHERE registration_rule_id = $1
+			)
+		`, ruleId).Scan(&roleIds); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		UPDATE instance.login
+		SET active = true
+		WHERE id = $1
+	`, loginId); err != nil {
+		return err
+	}
+	if err := setRoleIds_tx(tx, loginId, roleIds); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.login_registration
+		WHERE login_id = $1
+	`, loginId); err != nil {
+		return err
+	}
+	return spoolRegistrationMail_tx(tx, mail,
+		fmt.Sprintf("%s: registration approved", config.GetString("appName")),
+		fmt.Sprintf("Your registration was approved. You can now log in as '%s'.", mail))
+}
+
+// rejects verified registration, the registered login is removed
+func RejectRegistration_tx(tx pgx.Tx, loginId int64) error {
+
+	mail, _, err := getRegistrationPending_tx(tx, loginId)
+	if err != nil {
+		return err
+	}
+	if err := Del_tx(tx, loginId); err != nil {
+		return err
+	}
+	return spoolRegistrationMail_tx(tx, mail,
+		fmt.Sprintf("%s: registration rejected", config.GetString("appName")),
+		"Your registration was rejected by an administrator.")
+}
+
+// removes registrations (and their logins) that were not verified in time
+func DelRegistrationsExpired() error {
+	res, err := db.Pool.Exec(db.Ctx, `
+		DELETE FROM instance.login
+		WHERE id IN (
+			SELECT login_id
+			FROM instance.login_registration
+			WHERE state       =  'unverified'
+			AND   code_expiry <= $1
+		)
+	`, tools.GetTimeUnix())
+	if err != nil {
+		return err
+	}
+	if res.RowsAffected() != 0 {
+		log.Info("server", fmt.Sprintf("removed %d unverified registration(s)", res.RowsAffected()))
+	}
+	return nil
+}
+
+// registration rules
+func GetRegistrationRules() ([]types.RegistrationRule, error) {
+	rules := make([]types.RegistrationRule, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT id, domain, approve_auto, ARRAY(
+			SELECT role_id
+			FROM instance.registration_rule_role
+			WHERE registration_rule_id = r.id
+		)
+		FROM instance.registration_rule AS r
+		ORDER BY domain ASC
+	`)
+	if err != nil {
+		return rules, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r types.RegistrationRule
+		if err := rows.Scan(&r.Id, &r.Domain, &r.ApproveAuto, &r.RoleIds); err != nil {
+			return rules, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+func DelRegistrationRule_tx(tx pgx.Tx, id int32) error {
+	_, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.registration_rule
+		WHERE id = $1
+	`, id)
+	return err
+}
+func SetRegistrationRule_tx(tx pgx.Tx, r types.RegistrationRule) error {
+
+	r.Domain = strings.ToLower(strings.TrimSpace(r.Domain))
+	if r.Domain == "" || strings.Contains(r.Domain, "@") {
+		return fmt.Errorf("invalid registration domain '%s'", r.Domain)
+	}
+
+	if r.Id == 0 {
+		if err := tx.QueryRow(db.Ctx, `
+			INSERT INTO instance.registration_rule (domain, approve_auto)
+			VALUES ($1,$2)
+			RETURNING id
+		`, r.Domain, r.ApproveAuto).Scan(&r.Id); err != nil {
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(db.Ctx, `
+			UPDATE instance.registration_rule
+			SET domain = $1, approve_auto = $2
+			WHERE id = $3
+		`, r.Domain, r.ApproveAuto, r.Id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(db.Ctx, `
+			DELETE FROM instance.registration_rule_role
+			WHERE registration_rule_id = $1
+		`, r.Id); err != nil {
+			return err
+		}
+	}
+
+	for _, roleId := range r.RoleIds {
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO instance.registration_rule_role (registration_rule_id, role_id)
+			VALUES ($1,$2)
+		`, r.Id, roleId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns rule for domain of mail address, rules for specific domains take precedence over wildcard rule
+// registration rules are the allow list, mail addresses without matching rule cannot register
+func getRegistrationRuleId_tx(tx pgx.Tx, mail string) (int32, error) {
+	_, domain, found := strings.Cut(mail, "@")
+	if !found || domain == "" || strings.Contains(domain, "@") {
+		return 0, fmt.Errorf("invalid mail address '%s'", mail)
+	}
+
+	var id int32
+	err := tx.QueryRow(db.Ctx, `
+		SELECT id
+		FROM instance.registration_rule
+		WHERE domain IN ($1,'*')
+		ORDER BY domain = '*' ASC
+		LIMIT 1
+	`, domain).Scan(&id)
+
+	if err == pgx.ErrNoRows {
+		return 0, fmt.Errorf("mail domain '%s' is not allowed to register", domain)
+	}
+	return id, err
+}
+
+func getRegistrationPending_tx(tx pgx.Tx, loginId int64) (string, pgtype.Int4, error) {
+	var mail string
+	var ruleId pgtype.Int4
+	err := tx.QueryRow(db.Ctx, `
+		SELECT mail, registration_rule_id
+		FROM instance.login_registration
+		WHERE login_id = $1
+		AND   state    = 'pending'
+	`, loginId).Scan(&mail, &ruleId)
+
+	if err == pgx.ErrNoRows {
+		return mail, ruleId, fmt.Errorf("no verified registration for login %d", loginId)
+	}
+	return mail, ruleId, err
+}
+
+func getRegistrationCodeHash(loginId int64, code string) string {
+	return tools.Hash(fmt.Sprintf("%d_%s", loginId, code))
+}
+
+func getRegistrationCodeNew() (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(registrationCodeDigits)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", registrationCodeDigits, n), nil
+}
+
+func spoolRegistrationMail_tx(tx pgx.Tx, to string, subject string, body string) error {
+	var accountId pgtype.Int4
+	if id := config.GetUint64("registrationMailAccountId"); id != 0 {
+		accountId.Int32 = int32(id)
+		accountId.Valid = true
+	}
+
+	_, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.mail_spool (to_list, cc_list, bcc_list,
+			subject, body, outgoing, date, mail_account_id)
+		VALUES ($1,'','',$2,$3,TRUE,$4,$5)
+	`, to, subject, body, tools.GetTimeUnix(), accountId)
+	return err
+}
@@ -1,10 +1,18 @@
 package login
 
 import (
+	"errors"
+	"fmt"
+	"r3/cluster"
 	"r3/db"
+	"r3/log"
+	"r3/tools"
+	"r3/types"
+	"slices"
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func getRoleIds(loginId int64) ([]uuid.UUID, error) {
@@ -30,32 +38,242 @@ func getRoleIds(loginId int64) ([]uuid.UUID, error) {
 	return roleIds, nil
 }
 
+// get validity of time-limited role assignments of login
+func getRoleDates(loginId int64) (map[uuid.UUID]types.LoginAdminRoleDates, error) {
+	roleIdMapDates := make(map[uuid.UUID]types.LoginAdminRoleDates)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT role_id, date_from, date_to
+		FROM instance.login_role
+		WHERE login_id = $1
+		AND (
+			date_from IS NOT NULL OR
+			date_to   IS NOT NULL
+		)
+	`, loginId)
+	if err != nil {
+		return roleIdMapDates, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roleId uuid.UUID
+		var d types.LoginAdminRoleDates
+		if err := rows.Scan(&roleId, &d.DateFrom, &d.DateTo); err != nil {
+			return roleIdMapDates, err
+		}
+		roleIdMapDates[roleId] = d
+	}
+	return roleIdMapDates, nil
+}
+
+// get audit log of time-limited and delegated role assignments of login
+func GetRoleLog(loginId int64, limit int) ([]types.LoginRoleLog, error) {
+	logs := make([]types.LoginRoleLog, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT lr.action, lr.date, lr.login_role_delegation_id,
+			lr.login_id_actor, l.name, lr.role_id
+		FROM instance.login_role_log AS lr
+		LEFT JOIN instance.login AS l ON l.id = lr.login_id_actor
+		WHERE lr.login_id = $1
+		ORDER BY lr.date DESC, lr.id DESC
+		LIMIT $2
+	`, loginId, limit)
+	if err != nil {
+		return logs, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l types.LoginRoleLog
+		if err := rows.Scan(&l.Action, &l.Date, &l.DelegationId,
+			&l.LoginIdActor, &l.LoginNameActor, &l.RoleId); err != nil {
+
+			return logs, err
+		}
+		logs = append(logs, l)
+	}
+	return logs, nil
+}
+
+// removes role assignments and ends role delegations that have expired
+// affected logins are reauthorized & informed
+func ExpireRoles() error {
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	now := tools.GetTimeUnix()
+	loginIdsReauth := make([]int64, 0)
+	loginIdsInform := make([]int64, 0)
+
+	// expired role assignments
+	rows, err := tx.Query(db.Ctx, `
+		DELETE FROM instance.login_role
+		WHERE date_to <= $1
+		RETURNING login_id, role_id
+	`, now)
+	if err != nil {
+		return err
+	}
+
+	type expired struct {
+		delegationId pgtype.Int8
+		loginId      int64
+		roleId       uuid.UUID
+	}
+	expiredAssignments := make([]expired, 0)
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.loginId, &e.roleId); err != nil {
+			rows.Close()
+			return err
+		}
+		expiredAssignments = append(expiredAssignments, e)
+	}
+	rows.Close()
+
+	for _, e := range expiredAssignments {
+		if err := setRoleLog_tx(tx, e.loginId, pgtype.Int8{}, e.roleId, pgtype.Int8{}, "assignmentExpired"); err != nil {
+			return err
+		}
+		if !slices.Contains(loginIdsReauth, e.loginId) {
+			loginIdsReauth = append(loginIdsReauth, e.loginId)
+		}
+	}
+
+	// expired role delegations
+	rows, err = tx.Query(db.Ctx, `
+		UPDATE instance.login_role_delegation
+		SET date_ended = $1
+		WHERE date_ended IS NULL
+		AND   date_to <= $2
+		RETURNING id, login_id_substitute, role_id
+	`, now, now)
+	if err != nil {
+		return err
+	}
+
+	expiredDelegations := make([]expired, 0)
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.delegationId, &e.loginId, &e.roleId); err != nil {
+			rows.Close()
+			return err
+		}
+		expiredDelegations = append(expiredDelegations, e)
+	}
+	rows.Close()
+
+	for _, e := range expiredDelegations {
+		if err := setRoleLog_tx(tx, e.loginId, pgtype.Int8{}, e.roleId, e.delegationId, "delegationExpired"); err != nil {
+			return err
+		}
+		if !slices.Contains(loginIdsInform, e.loginId) {
+			loginIdsInform = append(loginIdsInform, e.loginId)
+		}
+	}
+
+	if err := tx.Commit(db.Ctx); err != nil {
+		return err
+	}
+
+	if len(expiredAssignments) != 0 || len(expiredDelegations) != 0 {
+		log.Info("server", fmt.Sprintf("expired %d role assignment(s) and %d role delegation(s)",
+			len(expiredAssignments), len(expiredDelegations)))
+	}
+
+	for _, loginId := range loginIdsReauth {
+		if err := cluster.LoginReauthorized(true, loginId); err != nil {
+			return err
+		}
+	}
+	for _, loginId := range loginIdsInform {
+		if err := cluster.LoginRoleDelegationChanged(true, loginId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sets validity of role assignments of login, roles not included are valid without limit
+func SetRoleDates_tx(tx pgx.Tx, loginId int64, roleIdMapDates map[uuid.UUID]types.LoginAdminRoleDates) error {
+
+	if _, err := tx.Exec(db.Ctx, `
+		UPDATE instance.login_role
+		SET date_from = NULL, date_to = NULL
+		WHERE login_id = $1
+	`, loginId); err != nil {
+		return err
+	}
+
+	for roleId, d := range roleIdMapDates {
+		if d.DateFrom.Valid && d.DateTo.Valid && d.DateFrom.Int64 >= d.DateTo.Int64 {
+			return errors.New("role assignment must start before it expires")
+		}
+		if _, err := tx.Exec(db.Ctx, `
+			UPDATE instance.login_role
+			SET date_from = $1, date_to = $2
+			WHERE login_id = $3
+			AND   role_id  = $4
+		`, d.DateFrom, d.DateTo, loginId, roleId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sets logins of role, validity of existing role assignments is kept
 func SetRoleLoginIds_tx(tx pgx.Tx, roleId uuid.UUID, loginIds []int64) error {
 
+	if loginIds == nil {
+		loginIds = make([]int64, 0)
+	}
+
 	if _, err := tx.Exec(db.Ctx, `
 		DELETE FROM instance.login_role
 		WHERE role_id = $1
-	`, roleId); err != nil {
+		AND login_id <> ALL($2)
+	`, roleId, loginIds); err != nil {
 		return err
 	}
 
 	for _, loginId := range loginIds {
-		if _, err := tx.Exec(db.Ctx, `
+		tag, err := tx.Exec(db.Ctx, `
 			INSERT INTO instance.login_role (login_id, role_id)
 			VALUES ($1,$2)
-		`, loginId, roleId); err != nil {
+			ON CONFLICT ON CONSTRAINT login_role_pkey DO NOTHING
+		`, loginId, roleId)
+		if err != nil {
 			return err
 		}
+
+		// newly assigned role must not conflict with other roles of login
+		if tag.RowsAffected() != 0 {
+			if err := checkRoleConflicts_tx(tx, loginId); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// sets roles of login, validity of existing role assignments is kept
 func setRoleIds_tx(tx pgx.Tx, loginId int64, roleIds []uuid.UUID) error {
 
+	if roleIds == nil {
+		roleIds = make([]uuid.UUID, 0)
+	}
+
 	if _, err := tx.Exec(db.Ctx, `
 		DELETE FROM instance.login_role
 		WHERE login_id = $1
-	`, loginId); err != nil {
+		AND role_id <> ALL($2)
+	`, loginId, roleIds); err != nil {
 		return err
 	}
 
@@ -63,9 +281,21 @@ func setRoleIds_tx(tx pgx.Tx, loginId int64, roleIds []uuid.UUID) error {
 		if _, err := tx.Exec(db.Ctx, `
 			INSERT INTO instance.login_role (login_id, role_id)
 			VALUES ($1,$2)
+			ON CONFLICT ON CONSTRAINT login_role_pkey DO NOTHING
 		`, loginId, roleId); err != nil {
 			return err
 		}
 	}
-	return nil
+	return checkRoleConflicts_tx(tx, loginId)
+}
+
+func setRoleLog_tx(tx pgx.Tx, loginId int64, loginIdActor pgtype.Int8,
+	roleId uuid.UUID, delegationId pgtype.Int8, action string) error {
+
+	_, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.login_role_log (login_id, login_id_actor,
+			role_id, login_role_delegation_id, action, date)
+		VALUES ($1,$2,$3,$4,$5,$6)
+	`, loginId, loginIdActor, roleId, delegationId, action, tools.GetTimeUnix())
+	return err
 }
@@ -0,0 +1,144 @@
+package login
+
+import (
+	"fmt"
+	"r3/cache"
+	"r3/db"
+	"r3/types"
+	"slices"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// returns conflicting roles (segregation of duties) of all logins
+// assigned roles, active role delegations and implicit 'everyone' roles are considered, including inherited roles
+func GetRoleConflicts() ([]types.LoginRoleConflict, error) {
+	conflicts := make([]types.LoginRoleConflict, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT l.id, l.name, l.active, ARRAY(
+			SELECT role_id
+			FROM instance.login_role
+			WHERE login_id = l.id
+			UNION
+			SELECT role_id
+			FROM instance.login_role_delegation
+			WHERE login_id_substitute = l.id
+			AND   date_ended IS NULL
+			UNION
+			SELECT id
+			FROM app.role
+			WHERE content = 'everyone'
+		)
+		FROM instance.login AS l
+		ORDER BY l.name ASC
+	`)
+	if err != nil {
+		return conflicts, err
+	}
+	defer rows.Close()
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	for rows.Next() {
+		var loginId int64
+		var loginName string
+		var active bool
+		var roleIds []uuid.UUID
+		if err := rows.Scan(&loginId, &loginName, &active, &roleIds); err != nil {
+			return conflicts, err
+		}
+		for _, pair := range getRoleConflicts(roleIds) {
+			conflicts = append(conflicts, types.LoginRoleConflict{
+				LoginId:        loginId,
+				LoginName:      loginName,
+				LoginActive:    active,
+				RoleId:         pair[0],
+				RoleIdConflict: pair[1],
+			})
+		}
+	}
+	return conflicts, nil
+}
+
+// checks whether roles granted to login conflict with each other
+// assigned roles, active role delegations and implicit 'everyone' roles are considered, including inherited roles
+func checkRoleConflicts_tx(tx pgx.Tx, loginId int64) error {
+
+	roleIds := make([]uuid.UUID, 0)
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT role_id
+			FROM instance.login_role
+			WHERE login_id = $1
+			UNION
+			SELECT role_id
+			FROM instance.login_role_delegation
+			WHERE login_id_substitute = $1
+			AND   date_ended IS NULL
+			UNION
+			SELECT id
+			FROM app.role
+			WHERE content = 'everyone'
+		)
+	`, loginId).Scan(&roleIds); err != nil {
+		return err
+	}
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	pairs := getRoleConflicts(roleIds)
+	if len(pairs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("roles '%s' and '%s' must not be granted to the same login",
+		getRoleName(pairs[0][0]), getRoleName(pairs[0][1]))
+}
+
+// returns pairs of conflicting roles from given roles, including inherited roles
+// schema cache must be read locked
+func getRoleConflicts(roleIds []uuid.UUID) [][2]uuid.UUID {
+	pairs := make([][2]uuid.UUID, 0)
+	roleIdsAll := make([]uuid.UUID, 0)
+
+	var addRole func(roleId uuid.UUID)
+	addRole = func(roleId uuid.UUID) {
+		if slices.Contains(roleIdsAll, roleId) {
+			return
+		}
+		role, exists := cache.RoleIdMap[roleId]
+		if !exists {
+			return
+		}
+		roleIdsAll = append(roleIdsAll, roleId)
+
+		for _, childId := range role.ChildrenIds {
+			addRole(childId)
+		}
+	}
+	for _, roleId := range roleIds {
+		addRole(roleId)
+	}
+
+	// conflicts are defined on one role only but apply both ways, each pair is returned once
+	for _, roleId := range roleIdsAll {
+		for _, conflictId := range cache.RoleIdMap[roleId].ConflictIds {
+			if !slices.Contains(roleIdsAll, conflictId) ||
+				slices.Contains(pairs, [2]uuid.UUID{conflictId, roleId}) {
+
+				continue
+			}
+			pairs = append(pairs, [2]uuid.UUID{roleId, conflictId})
+		}
+	}
+	return pairs
+}
+
+// returns role name prefixed with its module name, schema cache must be read locked
+func getRoleName(roleId uuid.UUID) string {
+	role := cache.RoleIdMap[roleId]
+	return fmt.Sprintf("%s.%s", cache.ModuleIdMap[role.ModuleId].Name, role.Name)
+}
@@ -0,0 +1,64 @@
+package login
+
+import (
+	"r3/cache"
+	"r3/types"
+	"reflect"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestGetRoleConflicts(t *testing.T) {
+	var (
+		roleA     = uuid.Must(uuid.NewV4()) // conflicts with B
+		roleB     = uuid.Must(uuid.NewV4())
+		roleC     = uuid.Must(uuid.NewV4()) // inherits B
+		roleD     = uuid.Must(uuid.NewV4()) // conflicts with A, defined on both sides
+		roleE     = uuid.Must(uuid.NewV4()) // inherits C
+		roleOther = uuid.Must(uuid.NewV4())
+		roleGone  = uuid.Must(uuid.NewV4()) // not in schema cache
+	)
+
+	cache.Schema_mx.Lock()
+	roleIdMapEx := cache.RoleIdMap
+	cache.RoleIdMap = map[uuid.UUID]types.Role{
+		roleA:     {Id: roleA, ConflictIds: []uuid.UUID{roleB, roleD}},
+		roleB:     {Id: roleB},
+		roleC:     {Id: roleC, ChildrenIds: []uuid.UUID{roleB}},
+		roleD:     {Id: roleD, ConflictIds: []uuid.UUID{roleA}},
+		roleE:     {Id: roleE, ChildrenIds: []uuid.UUID{roleC, roleE}},
+		roleOther: {Id: roleOther},
+	}
+	cache.Schema_mx.Unlock()
+
+	defer func() {
+		cache.Schema_mx.Lock()
+		cache.RoleIdMap = roleIdMapEx
+		cache.Schema_mx.Unlock()
+	}()
+
+	tests := []struct {
+		name    string
+		roleIds []uuid.UUID
+		want    [][2]uuid.UUID
+	}{
+		{"none", []uuid.UUID{}, [][2]uuid.UUID{}},
+		{"no conflict", []uuid.UUID{roleA, roleOther}, [][2]uuid.UUID{}},
+		{"direct", []uuid.UUID{roleA, roleB}, [][2]uuid.UUID{{roleA, roleB}}},
+		{"direct reversed", []uuid.UUID{roleB, roleA}, [][2]uuid.UUID{{roleA, roleB}}},
+		{"inherited", []uuid.UUID{roleA, roleC}, [][2]uuid.UUID{{roleA, roleB}}},
+		{"inherited twice, with cycle", []uuid.UUID{roleE, roleA}, [][2]uuid.UUID{{roleA, roleB}}},
+		{"defined on both sides", []uuid.UUID{roleA, roleD}, [][2]uuid.UUID{{roleA, roleD}}},
+		{"unknown role", []uuid.UUID{roleGone, roleB}, [][2]uuid.UUID{}},
+	}
+
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	for _, test := range tests {
+		if got := getRoleConflicts(test.roleIds); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("getRoleConflicts(%s) = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package login
+
+import (
+	"errors"
+	"r3/db"
+	"r3/tools"
+	"r3/types"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// updates internal login backend with login provisioned via SCIM
+// creates login if ID is 0, otherwise login must already be SCIM managed
+// roles are not touched here, they are defined by SCIM group memberships
+// returns login ID
+func SetScimLogin_tx(tx pgx.Tx, id int64, externalId pgtype.Text, name string,
+	nameGiven pgtype.Text, nameFamily pgtype.Text, active bool,
+	loginTemplateId pgtype.Int8) (int64, error) {
+
+	if name == "" {
+		return 0, errors.New("name must not be empty")
+	}
+	now := tools.GetTimeUnix()
+
+	if id == 0 {
+		id, err := Set_tx(tx, 0, loginTemplateId, pgtype.Int4{}, pgtype.Text{},
+			name, "", false, false, active, []uuid.UUID{},
+			[]types.LoginAdminRecordSet{})
+
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = tx.Exec(db.Ctx, `
+			INSERT INTO instance.scim_login (login_id, external_id,
+				name_given, name_family, date_created, date_changed)
+			VALUES ($1,$2,$3,$4,$5,$5)
+		`, id, externalId, nameGiven, nameFamily, now)
+		return id, err
+	}
+
+	tag, err := tx.Exec(db.Ctx, `
+		UPDATE instance.scim_login
+		SET external_id = $1, name_given = $2, name_family = $3, date_changed = $4
+		WHERE login_id = $5
+	`, externalId, nameGiven, nameFamily, now, id)
+	if err != nil {
+		return 0, err
+	}
+	if tag.RowsAffected() == 0 {
+		return 0, pgx.ErrNoRows
+	}
+
+	_, err = tx.Exec(db.Ctx, `
+		UPDATE instance.login
+		SET name = $1, active = $2
+		WHERE id = $3
+	`, strings.ToLower(name), active, id)
+	return id, err
+}
+
+// applies roles to login, based on its SCIM group memberships and the roles assigned to these groups
+// only roles assigned via SCIM are removed, roles assigned otherwise (like by an admin) are kept
+func SetRoleIdsByScimGroups_tx(tx pgx.Tx, loginId int64) error {
+
+	roleIds := make([]uuid.UUID, 0)
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT DISTINCT gr.role_id
+			FROM instance.scim_group_role  AS gr
+			JOIN instance.scim_group_login AS gl ON gl.scim_group_id = gr.scim_group_id
+			WHERE gl.login_id = $1
+		)
+	`, loginId).Scan(&roleIds); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.login_role
+		WHERE login_id = $1
+		AND   scim
+		AND   role_id <> ALL($2)
+	`, loginId, roleIds); err != nil {
+		return err
+	}
+
+	for _, roleId := range roleIds {
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO instance.login_role (login_id, role_id, scim)
+			VALUES ($1,$2,TRUE)
+			ON CONFLICT ON CONSTRAINT login_role_pkey DO NOTHING
+		`, loginId, roleId); err != nil {
+			return err
+		}
+	}
+	return checkRoleConflicts_tx(tx, loginId)
+}
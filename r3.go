@@ -13,6 +13,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"r3/bruteforce"
 	"r3/cache"
 	"r3/cluster"
 	"r3/config"
@@ -37,11 +38,13 @@ import (
 	"r3/handler/ics_download"
 	"r3/handler/license_upload"
 	"r3/handler/manifest_download"
+	"r3/handler/scim"
 	"r3/handler/transfer_export"
 	"r3/handler/transfer_import"
 	"r3/handler/websocket"
 	"r3/log"
 	"r3/login"
+	"r3/login/login_cert"
 	"r3/scheduler"
 	"r3/tools"
 	"strings"
@@ -367,12 +370,21 @@ func (prg *program) execute(svc service.Service) {
 		return
 	}
 
+	// listen for data changes to inform subscribed clients
+	go cluster.ListenDataChanges()
+
 	// initialize module schema cache
 	if err := cluster.SchemaChangedAll(false, false); err != nil {
 		prg.executeAborted(svc, fmt.Errorf("failed to initialize schema cache, %v", err))
 		return
 	}
 
+	// initialize bruteforce host blocks
+	if err := bruteforce.LoadBlocked(); err != nil {
+		prg.executeAborted(svc, fmt.Errorf("failed to initialize bruteforce host blocks, %v", err))
+		return
+	}
+
 	// initialize LDAP cache
 	if err := cache.LoadLdapMap(); err != nil {
 		prg.executeAborted(svc, fmt.Errorf("failed to initialize LDAP cache, %v", err))
@@ -445,6 +457,7 @@ func (prg *program) execute(svc service.Service) {
 	mux.HandleFunc("/ics/download/", ics_download.Handler)
 	mux.HandleFunc("/license/upload", license_upload.Handler)
 	mux.HandleFunc("/manifests/", manifest_download.Handler)
+	mux.HandleFunc("/scim/v2/", scim.Handler)
 	mux.HandleFunc("/websocket", websocket.Handler)
 	mux.HandleFunc("/export/", transfer_export.Handler)
 	mux.HandleFunc("/import", transfer_import.Handler)
@@ -461,9 +474,17 @@ func (prg *program) execute(svc service.Service) {
 	}
 	config.File.Web.Port = webListener.Addr().(*net.TCPAddr).Port
 
+	// client address is resolved once per request, network conditions of roles are checked against it
+	handlerWithAddress := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if host, err := bruteforce.GetHost(r); err == nil {
+			r = r.WithContext(cache.SetContextAddress(r.Context(), host))
+		}
+		mux.ServeHTTP(w, r)
+	})
+
 	prg.webServer = &http.Server{
 		Addr:              webServerString,
-		Handler:           mux,
+		Handler:           handlerWithAddress,
 		IdleTimeout:       120 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
@@ -498,7 +519,8 @@ func (prg *program) execute(svc service.Service) {
 			return
 		}
 		prg.webServer.TLSConfig = &tls.Config{
-			GetCertificate: cache.GetCert,
+			GetCertificate:     cache.GetCert,
+			GetConfigForClient: login_cert.GetConfigForClient,
 		}
 		if err := prg.webServer.ServeTLS(webListener, "", ""); err != nil && err != http.ErrServerClosed {
 			prg.executeAborted(svc, err)
@@ -11,6 +11,7 @@ import (
 	"r3/db"
 	"r3/handler"
 	"r3/log"
+	"r3/login/login_impersonate"
 	"r3/types"
 	"strconv"
 	"time"
@@ -19,7 +20,7 @@ import (
 )
 
 func ExecTransaction(ctxClient context.Context, loginId int64, isAdmin bool,
-	isNoAuth bool, reqTrans types.RequestTransaction,
+	isNoAuth bool, impersonationId int64, reqTrans types.RequestTransaction,
 	resTrans types.ResponseTransaction) types.ResponseTransaction {
 
 	// start transaction
@@ -53,8 +54,25 @@ func ExecTransaction(ctxClient context.Context, loginId int64, isAdmin bool,
 		log.Info("websocket", fmt.Sprintf("TRANSACTION %d, %s %s, payload: %s",
 			reqTrans.TransactionNr, req.Action, req.Ressource, req.Payload))
 
+		// requests during impersonation are always logged, even if they fail
+		// blocked requests are rejected before, they are neither executed nor logged
+		if impersonationId != 0 {
+			if login_impersonate.IsBlocked(req.Ressource, req.Action) {
+				resTrans.Error = handler.ErrUnauthorized
+				resTrans.Responses = make([]types.Response, 0)
+				break
+			}
+			if err := login_impersonate.Log(impersonationId, req.Ressource, req.Action, req.Payload); err != nil {
+				log.Error("websocket", fmt.Sprintf("TRANSACTION %d, failed to log impersonated request",
+					reqTrans.TransactionNr), err)
+
+				resTrans.Error = handler.ErrGeneral
+				break
+			}
+		}
+
 		payload, err := Exec_tx(ctx, tx, loginId, isAdmin, isNoAuth,
-			req.Ressource, req.Action, req.Payload)
+			impersonationId, req.Ressource, req.Action, req.Payload)
 
 		if err == nil {
 			// all clear, prepare response payload
@@ -99,7 +117,7 @@ func ExecTransaction(ctxClient context.Context, loginId int64, isAdmin bool,
 }
 
 func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAuth bool,
-	ressource string, action string, reqJson json.RawMessage) (interface{}, error) {
+	impersonationId int64, ressource string, action string, reqJson json.RawMessage) (interface{}, error) {
 
 	// public requests
 	switch ressource {
@@ -108,6 +126,13 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "get":
 			return PublicGet()
 		}
+	case "registration":
+		switch action {
+		case "register":
+			return RegistrationRegister_tx(ctx, tx, reqJson)
+		case "verify":
+			return RegistrationVerify_tx(ctx, tx, reqJson)
+		}
 	}
 
 	// authorized requests: non-admin
@@ -115,6 +140,11 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		return nil, errors.New(handler.ErrUnauthorized)
 	}
 
+	// some requests must not be executed by impersonating admins
+	if impersonationId != 0 && login_impersonate.IsBlocked(ressource, action) {
+		return nil, errors.New(handler.ErrUnauthorized)
+	}
+
 	switch ressource {
 	case "data":
 		switch action {
@@ -126,10 +156,20 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 			return DataGetKeys_tx(ctx, tx, reqJson, loginId)
 		case "getLog":
 			return DataLogGet_tx(ctx, tx, reqJson, loginId)
+		case "getLogRestore":
+			return DataLogRestoreGet_tx(ctx, tx, reqJson, loginId)
+		case "getRecycled":
+			return DataRecycledGet_tx(ctx, tx, loginId, false)
+		case "restoreRecycled":
+			return DataRecycledRestore_tx(ctx, tx, reqJson, loginId, false)
+		case "search":
+			return DataSearch_tx(ctx, tx, reqJson, loginId)
 		case "set":
 			return DataSet_tx(ctx, tx, reqJson, loginId)
 		case "setKeys":
 			return DataSetKeys_tx(ctx, tx, reqJson)
+		case "setLogRestore":
+			return DataLogRestoreSet_tx(ctx, tx, reqJson, loginId)
 		}
 	case "feedback":
 		switch action {
@@ -141,12 +181,14 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "copy":
 			return FilesCopy(reqJson, loginId)
 		case "paste":
-			return FilesPaste(reqJson, loginId)
+			return FilesPaste(ctx, reqJson, loginId)
 		case "request":
 			return FileRequest(reqJson, loginId)
 		}
 	case "login":
 		switch action {
+		case "getImpersonations":
+			return LoginGetImpersonations(loginId)
 		case "getNames":
 			return LoginGetNames(reqJson)
 		case "delTokenFixed":
@@ -156,6 +198,23 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "setTokenFixed":
 			return LoginSetTokenFixed_tx(tx, reqJson, loginId)
 		}
+	case "loginImpersonation":
+		switch action {
+		case "end": // impersonating admin ends own session
+			if impersonationId == 0 {
+				return nil, errors.New(handler.ErrUnauthorized)
+			}
+			return nil, login_impersonate.End_tx(tx, impersonationId)
+		}
+	case "loginRoleDelegation":
+		switch action {
+		case "del":
+			return LoginRoleDelegationDel(reqJson, loginId)
+		case "get":
+			return LoginRoleDelegationGet(loginId)
+		case "set":
+			return LoginRoleDelegationSet(reqJson, loginId)
+		}
 	case "loginKeys":
 		switch action {
 		case "getPublic":
@@ -185,7 +244,7 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 	case "lookup":
 		switch action {
 		case "get":
-			return LookupGet(reqJson, loginId)
+			return LookupGet(ctx, reqJson, loginId)
 		}
 	case "pgFunction":
 		switch action {
@@ -236,8 +295,21 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		}
 	case "bruteforce":
 		switch action {
+		case "del":
+			return BruteforceDel_tx(tx, reqJson)
 		case "get":
 			return BruteforceGet(reqJson)
+		case "getBlocked":
+			return BruteforceGetBlocked()
+		}
+	case "clientCert":
+		switch action {
+		case "delRevoked":
+			return ClientCertDelRevoked_tx(tx, reqJson)
+		case "getRevoked":
+			return ClientCertGetRevoked()
+		case "setRevoked":
+			return ClientCertSetRevoked_tx(tx, reqJson)
 		}
 	case "collection":
 		switch action {
@@ -321,6 +393,10 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 			return LdapDel_tx(tx, reqJson)
 		case "get":
 			return LdapGet()
+		case "getHostStates":
+			return LdapGetHostStates(reqJson)
+		case "getRuns":
+			return LdapGetRuns(reqJson)
 		case "import":
 			return LdapImport(reqJson)
 		case "reload":
@@ -352,6 +428,10 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 			return LoginGetMembers(reqJson)
 		case "getRecords":
 			return LoginGetRecords(reqJson)
+		case "getRoleConflicts":
+			return LoginGetRoleConflicts()
+		case "getRoleLog":
+			return LoginGetRoleLog(reqJson)
 		case "kick":
 			return LoginKick(reqJson)
 		case "reauth":
@@ -374,6 +454,24 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "set":
 			return LoginFormSet_tx(tx, reqJson)
 		}
+	case "loginImpersonation":
+		switch action {
+		case "endById":
+			return LoginImpersonationEnd_tx(tx, reqJson)
+		case "get":
+			return LoginImpersonationGet(reqJson)
+		case "getLogs":
+			return LoginImpersonationGetLogs(reqJson)
+		case "start":
+			return LoginImpersonationStart_tx(tx, reqJson, loginId)
+		}
+	case "loginMfa":
+		switch action {
+		case "getRoleIds":
+			return LoginMfaGetRoleIds()
+		case "setRoleIds":
+			return LoginMfaSetRoleIds_tx(tx, reqJson)
+		}
 	case "loginTemplate":
 		switch action {
 		case "del":
@@ -481,6 +579,33 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "set":
 			return PwaDomainSet_tx(tx, reqJson)
 		}
+	case "recycleBin":
+		switch action {
+		case "get":
+			return DataRecycledGet_tx(ctx, tx, loginId, true)
+		case "purge":
+			return DataRecycledPurge_tx(ctx, tx, reqJson)
+		case "restore":
+			return DataRecycledRestore_tx(ctx, tx, reqJson, loginId, true)
+		}
+	case "registration":
+		switch action {
+		case "approve":
+			return RegistrationApprove_tx(tx, reqJson)
+		case "get":
+			return RegistrationGet()
+		case "reject":
+			return RegistrationReject_tx(tx, reqJson)
+		}
+	case "registrationRule":
+		switch action {
+		case "del":
+			return RegistrationRuleDel_tx(tx, reqJson)
+		case "get":
+			return RegistrationRuleGet()
+		case "set":
+			return RegistrationRuleSet_tx(tx, reqJson)
+		}
 	case "relation":
 		switch action {
 		case "del":
@@ -517,6 +642,17 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "get":
 			return Get()
 		}
+	case "scim":
+		switch action {
+		case "delToken":
+			return ScimDelToken_tx(tx)
+		case "get":
+			return ScimGet()
+		case "setGroupRoles":
+			return ScimSetGroupRoles_tx(tx, reqJson)
+		case "setToken":
+			return ScimSetToken_tx(tx)
+		}
 	case "schema":
 		switch action {
 		case "check":
@@ -524,6 +660,17 @@ func Exec_tx(ctx context.Context, tx pgx.Tx, loginId int64, isAdmin bool, isNoAu
 		case "reload":
 			return SchemaReload(reqJson)
 		}
+	case "slowQuery":
+		switch action {
+		case "del":
+			return SlowQueryDel_tx(tx)
+		case "get":
+			return SlowQueryGet(reqJson)
+		case "explain":
+			return SlowQueryExplain_tx(tx, reqJson)
+		case "getAggregated":
+			return SlowQueryGetAggregated()
+		}
 	case "system":
 		switch action {
 		case "get":
@@ -3,8 +3,25 @@ package request
 import (
 	"encoding/json"
 	"r3/bruteforce"
+	"r3/cluster"
+
+	"github.com/jackc/pgx/v5"
 )
 
+func BruteforceDel_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+
+	var req struct {
+		Host string `json:"host"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	if err := bruteforce.Unblock_tx(tx, req.Host); err != nil {
+		return nil, err
+	}
+	return nil, cluster.BruteforceUnblocked(true, req.Host)
+}
+
 func BruteforceGet(reqJson json.RawMessage) (interface{}, error) {
 
 	var res struct {
@@ -14,3 +31,7 @@ func BruteforceGet(reqJson json.RawMessage) (interface{}, error) {
 	res.HostsTracked, res.HostsBlocked = bruteforce.GetCounts()
 	return res, nil
 }
+
+func BruteforceGetBlocked() (interface{}, error) {
+	return bruteforce.GetBlocked()
+}
@@ -0,0 +1,33 @@
+package request
+
+import (
+	"encoding/json"
+	"r3/login/login_cert"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func ClientCertDelRevoked_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Serial string `json:"serial"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login_cert.DelRevoked_tx(tx, req.Serial)
+}
+
+func ClientCertGetRevoked() (interface{}, error) {
+	return login_cert.GetRevoked()
+}
+
+func ClientCertSetRevoked_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Serial  string `json:"serial"`
+		Comment string `json:"comment"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login_cert.SetRevoked_tx(tx, req.Serial, req.Comment)
+}
@@ -14,7 +14,7 @@ import (
 func ConfigGet() (interface{}, error) {
 
 	// not directly changeable configuration options
-	ignore := []string{"dbVersionCut", "tokenSecret"}
+	ignore := []string{"dbVersionCut", "scimTokenHash", "tokenSecret"}
 
 	res := make(map[string]string)
 
@@ -74,6 +74,42 @@ func DataDel_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
 	return nil, data.Del_tx(ctx, tx, req.RelationId, req.RecordId, loginId)
 }
 
+// data search
+func DataSearch_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
+	loginId int64) (interface{}, error) {
+
+	var req types.DataSearch
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return data.Search_tx(ctx, tx, req, loginId)
+}
+
+// data recycle bin
+func DataRecycledGet_tx(ctx context.Context, tx pgx.Tx, loginId int64, all bool) (interface{}, error) {
+	return data.GetRecycled_tx(ctx, tx, loginId, all)
+}
+func DataRecycledPurge_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Id int32 `json:"id"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, data.PurgeRecycled_tx(ctx, tx, req.Id)
+}
+func DataRecycledRestore_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
+	loginId int64, isAdmin bool) (interface{}, error) {
+
+	var req struct {
+		Id int32 `json:"id"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, data.RestoreRecycled_tx(ctx, tx, req.Id, loginId, isAdmin)
+}
+
 // data log
 func DataLogGet_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
 	loginId int64) (interface{}, error) {
@@ -88,6 +124,30 @@ func DataLogGet_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
 	}
 	return data.GetLogs_tx(ctx, tx, req.RecordId, req.AttributeIds, loginId)
 }
+func DataLogRestoreGet_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
+	loginId int64) (interface{}, error) {
+
+	var req struct {
+		LogId uuid.UUID `json:"logId"`
+	}
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return data.GetLogRestore_tx(ctx, tx, req.LogId, loginId)
+}
+func DataLogRestoreSet_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
+	loginId int64) (interface{}, error) {
+
+	var req struct {
+		LogId uuid.UUID `json:"logId"`
+	}
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, data.SetLogRestore_tx(ctx, tx, req.LogId, loginId)
+}
 
 // data SQL
 func DataSqlGet_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage,
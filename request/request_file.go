@@ -1,6 +1,7 @@
 package request
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"r3/cluster"
@@ -27,7 +28,7 @@ func FilesCopy(reqJson json.RawMessage, loginId int64) (interface{}, error) {
 }
 
 // request file(s) to be pasted
-func FilesPaste(reqJson json.RawMessage, loginId int64) (interface{}, error) {
+func FilesPaste(ctx context.Context, reqJson json.RawMessage, loginId int64) (interface{}, error) {
 	var req struct {
 		SrcAttributeId uuid.UUID   `json:"srcAttributeId"`
 		SrcFileIds     []uuid.UUID `json:"srcFileIds"`
@@ -37,7 +38,7 @@ func FilesPaste(reqJson json.RawMessage, loginId int64) (interface{}, error) {
 	if err := json.Unmarshal(reqJson, &req); err != nil {
 		return nil, err
 	}
-	return data.CopyFiles(loginId, req.SrcAttributeId,
+	return data.CopyFiles(ctx, loginId, req.SrcAttributeId,
 		req.SrcFileIds, req.SrcRecordId, req.DstAttributeId)
 }
 
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"r3/ldap"
 	"r3/ldap/ldap_check"
+	"r3/ldap/ldap_conn"
 	"r3/ldap/ldap_import"
 	"r3/types"
 
@@ -36,6 +37,31 @@ func LdapSet_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
 
 func LdapImport(reqJson json.RawMessage) (interface{}, error) {
 
+	var req struct {
+		Id       int32 `json:"id"`
+		FullSync bool  `json:"fullSync"`
+	}
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, ldap_import.Run(req.Id, req.FullSync)
+}
+
+func LdapGetHostStates(reqJson json.RawMessage) (interface{}, error) {
+
+	var req struct {
+		Id int32 `json:"id"`
+	}
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return ldap_conn.GetHostStates(req.Id)
+}
+
+func LdapGetRuns(reqJson json.RawMessage) (interface{}, error) {
+
 	var req struct {
 		Id int32 `json:"id"`
 	}
@@ -43,7 +69,7 @@ func LdapImport(reqJson json.RawMessage) (interface{}, error) {
 	if err := json.Unmarshal(reqJson, &req); err != nil {
 		return nil, err
 	}
-	return nil, ldap_import.Run(req.Id)
+	return ldap.GetRuns(req.Id, 10)
 }
 
 func LdapCheck(reqJson json.RawMessage) (interface{}, error) {
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"r3/cluster"
 	"r3/login"
+	"r3/login/login_impersonate"
 	"r3/login/login_license"
 	"r3/types"
 
@@ -28,6 +29,9 @@ func LoginGetNames(reqJson json.RawMessage) (interface{}, error) {
 	}
 	return login.GetNames(req.Id, req.IdsExclude, req.ByString, req.NoLdapAssign)
 }
+func LoginGetImpersonations(loginId int64) (interface{}, error) {
+	return login_impersonate.Get(loginId)
+}
 func LoginDelTokenFixed(reqJson json.RawMessage, loginId int64) (interface{}, error) {
 	var req struct {
 		Id int64 `json:"id"`
@@ -125,6 +129,21 @@ func LoginGetMembers(reqJson json.RawMessage) (interface{}, error) {
 	}
 	return res, nil
 }
+func LoginGetRoleConflicts() (interface{}, error) {
+	return login.GetRoleConflicts()
+}
+func LoginGetRoleLog(reqJson json.RawMessage) (interface{}, error) {
+
+	var req struct {
+		Id    int64 `json:"id"`
+		Limit int   `json:"limit"`
+	}
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return login.GetRoleLog(req.Id, req.Limit)
+}
 func LoginGetRecords(reqJson json.RawMessage) (interface{}, error) {
 
 	var req struct {
@@ -142,25 +161,39 @@ func LoginGetRecords(reqJson json.RawMessage) (interface{}, error) {
 func LoginSet_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
 
 	var req struct {
-		Id         int64                       `json:"id"`
-		LdapId     pgtype.Int4                 `json:"ldapId"`
-		LdapKey    pgtype.Text                 `json:"ldapKey"`
-		Name       string                      `json:"name"`
-		Pass       string                      `json:"pass"`
-		Active     bool                        `json:"active"`
-		Admin      bool                        `json:"admin"`
-		NoAuth     bool                        `json:"noAuth"`
-		RoleIds    []uuid.UUID                 `json:"roleIds"`
-		Records    []types.LoginAdminRecordSet `json:"records"`
-		TemplateId pgtype.Int8                 `json:"templateId"`
+		Id           int64                                   `json:"id"`
+		LdapId       pgtype.Int4                             `json:"ldapId"`
+		LdapKey      pgtype.Text                             `json:"ldapKey"`
+		Name         string                                  `json:"name"`
+		Pass         string                                  `json:"pass"`
+		Active       bool                                    `json:"active"`
+		Admin        bool                                    `json:"admin"`
+		NoAuth       bool                                    `json:"noAuth"`
+		CertRequired bool                                    `json:"certRequired"`
+		MfaMail      pgtype.Text                             `json:"mfaMail"`
+		RoleIds      []uuid.UUID                             `json:"roleIds"`
+		RoleDates    map[uuid.UUID]types.LoginAdminRoleDates `json:"roleDates"`
+		Records      []types.LoginAdminRecordSet             `json:"records"`
+		TemplateId   pgtype.Int8                             `json:"templateId"`
 	}
 
 	if err := json.Unmarshal(reqJson, &req); err != nil {
 		return nil, err
 	}
-	return login.Set_tx(tx, req.Id, req.TemplateId, req.LdapId, req.LdapKey,
+	id, err := login.Set_tx(tx, req.Id, req.TemplateId, req.LdapId, req.LdapKey,
 		req.Name, req.Pass, req.Admin, req.NoAuth, req.Active, req.RoleIds,
 		req.Records)
+
+	if err != nil {
+		return nil, err
+	}
+	if err := login.SetRoleDates_tx(tx, id, req.RoleDates); err != nil {
+		return nil, err
+	}
+	if err := login.SetMfaMail_tx(tx, id, req.MfaMail); err != nil {
+		return nil, err
+	}
+	return id, login.SetCertRequired_tx(tx, id, req.CertRequired)
 }
 func LoginSetMembers_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
 
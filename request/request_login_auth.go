@@ -1,6 +1,7 @@
 package request
 
 import (
+	"crypto/x509"
 	"encoding/json"
 	"r3/login/login_auth"
 	"r3/types"
@@ -11,7 +12,8 @@ import (
 // attempt login via user credentials
 // applies login ID, admin and no auth state to provided parameters if successful
 // returns token and success state
-func LoginAuthUser(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth *bool) (interface{}, error) {
+func LoginAuthUser(reqJson json.RawMessage, address string, loginId *int64,
+	admin *bool, noAuth *bool) (interface{}, error) {
 
 	var (
 		err error
@@ -22,6 +24,11 @@ func LoginAuthUser(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth
 			// MFA details, sent together with credentials (usually on second auth attempt)
 			MfaTokenId  pgtype.Int4 `json:"mfaTokenId"`
 			MfaTokenPin pgtype.Text `json:"mfaTokenPin"`
+			MfaMailSend bool        `json:"mfaMailSend"` // request MFA code to be sent via mail
+
+			// MFA setup details, sent together with credentials and PIN if MFA must be set up first
+			MfaSetupName   pgtype.Text `json:"mfaSetupName"`
+			MfaSetupSecret pgtype.Text `json:"mfaSetupSecret"`
 		}
 		res struct {
 			LoginId   int64  `json:"loginId"`
@@ -31,6 +38,9 @@ func LoginAuthUser(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth
 
 			// MFA token details, filled if login was successful but MFA not satisfied yet
 			MfaTokens []types.LoginMfaToken `json:"mfaTokens"`
+
+			// TOTP secret to set up, filled if login was successful but MFA is required and not set up yet
+			MfaSetupSecret string `json:"mfaSetupSecret"`
 		}
 	)
 
@@ -38,8 +48,9 @@ func LoginAuthUser(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth
 		return nil, err
 	}
 
-	res.Token, res.SaltKdf, res.MfaTokens, err = login_auth.User(req.Username,
-		req.Password, req.MfaTokenId, req.MfaTokenPin, loginId, admin, noAuth)
+	res.Token, res.SaltKdf, res.MfaTokens, res.MfaSetupSecret, err = login_auth.User(req.Username,
+		req.Password, address, req.MfaTokenId, req.MfaTokenPin, req.MfaMailSend,
+		req.MfaSetupName, req.MfaSetupSecret, loginId, admin, noAuth)
 
 	if err != nil {
 		return nil, err
@@ -50,8 +61,9 @@ func LoginAuthUser(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth
 }
 
 // attempt login via JWT
-// applies login ID, admin and no auth state to provided parameters if successful
-func LoginAuthToken(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth *bool) (interface{}, error) {
+// applies login ID, admin, no auth and impersonation state to provided parameters if successful
+func LoginAuthToken(reqJson json.RawMessage, loginId *int64, admin *bool,
+	noAuth *bool, impersonationId *int64) (interface{}, error) {
 
 	var (
 		err error
@@ -68,7 +80,7 @@ func LoginAuthToken(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth
 		return nil, err
 	}
 
-	res.LoginName, err = login_auth.Token(req.Token, loginId, admin, noAuth)
+	res.LoginName, err = login_auth.TokenImpersonated(req.Token, loginId, admin, noAuth, impersonationId)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +89,28 @@ func LoginAuthToken(reqJson json.RawMessage, loginId *int64, admin *bool, noAuth
 	return res, nil
 }
 
+// attempt login via client certificate, as provided during TLS handshake
+// applies login ID, admin and no auth state to provided parameters if successful
+func LoginAuthCert(certs []*x509.Certificate, address string, loginId *int64,
+	admin *bool, noAuth *bool) (interface{}, error) {
+
+	var (
+		err error
+		res struct {
+			LoginId   int64  `json:"loginId"`
+			LoginName string `json:"loginName"`
+			Token     string `json:"token"`
+		}
+	)
+
+	res.Token, res.LoginName, err = login_auth.Cert(certs, address, loginId, admin, noAuth)
+	if err != nil {
+		return nil, err
+	}
+	res.LoginId = *loginId
+	return res, nil
+}
+
 // attempt login via fixed token
 func LoginAuthTokenFixed(reqJson json.RawMessage, loginId *int64, fixedToken *bool) (interface{}, error) {
 
@@ -0,0 +1,34 @@
+package request
+
+import (
+	"encoding/json"
+	"r3/login"
+
+	"github.com/gofrs/uuid"
+)
+
+func LoginRoleDelegationDel(reqJson json.RawMessage, loginId int64) (interface{}, error) {
+	var req struct {
+		Id int64 `json:"id"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login.RevokeDelegation(loginId, req.Id)
+}
+func LoginRoleDelegationGet(loginId int64) (interface{}, error) {
+	return login.GetDelegations(loginId)
+}
+func LoginRoleDelegationSet(reqJson json.RawMessage, loginId int64) (interface{}, error) {
+	var req struct {
+		LoginIdSubstitute int64       `json:"loginIdSubstitute"`
+		RoleIds           []uuid.UUID `json:"roleIds"`
+		DateFrom          int64       `json:"dateFrom"`
+		DateTo            int64       `json:"dateTo"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login.SetDelegation(loginId, req.LoginIdSubstitute,
+		req.RoleIds, req.DateFrom, req.DateTo)
+}
@@ -0,0 +1,66 @@
+package request
+
+import (
+	"encoding/json"
+	"r3/login/login_auth"
+	"r3/login/login_impersonate"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func LoginImpersonationEnd_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+
+	var req struct {
+		Id int64 `json:"id"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login_impersonate.End_tx(tx, req.Id)
+}
+
+func LoginImpersonationGet(reqJson json.RawMessage) (interface{}, error) {
+
+	var req struct {
+		LoginId int64 `json:"loginId"` // 0 = all logins
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return login_impersonate.Get(req.LoginId)
+}
+
+func LoginImpersonationGetLogs(reqJson json.RawMessage) (interface{}, error) {
+
+	var req struct {
+		Id int64 `json:"id"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return login_impersonate.GetLogs(req.Id)
+}
+
+func LoginImpersonationStart_tx(tx pgx.Tx, reqJson json.RawMessage, loginIdAdmin int64) (interface{}, error) {
+
+	var (
+		err error
+		req struct {
+			LoginId int64  `json:"loginId"`
+			Reason  string `json:"reason"`
+		}
+		res struct {
+			Token string `json:"token"`
+		}
+	)
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+
+	id, dateEnd, err := login_impersonate.Start_tx(tx, loginIdAdmin, req.LoginId, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+	res.Token, err = login_auth.Impersonate(id, req.LoginId, dateEnd)
+	return res, err
+}
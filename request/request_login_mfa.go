@@ -0,0 +1,23 @@
+package request
+
+import (
+	"encoding/json"
+	"r3/login/login_mfa"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func LoginMfaGetRoleIds() (interface{}, error) {
+	return login_mfa.GetRoleIds()
+}
+
+func LoginMfaSetRoleIds_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		RoleIds []uuid.UUID `json:"roleIds"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login_mfa.SetRoleIds_tx(tx, req.RoleIds)
+}
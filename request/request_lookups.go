@@ -1,6 +1,7 @@
 package request
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"r3/cache"
@@ -10,7 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func LookupGet(reqJson json.RawMessage, loginId int64) (interface{}, error) {
+func LookupGet(ctx context.Context, reqJson json.RawMessage, loginId int64) (interface{}, error) {
 
 	var req struct {
 		Name string `json:"name"`
@@ -21,7 +22,7 @@ func LookupGet(reqJson json.RawMessage, loginId int64) (interface{}, error) {
 
 	switch req.Name {
 	case "access":
-		return cache.GetAccessById(loginId)
+		return cache.GetAccessById(ctx, loginId)
 
 	case "customizing":
 		var res struct {
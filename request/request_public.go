@@ -3,6 +3,8 @@ package request
 import (
 	"r3/cache"
 	"r3/config"
+	"r3/login"
+	"r3/login/login_cert"
 
 	"github.com/gofrs/uuid"
 )
@@ -13,6 +15,7 @@ func PublicGet() (interface{}, error) {
 		AppName            string               `json:"appName"`
 		AppNameShort       string               `json:"appNameShort"`
 		AppVersion         string               `json:"appVersion"`
+		ClientCertAuth     bool                 `json:"clientCertAuth"`
 		ClusterNodeName    string               `json:"clusterNodeName"`
 		CompanyColorHeader string               `json:"companyColorHeader"`
 		CompanyColorLogin  string               `json:"companyColorLogin"`
@@ -24,6 +27,7 @@ func PublicGet() (interface{}, error) {
 		LanguageCodes      []string             `json:"languageCodes"`
 		ProductionMode     uint64               `json:"productionMode"`
 		PwaDomainMap       map[string]uuid.UUID `json:"pwaDomainMap"`
+		Registration       bool                 `json:"registration"`
 		SchemaTimestamp    int64                `json:"schemaTimestamp"`
 		SearchDictionaries []string             `json:"searchDictionaries"`
 	}
@@ -31,6 +35,7 @@ func PublicGet() (interface{}, error) {
 	res.AppName = config.GetString("appName")
 	res.AppNameShort = config.GetString("appNameShort")
 	res.AppVersion, _, _, _ = config.GetAppVersions()
+	res.ClientCertAuth = login_cert.GetEnabled()
 	res.ClusterNodeName = cache.GetNodeName()
 	res.CompanyColorHeader = config.GetString("companyColorHeader")
 	res.CompanyColorLogin = config.GetString("companyColorLogin")
@@ -42,6 +47,7 @@ func PublicGet() (interface{}, error) {
 	res.LanguageCodes = cache.GetCaptionLanguageCodes()
 	res.ProductionMode = config.GetUint64("productionMode")
 	res.PwaDomainMap = cache.GetPwaDomainMap()
+	res.Registration = login.GetRegistrationActive()
 	res.SchemaTimestamp = cache.GetSchemaTimestamp()
 	res.SearchDictionaries = cache.GetSearchDictionaries()
 	return res, nil
@@ -0,0 +1,101 @@
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"r3/bruteforce"
+	"r3/cache"
+	"r3/handler"
+	"r3/login"
+	"r3/types"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// public
+// requests are made without login, so both are subject to bruteforce protection of the client host
+// only failed requests count as bad attempts, hosts shared by many users (like offices) are not blocked by valid registrations
+func RegistrationRegister_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Mail string `json:"mail"`
+		Pass string `json:"pass"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+
+	address := cache.GetContextAddress(ctx)
+	if bruteforce.CheckByHost(address) {
+		return nil, errors.New(handler.ErrBruteforceBlock)
+	}
+
+	if err := login.Register_tx(tx, req.Mail, req.Pass); err != nil {
+		bruteforce.BadAttemptByHost(address)
+		return nil, err
+	}
+	return nil, nil
+}
+func RegistrationVerify_tx(ctx context.Context, tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Mail string `json:"mail"`
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+
+	address := cache.GetContextAddress(ctx)
+	if bruteforce.CheckByHost(address) {
+		return nil, errors.New(handler.ErrBruteforceBlock)
+	}
+
+	verified, err := login.RegisterVerify_tx(tx, req.Mail, req.Code)
+	if !verified {
+		bruteforce.BadAttemptByHost(address)
+	}
+	return verified, err
+}
+
+// admin
+func RegistrationApprove_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		LoginId int64 `json:"loginId"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login.ApproveRegistration_tx(tx, req.LoginId)
+}
+func RegistrationGet() (interface{}, error) {
+	return login.GetRegistrations()
+}
+func RegistrationReject_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		LoginId int64 `json:"loginId"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login.RejectRegistration_tx(tx, req.LoginId)
+}
+
+func RegistrationRuleDel_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Id int32 `json:"id"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login.DelRegistrationRule_tx(tx, req.Id)
+}
+func RegistrationRuleGet() (interface{}, error) {
+	return login.GetRegistrationRules()
+}
+func RegistrationRuleSet_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req types.RegistrationRule
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, login.SetRegistrationRule_tx(tx, req)
+}
@@ -0,0 +1,45 @@
+package request
+
+import (
+	"encoding/json"
+	"r3/config"
+	"r3/scim"
+	"r3/types"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func ScimDelToken_tx(tx pgx.Tx) (interface{}, error) {
+	return nil, scim.DelToken_tx(tx)
+}
+
+func ScimGet() (interface{}, error) {
+	var (
+		err error
+		res struct {
+			Groups   []types.ScimGroup `json:"groups"`
+			TokenSet bool              `json:"tokenSet"`
+		}
+	)
+	res.Groups, err = scim.GetGroupsAdmin()
+	res.TokenSet = config.GetString("scimTokenHash") != ""
+	return res, err
+}
+
+func ScimSetGroupRoles_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		Id      uuid.UUID   `json:"id"`
+		RoleIds []uuid.UUID `json:"roleIds"`
+	}
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	return nil, scim.SetGroupRoleIds_tx(tx, req.Id, req.RoleIds)
+}
+
+// token is returned once, only its hash is stored
+func ScimSetToken_tx(tx pgx.Tx) (interface{}, error) {
+	return scim.SetToken_tx(tx)
+}
@@ -0,0 +1,179 @@
+package request
+
+import (
+	"encoding/json"
+	"r3/cache"
+	"r3/db"
+	"r3/schema"
+	"r3/tools"
+	"r3/types"
+	"slices"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func SlowQueryDel_tx(tx pgx.Tx) (interface{}, error) {
+	if _, err := tx.Exec(db.Ctx, `DELETE FROM instance.slow_query`); err != nil {
+		return nil, err
+	}
+	_, err := tx.Exec(db.Ctx, `DELETE FROM instance.slow_query_explain`)
+	return nil, err
+}
+
+// requests plan to be captured on the next slow run of query
+func SlowQueryExplain_tx(tx pgx.Tx, reqJson json.RawMessage) (interface{}, error) {
+	var req struct {
+		QuerySql string `json:"querySql"`
+	}
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+	_, err := tx.Exec(db.Ctx, `
+		INSERT INTO instance.slow_query_explain (query_sql, date)
+		SELECT $1, $2
+		WHERE NOT EXISTS (
+			SELECT id
+			FROM instance.slow_query_explain
+			WHERE query_sql = $1
+		)
+	`, req.QuerySql, tools.GetTimeUnix())
+	return nil, err
+}
+
+func SlowQueryGet(reqJson json.RawMessage) (interface{}, error) {
+
+	var (
+		req struct {
+			Limit    int    `json:"limit"`
+			Offset   int    `json:"offset"`
+			QuerySql string `json:"querySql"` // optional, only entries of given query
+		}
+		res struct {
+			Entries []types.SlowQuery `json:"entries"`
+			Total   int64             `json:"total"`
+		}
+	)
+
+	if err := json.Unmarshal(reqJson, &req); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT s.id, s.login_id, l.name, s.relation_id, s.api_id, s.form_id,
+			s.field_id, s.date, s.duration_ms, s.query_sql, s.query_args, s.explain
+		FROM instance.slow_query AS s
+		LEFT JOIN instance.login AS l ON l.id = s.login_id
+		WHERE $1 = ''
+		OR    $1 = s.query_sql
+		ORDER BY s.date DESC, s.id DESC
+		LIMIT  $2
+		OFFSET $3
+	`, req.QuerySql, req.Limit, req.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res.Entries = make([]types.SlowQuery, 0)
+	for rows.Next() {
+		var s types.SlowQuery
+		if err := rows.Scan(&s.Id, &s.LoginId, &s.LoginName, &s.RelationId,
+			&s.ApiId, &s.FormId, &s.FieldId, &s.Date, &s.DurationMs,
+			&s.QuerySql, &s.QueryArgs, &s.Explain); err != nil {
+
+			return nil, err
+		}
+		res.Entries = append(res.Entries, s)
+	}
+
+	if err := db.Pool.QueryRow(db.Ctx, `
+		SELECT COUNT(*)
+		FROM instance.slow_query
+		WHERE $1 = ''
+		OR    $1 = query_sql
+	`, req.QuerySql).Scan(&res.Total); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// slow queries, aggregated by SQL statement, ordered by total duration
+// filter/join attributes of the latest entry are checked for missing indexes
+func SlowQueryGetAggregated() (interface{}, error) {
+
+	aggregates := make([]types.SlowQueryAggregate, 0)
+	rows, err := db.Pool.Query(db.Ctx, `
+		WITH g AS (
+			SELECT query_sql, COUNT(*) AS cnt, SUM(duration_ms) AS duration_sum,
+				ROUND(AVG(duration_ms))::INTEGER AS duration_avg,
+				MAX(duration_ms) AS duration_max, MAX(date) AS date_last,
+				MAX(id) AS id_last
+			FROM instance.slow_query
+			GROUP BY query_sql
+		)
+		SELECT g.query_sql, s.relation_id, g.cnt, g.duration_avg, g.duration_max,
+			g.date_last, g.id_last, s.attribute_ids_filter || s.attribute_ids_join,
+			EXISTS(
+				SELECT id
+				FROM instance.slow_query_explain
+				WHERE query_sql = g.query_sql
+			)
+		FROM g
+		INNER JOIN instance.slow_query AS s ON s.id = g.id_last
+		ORDER BY g.duration_sum DESC
+		LIMIT 100
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a types.SlowQueryAggregate
+		var attributeIds []uuid.UUID
+		if err := rows.Scan(&a.QuerySql, &a.RelationId, &a.Count, &a.DurationAvgMs,
+			&a.DurationMaxMs, &a.DateLast, &a.IdLast, &attributeIds, &a.ExplainRequested); err != nil {
+
+			return nil, err
+		}
+		a.AttributeIdsIndex = getAttributeIdsWithoutIndex(attributeIds)
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, nil
+}
+
+// returns attributes that are not the first attribute of any index of their relation
+func getAttributeIdsWithoutIndex(attributeIds []uuid.UUID) []uuid.UUID {
+	cache.Schema_mx.RLock()
+	defer cache.Schema_mx.RUnlock()
+
+	ids := make([]uuid.UUID, 0)
+	for _, id := range attributeIds {
+		atr, exists := cache.AttributeIdMap[id]
+		if !exists || slices.Contains(ids, id) || atr.Name == schema.PkName ||
+			atr.Encrypted || schema.IsContentFiles(atr.Content) {
+
+			continue
+		}
+
+		rel, exists := cache.RelationIdMap[atr.RelationId]
+		if !exists {
+			continue
+		}
+
+		indexed := false
+		for _, ind := range rel.Indexes {
+			for _, indAtr := range ind.Attributes {
+				if indAtr.Position == 0 && indAtr.AttributeId == id {
+					indexed = true
+					break
+				}
+			}
+		}
+		if !indexed {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
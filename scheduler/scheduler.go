@@ -13,6 +13,7 @@ import (
 	"r3/db"
 	"r3/ldap/ldap_import"
 	"r3/log"
+	"r3/login"
 	"r3/repo"
 	"r3/schema"
 	"r3/spooler/mail_attach"
@@ -344,7 +345,7 @@ func load() error {
 			t.fn = backup.Run
 		case "cleanupBruteforce":
 			t.nameLog = "Cleanup of bruteforce cache"
-			t.fn = bruteforce.ClearHostMap
+			t.fn = bruteforce.Cleanup
 		case "cleanupTempDir":
 			t.nameLog = "Cleanup of temp. directory"
 			t.fn = cleanupTemp
@@ -360,6 +361,15 @@ func load() error {
 		case "cleanupMailTraffic":
 			t.nameLog = "Cleanup of mail traffic entries"
 			t.fn = cleanupMailTraffic
+		case "cleanupRecycleBin":
+			t.nameLog = "Purge of expired recycle bin entries"
+			t.fn = data.PurgeRecycledExpired
+		case "cleanupRegistrations":
+			t.nameLog = "Cleanup of unverified registrations"
+			t.fn = login.DelRegistrationsExpired
+		case "cleanupSlowQueries":
+			t.nameLog = "Cleanup of slow query entries"
+			t.fn = cleanupSlowQueries
 		case "clusterCheckIn":
 			t.nameLog = "Cluster node check-in to database"
 			t.fn = cluster.CheckInNode
@@ -369,6 +379,9 @@ func load() error {
 		case "clusterProcessEvents":
 			t.nameLog = "Cluster event processing"
 			t.fn = clusterProcessEvents
+		case "expireLoginRoles":
+			t.nameLog = "Expiry of role assignments & delegations"
+			t.fn = login.ExpireRoles
 		case "httpCertRenew":
 			t.nameLog = "Reload of updated HTTP certificate"
 			t.fn = cache.CheckRenewCert
@@ -76,6 +76,27 @@ func cleanupMailTraffic() error {
 	return err
 }
 
+// deletes expired slow query entries and plan requests
+func cleanupSlowQueries() error {
+	keepForDays := config.GetUint64("slowQueryKeepDays")
+	if keepForDays == 0 {
+		return nil
+	}
+
+	if _, err := db.Pool.Exec(db.Ctx, `
+		DELETE FROM instance.slow_query
+		WHERE date < $1
+	`, tools.GetTimeUnix()-(oneDayInSeconds*int64(keepForDays))); err != nil {
+		return err
+	}
+
+	_, err := db.Pool.Exec(db.Ctx, `
+		DELETE FROM instance.slow_query_explain
+		WHERE date < $1
+	`, tools.GetTimeUnix()-(oneDayInSeconds*int64(keepForDays)))
+	return err
+}
+
 // removes files that were deleted from their attribute or that are not assigned to a record
 func cleanUpFiles() error {
 
@@ -51,6 +51,18 @@ func clusterProcessEvents() error {
 		log.Info("cluster", fmt.Sprintf("node is reacting to event '%s'", e.Content))
 
 		switch e.Content {
+		case "bruteforceBlocked":
+			var p types.ClusterEventBruteforce
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				return err
+			}
+			err = cluster.BruteforceBlocked(p.Host, p.DateExpiry)
+		case "bruteforceUnblocked":
+			var p types.ClusterEventBruteforce
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				return err
+			}
+			err = cluster.BruteforceUnblocked(false, p.Host)
 		case "collectionUpdated":
 			var p types.ClusterEventCollectionUpdated
 			if err := json.Unmarshal(e.Payload, &p); err != nil {
@@ -89,6 +101,12 @@ func clusterProcessEvents() error {
 				return err
 			}
 			err = cluster.LoginReauthorized(false, p.LoginId)
+		case "loginRoleDelegationChanged":
+			var p types.ClusterEventLogin
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				return err
+			}
+			err = cluster.LoginRoleDelegationChanged(false, p.LoginId)
 		case "loginReauthorizedAll":
 			err = cluster.LoginReauthorizedAll(false)
 		case "masterAssigned":
@@ -97,6 +115,12 @@ func clusterProcessEvents() error {
 				return err
 			}
 			err = cluster.MasterAssigned(p.State)
+		case "recordPresenceChanged":
+			var p types.ClusterEventRecordPresence
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				return err
+			}
+			err = cluster.RecordPresenceChanged(false, p.RelationId, p.RecordId)
 		case "schemaChanged":
 			var p types.ClusterEventSchemaChanged
 			if err := json.Unmarshal(e.Payload, &p); err != nil {
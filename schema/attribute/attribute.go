@@ -20,7 +20,7 @@ import (
 
 var contentTypes = []string{"integer", "bigint", "numeric", "real",
 	"double precision", "varchar", "text", "boolean", "regconfig", "uuid",
-	"1:1", "n:1", "files"}
+	"jsonb", "point", "money", "interval", "1:1", "n:1", "files"}
 
 var contentUseTypes = []string{"default", "textarea",
 	"richtext", "date", "datetime", "time", "color", "iframe"}
@@ -41,6 +41,7 @@ func DelCheck_tx(tx pgx.Tx, attributeId uuid.UUID) (interface{}, error) {
 	}
 	var dependencies struct {
 		ApiIds         []uuid.UUID `json:"apiIds"`         // attribute used in API column or query
+		AttributeIds   []uuid.UUID `json:"attributeIds"`   // attribute used in computed or validation expression
 		CollectionIds  []uuid.UUID `json:"collectionIds"`  // attribute used in collection column or query
 		FormIds        []uuid.UUID `json:"formIds"`        // attribute used in form query
 		PgIndexIds     []uuid.UUID `json:"pgIndexIds"`     // attribute used in PG index
@@ -158,13 +159,19 @@ func DelCheck_tx(tx pgx.Tx, attributeId uuid.UUID) (interface{}, error) {
 				FROM app.login_form
 				WHERE attribute_id_login  = $1
 				OR    attribute_id_lookup = $1
-			) AS loginForms
+			) AS loginForms,
+			ARRAY(
+				SELECT attribute_id
+				FROM app.attribute_depends
+				WHERE attribute_id_on = $1
+			) AS attributes
 	`, attributeId, queryIds, columnIdsSubQueries).Scan(
 		&dependencies.ApiIds,
 		&dependencies.CollectionIds,
 		&dependencies.FormIds,
 		&dependencies.PgIndexIds,
-		&dependencies.LoginFormNames); err != nil {
+		&dependencies.LoginFormNames,
+		&dependencies.AttributeIds); err != nil {
 
 		return nil, err
 	}
@@ -229,6 +236,14 @@ func DelCheck_tx(tx pgx.Tx, attributeId uuid.UUID) (interface{}, error) {
 			SELECT field_id
 			FROM app.field_kanban
 			WHERE attribute_id_sort = $1
+			
+			UNION
+			
+			-- map fields
+			SELECT field_id
+			FROM app.field_map
+			WHERE attribute_id_color = $1
+			OR    attribute_id_geo   = $1
 		)
 	`, attributeId, queryIds, columnIdsSubQueries)
 	if err != nil {
@@ -267,6 +282,14 @@ func Del_tx(tx pgx.Tx, id uuid.UUID) error {
 			return err
 		}
 	} else {
+		// validation check constraint does not necessarily refer to its own column
+		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			ALTER TABLE "%s"."%s"
+			DROP CONSTRAINT IF EXISTS "%s"
+		`, moduleName, relationName, schema.GetCheckConstraintName(id))); err != nil {
+			return err
+		}
+
 		// DROP COLUMN removes constraints if there
 		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
 			ALTER TABLE "%s"."%s"
@@ -285,11 +308,14 @@ func Get(relationId uuid.UUID) ([]types.Attribute, error) {
 
 	var onUpdateNull pgtype.Text
 	var onDeleteNull pgtype.Text
+	var expressionNull pgtype.Text
 
 	attributes := make([]types.Attribute, 0)
 	rows, err := db.Pool.Query(db.Ctx, `
 		SELECT id, relationship_id, icon_id, name, content, content_use,
-			length, nullable, encrypted, def, on_update, on_delete
+			length, nullable, encrypted, def, on_update, on_delete, expression,
+			validation_regex, validation_min, validation_max, validation_values,
+			validation_expression
 		FROM app.attribute
 		WHERE relation_id = $1
 		ORDER BY CASE WHEN name = 'id' THEN 0 END, name ASC
@@ -302,12 +328,16 @@ func Get(relationId uuid.UUID) ([]types.Attribute, error) {
 		var atr types.Attribute
 		if err := rows.Scan(&atr.Id, &atr.RelationshipId, &atr.IconId,
 			&atr.Name, &atr.Content, &atr.ContentUse, &atr.Length, &atr.Nullable,
-			&atr.Encrypted, &atr.Def, &onUpdateNull, &onDeleteNull); err != nil {
+			&atr.Encrypted, &atr.Def, &onUpdateNull, &onDeleteNull,
+			&expressionNull, &atr.Validation.Regex, &atr.Validation.Min,
+			&atr.Validation.Max, &atr.Validation.Values,
+			&atr.Validation.Expression); err != nil {
 
 			return attributes, err
 		}
 		atr.OnUpdate = onUpdateNull.String
 		atr.OnDelete = onDeleteNull.String
+		atr.Expression = expressionNull.String
 		atr.RelationId = relationId
 		attributes = append(attributes, atr)
 	}
@@ -343,6 +373,20 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		return fmt.Errorf("invalid attribute content use type '%s'", atr.ContentUse)
 	}
 
+	// empty validation rules are not set
+	if atr.Validation.Regex.String == "" {
+		atr.Validation.Regex.Valid = false
+	}
+	if atr.Validation.Expression.String == "" {
+		atr.Validation.Expression.Valid = false
+	}
+	if atr.Validation.Values == nil {
+		atr.Validation.Values = make([]string, 0)
+	}
+	if err := checkValidation(atr); err != nil {
+		return err
+	}
+
 	_, moduleName, err := schema.GetModuleDetailsByRelationId_tx(tx, atr.RelationId)
 	if err != nil {
 		return err
@@ -355,6 +399,22 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 	isNew := atr.Id == uuid.Nil
 	isRel := schema.IsContentRelationship(atr.Content)
 	isFiles := schema.IsContentFiles(atr.Content)
+	isComputed := atr.Expression != ""
+
+	// computed attributes are generated from other attributes of the same relation
+	var expressionNull = pgtype.Text{}
+
+	if isComputed {
+		if atr.Name == schema.PkName || isRel || isFiles || atr.Encrypted || atr.RelationshipId.Valid {
+			return errors.New("primary key, relationship, files and encrypted attributes cannot be computed")
+		}
+		if atr.Def != "" {
+			return errors.New("computed attributes cannot have default values")
+		}
+		expressionNull.String = atr.Expression
+		expressionNull.Valid = true
+	}
+
 	known, err := schema.CheckCreateId_tx(tx, &atr.Id, "attribute", "id")
 	if err != nil {
 		return err
@@ -374,6 +434,10 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		atr.OnDelete = ""
 	}
 
+	// recreated columns lose check constraints of validation expressions that refer to them
+	var validationExpressionEx pgtype.Text
+	columnRecreated := false
+
 	if known {
 		// get existing attribute info
 		var nameEx string
@@ -384,13 +448,15 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		var onUpdateEx pgtype.Text
 		var onDeleteEx pgtype.Text
 		var relationshipIdEx pgtype.UUID
+		var expressionEx pgtype.Text
 		if err := tx.QueryRow(db.Ctx, `
 			SELECT name, content, length, nullable, def,
-				on_update, on_delete, relationship_id
+				on_update, on_delete, relationship_id, expression, validation_expression
 			FROM app.attribute
 			WHERE id = $1
 		`, atr.Id).Scan(&nameEx, &contentEx, &lengthEx, &nullableEx, &defEx,
-			&onUpdateEx, &onDeleteEx, &relationshipIdEx); err != nil {
+			&onUpdateEx, &onDeleteEx, &relationshipIdEx, &expressionEx,
+			&validationExpressionEx); err != nil {
 
 			return err
 		}
@@ -434,6 +500,18 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		case "uuid": // keep UUID
 			contentUpdateOk = atr.Content == "uuid"
 
+		case "jsonb": // keep JSONB
+			contentUpdateOk = atr.Content == "jsonb"
+
+		case "point": // keep geo point
+			contentUpdateOk = atr.Content == "point"
+
+		case "money": // keep money
+			contentUpdateOk = atr.Content == "money"
+
+		case "interval": // keep interval
+			contentUpdateOk = atr.Content == "interval"
+
 		case "1:1": // keep 1:1 or switch to n:1
 			fallthrough
 		case "n:1": // keep n:1 or switch to 1:1
@@ -465,8 +543,40 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 			}
 		}
 
-		// update attribute column definition (not for files attributes: no column)
-		if !isFiles && (contentEx != atr.Content || nullableEx != atr.Nullable || defEx != atr.Def ||
+		// regular attribute cannot become computed attribute
+		// generated columns are created from scratch, all stored values would be lost
+		if isComputed && !expressionEx.Valid {
+			return fmt.Errorf("existing attribute cannot become computed, as its values would be lost, create a new computed attribute instead")
+		}
+
+		// computed attribute became regular attribute, generated values are kept
+		if !isComputed && expressionEx.Valid {
+			if err := unsetComputedColumn_tx(tx, moduleName, relationName, atr.Name); err != nil {
+				return err
+			}
+		}
+
+		// update computed attribute column
+		// generated columns cannot change their definition, column is recreated instead
+		if isComputed {
+			if expressionEx.String != atr.Expression || contentEx != atr.Content || lengthEx != atr.Length {
+				if err := setComputedColumn_tx(tx, moduleName, relationName, atr, true); err != nil {
+					return err
+				}
+				columnRecreated = true
+			} else if nullableEx != atr.Nullable {
+				nullableDef := "DROP NOT NULL"
+				if !atr.Nullable {
+					nullableDef = "SET NOT NULL"
+				}
+				if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+					ALTER TABLE "%s"."%s"
+					ALTER COLUMN "%s" %s
+				`, moduleName, relationName, atr.Name, nullableDef)); err != nil {
+					return err
+				}
+			}
+		} else if !isFiles && (contentEx != atr.Content || nullableEx != atr.Nullable || defEx != atr.Def ||
 			(atr.Content == "varchar" && lengthEx != atr.Length)) {
 
 			// handle relationship attribute
@@ -544,10 +654,15 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		if _, err := tx.Exec(db.Ctx, `
 			UPDATE app.attribute
 			SET icon_id = $1, content = $2, content_use = $3, length = $4,
-				nullable = $5, def = $6, on_update = $7, on_delete = $8
-			WHERE id = $9
+				nullable = $5, def = $6, on_update = $7, on_delete = $8,
+				expression = $9, validation_regex = $10, validation_min = $11,
+				validation_max = $12, validation_values = $13,
+				validation_expression = $14
+			WHERE id = $15
 		`, atr.IconId, atr.Content, atr.ContentUse, atr.Length, atr.Nullable,
-			atr.Def, onUpdateNull, onDeleteNull, atr.Id); err != nil {
+			atr.Def, onUpdateNull, onDeleteNull, expressionNull,
+			atr.Validation.Regex, atr.Validation.Min, atr.Validation.Max,
+			atr.Validation.Values, atr.Validation.Expression, atr.Id); err != nil {
 
 			return err
 		}
@@ -567,6 +682,10 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 			if err := fileRelationsCreate_tx(tx, atr.Id, moduleName, relationName); err != nil {
 				return err
 			}
+		} else if isComputed {
+			if err := setComputedColumn_tx(tx, moduleName, relationName, atr, false); err != nil {
+				return err
+			}
 		} else {
 			// check relationship target if relationship attribute
 			var contentRel string
@@ -620,11 +739,15 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		if _, err := tx.Exec(db.Ctx, `
 			INSERT INTO app.attribute (id, relation_id, relationship_id,
 				icon_id, name, content, content_use, length, nullable,
-				encrypted, def, on_update, on_delete)
-			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+				encrypted, def, on_update, on_delete, expression,
+				validation_regex, validation_min, validation_max,
+				validation_values, validation_expression)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)
 		`, atr.Id, atr.RelationId, atr.RelationshipId, atr.IconId, atr.Name,
 			atr.Content, atr.ContentUse, atr.Length, atr.Nullable, atr.Encrypted,
-			atr.Def, onUpdateNull, onDeleteNull); err != nil {
+			atr.Def, onUpdateNull, onDeleteNull, expressionNull,
+			atr.Validation.Regex, atr.Validation.Min, atr.Validation.Max,
+			atr.Validation.Values, atr.Validation.Expression); err != nil {
 
 			return err
 		}
@@ -694,6 +817,30 @@ func Set_tx(tx pgx.Tx, atr types.Attribute) error {
 		}
 	}
 
+	// rebuild dependencies on other attributes (computed/validation expressions)
+	if err := setDependencies_tx(tx, atr); err != nil {
+		return err
+	}
+
+	// apply validation expressions as check constraints
+	if !isFiles {
+		constraintAttributeIds := make([]uuid.UUID, 0)
+
+		if validationExpressionEx.String != atr.Validation.Expression.String || columnRecreated {
+			constraintAttributeIds = append(constraintAttributeIds, atr.Id)
+		}
+		if columnRecreated {
+			ids, err := getValidationDependentIds_tx(tx, atr.Id)
+			if err != nil {
+				return err
+			}
+			constraintAttributeIds = append(constraintAttributeIds, ids...)
+		}
+		if err := setValidationConstraints_tx(tx, moduleName, relationName, constraintAttributeIds); err != nil {
+			return err
+		}
+	}
+
 	// set captions
 	return caption.Set_tx(tx, atr.Id, atr.Captions)
 }
@@ -754,6 +901,9 @@ func getContentColumnDefinition(content string, length int, contentRel string) (
 			return "", fmt.Errorf("varchar requires defined length")
 		}
 		columnDef = fmt.Sprintf("character varying(%d)", length)
+	case "money":
+		// amount with currency, composite type defined in instance schema
+		columnDef = "instance.money_value"
 	}
 
 	// overwrite relationship column
@@ -0,0 +1,173 @@
+package attribute
+
+import (
+	"errors"
+	"fmt"
+	"r3/db"
+	"r3/schema"
+	"r3/schema/pgIndex"
+	"r3/types"
+	"regexp"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// computed attributes are stored as generated columns, defined by a SQL expression
+// validation expressions are stored as check constraints, also defined by a SQL expression
+// attributes of the same relation are referred to by their IDs, syntax: (ATTRIBUTE_ID)
+// as attribute names can change, IDs are kept and replaced with names when the column/constraint is created
+var regexExpressionAttributeId = regexp.MustCompile(`\(([a-z0-9\-]{36})\)`)
+
+func setComputedColumn_tx(tx pgx.Tx, moduleName string, relationName string,
+	atr types.Attribute, recreate bool) error {
+
+	expression, err := getExpressionWithNames_tx(tx, atr.Id, atr.RelationId, atr.Expression, true)
+	if err != nil {
+		return err
+	}
+
+	columnDef, err := getContentColumnDefinition(atr.Content, atr.Length, "")
+	if err != nil {
+		return err
+	}
+
+	nullableDef := ""
+	if !atr.Nullable {
+		nullableDef = "NOT NULL"
+	}
+
+	// generated columns cannot be altered, drop and add column again
+	// values are generated from other attributes, nothing is lost
+	if recreate {
+		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			ALTER TABLE "%s"."%s"
+			DROP COLUMN "%s"
+		`, moduleName, relationName, atr.Name)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+		ALTER TABLE "%s"."%s"
+		ADD COLUMN "%s" %s GENERATED ALWAYS AS (%s) STORED %s
+	`, moduleName, relationName, atr.Name, columnDef, expression, nullableDef)); err != nil {
+		return err
+	}
+
+	// dropping the column also dropped its indexes
+	if recreate {
+		return pgIndex.RecreateForAttribute_tx(tx, atr.Id)
+	}
+	return nil
+}
+
+func unsetComputedColumn_tx(tx pgx.Tx, moduleName string, relationName string, name string) error {
+	_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+		ALTER TABLE "%s"."%s"
+		ALTER COLUMN "%s" DROP EXPRESSION
+	`, moduleName, relationName, name))
+	return err
+}
+
+// rebuild dependency records of attribute, based on attribute IDs in its computed/validation expressions
+func setDependencies_tx(tx pgx.Tx, atr types.Attribute) error {
+
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM app.attribute_depends
+		WHERE attribute_id = $1
+	`, atr.Id); err != nil {
+		return err
+	}
+
+	idMap := make(map[uuid.UUID]bool)
+	for _, expression := range []string{atr.Expression, atr.Validation.Expression.String} {
+
+		matches := regexExpressionAttributeId.FindAllStringSubmatch(expression, -1)
+		for _, matchesSub := range matches {
+
+			if len(matchesSub) != 2 {
+				continue
+			}
+
+			atrId, err := uuid.FromString(matchesSub[1])
+			if err != nil {
+				return err
+			}
+
+			// self reference is not a dependency
+			if _, exists := idMap[atrId]; exists || atrId == atr.Id {
+				continue
+			}
+			idMap[atrId] = true
+
+			if _, err := tx.Exec(db.Ctx, `
+				INSERT INTO app.attribute_depends (attribute_id, attribute_id_on)
+				VALUES ($1,$2)
+			`, atr.Id, atrId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// replaces attribute IDs in expression with column names
+// referred attributes must be columns of the same relation
+func getExpressionWithNames_tx(tx pgx.Tx, attributeId uuid.UUID, relationId uuid.UUID,
+	expression string, isComputed bool) (string, error) {
+
+	idMap := make(map[uuid.UUID]bool)
+	matches := regexExpressionAttributeId.FindAllStringSubmatch(expression, -1)
+	for _, matchesSub := range matches {
+
+		if len(matchesSub) != 2 {
+			continue
+		}
+		placeholder := matchesSub[0]
+
+		atrId, err := uuid.FromString(matchesSub[1])
+		if err != nil {
+			return "", err
+		}
+
+		if _, exists := idMap[atrId]; exists {
+			continue
+		}
+		idMap[atrId] = true
+
+		if isComputed && atrId == attributeId {
+			return "", errors.New("computed attribute cannot refer to itself")
+		}
+
+		var name string
+		var content string
+		var relationIdOn uuid.UUID
+		var expressionOn pgtype.Text
+		if err := tx.QueryRow(db.Ctx, `
+			SELECT name, content, relation_id, expression
+			FROM app.attribute
+			WHERE id = $1
+		`, atrId).Scan(&name, &content, &relationIdOn, &expressionOn); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return "", fmt.Errorf("expression refers to unknown attribute %s", atrId)
+			}
+			return "", err
+		}
+
+		if relationIdOn != relationId {
+			return "", errors.New("expression can only refer to attributes of the same relation")
+		}
+		if schema.IsContentFiles(content) {
+			return "", errors.New("expression cannot refer to files attribute")
+		}
+		if isComputed && expressionOn.Valid {
+			// not supported by generated columns
+			return "", errors.New("computed attribute cannot refer to other computed attribute")
+		}
+		expression = strings.ReplaceAll(expression, placeholder, fmt.Sprintf(`"%s"`, name))
+	}
+	return expression, nil
+}
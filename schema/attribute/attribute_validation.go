@@ -0,0 +1,163 @@
+package attribute
+
+import (
+	"errors"
+	"fmt"
+	"r3/db"
+	"r3/schema"
+	"r3/types"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// validation rules are checked when values are set (data.Set_tx)
+// validation expressions are applied as check constraints, which also cover changes from backend functions
+// regular expressions are checked with RE2 on the server and with JavaScript RegExp in the browser
+//  only the syntax both engines understand the same way is allowed
+
+func checkValidation(atr types.Attribute) error {
+	v := atr.Validation
+
+	if !v.Regex.Valid && !v.Min.Valid && !v.Max.Valid && len(v.Values) == 0 && !v.Expression.Valid {
+		return nil
+	}
+	if schema.IsContentFiles(atr.Content) || atr.Encrypted {
+		return errors.New("files and encrypted attributes cannot have validation rules")
+	}
+
+	isText := schema.IsContentText(atr.Content)
+	isNumber := schema.IsContentNumber(atr.Content)
+	isInterval := schema.IsContentInterval(atr.Content)
+
+	if v.Regex.Valid {
+		if !isText {
+			return errors.New("regular expression validation is only available for text attributes")
+		}
+		if _, err := regexp.Compile(v.Regex.String); err != nil {
+			return fmt.Errorf("invalid validation regular expression, %s", err)
+		}
+		if err := checkValidationRegexSyntax(v.Regex.String); err != nil {
+			return err
+		}
+	}
+
+	if v.Min.Valid || v.Max.Valid {
+		if !isText && !isNumber && !isInterval {
+			return errors.New("min./max. validation is only available for text, number and duration attributes")
+		}
+		if v.Min.NaN || v.Max.NaN {
+			return errors.New("min./max. validation values must be numbers")
+		}
+		if v.Min.Valid && v.Max.Valid {
+			min, _ := v.Min.Float64Value()
+			max, _ := v.Max.Float64Value()
+			if min.Float64 > max.Float64 {
+				return errors.New("min. validation value cannot be larger than max. value")
+			}
+		}
+	}
+
+	if len(v.Values) != 0 {
+		if !isText && !isNumber {
+			return errors.New("allowed values validation is only available for text and number attributes")
+		}
+		if isNumber {
+			for _, value := range v.Values {
+				if _, err := strconv.ParseFloat(value, 64); err != nil {
+					return fmt.Errorf("allowed value '%s' is not a number", value)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rejects RE2 syntax that JavaScript RegExp (without flags) does not support or interprets differently
+// lookarounds & backreferences are not supported by RE2 and already fail to compile
+func checkValidationRegexSyntax(regex string) error {
+	runes := []rune(regex)
+	inClass := false
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) && strings.ContainsRune("ACEPQpz", runes[i+1]) {
+				return fmt.Errorf("validation regular expression cannot use '\\%c', it is not supported in browsers", runes[i+1])
+			}
+			i++ // skip escaped character
+		case '(':
+			// non-capturing (?:) and named groups (?<name>) are supported by both
+			// flag groups like (?i) and named groups like (?P<name>) are RE2 only
+			if !inClass && i+2 < len(runes) && runes[i+1] == '?' && runes[i+2] != ':' && runes[i+2] != '<' {
+				return errors.New("validation regular expression cannot use flag groups or (?P<name>), they are not supported in browsers")
+			}
+		case '[':
+			if inClass && i+1 < len(runes) && runes[i+1] == ':' {
+				return errors.New("validation regular expression cannot use character classes like [[:alpha:]], they are not supported in browsers")
+			}
+			inClass = true
+		case ']':
+			inClass = false
+		}
+	}
+	return nil
+}
+
+// applies validation expressions of attributes as check constraints, removes them if not defined anymore
+func setValidationConstraints_tx(tx pgx.Tx, moduleName string, relationName string, attributeIds []uuid.UUID) error {
+
+	for _, id := range attributeIds {
+		var relationId uuid.UUID
+		var expression pgtype.Text
+		if err := tx.QueryRow(db.Ctx, `
+			SELECT relation_id, validation_expression
+			FROM app.attribute
+			WHERE id = $1
+		`, id).Scan(&relationId, &expression); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			ALTER TABLE "%s"."%s"
+			DROP CONSTRAINT IF EXISTS "%s"
+		`, moduleName, relationName, schema.GetCheckConstraintName(id))); err != nil {
+			return err
+		}
+
+		if !expression.Valid {
+			continue
+		}
+
+		expressionNames, err := getExpressionWithNames_tx(tx, id, relationId, expression.String, false)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			ALTER TABLE "%s"."%s"
+			ADD CONSTRAINT "%s" CHECK (%s)
+		`, moduleName, relationName, schema.GetCheckConstraintName(id), expressionNames)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns IDs of attributes with validation expressions, referring to given attribute
+func getValidationDependentIds_tx(tx pgx.Tx, attributeId uuid.UUID) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0)
+	err := tx.QueryRow(db.Ctx, `
+		SELECT ARRAY(
+			SELECT a.id
+			FROM app.attribute_depends AS d
+			INNER JOIN app.attribute   AS a ON a.id = d.attribute_id
+			WHERE d.attribute_id_on = $1
+			AND   a.validation_expression IS NOT NULL
+		)
+	`, attributeId).Scan(&ids)
+	return ids, err
+}
@@ -8,14 +8,27 @@ import (
 
 // constants
 var PkName = "id"
+var SoftDeleteName = "_deleted" // system column of relations with soft delete, references recycle bin entry
 
 // database entity names
+func GetDataChangeTriggerName(relationId uuid.UUID) string {
+	return fmt.Sprintf("trg_data_change_%s", relationId.String())
+}
 func GetPkConstraintName(relationId uuid.UUID) string {
 	return fmt.Sprintf("pk_%s", relationId.String())
 }
 func GetFkConstraintName(attributeId uuid.UUID) string {
 	return fmt.Sprintf("fk_%s", attributeId.String())
 }
+func GetCheckConstraintName(attributeId uuid.UUID) string {
+	return fmt.Sprintf("chk_%s", attributeId.String())
+}
+func GetSoftDeleteFkName(relationId uuid.UUID) string {
+	return fmt.Sprintf("fk_deleted_%s", relationId.String())
+}
+func GetSoftDeleteIndexName(relationId uuid.UUID) string {
+	return fmt.Sprintf("ind_deleted_%s", relationId.String())
+}
 func GetSequenceName(relationId uuid.UUID) string {
 	return fmt.Sprintf("sq_%s", relationId.String())
 }
@@ -73,7 +73,10 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 		
 		-- list field
 		fl.auto_renew, fl.csv_export, fl.csv_import, fl.layout,
-		fl.filter_quick, fl.result_limit
+		fl.filter_quick, fl.result_limit,
+		
+		-- map field
+		fm.attribute_id_geo, fm.attribute_id_color, fm.index_geo, fm.index_color
 		
 		FROM app.field AS f
 		LEFT JOIN app.field_button            AS fb ON fb.field_id = f.id
@@ -85,6 +88,7 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 		LEFT JOIN app.field_header            AS fh ON fh.field_id = f.id
 		LEFT JOIN app.field_kanban            AS fk ON fk.field_id = f.id
 		LEFT JOIN app.field_list              AS fl ON fl.field_id = f.id
+		LEFT JOIN app.field_map               AS fm ON fm.field_id = f.id
 		LEFT JOIN app.attribute               AS a  ON a.id        = fd.attribute_id
 		WHERE f.form_id = $1
 		ORDER BY f.position ASC
@@ -104,6 +108,7 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 	posHeaderLookup := make([]int, 0)
 	posKanbanLookup := make([]int, 0)
 	posListLookup := make([]int, 0)
+	posMapLookup := make([]int, 0)
 	posParentLookup := make([]int, 0)
 	posTabsLookup := make([]int, 0)
 	posMapParentId := make(map[int]uuid.UUID)
@@ -119,14 +124,15 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 		var alignItems, alignContent, chartOption, def, direction, display,
 			ganttSteps, justifyContent, layout, regexCheck pgtype.Text
 		var autoSelect, days, grow, shrink, basis, perMin, perMax, index,
-			indexDate0, indexDate1, size, relationIndexKanbanData,
+			indexDate0, indexDate1, indexGeo, size, relationIndexKanbanData,
 			relationIndexKanbanAxisX, relationIndexKanbanAxisY,
 			resultLimit pgtype.Int2
-		var autoRenew, dateRange0, dateRange1, indexColor, min, max pgtype.Int4
+		var autoRenew, dateRange0, dateRange1, indexColor, indexColorMap, min,
+			max pgtype.Int4
 		var attributeId, attributeIdAlt, attributeIdNm, attributeIdDate0,
-			attributeIdDate1, attributeIdColor, attributeIdKanbanSort,
-			fieldParentId, iconId, jsFunctionIdButton, jsFunctionIdData,
-			tabId pgtype.UUID
+			attributeIdDate1, attributeIdColor, attributeIdColorMap,
+			attributeIdGeo, attributeIdKanbanSort, fieldParentId, iconId,
+			jsFunctionIdButton, jsFunctionIdData, tabId pgtype.UUID
 		var category, clipboard, csvExport, csvImport, daysToggle, filterQuick,
 			filterQuickList, gantt, ganttStepsToggle, ics, outsideIn, richtext,
 			wrap pgtype.Bool
@@ -144,7 +150,8 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 			&category, &filterQuick, &outsideIn, &autoSelect, &defPresetIds,
 			&relationIndexKanbanData, &relationIndexKanbanAxisX,
 			&relationIndexKanbanAxisY, &attributeIdKanbanSort, &autoRenew,
-			&csvExport, &csvImport, &layout, &filterQuickList, &resultLimit); err != nil {
+			&csvExport, &csvImport, &layout, &filterQuickList, &resultLimit,
+			&attributeIdGeo, &attributeIdColorMap, &indexGeo, &indexColorMap); err != nil {
 
 			rows.Close()
 			return fields, err
@@ -357,6 +364,24 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 			})
 			posListLookup = append(posListLookup, pos)
 
+		case "map":
+			fields = append(fields, types.FieldMap{
+				Id:               fieldId,
+				TabId:            tabId,
+				IconId:           iconId,
+				Content:          content,
+				State:            state,
+				OnMobile:         onMobile,
+				AttributeIdGeo:   attributeIdGeo.Bytes,
+				AttributeIdColor: attributeIdColorMap,
+				IndexGeo:         int(indexGeo.Int16),
+				IndexColor:       indexColorMap,
+				Columns:          []types.Column{},
+				Query:            types.Query{},
+				OpenForm:         types.OpenForm{},
+			})
+			posMapLookup = append(posMapLookup, pos)
+
 		case "tabs":
 			fields = append(fields, types.FieldTabs{
 				Id:       fieldId,
@@ -530,6 +555,29 @@ func Get(formId uuid.UUID) ([]interface{}, error) {
 		fields[pos] = field
 	}
 
+	// lookup map fields: open form, query, columns, consumed collections
+	for _, pos := range posMapLookup {
+		var field = fields[pos].(types.FieldMap)
+
+		field.OpenForm, err = openForm.Get("field", field.Id, pgtype.Text{})
+		if err != nil {
+			return fields, err
+		}
+		field.Query, err = query.Get("field", field.Id, 0, 0)
+		if err != nil {
+			return fields, err
+		}
+		field.Columns, err = column.Get("field", field.Id)
+		if err != nil {
+			return fields, err
+		}
+		field.Collections, err = consumer.Get("field", field.Id, "fieldFilterSelector")
+		if err != nil {
+			return fields, err
+		}
+		fields[pos] = field
+	}
+
 	// lookup tabs fields: get tabs
 	for _, pos := range posTabsLookup {
 		var field = fields[pos].(types.FieldTabs)
@@ -770,6 +818,16 @@ func Set_tx(tx pgx.Tx, formId uuid.UUID, parentId pgtype.UUID, tabId pgtype.UUID
 			}
 			fieldIdMapQuery[fieldId] = f.Query
 
+		case "map":
+			var f types.FieldMap
+			if err := json.Unmarshal(fieldJson, &f); err != nil {
+				return err
+			}
+			if err := setMap_tx(tx, fieldId, f); err != nil {
+				return err
+			}
+			fieldIdMapQuery[fieldId] = f.Query
+
 		case "tabs":
 			var f types.FieldTabs
 			if err := json.Unmarshal(fieldJson, &f); err != nil {
@@ -1242,3 +1300,46 @@ func setList_tx(tx pgx.Tx, fieldId uuid.UUID, attributeIdRecord pgtype.UUID,
 	// set columns
 	return column.Set_tx(tx, "field", fieldId, columns)
 }
+func setMap_tx(tx pgx.Tx, fieldId uuid.UUID, f types.FieldMap) error {
+
+	known, err := schema.CheckCreateId_tx(tx, &fieldId, "field_map", "field_id")
+	if err != nil {
+		return err
+	}
+
+	if known {
+		if _, err := tx.Exec(db.Ctx, `
+			UPDATE app.field_map
+			SET attribute_id_geo = $1, attribute_id_color = $2, index_geo = $3,
+				index_color = $4
+			WHERE field_id = $5
+		`, f.AttributeIdGeo, f.AttributeIdColor, f.IndexGeo, f.IndexColor,
+			fieldId); err != nil {
+
+			return err
+		}
+	} else {
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO app.field_map (field_id, attribute_id_geo,
+				attribute_id_color, index_geo, index_color)
+			VALUES ($1,$2,$3,$4,$5)
+		`, fieldId, f.AttributeIdGeo, f.AttributeIdColor, f.IndexGeo,
+			f.IndexColor); err != nil {
+
+			return err
+		}
+	}
+
+	// set open form
+	if err := openForm.Set_tx(tx, "field", fieldId, f.OpenForm, pgtype.Text{}); err != nil {
+		return err
+	}
+
+	// set collection consumer
+	if err := consumer.Set_tx(tx, "field", fieldId, "fieldFilterSelector", f.Collections); err != nil {
+		return err
+	}
+
+	// set columns
+	return column.Set_tx(tx, "field", fieldId, f.Columns)
+}
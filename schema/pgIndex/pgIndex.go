@@ -177,21 +177,27 @@ func Set_tx(tx pgx.Tx, pgi types.PgIndex) error {
 	pgi.Method = compatible.FixPgIndexMethod(pgi.Method)
 
 	isGin := pgi.Method == "GIN"
+	isGist := pgi.Method == "GIST"
 	isBtree := pgi.Method == "BTREE"
 
-	if !isGin && !isBtree {
+	if !isGin && !isGist && !isBtree {
 		return fmt.Errorf("unsupported index type '%s'", pgi.Method)
 	}
 
 	if isGin && len(pgi.Attributes) != 1 {
-		// we currently use GIN exclusively with to_tsvector on a single column
+		// we currently use GIN exclusively with to_tsvector or JSONB on a single column
 		// reason: doing any regular lookup (such as quick filters) checks attributes individually
 		//  the same with complex filters where each line is a single attribute
-		return fmt.Errorf("text index must have a single attribute")
+		return fmt.Errorf("GIN index must have a single attribute")
 	}
 
-	if isGin {
-		// no unique constraints on GIN
+	if isGist && len(pgi.Attributes) != 1 {
+		// GIST is used for geo point attributes, lookups check single attributes
+		return fmt.Errorf("GIST index must have a single attribute")
+	}
+
+	if isGin || isGist {
+		// no unique constraints on GIN/GIST
 		pgi.NoDuplicates = false
 	}
 
@@ -218,8 +224,77 @@ func Set_tx(tx pgx.Tx, pgi types.PgIndex) error {
 	if pgi.PrimaryKey {
 		return nil
 	}
+	return create_tx(tx, pgi)
+}
+
+// recreate PG indexes that include attribute
+// required when attribute column was dropped and added again (computed attributes)
+func RecreateForAttribute_tx(tx pgx.Tx, attributeId uuid.UUID) error {
+	pgIndexes := make([]types.PgIndex, 0)
+
+	rows, err := tx.Query(db.Ctx, `
+		SELECT id, relation_id, attribute_id_dict, method, no_duplicates
+		FROM app.pg_index
+		WHERE primary_key = FALSE
+		AND (
+			attribute_id_dict = $1
+			OR id IN (
+				SELECT pg_index_id
+				FROM app.pg_index_attribute
+				WHERE attribute_id = $2
+			)
+		)
+	`, attributeId, attributeId)
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var pgi types.PgIndex
+		if err := rows.Scan(&pgi.Id, &pgi.RelationId, &pgi.AttributeIdDict,
+			&pgi.Method, &pgi.NoDuplicates); err != nil {
+
+			return err
+		}
+		pgIndexes = append(pgIndexes, pgi)
+	}
+	rows.Close()
+
+	for _, pgi := range pgIndexes {
+		rows, err := tx.Query(db.Ctx, `
+			SELECT attribute_id, order_asc
+			FROM app.pg_index_attribute
+			WHERE pg_index_id = $1
+			ORDER BY position ASC
+		`, pgi.Id)
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var a types.PgIndexAttribute
+			if err := rows.Scan(&a.AttributeId, &a.OrderAsc); err != nil {
+				return err
+			}
+			pgi.Attributes = append(pgi.Attributes, a)
+		}
+		rows.Close()
+
+		if err := create_tx(tx, pgi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// create index in module
+func create_tx(tx pgx.Tx, pgi types.PgIndex) error {
+	var err error
+
+	isGin := pgi.Method == "GIN"
+	isGist := pgi.Method == "GIST"
+	isBtree := pgi.Method == "BTREE"
 
-	// create index in module
 	indexDef := ""
 	if isBtree {
 		indexCols := make([]string, 0)
@@ -246,12 +321,15 @@ func Set_tx(tx pgx.Tx, pgi types.PgIndex) error {
 			}
 		}
 
-		name, err := schema.GetAttributeNameById_tx(tx, pgi.Attributes[0].AttributeId)
+		_, _, name, content, err := schema.GetAttributeDetailsById_tx(tx, pgi.Attributes[0].AttributeId)
 		if err != nil {
 			return err
 		}
 
-		if nameDict == "" {
+		if schema.IsContentJson(content) {
+			// JSONB supports key exists (?) and contains (@>) operators with default operator class
+			indexDef = fmt.Sprintf(`GIN ("%s")`, name)
+		} else if nameDict == "" {
 			indexDef = fmt.Sprintf("GIN (TO_TSVECTOR('simple'::REGCONFIG,%s))", name)
 		} else {
 			indexDef = fmt.Sprintf("GIN (TO_TSVECTOR(CASE WHEN %s IS NULL THEN 'simple'::REGCONFIG ELSE %s END,%s))",
@@ -260,6 +338,18 @@ func Set_tx(tx pgx.Tx, pgi types.PgIndex) error {
 
 	}
 
+	if isGist {
+		_, _, name, content, err := schema.GetAttributeDetailsById_tx(tx, pgi.Attributes[0].AttributeId)
+		if err != nil {
+			return err
+		}
+		if !schema.IsContentPoint(content) {
+			return fmt.Errorf("GIST index requires geo point attribute")
+		}
+		// supports bounding box (<@) and distance (<->) operators
+		indexDef = fmt.Sprintf(`GIST ("%s")`, name)
+	}
+
 	modName, relName, err := schema.GetRelationNamesById_tx(tx, pgi.RelationId)
 	if err != nil {
 		return err
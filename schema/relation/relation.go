@@ -77,7 +77,8 @@ func Get(moduleId uuid.UUID) ([]types.Relation, error) {
 
 	relations := make([]types.Relation, 0)
 	rows, err := db.Pool.Query(db.Ctx, `
-		SELECT id, name, comment, encryption, retention_count, retention_days, (
+		SELECT id, name, comment, encryption, retention_count, retention_days,
+			soft_delete, search_form_id, data_change_notify, (
 			SELECT id
 			FROM app.attribute
 			WHERE relation_id = app.relation.id
@@ -95,7 +96,8 @@ func Get(moduleId uuid.UUID) ([]types.Relation, error) {
 	for rows.Next() {
 		var r types.Relation
 		if err := rows.Scan(&r.Id, &r.Name, &r.Comment, &r.Encryption,
-			&r.RetentionCount, &r.RetentionDays, &r.AttributeIdPk); err != nil {
+			&r.RetentionCount, &r.RetentionDays, &r.SoftDelete, &r.SearchFormId,
+			&r.DataChangeNotify, &r.AttributeIdPk); err != nil {
 
 			return relations, err
 		}
@@ -129,18 +131,34 @@ func Set_tx(tx pgx.Tx, rel types.Relation) error {
 		return err
 	}
 
+	if err := checkSearchForm_tx(tx, rel.ModuleId, rel.Id, rel.SearchFormId); err != nil {
+		return err
+	}
+
 	if known {
 		_, nameEx, err := schema.GetRelationNamesById_tx(tx, rel.Id)
 		if err != nil {
 			return err
 		}
 
+		var softDeleteEx, dataChangeNotifyEx bool
+		if err := tx.QueryRow(db.Ctx, `
+			SELECT soft_delete, data_change_notify
+			FROM app.relation
+			WHERE id = $1
+		`, rel.Id).Scan(&softDeleteEx, &dataChangeNotifyEx); err != nil {
+			return err
+		}
+
 		// update relation reference
 		if _, err := tx.Exec(db.Ctx, `
 			UPDATE app.relation
-			SET name = $1, comment = $2, retention_count = $3, retention_days = $4
-			WHERE id = $5
-		`, rel.Name, rel.Comment, rel.RetentionCount, rel.RetentionDays, rel.Id); err != nil {
+			SET name = $1, comment = $2, retention_count = $3,
+				retention_days = $4, soft_delete = $5, search_form_id = $6,
+				data_change_notify = $7
+			WHERE id = $8
+		`, rel.Name, rel.Comment, rel.RetentionCount, rel.RetentionDays,
+			rel.SoftDelete, rel.SearchFormId, rel.DataChangeNotify, rel.Id); err != nil {
 			return err
 		}
 
@@ -157,6 +175,18 @@ func Set_tx(tx pgx.Tx, rel types.Relation) error {
 				return fmt.Errorf("failed to recreate affected PG functions, %s", err)
 			}
 		}
+
+		if softDeleteEx != rel.SoftDelete {
+			if err := setSoftDelete_tx(tx, moduleName, rel.Name, rel.Id, rel.SoftDelete); err != nil {
+				return err
+			}
+		}
+
+		if dataChangeNotifyEx != rel.DataChangeNotify {
+			if err := setDataChangeNotify_tx(tx, moduleName, rel.Name, rel.Id, rel.DataChangeNotify); err != nil {
+				return err
+			}
+		}
 	} else {
 		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
 			CREATE TABLE "%s"."%s" ()
@@ -167,14 +197,28 @@ func Set_tx(tx pgx.Tx, rel types.Relation) error {
 		// insert relation reference
 		if _, err := tx.Exec(db.Ctx, `
 			INSERT INTO app.relation (id, module_id, name, comment,
-				encryption, retention_count, retention_days)
-			VALUES ($1,$2,$3,$4,$5,$6,$7)
+				encryption, retention_count, retention_days, soft_delete, search_form_id,
+				data_change_notify)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
 		`, rel.Id, rel.ModuleId, rel.Name, rel.Comment, rel.Encryption,
-			rel.RetentionCount, rel.RetentionDays); err != nil {
+			rel.RetentionCount, rel.RetentionDays, rel.SoftDelete, rel.SearchFormId,
+			rel.DataChangeNotify); err != nil {
 
 			return err
 		}
 
+		if rel.SoftDelete {
+			if err := setSoftDelete_tx(tx, moduleName, rel.Name, rel.Id, true); err != nil {
+				return err
+			}
+		}
+
+		if rel.DataChangeNotify {
+			if err := setDataChangeNotify_tx(tx, moduleName, rel.Name, rel.Id, true); err != nil {
+				return err
+			}
+		}
+
 		// create primary key attribute if relation is new (e. g. not imported or updated)
 		if isNew {
 			if err := attribute.Set_tx(tx, types.Attribute{
@@ -201,3 +245,98 @@ func Set_tx(tx pgx.Tx, rel types.Relation) error {
 	// set policies
 	return setPolicies_tx(tx, rel.Id, rel.Policies)
 }
+
+// adds or removes trigger that notifies listeners about data changes, incl. direct SQL changes
+// opt-in as every notifying transaction is serialized on commit and each changed row adds a notification
+func setDataChangeNotify_tx(tx pgx.Tx, moduleName string, relationName string,
+	relationId uuid.UUID, state bool) error {
+
+	if !state {
+		_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			DROP TRIGGER IF EXISTS "%s" ON "%s"."%s"
+		`, schema.GetDataChangeTriggerName(relationId), moduleName, relationName))
+		return err
+	}
+
+	_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+		CREATE TRIGGER "%s" AFTER INSERT OR UPDATE OR DELETE ON "%s"."%s"
+			FOR EACH ROW EXECUTE FUNCTION instance.trg_data_change_notify('%s')
+	`, schema.GetDataChangeTriggerName(relationId), moduleName, relationName, relationId))
+	return err
+}
+
+// adds or removes system column that marks records as deleted, by referencing their recycle bin entry
+// soft delete can only be disabled if no deleted records are left in the recycle bin
+// search form must be part of the same module and its query must be based on the relation
+// forms not yet stored are skipped (import order), foreign key is deferred and checked on commit
+func checkSearchForm_tx(tx pgx.Tx, moduleId uuid.UUID, id uuid.UUID, searchFormId pgtype.UUID) error {
+	if !searchFormId.Valid {
+		return nil
+	}
+
+	var formExists, relationMatches bool
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT
+			EXISTS(SELECT 1 FROM app.form WHERE id = $1),
+			EXISTS(
+				SELECT 1
+				FROM app.query AS q
+				JOIN app.form  AS f ON f.id = q.form_id
+				WHERE q.form_id     = $1
+				AND   q.relation_id = $2
+				AND   f.module_id   = $3
+			)
+	`, searchFormId, id, moduleId).Scan(&formExists, &relationMatches); err != nil {
+		return err
+	}
+	if formExists && !relationMatches {
+		return fmt.Errorf("search form must be part of the same module and be based on relation %s", id)
+	}
+	return nil
+}
+
+func setSoftDelete_tx(tx pgx.Tx, moduleName string, relationName string,
+	relationId uuid.UUID, enable bool) error {
+
+	if enable {
+		if _, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			ALTER TABLE "%s"."%s"
+			ADD COLUMN "%s" INTEGER,
+			ADD CONSTRAINT "%s" FOREIGN KEY ("%s")
+				REFERENCES instance.recycle_bin (id) MATCH SIMPLE
+				ON UPDATE CASCADE
+				ON DELETE SET NULL
+		`, moduleName, relationName, schema.SoftDeleteName,
+			schema.GetSoftDeleteFkName(relationId), schema.SoftDeleteName)); err != nil {
+
+			return err
+		}
+		_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+			CREATE INDEX "%s" ON "%s"."%s" ("%s")
+		`, schema.GetSoftDeleteIndexName(relationId), moduleName, relationName,
+			schema.SoftDeleteName))
+
+		return err
+	}
+
+	var deletedExist bool
+	if err := tx.QueryRow(db.Ctx, fmt.Sprintf(`
+		SELECT EXISTS(
+			SELECT 1
+			FROM "%s"."%s"
+			WHERE "%s" IS NOT NULL
+		)
+	`, moduleName, relationName, schema.SoftDeleteName)).Scan(&deletedExist); err != nil {
+		return err
+	}
+	if deletedExist {
+		return fmt.Errorf("cannot disable soft delete for relation '%s', deleted records must be restored or purged first",
+			relationName)
+	}
+
+	_, err := tx.Exec(db.Ctx, fmt.Sprintf(`
+		ALTER TABLE "%s"."%s"
+		DROP COLUMN "%s"
+	`, moduleName, relationName, schema.SoftDeleteName))
+	return err
+}
@@ -3,11 +3,14 @@ package role
 import (
 	"errors"
 	"fmt"
+	"net"
 	"r3/db"
 	"r3/schema"
 	"r3/schema/caption"
 	"r3/types"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/jackc/pgx/v5"
@@ -26,11 +29,16 @@ func Get(moduleId uuid.UUID) ([]types.Role, error) {
 	roles := make([]types.Role, 0)
 
 	rows, err := db.Pool.Query(db.Ctx, `
-		SELECT r.id, r.name, r.content, r.assignable, ARRAY(
-			SELECT role_id_child
-			FROM app.role_child
-			WHERE role_id = r.id
-		)
+		SELECT r.id, r.name, r.content, r.assignable, r.access_networks,
+			r.access_timezone, ARRAY(
+				SELECT role_id_child
+				FROM app.role_child
+				WHERE role_id = r.id
+			), ARRAY(
+				SELECT role_id_conflict
+				FROM app.role_conflict
+				WHERE role_id = r.id
+			)
 		FROM app.role AS r
 		WHERE r.module_id = $1
 		ORDER BY r.content = 'everyone' DESC, r.name ASC
@@ -41,7 +49,10 @@ func Get(moduleId uuid.UUID) ([]types.Role, error) {
 
 	for rows.Next() {
 		var r types.Role
-		if err := rows.Scan(&r.Id, &r.Name, &r.Content, &r.Assignable, &r.ChildrenIds); err != nil {
+		if err := rows.Scan(&r.Id, &r.Name, &r.Content, &r.Assignable,
+			&r.AccessNetworks, &r.AccessTimezone, &r.ChildrenIds,
+			&r.ConflictIds); err != nil {
+
 			return roles, err
 		}
 		r.ModuleId = moduleId
@@ -57,6 +68,11 @@ func Get(moduleId uuid.UUID) ([]types.Role, error) {
 			return roles, err
 		}
 
+		r.AccessWindows, err = getWindows(r.Id)
+		if err != nil {
+			return roles, err
+		}
+
 		r.Captions, err = caption.Get("role", r.Id, []string{"roleTitle", "roleDesc"})
 		if err != nil {
 			return roles, err
@@ -112,12 +128,48 @@ func getAccess(role types.Role) (types.Role, error) {
 	return role, nil
 }
 
+func getWindows(roleId uuid.UUID) ([]types.RoleWindow, error) {
+	windows := make([]types.RoleWindow, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT weekdays, time_from, time_to
+		FROM app.role_window
+		WHERE role_id = $1
+		ORDER BY position ASC
+	`, roleId)
+	if err != nil {
+		return windows, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var w types.RoleWindow
+		if err := rows.Scan(&w.Weekdays, &w.TimeFrom, &w.TimeTo); err != nil {
+			return windows, err
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
 func Set_tx(tx pgx.Tx, role types.Role) error {
 
 	if role.Name == "" {
 		return errors.New("missing name")
 	}
 
+	// compatibility fix: missing access conditions <3.5
+	if role.AccessNetworks == nil {
+		role.AccessNetworks = make([]string, 0)
+	}
+	if role.AccessTimezone == "" {
+		role.AccessTimezone = "UTC"
+	}
+
+	if err := checkAccessConditions(role); err != nil {
+		return err
+	}
+
 	// compatibility fix: missing role content <3.0
 	if role.Content == "" {
 		if role.Name == "everyone" {
@@ -141,17 +193,23 @@ func Set_tx(tx pgx.Tx, role types.Role) error {
 	if known {
 		if _, err := tx.Exec(db.Ctx, `
 			UPDATE app.role
-			SET name = $1, content = $2, assignable = $3
-			WHERE id = $4
+			SET name = $1, content = $2, assignable = $3, access_networks = $4,
+				access_timezone = $5
+			WHERE id = $6
 			AND content <> 'everyone' -- cannot update default role
-		`, role.Name, role.Content, role.Assignable, role.Id); err != nil {
+		`, role.Name, role.Content, role.Assignable, role.AccessNetworks,
+			role.AccessTimezone, role.Id); err != nil {
+
 			return err
 		}
 	} else {
 		if _, err := tx.Exec(db.Ctx, `
-			INSERT INTO app.role (id, module_id, name, content, assignable)
-			VALUES ($1,$2,$3,$4,$5)
-		`, role.Id, role.ModuleId, role.Name, role.Content, role.Assignable); err != nil {
+			INSERT INTO app.role (id, module_id, name, content, assignable,
+				access_networks, access_timezone)
+			VALUES ($1,$2,$3,$4,$5,$6,$7)
+		`, role.Id, role.ModuleId, role.Name, role.Content, role.Assignable,
+			role.AccessNetworks, role.AccessTimezone); err != nil {
+
 			return err
 		}
 	}
@@ -172,6 +230,44 @@ func Set_tx(tx pgx.Tx, role types.Role) error {
 		}
 	}
 
+	// set conflicting roles
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM app.role_conflict
+		WHERE role_id = $1
+	`, role.Id); err != nil {
+		return err
+	}
+	for _, conflictId := range role.ConflictIds {
+		if conflictId == role.Id {
+			return errors.New("role cannot conflict with itself")
+		}
+		if slices.Contains(role.ChildrenIds, conflictId) {
+			return errors.New("role cannot conflict with its own member role")
+		}
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO app.role_conflict (role_id, role_id_conflict)
+			VALUES ($1,$2)
+		`, role.Id, conflictId); err != nil {
+			return err
+		}
+	}
+
+	// set access windows
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM app.role_window
+		WHERE role_id = $1
+	`, role.Id); err != nil {
+		return err
+	}
+	for i, w := range role.AccessWindows {
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO app.role_window (role_id, position, weekdays, time_from, time_to)
+			VALUES ($1,$2,$3,$4,$5)
+		`, role.Id, i, w.Weekdays, w.TimeFrom, w.TimeTo); err != nil {
+			return err
+		}
+	}
+
 	// set access
 	if _, err := tx.Exec(db.Ctx, `
 		DELETE FROM app.role_access
@@ -250,3 +346,33 @@ func setAccess_tx(tx pgx.Tx, roleId uuid.UUID, id uuid.UUID, entity string, acce
 
 	return err
 }
+
+// access conditions cannot apply to default role, as it is always granted
+func checkAccessConditions(role types.Role) error {
+
+	if role.Content == "everyone" && (len(role.AccessNetworks) != 0 || len(role.AccessWindows) != 0) {
+		return errors.New("default role cannot have access conditions")
+	}
+	for _, network := range role.AccessNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			return fmt.Errorf("invalid access network '%s', CIDR notation required", network)
+		}
+	}
+	if _, err := time.LoadLocation(role.AccessTimezone); err != nil {
+		return fmt.Errorf("invalid access timezone '%s'", role.AccessTimezone)
+	}
+	for _, w := range role.AccessWindows {
+		if len(w.Weekdays) == 0 {
+			return errors.New("access window requires at least one weekday")
+		}
+		for _, day := range w.Weekdays {
+			if day < 0 || day > 6 {
+				return fmt.Errorf("invalid weekday %d in access window", day)
+			}
+		}
+		if w.TimeFrom < 0 || w.TimeTo > 86400 || w.TimeFrom >= w.TimeTo {
+			return errors.New("invalid access window time range, must be within one day")
+		}
+	}
+	return nil
+}
@@ -33,9 +33,25 @@ func CheckCreateId_tx(tx pgx.Tx, id *uuid.UUID, relName string, pkName string) (
 func IsContentFiles(content string) bool {
 	return content == "files"
 }
+func IsContentInterval(content string) bool {
+	return content == "interval"
+}
+func IsContentJson(content string) bool {
+	return content == "jsonb"
+}
+func IsContentMoney(content string) bool {
+	return content == "money"
+}
+func IsContentNumber(content string) bool {
+	return content == "integer" || content == "bigint" || content == "numeric" ||
+		content == "real" || content == "double precision"
+}
 func IsContentNumeric(content string) bool {
 	return content == "numeric"
 }
+func IsContentPoint(content string) bool {
+	return content == "point"
+}
 func IsContentRelationship(content string) bool {
 	return content == "1:1" || content == "n:1"
 }
@@ -548,6 +564,37 @@ func ValidateDependency_tx(tx pgx.Tx, moduleId uuid.UUID) error {
 			name1.String)
 	}
 
+	// check role conflicts with roles from independent modules
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT COUNT(*), STRING_AGG(r.name, ', ')
+		FROM app.role AS r
+		INNER JOIN app.module AS m
+			ON m.id = r.module_id
+			AND m.id = $1
+		
+		WHERE r.id IN (
+			SELECT role_id
+			FROM app.role_conflict
+			WHERE role_id_conflict NOT IN (
+				SELECT id
+				FROM app.role
+				WHERE module_id = m.id
+				OR module_id IN (
+					SELECT module_id_on
+					FROM app.module_depends
+					WHERE module_id = m.id
+				)
+			)
+		)
+	`, moduleId).Scan(&cnt, &name1); err != nil {
+		return err
+	}
+
+	if cnt != 0 {
+		return fmt.Errorf("dependency check failed, role(s) '%s' conflict(s) with role(s) from independent module(s)",
+			name1.String)
+	}
+
 	// check data presets without dependency
 	if err := tx.QueryRow(db.Ctx, `
 		SELECT COUNT(*)
@@ -0,0 +1,186 @@
+package scim
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"r3/cluster"
+	"r3/config"
+	"r3/db"
+	"r3/log"
+	"r3/tools"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	SchemaError         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaGroup         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp       = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaServiceConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SchemaUser          = "urn:ietf:params:scim:schemas:core:2.0:User"
+)
+
+// SCIM resources
+type Meta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location,omitempty"`
+}
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+type Name struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+type User struct {
+	Schemas    []string `json:"schemas"`
+	Id         string   `json:"id"`
+	ExternalId string   `json:"externalId,omitempty"`
+	UserName   string   `json:"userName"`
+	Name       *Name    `json:"name,omitempty"`
+	Active     bool     `json:"active"`
+	Groups     []Member `json:"groups"`
+	Meta       Meta     `json:"meta"`
+}
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	Id          string   `json:"id"`
+	ExternalId  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members,omitempty"`
+	Meta        Meta     `json:"meta"`
+}
+type ListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+type PatchOp struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// SCIM error, responded to client with HTTP status code
+type Error struct {
+	Status   int    `json:"-"`
+	ScimType string `json:"scimType,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+func errBadRequest(scimType string, detail string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusBadRequest, ScimType: scimType, Detail: fmt.Sprintf(detail, args...)}
+}
+func errConflict(detail string, args ...interface{}) *Error {
+	return &Error{Status: http.StatusConflict, ScimType: "uniqueness", Detail: fmt.Sprintf(detail, args...)}
+}
+func errNotFound(resourceType string, id string) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: fmt.Sprintf("%s '%s' not found", resourceType, id)}
+}
+
+// bearer token
+// only the hash of the token is stored, token itself is shown once when created
+func CheckToken(token string) bool {
+	hash := config.GetString("scimTokenHash")
+	if hash == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(tools.Hash(token))) == 1
+}
+func DelToken_tx(tx pgx.Tx) error {
+	return config.SetString_tx(tx, "scimTokenHash", "")
+}
+func SetToken_tx(tx pgx.Tx) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, config.SetString_tx(tx, "scimTokenHash", tools.Hash(token))
+}
+
+// login template to apply to newly provisioned logins, 0 = GLOBAL template
+func getLoginTemplateId() pgtype.Int8 {
+	id := config.GetUint64("scimLoginTemplateId")
+	return pgtype.Int8{Int64: int64(id), Valid: id != 0}
+}
+
+// executes function in own transaction, afterwards applies login changes to active sessions
+func execWithLoginChanges(fn func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error) error {
+
+	tx, err := db.Pool.Begin(db.Ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(db.Ctx)
+
+	loginIdsChanged := make([]int64, 0)
+	loginIdsDisabled := make([]int64, 0)
+	if err := fn(tx, &loginIdsChanged, &loginIdsDisabled); err != nil {
+		return err
+	}
+
+	// commit before renewing access cache (to apply new permissions)
+	if err := tx.Commit(db.Ctx); err != nil {
+		return err
+	}
+
+	for _, loginId := range loginIdsDisabled {
+		log.Info("scim", fmt.Sprintf("login ID %d is disabled, kicking active sessions", loginId))
+		if err := cluster.LoginDisabled(true, loginId); err != nil {
+			log.Warning("scim", fmt.Sprintf("could not kick sessions of login ID %d", loginId), err)
+		}
+	}
+	for _, loginId := range loginIdsChanged {
+		if slices.Contains(loginIdsDisabled, loginId) {
+			continue
+		}
+		if err := cluster.LoginReauthorized(true, loginId); err != nil {
+			log.Warning("scim", fmt.Sprintf("could not renew access permissions for login ID %d", loginId), err)
+		}
+	}
+	return nil
+}
+
+// parses boolean value, some SCIM clients send booleans as strings ("True", "False")
+func parseBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, errBadRequest("invalidValue", "invalid boolean value '%s'", string(value))
+	}
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, errBadRequest("invalidValue", "invalid boolean value '%s'", s)
+}
+
+func getTimeString(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,204 @@
+package scim
+
+import (
+	"fmt"
+	"r3/tools"
+	"strconv"
+	"strings"
+)
+
+/*
+	SCIM filter support (RFC 7644, 3.4.2.2)
+	Supported: attribute expressions with operators eq, ne, co, sw, ew, pr,
+	combined via 'and', such as: userName eq "john" and active eq true
+
+	Value filters on multi-valued attributes are reduced to sub-attributes:
+	members[value eq "12"] is handled as members.value eq "12"
+*/
+
+type filterExpr struct {
+	attribute string // lower case attribute path, such as 'username' or 'members.value'
+	operator  string // eq, ne, co, sw, ew, pr
+	value     string
+}
+
+type filterAttribute struct {
+	column  string // SQL expression to compare against
+	isBool  bool   // boolean attribute, only eq/ne/pr are valid
+	isMulti bool   // column is an array, only eq/pr are valid
+}
+
+func parseFilter(filter string) ([]filterExpr, error) {
+	exprs := make([]filterExpr, 0)
+
+	tokens, err := tokenizeFilter(filter)
+	if err != nil {
+		return exprs, err
+	}
+
+	for i := 0; i < len(tokens); {
+		if len(exprs) != 0 {
+			if strings.ToLower(tokens[i]) != "and" || i+1 >= len(tokens) {
+				return exprs, errBadRequest("invalidFilter", "unsupported filter, only 'and' is supported to combine expressions")
+			}
+			i++
+		}
+		attribute := tokens[i]
+		i++
+
+		// value filter on multi-valued attribute, such as members[value eq "12"]
+		if pos := strings.Index(attribute, "["); pos != -1 {
+			if !strings.HasSuffix(attribute, "]") {
+				return exprs, errBadRequest("invalidFilter", "invalid value filter '%s'", attribute)
+			}
+			parts := strings.Fields(attribute[pos+1 : len(attribute)-1])
+			if len(parts) != 3 {
+				return exprs, errBadRequest("invalidFilter", "invalid value filter '%s'", attribute)
+			}
+			value, err := strconv.Unquote(parts[2])
+			if err != nil {
+				value = parts[2]
+			}
+			exprs = append(exprs, filterExpr{
+				attribute: strings.ToLower(fmt.Sprintf("%s.%s", attribute[:pos], parts[0])),
+				operator:  strings.ToLower(parts[1]),
+				value:     value,
+			})
+			continue
+		}
+
+		if i >= len(tokens) {
+			return exprs, errBadRequest("invalidFilter", "missing operator for attribute '%s'", attribute)
+		}
+		e := filterExpr{attribute: strings.ToLower(attribute), operator: strings.ToLower(tokens[i])}
+		i++
+
+		if e.operator != "pr" {
+			if i >= len(tokens) {
+				return exprs, errBadRequest("invalidFilter", "missing value for attribute '%s'", e.attribute)
+			}
+			e.value = tokens[i]
+			i++
+		}
+		exprs = append(exprs, e)
+	}
+	return exprs, nil
+}
+
+// splits filter into tokens, quoted strings are unquoted
+// brackets of value filters are kept as one token, such as members[value eq "12"]
+func tokenizeFilter(filter string) ([]string, error) {
+	tokens := make([]string, 0)
+	runes := []rune(strings.TrimSpace(filter))
+
+	for i := 0; i < len(runes); {
+		switch {
+		case runes[i] == ' ':
+			i++
+
+		case runes[i] == '"':
+			j := i + 1
+			for ; j < len(runes) && runes[j] != '"'; j++ {
+				if runes[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(runes) {
+				return tokens, errBadRequest("invalidFilter", "unterminated string in filter")
+			}
+			value, err := strconv.Unquote(string(runes[i : j+1]))
+			if err != nil {
+				return tokens, errBadRequest("invalidFilter", "invalid string in filter")
+			}
+			tokens = append(tokens, value)
+			i = j + 1
+
+		default:
+			j := i
+			inBrackets := false
+			for ; j < len(runes) && (inBrackets || runes[j] != ' '); j++ {
+				switch runes[j] {
+				case '[':
+					inBrackets = true
+				case ']':
+					inBrackets = false
+				}
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+// applies filter expressions as WHERE conditions to query builder
+func applyFilter(qb *tools.QueryBuilder, exprs []filterExpr, attributes map[string]filterAttribute) error {
+
+	for i, e := range exprs {
+		atr, exists := attributes[e.attribute]
+		if !exists {
+			return errBadRequest("invalidFilter", "filtering on attribute '%s' is not supported", e.attribute)
+		}
+
+		para := fmt.Sprintf("{FILTER%d}", i)
+
+		if e.operator == "pr" {
+			if atr.isMulti {
+				qb.Add("WHERE", fmt.Sprintf("CARDINALITY(%s) <> 0", atr.column))
+			} else {
+				qb.Add("WHERE", fmt.Sprintf("%s IS NOT NULL", atr.column))
+			}
+			continue
+		}
+
+		if atr.isBool {
+			value, err := strconv.ParseBool(e.value)
+			if err != nil {
+				return errBadRequest("invalidFilter", "invalid boolean value '%s'", e.value)
+			}
+			switch e.operator {
+			case "eq":
+				qb.Add("WHERE", fmt.Sprintf("%s = %s", atr.column, para))
+			case "ne":
+				qb.Add("WHERE", fmt.Sprintf("%s <> %s", atr.column, para))
+			default:
+				return errBadRequest("invalidFilter", "operator '%s' is not supported for attribute '%s'", e.operator, e.attribute)
+			}
+			qb.AddPara(para, value)
+			continue
+		}
+
+		if atr.isMulti {
+			if e.operator != "eq" {
+				return errBadRequest("invalidFilter", "operator '%s' is not supported for attribute '%s'", e.operator, e.attribute)
+			}
+			qb.Add("WHERE", fmt.Sprintf("%s = ANY(%s)", para, atr.column))
+			qb.AddPara(para, e.value)
+			continue
+		}
+
+		// string comparisons are case insensitive
+		value := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(e.value)
+		switch e.operator {
+		case "eq":
+			qb.Add("WHERE", fmt.Sprintf("LOWER(%s) = LOWER(%s)", atr.column, para))
+			value = e.value
+		case "ne":
+			qb.Add("WHERE", fmt.Sprintf("(%s IS NULL OR LOWER(%s) <> LOWER(%s))", atr.column, atr.column, para))
+			value = e.value
+		case "co":
+			qb.Add("WHERE", fmt.Sprintf("%s ILIKE %s", atr.column, para))
+			value = fmt.Sprintf("%%%s%%", value)
+		case "sw":
+			qb.Add("WHERE", fmt.Sprintf("%s ILIKE %s", atr.column, para))
+			value = fmt.Sprintf("%s%%", value)
+		case "ew":
+			qb.Add("WHERE", fmt.Sprintf("%s ILIKE %s", atr.column, para))
+			value = fmt.Sprintf("%%%s", value)
+		default:
+			return errBadRequest("invalidFilter", "operator '%s' is not supported", e.operator)
+		}
+		qb.AddPara(para, value)
+	}
+	return nil
+}
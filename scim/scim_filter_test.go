@@ -0,0 +1,67 @@
+package scim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    []string
+		wantErr bool
+	}{
+		{`userName eq "john"`, []string{"userName", "eq", "john"}, false},
+		{`  active   eq true `, []string{"active", "eq", "true"}, false},
+		{`displayName eq "team a" and externalId pr`, []string{"displayName", "eq", "team a", "and", "externalId", "pr"}, false},
+		{`userName eq "say \"hi\""`, []string{"userName", "eq", `say "hi"`}, false},
+		{`members[value eq "12"]`, []string{`members[value eq "12"]`}, false},
+		{`userName eq "john`, nil, true},
+		{``, []string{}, false},
+	}
+	for _, test := range tests {
+		got, err := tokenizeFilter(test.filter)
+		if (err != nil) != test.wantErr {
+			t.Errorf("tokenizeFilter(%q) error = %v, want error %v", test.filter, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tokenizeFilter(%q) = %q, want %q", test.filter, got, test.want)
+		}
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter  string
+		want    []filterExpr
+		wantErr bool
+	}{
+		{`userName eq "John"`, []filterExpr{{"username", "eq", "John"}}, false},
+		{`userName EQ "john" AND active eq true`, []filterExpr{
+			{"username", "eq", "john"},
+			{"active", "eq", "true"},
+		}, false},
+		{`externalId pr`, []filterExpr{{"externalid", "pr", ""}}, false},
+		{`name.givenName sw "Jo"`, []filterExpr{{"name.givenname", "sw", "Jo"}}, false},
+		{`members[value eq "12"]`, []filterExpr{{"members.value", "eq", "12"}}, false},
+		{`members[value eq 12]`, []filterExpr{{"members.value", "eq", "12"}}, false},
+		{`members[value eq "12"`, nil, true},
+		{`members[value]`, nil, true},
+		{`userName eq "john" or active eq true`, nil, true},
+		{`userName eq "john" and`, nil, true},
+		{`userName`, nil, true},
+		{`userName eq`, nil, true},
+		{``, []filterExpr{}, false},
+	}
+	for _, test := range tests {
+		got, err := parseFilter(test.filter)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseFilter(%q) error = %v, want error %v", test.filter, err, test.wantErr)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseFilter(%q) = %+v, want %+v", test.filter, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,615 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"r3/db"
+	"r3/login"
+	"r3/tools"
+	"r3/types"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var groupFilterAttributes = map[string]filterAttribute{
+	"displayname":   {column: "g.name"},
+	"externalid":    {column: "g.external_id"},
+	"id":            {column: "g.id::TEXT"},
+	"members.value": {column: "ARRAY(SELECT gl.login_id::TEXT FROM instance.scim_group_login AS gl WHERE gl.scim_group_id = g.id)", isMulti: true},
+}
+
+// group state as managed by SCIM
+type groupState struct {
+	externalId pgtype.Text
+	loginIds   []int64
+	name       string
+}
+
+func DelGroup(id string) error {
+	groupId, err := getGroupId(id)
+	if err != nil {
+		return err
+	}
+	return execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		s, err := getGroupState_tx(tx, groupId)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(db.Ctx, `
+			DELETE FROM instance.scim_group
+			WHERE id = $1
+		`, groupId); err != nil {
+			return err
+		}
+
+		// roles of former members must be updated
+		for _, loginId := range s.loginIds {
+			if err := login.SetRoleIdsByScimGroups_tx(tx, loginId); err != nil {
+				return err
+			}
+		}
+		*loginIdsChanged = append(*loginIdsChanged, s.loginIds...)
+		return nil
+	})
+}
+
+func GetGroup(id string, excludeMembers bool) (Group, error) {
+	groupId, err := getGroupId(id)
+	if err != nil {
+		return Group{}, err
+	}
+
+	groups, _, err := getGroups(groupId, nil, 1, 1, excludeMembers)
+	if err != nil {
+		return Group{}, err
+	}
+	if len(groups) != 1 {
+		return Group{}, errNotFound("Group", id)
+	}
+	return groups[0], nil
+}
+
+func GetGroups(filter string, startIndex int, count int, excludeMembers bool) (ListResponse, error) {
+	res := ListResponse{
+		Schemas:    []string{SchemaListResponse},
+		StartIndex: startIndex,
+		Resources:  make([]interface{}, 0),
+	}
+
+	exprs, err := parseFilter(filter)
+	if err != nil {
+		return res, err
+	}
+
+	groups, total, err := getGroups(uuid.Nil, exprs, startIndex, count, excludeMembers)
+	if err != nil {
+		return res, err
+	}
+	for _, g := range groups {
+		res.Resources = append(res.Resources, g)
+	}
+	res.ItemsPerPage = len(res.Resources)
+	res.TotalResults = total
+	return res, nil
+}
+
+func CreateGroup(input json.RawMessage) (Group, error) {
+
+	s, err := parseGroupInput(input)
+	if err != nil {
+		return Group{}, err
+	}
+
+	groupId, err := uuid.NewV4()
+	if err != nil {
+		return Group{}, err
+	}
+
+	if err := execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		if err := checkGroupNameFree_tx(tx, s.name, groupId); err != nil {
+			return err
+		}
+
+		now := tools.GetTimeUnix()
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO instance.scim_group (id, name, external_id, date_created, date_changed)
+			VALUES ($1,$2,$3,$4,$4)
+		`, groupId, s.name, s.externalId, now); err != nil {
+			return err
+		}
+		return setGroupMembers_tx(tx, groupId, []int64{}, s.loginIds, loginIdsChanged)
+	}); err != nil {
+		return Group{}, err
+	}
+	return GetGroup(groupId.String(), false)
+}
+
+func ReplaceGroup(id string, input json.RawMessage) (Group, error) {
+	groupId, err := getGroupId(id)
+	if err != nil {
+		return Group{}, err
+	}
+
+	s, err := parseGroupInput(input)
+	if err != nil {
+		return Group{}, err
+	}
+
+	if err := execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		return setGroup_tx(tx, groupId, s, loginIdsChanged)
+	}); err != nil {
+		return Group{}, err
+	}
+	return GetGroup(id, false)
+}
+
+func PatchGroup(id string, input json.RawMessage) (Group, error) {
+	groupId, err := getGroupId(id)
+	if err != nil {
+		return Group{}, err
+	}
+
+	var patch PatchOp
+	if err := json.Unmarshal(input, &patch); err != nil {
+		return Group{}, errBadRequest("invalidSyntax", "invalid patch request: %s", err)
+	}
+
+	if err := execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		s, err := getGroupState_tx(tx, groupId)
+		if err != nil {
+			return err
+		}
+
+		for _, op := range patch.Operations {
+			operation := strings.ToLower(op.Op)
+			if !slices.Contains([]string{"add", "remove", "replace"}, operation) {
+				return errBadRequest("invalidSyntax", "invalid patch operation '%s'", op.Op)
+			}
+
+			// without path, value contains attributes to apply
+			if op.Path == "" {
+				values := make(map[string]json.RawMessage)
+				if err := json.Unmarshal(op.Value, &values); err != nil {
+					return errBadRequest("invalidValue", "patch operation without path requires object value")
+				}
+				for name, value := range values {
+					if err := applyGroupAttribute(&s, operation, name, value); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := applyGroupAttribute(&s, operation, op.Path, op.Value); err != nil {
+				return err
+			}
+		}
+		return setGroup_tx(tx, groupId, s, loginIdsChanged)
+	}); err != nil {
+		return Group{}, err
+	}
+	return GetGroup(id, false)
+}
+
+// admin functions
+// groups are created by SCIM client, role assignments are defined by admin
+func GetGroupsAdmin() ([]types.ScimGroup, error) {
+	groups := make([]types.ScimGroup, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT g.id, g.name, g.external_id, (
+			SELECT COUNT(*)
+			FROM instance.scim_group_login
+			WHERE scim_group_id = g.id
+		), ARRAY(
+			SELECT role_id
+			FROM instance.scim_group_role
+			WHERE scim_group_id = g.id
+		)
+		FROM instance.scim_group AS g
+		ORDER BY g.name ASC
+	`)
+	if err != nil {
+		return groups, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var g types.ScimGroup
+		if err := rows.Scan(&g.Id, &g.Name, &g.ExternalId, &g.MemberCount, &g.RoleIds); err != nil {
+			return groups, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, nil
+}
+
+// updates roles assigned to group and applies them to group members
+// member sessions must be reauthorized after commit
+func SetGroupRoleIds_tx(tx pgx.Tx, groupId uuid.UUID, roleIds []uuid.UUID) error {
+
+	s, err := getGroupState_tx(tx, groupId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		DELETE FROM instance.scim_group_role
+		WHERE scim_group_id = $1
+	`, groupId); err != nil {
+		return err
+	}
+
+	for _, roleId := range roleIds {
+		if _, err := tx.Exec(db.Ctx, `
+			INSERT INTO instance.scim_group_role (scim_group_id, role_id)
+			VALUES ($1,$2)
+		`, groupId, roleId); err != nil {
+			return err
+		}
+	}
+
+	for _, loginId := range s.loginIds {
+		if err := login.SetRoleIdsByScimGroups_tx(tx, loginId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applies single attribute change to group state
+// attributes that are not stored are ignored, same as when creating or replacing groups
+func applyGroupAttribute(s *groupState, operation string, path string, value json.RawMessage) error {
+
+	path = strings.TrimPrefix(path, SchemaGroup+":")
+
+	// member removal by value filter, such as: members[value eq "12"]
+	if pos := strings.Index(path, "["); pos != -1 {
+		if operation != "remove" || strings.ToLower(path[:pos]) != "members" {
+			return errBadRequest("invalidPath", "unsupported path '%s'", path)
+		}
+		exprs, err := parseFilter(path)
+		if err != nil {
+			return err
+		}
+		if len(exprs) != 1 || exprs[0].attribute != "members.value" || exprs[0].operator != "eq" {
+			return errBadRequest("invalidPath", "unsupported path '%s'", path)
+		}
+		loginId, err := strconv.ParseInt(exprs[0].value, 10, 64)
+		if err != nil {
+			return errBadRequest("invalidValue", "invalid member '%s'", exprs[0].value)
+		}
+		s.loginIds = slices.DeleteFunc(s.loginIds, func(id int64) bool { return id == loginId })
+		return nil
+	}
+
+	switch strings.ToLower(path) {
+	case "displayname":
+		if operation == "remove" {
+			return errBadRequest("mutability", "attribute 'displayName' cannot be removed")
+		}
+		if err := json.Unmarshal(value, &s.name); err != nil || s.name == "" {
+			return errBadRequest("invalidValue", "invalid value for attribute 'displayName'")
+		}
+
+	case "externalid":
+		if operation == "remove" {
+			s.externalId = pgtype.Text{}
+			return nil
+		}
+		if err := json.Unmarshal(value, &s.externalId.String); err != nil {
+			return errBadRequest("invalidValue", "invalid value for attribute 'externalId'")
+		}
+		s.externalId.Valid = true
+
+	case "members":
+		// remove without value removes all members
+		if operation == "remove" && len(value) == 0 {
+			s.loginIds = make([]int64, 0)
+			return nil
+		}
+
+		var members []Member
+		if err := json.Unmarshal(value, &members); err != nil {
+			return errBadRequest("invalidValue", "invalid value for attribute 'members'")
+		}
+		loginIds, err := getLoginIdsFromMembers(members)
+		if err != nil {
+			return err
+		}
+
+		switch operation {
+		case "add":
+			for _, loginId := range loginIds {
+				if !slices.Contains(s.loginIds, loginId) {
+					s.loginIds = append(s.loginIds, loginId)
+				}
+			}
+		case "remove":
+			s.loginIds = slices.DeleteFunc(s.loginIds, func(id int64) bool {
+				return slices.Contains(loginIds, id)
+			})
+		case "replace":
+			s.loginIds = loginIds
+		}
+	}
+	return nil
+}
+
+func parseGroupInput(input json.RawMessage) (groupState, error) {
+	var s groupState
+	var req struct {
+		DisplayName string   `json:"displayName"`
+		ExternalId  *string  `json:"externalId"`
+		Members     []Member `json:"members"`
+	}
+	if err := json.Unmarshal(input, &req); err != nil {
+		return s, errBadRequest("invalidSyntax", "invalid group: %s", err)
+	}
+	if req.DisplayName == "" {
+		return s, errBadRequest("invalidValue", "attribute 'displayName' is required")
+	}
+
+	loginIds, err := getLoginIdsFromMembers(req.Members)
+	if err != nil {
+		return s, err
+	}
+
+	s.name = req.DisplayName
+	s.loginIds = loginIds
+	if req.ExternalId != nil {
+		s.externalId = pgtype.Text{String: *req.ExternalId, Valid: true}
+	}
+	return s, nil
+}
+
+func setGroup_tx(tx pgx.Tx, groupId uuid.UUID, s groupState, loginIdsChanged *[]int64) error {
+
+	sEx, err := getGroupState_tx(tx, groupId)
+	if err != nil {
+		return err
+	}
+	if err := checkGroupNameFree_tx(tx, s.name, groupId); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(db.Ctx, `
+		UPDATE instance.scim_group
+		SET name = $1, external_id = $2, date_changed = $3
+		WHERE id = $4
+	`, s.name, s.externalId, tools.GetTimeUnix(), groupId); err != nil {
+		return err
+	}
+	return setGroupMembers_tx(tx, groupId, sEx.loginIds, s.loginIds, loginIdsChanged)
+}
+
+// updates group memberships and roles of affected logins
+func setGroupMembers_tx(tx pgx.Tx, groupId uuid.UUID, loginIdsEx []int64,
+	loginIds []int64, loginIdsChanged *[]int64) error {
+
+	// only SCIM managed logins can be group members
+	var cnt int
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT COUNT(*)
+		FROM instance.scim_login
+		WHERE login_id = ANY($1)
+	`, loginIds).Scan(&cnt); err != nil {
+		return err
+	}
+	if cnt != len(loginIds) {
+		return errBadRequest("invalidValue", "group members must be existing users")
+	}
+
+	affected := make([]int64, 0)
+	for _, loginId := range loginIdsEx {
+		if !slices.Contains(loginIds, loginId) {
+			if _, err := tx.Exec(db.Ctx, `
+				DELETE FROM instance.scim_group_login
+				WHERE scim_group_id = $1
+				AND   login_id      = $2
+			`, groupId, loginId); err != nil {
+				return err
+			}
+			affected = append(affected, loginId)
+		}
+	}
+	for _, loginId := range loginIds {
+		if !slices.Contains(loginIdsEx, loginId) {
+			if _, err := tx.Exec(db.Ctx, `
+				INSERT INTO instance.scim_group_login (scim_group_id, login_id)
+				VALUES ($1,$2)
+			`, groupId, loginId); err != nil {
+				return err
+			}
+			affected = append(affected, loginId)
+		}
+	}
+
+	for _, loginId := range affected {
+		if err := login.SetRoleIdsByScimGroups_tx(tx, loginId); err != nil {
+			return err
+		}
+	}
+	*loginIdsChanged = append(*loginIdsChanged, affected...)
+	return nil
+}
+
+func checkGroupNameFree_tx(tx pgx.Tx, name string, groupIdSelf uuid.UUID) error {
+	exists := false
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT EXISTS(
+			SELECT id
+			FROM instance.scim_group
+			WHERE name = $1
+			AND   id  <> $2
+		)
+	`, name, groupIdSelf).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return errConflict("group with name '%s' already exists", name)
+	}
+	return nil
+}
+
+func getGroupId(id string) (uuid.UUID, error) {
+	groupId, err := uuid.FromString(id)
+	if err != nil {
+		return groupId, errNotFound("Group", id)
+	}
+	return groupId, nil
+}
+
+func getGroupState_tx(tx pgx.Tx, groupId uuid.UUID) (groupState, error) {
+	var s groupState
+	err := tx.QueryRow(db.Ctx, `
+		SELECT name, external_id, ARRAY(
+			SELECT login_id
+			FROM instance.scim_group_login
+			WHERE scim_group_id = g.id
+		)
+		FROM instance.scim_group AS g
+		WHERE g.id = $1
+	`, groupId).Scan(&s.name, &s.externalId, &s.loginIds)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, errNotFound("Group", groupId.String())
+	}
+	return s, err
+}
+
+func getLoginIdsFromMembers(members []Member) ([]int64, error) {
+	loginIds := make([]int64, 0)
+	for _, m := range members {
+		loginId, err := strconv.ParseInt(m.Value, 10, 64)
+		if err != nil {
+			return loginIds, errBadRequest("invalidValue", "invalid member '%s'", m.Value)
+		}
+		if !slices.Contains(loginIds, loginId) {
+			loginIds = append(loginIds, loginId)
+		}
+	}
+	return loginIds, nil
+}
+
+// returns SCIM groups and total count
+// groups are either retrieved by ID or by filter expressions
+func getGroups(groupId uuid.UUID, exprs []filterExpr, startIndex int,
+	count int, excludeMembers bool) ([]Group, int, error) {
+
+	groups := make([]Group, 0)
+
+	var qb tools.QueryBuilder
+	qb.UseDollarSigns()
+	qb.AddList("SELECT", []string{"g.id", "g.name", "g.external_id",
+		"g.date_created", "g.date_changed"})
+
+	qb.Set("FROM", "instance.scim_group AS g")
+
+	if groupId != uuid.Nil {
+		qb.Add("WHERE", "g.id = {ID}")
+		qb.AddPara("{ID}", groupId)
+	}
+	if err := applyFilter(&qb, exprs, groupFilterAttributes); err != nil {
+		return groups, 0, err
+	}
+
+	qb.Add("ORDER", "g.name ASC")
+	qb.Set("OFFSET", startIndex-1)
+	qb.Set("LIMIT", count)
+
+	query, err := qb.GetQuery()
+	if err != nil {
+		return groups, 0, err
+	}
+
+	if count != 0 {
+		rows, err := db.Pool.Query(db.Ctx, query, qb.GetParaValues()...)
+		if err != nil {
+			return groups, 0, err
+		}
+
+		for rows.Next() {
+			var id uuid.UUID
+			var dateCreated, dateChanged int64
+			var externalId pgtype.Text
+			g := Group{Schemas: []string{SchemaGroup}}
+
+			if err := rows.Scan(&id, &g.DisplayName, &externalId,
+				&dateCreated, &dateChanged); err != nil {
+
+				rows.Close()
+				return groups, 0, err
+			}
+			g.Id = id.String()
+			g.ExternalId = externalId.String
+			g.Meta = Meta{
+				ResourceType: "Group",
+				Created:      getTimeString(dateCreated),
+				LastModified: getTimeString(dateChanged),
+			}
+			groups = append(groups, g)
+		}
+		rows.Close()
+	}
+
+	if !excludeMembers {
+		for i, g := range groups {
+			groups[i].Members, err = getGroupMembers(g.Id)
+			if err != nil {
+				return groups, 0, err
+			}
+		}
+	}
+
+	// get total count
+	qb.UseDollarSigns()
+	qb.Reset("SELECT")
+	qb.Reset("ORDER")
+	qb.Reset("LIMIT")
+	qb.Reset("OFFSET")
+	qb.Add("SELECT", "COUNT(*)")
+
+	query, err = qb.GetQuery()
+	if err != nil {
+		return groups, 0, err
+	}
+
+	var total int
+	if err := db.Pool.QueryRow(db.Ctx, query, qb.GetParaValues()...).Scan(&total); err != nil {
+		return groups, 0, err
+	}
+	return groups, total, nil
+}
+
+func getGroupMembers(groupId string) ([]Member, error) {
+	members := make([]Member, 0)
+
+	rows, err := db.Pool.Query(db.Ctx, `
+		SELECT l.id, l.name
+		FROM instance.scim_group_login AS gl
+		JOIN instance.login            AS l ON l.id = gl.login_id
+		WHERE gl.scim_group_id = $1
+		ORDER BY l.name ASC
+	`, groupId)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var m Member
+		if err := rows.Scan(&id, &m.Display); err != nil {
+			return members, err
+		}
+		m.Value = fmt.Sprintf("%d", id)
+		members = append(members, m)
+	}
+	return members, nil
+}
@@ -0,0 +1,440 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"r3/db"
+	"r3/login"
+	"r3/tools"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var userFilterAttributes = map[string]filterAttribute{
+	"active":          {column: "l.active", isBool: true},
+	"externalid":      {column: "s.external_id"},
+	"id":              {column: "l.id::TEXT"},
+	"name.familyname": {column: "s.name_family"},
+	"name.givenname":  {column: "s.name_given"},
+	"username":        {column: "l.name"},
+}
+
+// login state as managed by SCIM
+type userState struct {
+	active     bool
+	externalId pgtype.Text
+	name       string
+	nameFamily pgtype.Text
+	nameGiven  pgtype.Text
+}
+
+func DelUser(id string) error {
+	loginId, err := getUserLoginId(id)
+	if err != nil {
+		return err
+	}
+	return execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		if _, err := getUserState_tx(tx, loginId); err != nil {
+			return err
+		}
+		*loginIdsDisabled = append(*loginIdsDisabled, loginId)
+		return login.Del_tx(tx, loginId)
+	})
+}
+
+func GetUser(id string) (User, error) {
+	loginId, err := getUserLoginId(id)
+	if err != nil {
+		return User{}, err
+	}
+
+	users, _, err := getUsers(loginId, nil, 1, 1)
+	if err != nil {
+		return User{}, err
+	}
+	if len(users) != 1 {
+		return User{}, errNotFound("User", id)
+	}
+	return users[0], nil
+}
+
+func GetUsers(filter string, startIndex int, count int) (ListResponse, error) {
+	res := ListResponse{
+		Schemas:    []string{SchemaListResponse},
+		StartIndex: startIndex,
+		Resources:  make([]interface{}, 0),
+	}
+
+	exprs, err := parseFilter(filter)
+	if err != nil {
+		return res, err
+	}
+
+	users, total, err := getUsers(0, exprs, startIndex, count)
+	if err != nil {
+		return res, err
+	}
+	for _, u := range users {
+		res.Resources = append(res.Resources, u)
+	}
+	res.ItemsPerPage = len(res.Resources)
+	res.TotalResults = total
+	return res, nil
+}
+
+func CreateUser(input json.RawMessage) (User, error) {
+	var loginId int64
+
+	s, err := parseUserInput(input)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		if err := checkUserNameFree_tx(tx, s.name, 0); err != nil {
+			return err
+		}
+		loginId, err = login.SetScimLogin_tx(tx, 0, s.externalId, s.name,
+			s.nameGiven, s.nameFamily, s.active, getLoginTemplateId())
+		return err
+	}); err != nil {
+		return User{}, err
+	}
+	return GetUser(fmt.Sprintf("%d", loginId))
+}
+
+func ReplaceUser(id string, input json.RawMessage) (User, error) {
+	loginId, err := getUserLoginId(id)
+	if err != nil {
+		return User{}, err
+	}
+
+	s, err := parseUserInput(input)
+	if err != nil {
+		return User{}, err
+	}
+
+	if err := execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		return setUser_tx(tx, loginId, s, loginIdsDisabled)
+	}); err != nil {
+		return User{}, err
+	}
+	return GetUser(id)
+}
+
+func PatchUser(id string, input json.RawMessage) (User, error) {
+	loginId, err := getUserLoginId(id)
+	if err != nil {
+		return User{}, err
+	}
+
+	var patch PatchOp
+	if err := json.Unmarshal(input, &patch); err != nil {
+		return User{}, errBadRequest("invalidSyntax", "invalid patch request: %s", err)
+	}
+
+	if err := execWithLoginChanges(func(tx pgx.Tx, loginIdsChanged *[]int64, loginIdsDisabled *[]int64) error {
+		s, err := getUserState_tx(tx, loginId)
+		if err != nil {
+			return err
+		}
+
+		for _, op := range patch.Operations {
+			remove := false
+			switch strings.ToLower(op.Op) {
+			case "add", "replace":
+			case "remove":
+				remove = true
+			default:
+				return errBadRequest("invalidSyntax", "invalid patch operation '%s'", op.Op)
+			}
+
+			// without path, value contains attributes to apply
+			if op.Path == "" {
+				values := make(map[string]json.RawMessage)
+				if err := json.Unmarshal(op.Value, &values); err != nil {
+					return errBadRequest("invalidValue", "patch operation without path requires object value")
+				}
+				for name, value := range values {
+					if err := applyUserAttribute(&s, name, value, remove); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := applyUserAttribute(&s, op.Path, op.Value, remove); err != nil {
+				return err
+			}
+		}
+		return setUser_tx(tx, loginId, s, loginIdsDisabled)
+	}); err != nil {
+		return User{}, err
+	}
+	return GetUser(id)
+}
+
+// applies single attribute change to user state
+// attributes that are not stored (like emails) are ignored, same as when creating or replacing users
+func applyUserAttribute(s *userState, path string, value json.RawMessage, remove bool) error {
+
+	path = strings.ToLower(strings.TrimPrefix(path, SchemaUser+":"))
+
+	switch path {
+	case "active":
+		if remove {
+			return errBadRequest("mutability", "attribute 'active' cannot be removed")
+		}
+		active, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		s.active = active
+
+	case "externalid":
+		if remove {
+			s.externalId = pgtype.Text{}
+			return nil
+		}
+		if err := json.Unmarshal(value, &s.externalId.String); err != nil {
+			return errBadRequest("invalidValue", "invalid value for attribute 'externalId'")
+		}
+		s.externalId.Valid = true
+
+	case "username":
+		if remove {
+			return errBadRequest("mutability", "attribute 'userName' cannot be removed")
+		}
+		if err := json.Unmarshal(value, &s.name); err != nil || s.name == "" {
+			return errBadRequest("invalidValue", "invalid value for attribute 'userName'")
+		}
+
+	case "name":
+		if remove {
+			s.nameFamily = pgtype.Text{}
+			s.nameGiven = pgtype.Text{}
+			return nil
+		}
+		values := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(value, &values); err != nil {
+			return errBadRequest("invalidValue", "invalid value for attribute 'name'")
+		}
+		for name, v := range values {
+			if err := applyUserAttribute(s, "name."+name, v, false); err != nil {
+				return err
+			}
+		}
+
+	case "name.familyname", "name.givenname":
+		var v pgtype.Text
+		if !remove {
+			if err := json.Unmarshal(value, &v.String); err != nil {
+				return errBadRequest("invalidValue", "invalid value for attribute '%s'", path)
+			}
+			v.Valid = v.String != ""
+		}
+		if path == "name.familyname" {
+			s.nameFamily = v
+		} else {
+			s.nameGiven = v
+		}
+	}
+	return nil
+}
+
+func parseUserInput(input json.RawMessage) (userState, error) {
+	var s userState
+	var req struct {
+		Active     *bool   `json:"active"`
+		ExternalId *string `json:"externalId"`
+		UserName   string  `json:"userName"`
+		Name       *Name   `json:"name"`
+	}
+	if err := json.Unmarshal(input, &req); err != nil {
+		return s, errBadRequest("invalidSyntax", "invalid user: %s", err)
+	}
+	if req.UserName == "" {
+		return s, errBadRequest("invalidValue", "attribute 'userName' is required")
+	}
+
+	// logins are active unless stated otherwise
+	s.active = req.Active == nil || *req.Active
+	s.name = req.UserName
+
+	if req.ExternalId != nil {
+		s.externalId = pgtype.Text{String: *req.ExternalId, Valid: true}
+	}
+	if req.Name != nil {
+		s.nameFamily = pgtype.Text{String: req.Name.FamilyName, Valid: req.Name.FamilyName != ""}
+		s.nameGiven = pgtype.Text{String: req.Name.GivenName, Valid: req.Name.GivenName != ""}
+	}
+	return s, nil
+}
+
+func setUser_tx(tx pgx.Tx, loginId int64, s userState, loginIdsDisabled *[]int64) error {
+
+	sEx, err := getUserState_tx(tx, loginId)
+	if err != nil {
+		return err
+	}
+	if err := checkUserNameFree_tx(tx, s.name, loginId); err != nil {
+		return err
+	}
+	if _, err := login.SetScimLogin_tx(tx, loginId, s.externalId, s.name,
+		s.nameGiven, s.nameFamily, s.active, pgtype.Int8{}); err != nil {
+
+		return err
+	}
+
+	if sEx.active && !s.active {
+		*loginIdsDisabled = append(*loginIdsDisabled, loginId)
+	}
+	return nil
+}
+
+func checkUserNameFree_tx(tx pgx.Tx, name string, loginIdSelf int64) error {
+	exists := false
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT EXISTS(
+			SELECT id
+			FROM instance.login
+			WHERE name = LOWER($1)
+			AND   id  <> $2
+		)
+	`, name, loginIdSelf).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return errConflict("login with name '%s' already exists", name)
+	}
+	return nil
+}
+
+func getUserLoginId(id string) (int64, error) {
+	loginId, err := strconv.ParseInt(id, 10, 64)
+	if err != nil || loginId <= 0 {
+		return 0, errNotFound("User", id)
+	}
+	return loginId, nil
+}
+
+func getUserState_tx(tx pgx.Tx, loginId int64) (userState, error) {
+	var s userState
+	err := tx.QueryRow(db.Ctx, `
+		SELECT l.name, l.active, s.external_id, s.name_given, s.name_family
+		FROM instance.login      AS l
+		JOIN instance.scim_login AS s ON s.login_id = l.id
+		WHERE l.id = $1
+	`, loginId).Scan(&s.name, &s.active, &s.externalId, &s.nameGiven, &s.nameFamily)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s, errNotFound("User", fmt.Sprintf("%d", loginId))
+	}
+	return s, err
+}
+
+// returns SCIM managed logins and total count
+// logins are either retrieved by ID or by filter expressions
+func getUsers(loginId int64, exprs []filterExpr, startIndex int, count int) ([]User, int, error) {
+	users := make([]User, 0)
+
+	var qb tools.QueryBuilder
+	qb.UseDollarSigns()
+	qb.AddList("SELECT", []string{"l.id", "l.name", "l.active", "s.external_id",
+		"s.name_given", "s.name_family", "s.date_created", "s.date_changed"})
+
+	qb.Add("SELECT", `ARRAY(
+		SELECT g.id::TEXT
+		FROM instance.scim_group_login AS gl
+		JOIN instance.scim_group       AS g ON g.id = gl.scim_group_id
+		WHERE gl.login_id = l.id
+		ORDER BY g.name ASC
+	)`)
+	qb.Add("SELECT", `ARRAY(
+		SELECT g.name
+		FROM instance.scim_group_login AS gl
+		JOIN instance.scim_group       AS g ON g.id = gl.scim_group_id
+		WHERE gl.login_id = l.id
+		ORDER BY g.name ASC
+	)`)
+
+	qb.Set("FROM", "instance.login AS l")
+	qb.Add("JOIN", "INNER JOIN instance.scim_login AS s ON s.login_id = l.id")
+
+	if loginId != 0 {
+		qb.Add("WHERE", "l.id = {ID}")
+		qb.AddPara("{ID}", loginId)
+	}
+	if err := applyFilter(&qb, exprs, userFilterAttributes); err != nil {
+		return users, 0, err
+	}
+
+	qb.Add("ORDER", "l.id ASC")
+	qb.Set("OFFSET", startIndex-1)
+	qb.Set("LIMIT", count)
+
+	query, err := qb.GetQuery()
+	if err != nil {
+		return users, 0, err
+	}
+
+	if count != 0 {
+		rows, err := db.Pool.Query(db.Ctx, query, qb.GetParaValues()...)
+		if err != nil {
+			return users, 0, err
+		}
+
+		for rows.Next() {
+			var id, dateCreated, dateChanged int64
+			var externalId, nameGiven, nameFamily pgtype.Text
+			var groupIds, groupNames []string
+			u := User{
+				Schemas: []string{SchemaUser},
+				Groups:  make([]Member, 0),
+			}
+			if err := rows.Scan(&id, &u.UserName, &u.Active, &externalId, &nameGiven,
+				&nameFamily, &dateCreated, &dateChanged, &groupIds, &groupNames); err != nil {
+
+				rows.Close()
+				return users, 0, err
+			}
+			u.Id = fmt.Sprintf("%d", id)
+			u.ExternalId = externalId.String
+			if nameGiven.Valid || nameFamily.Valid {
+				u.Name = &Name{GivenName: nameGiven.String, FamilyName: nameFamily.String}
+			}
+			u.Meta = Meta{
+				ResourceType: "User",
+				Created:      getTimeString(dateCreated),
+				LastModified: getTimeString(dateChanged),
+			}
+			for i, groupId := range groupIds {
+				u.Groups = append(u.Groups, Member{Value: groupId, Display: groupNames[i]})
+			}
+			users = append(users, u)
+		}
+		rows.Close()
+	}
+
+	// get total count
+	qb.UseDollarSigns()
+	qb.Reset("SELECT")
+	qb.Reset("ORDER")
+	qb.Reset("LIMIT")
+	qb.Reset("OFFSET")
+	qb.Add("SELECT", "COUNT(*)")
+
+	query, err = qb.GetQuery()
+	if err != nil {
+		return users, 0, err
+	}
+
+	var total int
+	if err := db.Pool.QueryRow(db.Ctx, query, qb.GetParaValues()...).Scan(&total); err != nil {
+		return users, 0, err
+	}
+	return users, total, nil
+}
@@ -0,0 +1,53 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestApplyUserAttribute(t *testing.T) {
+	tests := []struct {
+		path         string
+		value        string
+		remove       bool
+		wantGiven    string
+		wantFamily   string
+		wantScimType string
+	}{
+		{"name", `{"givenName":"John","familyName":"Doe"}`, false, "John", "Doe", ""},
+		{"name.givenName", `"Jane"`, false, "Jane", "Smith", ""},
+		{SchemaUser + ":name.familyName", `"Doe"`, false, "Max", "Doe", ""},
+		{"name.familyName", ``, true, "Max", "", ""},
+		{"name", ``, true, "", "", ""},
+		{"name", `"John Doe"`, false, "Max", "Smith", "invalidValue"},
+		{"name", `{"formatted":"John Doe"}`, false, "Max", "Smith", ""},
+		{"emails", `[{"value":"john@example.com"}]`, false, "Max", "Smith", ""},
+		{`emails[type eq "work"].value`, `"john@example.com"`, false, "Max", "Smith", ""},
+		{"displayName", `"John Doe"`, false, "Max", "Smith", ""},
+		{"userName", ``, true, "Max", "Smith", "mutability"},
+	}
+	for _, test := range tests {
+		s := userState{name: "max"}
+		s.nameGiven.String, s.nameGiven.Valid = "Max", true
+		s.nameFamily.String, s.nameFamily.Valid = "Smith", true
+
+		err := applyUserAttribute(&s, test.path, json.RawMessage(test.value), test.remove)
+
+		var errScim *Error
+		if test.wantScimType != "" {
+			if !errors.As(err, &errScim) || errScim.ScimType != test.wantScimType {
+				t.Errorf("applyUserAttribute(%q) error = %v, want SCIM error type %q", test.path, err, test.wantScimType)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("applyUserAttribute(%q) error = %v", test.path, err)
+			continue
+		}
+		if s.nameGiven.String != test.wantGiven || s.nameFamily.String != test.wantFamily {
+			t.Errorf("applyUserAttribute(%q) name = %q %q, want %q %q", test.path,
+				s.nameGiven.String, s.nameFamily.String, test.wantGiven, test.wantFamily)
+		}
+	}
+}
@@ -186,6 +186,16 @@ func deleteRelationAttributes_tx(tx pgx.Tx, moduleId uuid.UUID, relations []type
 	if err != nil {
 		return err
 	}
+
+	// delete computed attributes first, their columns depend on other attributes
+	if err := tx.QueryRow(db.Ctx, `
+		SELECT ARRAY_AGG(id ORDER BY expression IS NULL)
+		FROM app.attribute
+		WHERE id = ANY($1)
+	`, idsDelete).Scan(&idsDelete); err != nil {
+		return err
+	}
+
 	for _, id := range idsDelete {
 		log.Info("transfer", fmt.Sprintf("del attribute %s", id.String()))
 		if err := attribute.Del_tx(tx, id); err != nil {
@@ -374,6 +384,15 @@ func deleteFormFields_tx(tx pgx.Tx, moduleId uuid.UUID, form types.Form) error {
 					idsKeepColumns = append(idsKeepColumns, column.Id)
 				}
 
+			case "map":
+				var fieldMap types.FieldMap
+				if err := json.Unmarshal(fieldJson, &fieldMap); err != nil {
+					return err
+				}
+				for _, column := range fieldMap.Columns {
+					idsKeepColumns = append(idsKeepColumns, column.Id)
+				}
+
 			case "tabs":
 				var fieldTabs types.FieldTabs
 				if err := json.Unmarshal(fieldJson, &fieldTabs); err != nil {
@@ -269,7 +269,7 @@ func importModule_tx(tx pgx.Tx, mod types.Module, firstRun bool, lastRun bool,
 	// attributes
 	for _, relation := range mod.Relations {
 		for _, e := range relation.Attributes {
-			if e.Name == schema.PkName {
+			if e.Name == schema.PkName || e.Expression != "" {
 				continue
 			}
 
@@ -288,6 +288,28 @@ func importModule_tx(tx pgx.Tx, mod types.Module, firstRun bool, lastRun bool,
 		}
 	}
 
+	// computed attributes, refer to other attributes of the same relation
+	for _, relation := range mod.Relations {
+		for _, e := range relation.Attributes {
+			if e.Expression == "" {
+				continue
+			}
+
+			run, err := importCheckRunAndSave(tx, firstRun, e.Id, idMapSkipped)
+			if err != nil {
+				return err
+			}
+			if !run {
+				continue
+			}
+			log.Info("transfer", fmt.Sprintf("set computed attribute %s", e.Id))
+
+			if err := importCheckResultAndApply(tx, attribute.Set_tx(tx, e), e.Id, idMapSkipped); err != nil {
+				return err
+			}
+		}
+	}
+
 	// collections
 	for _, e := range mod.Collections {
 		run, err := importCheckRunAndSave(tx, firstRun, e.Id, idMapSkipped)
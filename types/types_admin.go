@@ -14,6 +14,18 @@ type BackupTocFile struct {
 	Backups []BackupDef `json:"backups"`
 }
 
+type BruteforceBlock struct {
+	Host        string      `json:"host"`        // blocked IP address or IPv6 network prefix
+	DateBlocked int64       `json:"dateBlocked"` // unix time of block
+	DateExpiry  pgtype.Int8 `json:"dateExpiry"`  // unix time of block expiry, null if block does not expire
+	NodeName    string      `json:"nodeName"`    // cluster node that blocked the host
+}
+
+type ClientCertRevoked struct {
+	Serial      string      `json:"serial"` // certificate serial number, lower case hex
+	Comment     pgtype.Text `json:"comment"`
+	DateRevoked int64       `json:"dateRevoked"`
+}
 type Log struct {
 	Level      int         `json:"level"`
 	Context    string      `json:"context"`
@@ -24,16 +36,24 @@ type Log struct {
 }
 
 type LoginAdmin struct {
-	Id           int64              `json:"id"`
-	LdapId       pgtype.Int4        `json:"ldapId"`
-	LdapKey      pgtype.Text        `json:"ldapKey"`
-	Name         string             `json:"name"`
-	Active       bool               `json:"active"`
-	Admin        bool               `json:"admin"`
-	NoAuth       bool               `json:"noAuth"`
-	LanguageCode string             `json:"languageCode"`
-	Records      []LoginAdminRecord `json:"records"`
-	RoleIds      []uuid.UUID        `json:"roleIds"`
+	Id           int64                             `json:"id"`
+	LdapId       pgtype.Int4                       `json:"ldapId"`
+	LdapKey      pgtype.Text                       `json:"ldapKey"`
+	Name         string                            `json:"name"`
+	Active       bool                              `json:"active"`
+	Admin        bool                              `json:"admin"`
+	NoAuth       bool                              `json:"noAuth"`
+	CertRequired bool                              `json:"certRequired"` // login must authenticate with client certificate
+	MfaMail      pgtype.Text                       `json:"mfaMail"`      // mail address to send MFA codes to
+	MfaMissing   bool                              `json:"mfaMissing"`   // MFA is required but no MFA method is set up
+	LanguageCode string                            `json:"languageCode"`
+	Records      []LoginAdminRecord                `json:"records"`
+	RoleIds      []uuid.UUID                       `json:"roleIds"`
+	RoleDates    map[uuid.UUID]LoginAdminRoleDates `json:"roleDates"` // validity of time-limited role assignments
+}
+type LoginAdminRoleDates struct {
+	DateFrom pgtype.Int8 `json:"dateFrom"` // role is assigned from this date on, NULL = immediately
+	DateTo   pgtype.Int8 `json:"dateTo"`   // role assignment expires at this date, NULL = never
 }
 type LoginAdminRecord struct {
 	Id    pgtype.Int8 `json:"id"`    // record ID
@@ -47,6 +67,14 @@ type LoginAdminRecordSet struct {
 	AttributeId uuid.UUID `json:"attributeId"` // login attribute
 	RecordId    int64     `json:"recordId"`
 }
+type LoginRegistration struct {
+	LoginId     int64       `json:"loginId"`
+	LoginName   string      `json:"loginName"`
+	Mail        string      `json:"mail"`
+	State       string      `json:"state"`       // unverified (mail not yet confirmed), pending (awaiting approval)
+	RuleId      pgtype.Int4 `json:"ruleId"`      // registration rule matching mail domain, NULL if rule was deleted
+	DateCreated int64       `json:"dateCreated"` // unix time of registration
+}
 type LoginTemplateAdmin struct {
 	Id       int64       `json:"id"`
 	Name     string      `json:"name"`
@@ -73,9 +101,88 @@ type Ldap struct {
 	Tls             bool        `json:"tls"`             // connect to LDAP via SSL/TLS (LDAPS)
 	TlsVerify       bool        `json:"tlsVerify"`       // verify TLS connection, can be used to allow non-trusted certificates
 	Roles           []LdapRole  `json:"roles"`
+
+	// failover hosts, used in order if the primary host is not reachable
+	// entries as 'HOST' or 'HOST:PORT', port of primary host is used if not defined
+	HostsFailover []string `json:"hostsFailover"`
+
+	// resolve nested group memberships on generic LDAP servers (MS AD always resolves via matching rule)
+	NestedGroups bool `json:"nestedGroups"`
+
+	// incremental sync, only entries changed since last run are imported (uSNChanged for MS AD, modifyTimestamp otherwise)
+	// full sync is executed in interval, to apply group membership changes & deactivate deleted or moved users
+	DeltaSync        bool `json:"deltaSync"`
+	FullSyncInterval int  `json:"fullSyncInterval"` // hours between full syncs
+
+	// login records, updated from LDAP attributes
+	LoginRecordAttributeId pgtype.UUID           `json:"loginRecordAttributeId"` // login attribute of relation to update (as in login form)
+	RecordAttributes       []LdapRecordAttribute `json:"recordAttributes"`
+}
+type LdapRun struct {
+	Id               int64       `json:"id"`
+	LdapId           int32       `json:"ldapId"`
+	DateStart        int64       `json:"dateStart"`
+	DurationMs       int64       `json:"durationMs"`
+	FullSync         bool        `json:"fullSync"`
+	CountCreated     int         `json:"countCreated"`
+	CountUpdated     int         `json:"countUpdated"`
+	CountDeactivated int         `json:"countDeactivated"`
+	CountFailed      int         `json:"countFailed"`
+	Error            pgtype.Text `json:"error"`
+}
+type LdapHostState struct {
+	Host       string `json:"host"`       // host with port, as connected to
+	Healthy    bool   `json:"healthy"`    // false if last connection attempt failed
+	Failures   int    `json:"failures"`   // consecutive failed connection attempts
+	DateFailed int64  `json:"dateFailed"` // last failed connection attempt
+	DateOk     int64  `json:"dateOk"`     // last successful connection
+	LastError  string `json:"lastError"`
 }
 type LdapRole struct {
 	LdapId  int32     `json:"ldapId"`
 	RoleId  uuid.UUID `json:"roleId"`
 	GroupDn string    `json:"groupDn"`
 }
+type LdapRecordAttribute struct {
+	LdapId        int32     `json:"ldapId"`
+	AttributeId   uuid.UUID `json:"attributeId"`   // attribute of login record relation
+	LdapAttribute string    `json:"ldapAttribute"` // name of LDAP attribute, example: 'mail'
+}
+type ScimGroup struct {
+	Id          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`        // display name, as defined by SCIM client
+	ExternalId  pgtype.Text `json:"externalId"`  // ID of group in SCIM client
+	MemberCount int         `json:"memberCount"` // count of logins in group
+	RoleIds     []uuid.UUID `json:"roleIds"`     // roles assigned to group members
+}
+type RegistrationRule struct {
+	Id          int32       `json:"id"`
+	Domain      string      `json:"domain"`      // mail domain allowed to register, '*' = any domain
+	ApproveAuto bool        `json:"approveAuto"` // verified registrations are approved without admin
+	RoleIds     []uuid.UUID `json:"roleIds"`     // roles assigned on approval
+}
+type SlowQuery struct {
+	Id         int32       `json:"id"`
+	LoginId    pgtype.Int8 `json:"loginId"` // login executing the query, NULL if login was removed
+	LoginName  pgtype.Text `json:"loginName"`
+	RelationId uuid.UUID   `json:"relationId"` // source relation of query
+	ApiId      pgtype.UUID `json:"apiId"`      // origin of query (API, form and/or field), if known
+	FormId     pgtype.UUID `json:"formId"`
+	FieldId    pgtype.UUID `json:"fieldId"`
+	Date       int64       `json:"date"`
+	DurationMs int32       `json:"durationMs"`
+	QuerySql   string      `json:"querySql"`
+	QueryArgs  []string    `json:"queryArgs"` // redacted query arguments, only types and lengths are kept
+	Explain    pgtype.Text `json:"explain"`   // EXPLAIN (ANALYZE, BUFFERS) plan, if sampled
+}
+type SlowQueryAggregate struct {
+	QuerySql          string      `json:"querySql"`
+	RelationId        uuid.UUID   `json:"relationId"`
+	Count             int64       `json:"count"`
+	DurationAvgMs     int32       `json:"durationAvgMs"`
+	DurationMaxMs     int32       `json:"durationMaxMs"`
+	DateLast          int64       `json:"dateLast"`
+	IdLast            int32       `json:"idLast"`            // latest entry of query
+	AttributeIdsIndex []uuid.UUID `json:"attributeIdsIndex"` // filter/join attributes without index, suggested for indexing
+	ExplainRequested  bool        `json:"explainRequested"`  // plan is captured on the next slow run of query
+}
@@ -6,6 +6,10 @@ type ClusterEvent struct {
 	Content string
 	Payload []byte
 }
+type ClusterEventBruteforce struct {
+	Host       string `json:"host"`
+	DateExpiry int64  `json:"dateExpiry"`
+}
 type ClusterEventCollectionUpdated struct {
 	CollectionId uuid.UUID `json:"collectionId"`
 	LoginIds     []int64   `json:"loginIds"`
@@ -23,6 +27,10 @@ type ClusterEventSchemaChanged struct {
 	ModuleIdsUpdateOnly []uuid.UUID `json:"moduleIdsUpdateOnly"`
 	NewVersion          bool        `json:"newVersion"`
 }
+type ClusterEventRecordPresence struct {
+	RelationId uuid.UUID `json:"relationId"`
+	RecordId   int64     `json:"recordId"`
+}
 type ClusterEventTaskTriggered struct {
 	PgFunctionId         uuid.UUID `json:"pgFunctionId"`
 	PgFunctionScheduleId uuid.UUID `json:"pgFunctionScheduleId"`
@@ -59,13 +67,14 @@ type ClusterNode struct {
 type ClusterWebsocketClientEvent struct {
 	LoginId int64 // affected login (0=all logins)
 
-	CollectionChanged uuid.UUID // inform client: collection has changed (should update it)
-	ConfigChanged     bool      // system config has changed (only relevant for admins)
-	Kick              bool      // kick login (usually because it was disabled)
-	KickNonAdmin      bool      // kick login if not admin (usually because maintenance mode was enabled)
-	Renew             bool      // renew login (permissions changed)
-	SchemaLoading     bool      // inform client: schema is loading
-	SchemaTimestamp   int64     // inform client: schema has a new timestamp (new version)
+	CollectionChanged     uuid.UUID // inform client: collection has changed (should update it)
+	ConfigChanged         bool      // system config has changed (only relevant for admins)
+	Kick                  bool      // kick login (usually because it was disabled)
+	KickNonAdmin          bool      // kick login if not admin (usually because maintenance mode was enabled)
+	Renew                 bool      // renew login (permissions changed)
+	RoleDelegationChanged bool      // renew login (role delegation to login changed)
+	SchemaLoading         bool      // inform client: schema is loading
+	SchemaTimestamp       int64     // inform client: schema has a new timestamp (new version)
 
 	// file open request for fat client
 	FileRequestedAttributeId uuid.UUID
@@ -78,4 +87,10 @@ type ClusterWebsocketClientEvent struct {
 	FilesCopiedAttributeId uuid.UUID
 	FilesCopiedFileIds     []uuid.UUID
 	FilesCopiedRecordId    int64
+
+	// record presence changed, sent to clients having the record open
+	RecordPresence *DataPresenceRecord
+
+	// data changed, sent to clients subscribed to changed relations/records
+	DataChanged []DataChange
 }
@@ -29,6 +29,7 @@ type DataGetFilterSide struct {
 	AttributeNested int         `json:"attributeNested"` // attribute nesting level (0 = main query, 1 = 1st sub query)
 	Brackets        int         `json:"brackets"`        // brackets before (side0) or after (side1)
 	FtsDict         pgtype.Text `json:"ftsDict"`         // dictionary for full text search, execute tsquery on value and convert attribute side to tsvector if set
+	JsonPath        string      `json:"jsonPath"`        // path to value inside JSONB attribute, optional, compared as text
 	Query           DataGet     `json:"query"`           // sub query, optional
 	QueryAggregator pgtype.Text `json:"queryAggregator"` // sub query aggregator, optional
 	Value           interface{} `json:"value"`           // fixed value, optional, filled by frontend with value of field/login ID/record/...
@@ -54,6 +55,8 @@ type DataGetExpression struct {
 	AttributeIdNm pgtype.UUID `json:"attributeIdNm"` // ID of n:m attribute to retrieve
 	Index         int         `json:"index"`         // relation index attribute belongs to
 	OutsideIn     bool        `json:"outsideIn"`     // attribute comes from other relation
+	JsonPath      string      `json:"jsonPath"`      // path to value inside JSONB attribute, optional, value is returned as text
+	GeoDistanceTo []float64   `json:"geoDistanceTo"` // longitude/latitude to return distance (meters) to from geo point attribute, optional
 
 	// sub query expression
 	Query DataGet `json:"query"` // a regular data GET request
@@ -70,6 +73,9 @@ type DataGetOrder struct {
 	AttributeId pgtype.UUID `json:"attributeId"`
 	Index       pgtype.Int4 `json:"index"` // join relation index
 
+	// order by distance between geo point attribute and longitude/latitude
+	GeoDistanceTo []float64 `json:"geoDistanceTo"`
+
 	// order by expression
 	ExpressionPos pgtype.Int4 `json:"expressionPos"` // array index of expression to order by
 
@@ -87,14 +93,21 @@ type DataGet struct {
 	Limit       int                 `json:"limit"`       // result limit
 	Offset      int                 `json:"offset"`      // result offset
 	GetPerm     bool                `json:"getPerm"`     // get result permissions (SET/DEL) from relation policy, GET is ignored as results are filtered by it already
+	GetVersion  bool                `json:"getVersion"`  // get record versions, to be sent back on SET to detect concurrent changes
 	SearchDicts []string            `json:"searchDicts"` // list of fulltext search dictionaries (english, german, ...)
+
+	// origin of request, only used to capture slow queries
+	ApiId   pgtype.UUID `json:"-"`       // set by API handler
+	FieldId pgtype.UUID `json:"fieldId"` // field requesting data (list, calendar, etc.)
+	FormId  pgtype.UUID `json:"formId"`  // form requesting record data
 }
 type DataGetResult struct {
-	IndexRecordIds     map[int]interface{} `json:"indexRecordIds"`     // IDs of relation records, key: relation index
-	IndexRecordEncKeys map[int]string      `json:"indexRecordEncKeys"` // record data keys, encrypted with login´s public key, key: relation index
-	IndexesPermNoDel   []int               `json:"indexesPermNoDel"`   // if getPerm, relation indexes of which records may not be deleted
-	IndexesPermNoSet   []int               `json:"indexesPermNoSet"`   // if getPerm, relation indexes of which records may not be updated
-	Values             []interface{}       `json:"values"`             // expression values, same order as requested expressions
+	IndexRecordIds      map[int]interface{} `json:"indexRecordIds"`      // IDs of relation records, key: relation index
+	IndexRecordEncKeys  map[int]string      `json:"indexRecordEncKeys"`  // record data keys, encrypted with login´s public key, key: relation index
+	IndexesPermNoDel    []int               `json:"indexesPermNoDel"`    // if getPerm, relation indexes of which records may not be deleted
+	IndexesPermNoSet    []int               `json:"indexesPermNoSet"`    // if getPerm, relation indexes of which records may not be updated
+	IndexRecordVersions map[int]int64       `json:"indexRecordVersions"` // if getVersion, versions of relation records, key: relation index
+	Values              []interface{}       `json:"values"`              // expression values, same order as requested expressions
 }
 type DataGetValueFile struct {
 	Id      uuid.UUID `json:"id"`
@@ -111,6 +124,7 @@ type DataSetAttribute struct {
 	AttributeIdNm pgtype.UUID `json:"attributeIdNm"` // attribute ID for n:m relationship
 	OutsideIn     bool        `json:"outsideIn"`     // not from this index, comes from other relation via relationship attribute
 	Value         interface{} `json:"value"`
+	ValueOrg      interface{} `json:"valueOrg"` // value as originally retrieved, used to detect conflicting changes if record version is set
 }
 type DataSetEncKeys struct {
 	LoginId int64  `json:"loginId"`
@@ -129,6 +143,7 @@ type DataSet struct {
 	AttributeId uuid.UUID          `json:"attributeId"` // attribute ID of relationship to join with
 	IndexFrom   int                `json:"indexFrom"`   // from relation index
 	RecordId    int64              `json:"recordId"`    // record ID to update (0 if new)
+	Version     int64              `json:"version"`     // record version as originally retrieved, conflicting changes since are rejected (0 to skip check)
 	Attributes  []DataSetAttribute `json:"attributes"`  // attribute values to set
 	EncKeysSet  []DataSetEncKeys   `json:"encKeysSet"`  // data encryption keys to store, encrypted with login´s public key
 }
@@ -142,6 +157,98 @@ type DataLog struct {
 	RelationId uuid.UUID          `json:"relationId"`
 	RecordId   int64              `json:"recordId"`
 	DateChange int64              `json:"dateChange"`
+	Deleted    bool               `json:"deleted"` // record was deleted, values are last known values
 	LoginName  string             `json:"loginName"`
 	Attributes []DataSetAttribute `json:"attributes"`
 }
+
+// data LOG restore, changes required to bring record back to its state at a log entry
+type DataLogRestore struct {
+	LogId      uuid.UUID                 `json:"logId"`
+	RelationId uuid.UUID                 `json:"relationId"`
+	RecordId   int64                     `json:"recordId"`
+	DateChange int64                     `json:"dateChange"` // date of log entry to restore
+	Attributes []DataLogRestoreAttribute `json:"attributes"` // attribute values that differ from state at log entry
+	Files      []DataLogRestoreFile      `json:"files"`      // file changes that differ from state at log entry
+}
+type DataLogRestoreAttribute struct {
+	AttributeId   uuid.UUID   `json:"attributeId"`
+	AttributeIdNm pgtype.UUID `json:"attributeIdNm"`
+	OutsideIn     bool        `json:"outsideIn"`
+	ValueCurrent  interface{} `json:"valueCurrent"`
+	ValueRestored interface{} `json:"valueRestored"`
+}
+type DataLogRestoreFile struct {
+	AttributeId    uuid.UUID `json:"attributeId"`
+	FileId         uuid.UUID `json:"fileId"`
+	Action         string    `json:"action"`         // assign (file was removed since), delete (file was added since), rename, version
+	Name           string    `json:"name"`           // file name at log entry
+	NameCurrent    string    `json:"nameCurrent"`    // current file name, empty if file is not assigned anymore
+	Version        int64     `json:"version"`        // file version at log entry
+	VersionCurrent int64     `json:"versionCurrent"` // latest file version
+}
+
+// data recycle bin, records deleted from relations with soft delete
+type DataRecycled struct {
+	Id          int32       `json:"id"`
+	RelationId  uuid.UUID   `json:"relationId"`
+	RecordId    int64       `json:"recordId"`    // deleted record, cascaded records are restored/purged together with it
+	RecordCount int         `json:"recordCount"` // count of deleted records, incl. cascaded records
+	LoginId     pgtype.Int8 `json:"loginId"`     // login that deleted the record, NULL if login was removed
+	LoginName   pgtype.Text `json:"loginName"`
+	DateDeleted int64       `json:"dateDeleted"`
+}
+
+// data search, full text search over all searchable relations
+type DataSearch struct {
+	Dictionary string `json:"dictionary"` // dictionary used for search query, defaults to 'simple'
+	Limit      int    `json:"limit"`      // max. number of results, across all relations
+	Text       string `json:"text"`       // search text, websearch syntax (AND, OR, -negation, "phrase")
+}
+type DataSearchResult struct {
+	AttributeId uuid.UUID `json:"attributeId"` // attribute with best match
+	FormId      uuid.UUID `json:"formId"`      // form to open record with
+	RecordId    int64     `json:"recordId"`
+	RelationId  uuid.UUID `json:"relationId"`
+	Rank        float32   `json:"rank"`    // search rank, results are ordered by it
+	Snippet     string    `json:"snippet"` // text fragments of matching attribute, matches are enclosed in <mark></mark>
+}
+
+// data presence, logins having records open (shared across cluster nodes)
+type DataPresence struct {
+	LoginId    int64  `json:"loginId"`
+	LoginName  string `json:"loginName"`
+	Edit       bool   `json:"edit"`       // login is editing record
+	Lock       bool   `json:"lock"`       // login holds exclusive edit lock on record
+	DateExpiry int64  `json:"dateExpiry"` // presence expires if not renewed by client
+}
+type DataPresenceRecord struct {
+	RelationId uuid.UUID      `json:"relationId"`
+	RecordId   int64          `json:"recordId"`
+	Presence   []DataPresence `json:"presence"`
+}
+
+// geo point as GeoJSON geometry, coordinates are longitude/latitude
+type DataGeoPoint struct {
+	Type        string     `json:"type"` // always 'Point'
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// money value, amount is kept as text to avoid precision loss
+type DataMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"` // ISO 4217 currency code
+}
+
+// data change, records of relation were created, updated or deleted
+type DataChange struct {
+	RelationId uuid.UUID `json:"relationId"`
+	RecordIds  []int64   `json:"recordIds"`
+}
+
+// data change subscription of websocket client
+type DataSubscription struct {
+	Id         string    `json:"id"` // subscription ID, defined by client
+	RelationId uuid.UUID `json:"relationId"`
+	RecordIds  []int64   `json:"recordIds"` // subscribed records, empty = all records of relation
+}
@@ -1,6 +1,11 @@
 package types
 
-import "github.com/gofrs/uuid"
+import (
+	"encoding/json"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
 
 type Login struct {
 	Id   int64  `json:"id"`
@@ -30,7 +35,53 @@ type LoginTokenFixed struct {
 	Token      string `json:"token"`
 	DateCreate int64  `json:"dateCreate"`
 }
+type LoginImpersonation struct {
+	Id             int64  `json:"id"`
+	LoginId        int64  `json:"loginId"` // impersonated login
+	LoginName      string `json:"loginName"`
+	LoginIdAdmin   int64  `json:"loginIdAdmin"` // admin login that impersonated, 0 if admin login was deleted
+	LoginNameAdmin string `json:"loginNameAdmin"`
+	Reason         string `json:"reason"`
+	DateStart      int64  `json:"dateStart"`
+	DateEnd        int64  `json:"dateEnd"`
+	RequestCount   int64  `json:"requestCount"` // number of requests executed during impersonation
+}
+type LoginImpersonationLog struct {
+	DateMilli int64           `json:"dateMilli"`
+	Ressource string          `json:"ressource"`
+	Action    string          `json:"action"`
+	Payload   json.RawMessage `json:"payload"`
+}
+type LoginRoleDelegation struct {
+	Id                  int64       `json:"id"`
+	LoginId             int64       `json:"loginId"` // delegating login
+	LoginName           string      `json:"loginName"`
+	LoginIdSubstitute   int64       `json:"loginIdSubstitute"` // login that receives role
+	LoginNameSubstitute string      `json:"loginNameSubstitute"`
+	RoleId              uuid.UUID   `json:"roleId"`
+	DateFrom            int64       `json:"dateFrom"`
+	DateTo              int64       `json:"dateTo"`
+	DateCreated         int64       `json:"dateCreated"`
+	DateEnded           pgtype.Int8 `json:"dateEnded"` // set once delegation expired or was revoked
+	Revoked             bool        `json:"revoked"`
+}
+type LoginRoleLog struct {
+	Action         string      `json:"action"` // assignmentExpired, delegationCreated, delegationExpired, delegationRevoked
+	Date           int64       `json:"date"`
+	DelegationId   pgtype.Int8 `json:"delegationId"`
+	LoginIdActor   pgtype.Int8 `json:"loginIdActor"` // login that caused action, NULL if done by system
+	LoginNameActor pgtype.Text `json:"loginNameActor"`
+	RoleId         uuid.UUID   `json:"roleId"`
+}
+type LoginRoleConflict struct {
+	LoginId        int64     `json:"loginId"`
+	LoginName      string    `json:"loginName"`
+	LoginActive    bool      `json:"loginActive"`
+	RoleId         uuid.UUID `json:"roleId"`
+	RoleIdConflict uuid.UUID `json:"roleIdConflict"`
+}
 type LoginMfaToken struct {
-	Id   int64  `json:"id"`
-	Name string `json:"name"`
+	Id      int64  `json:"id"` // 0 = code sent via mail
+	Name    string `json:"name"`
+	Context string `json:"context"` // mail, totp
 }
@@ -67,19 +67,22 @@ type Article struct {
 	Captions CaptionMap `json:"captions"`
 }
 type Relation struct {
-	Id             uuid.UUID        `json:"id"`
-	ModuleId       uuid.UUID        `json:"moduleId"`
-	AttributeIdPk  uuid.UUID        `json:"attributeIdPk"`  // read only, ID of PK attribute
-	Name           string           `json:"name"`           // unique (within module) relation name
-	Comment        pgtype.Text      `json:"comment"`        // author comment
-	Encryption     bool             `json:"encryption"`     // relation supports encrypted attribute values
-	RetentionCount pgtype.Int4      `json:"retentionCount"` // minimum number of retained change events
-	RetentionDays  pgtype.Int4      `json:"retentionDays"`  // minimum age of retained change events
-	Attributes     []Attribute      `json:"attributes"`     // read only, all relation attributes
-	Indexes        []PgIndex        `json:"indexes"`        // read only, all relation indexes
-	Policies       []RelationPolicy `json:"policies"`       // read only, all relation policies
-	Presets        []Preset         `json:"presets"`        // read only, all relation presets
-	Triggers       []PgTrigger      `json:"triggers"`       // read only, all relation triggers
+	Id               uuid.UUID        `json:"id"`
+	ModuleId         uuid.UUID        `json:"moduleId"`
+	AttributeIdPk    uuid.UUID        `json:"attributeIdPk"`    // read only, ID of PK attribute
+	Name             string           `json:"name"`             // unique (within module) relation name
+	Comment          pgtype.Text      `json:"comment"`          // author comment
+	Encryption       bool             `json:"encryption"`       // relation supports encrypted attribute values
+	RetentionCount   pgtype.Int4      `json:"retentionCount"`   // minimum number of retained change events
+	RetentionDays    pgtype.Int4      `json:"retentionDays"`    // minimum age of retained change events
+	SoftDelete       bool             `json:"softDelete"`       // deleted records are kept in recycle bin until purged
+	SearchFormId     pgtype.UUID      `json:"searchFormId"`     // relation is included in global search if set, form opens found records
+	DataChangeNotify bool             `json:"dataChangeNotify"` // changes are notified to subscribed clients, incl. direct SQL changes
+	Attributes       []Attribute      `json:"attributes"`       // read only, all relation attributes
+	Indexes          []PgIndex        `json:"indexes"`          // read only, all relation indexes
+	Policies         []RelationPolicy `json:"policies"`         // read only, all relation policies
+	Presets          []Preset         `json:"presets"`          // read only, all relation presets
+	Triggers         []PgTrigger      `json:"triggers"`         // read only, all relation triggers
 }
 type RelationPolicy struct {
 	RoleId           uuid.UUID     `json:"roleId"`
@@ -105,20 +108,29 @@ type PresetValue struct {
 	Value         string      `json:"value"`
 }
 type Attribute struct {
-	Id             uuid.UUID   `json:"id"`
-	RelationId     uuid.UUID   `json:"relationId"`     // attribute belongs to this relation
-	RelationshipId pgtype.UUID `json:"relationshipId"` // ID of target relation
-	IconId         pgtype.UUID `json:"iconId"`         // default icon
-	Name           string      `json:"name"`           // name, used as table column
-	Content        string      `json:"content"`        // content (integer, varchar, text, real, uuid, files, n:1, ...)
-	ContentUse     string      `json:"contentUse"`     // content use (default, richtext, color, datetime, ...)
-	Length         int         `json:"length"`         // varchar length or max file size in KB (files attribute)
-	Nullable       bool        `json:"nullable"`       // value is nullable
-	Encrypted      bool        `json:"encrypted"`      // value is encrypted (end-to-end for logins)
-	Def            string      `json:"def"`            // default value
-	OnUpdate       string      `json:"onUpdate"`       // relationship attribute, action on 'UPDATE'
-	OnDelete       string      `json:"onDelete"`       // relationship attribute, action on 'DELETE'
-	Captions       CaptionMap  `json:"captions"`
+	Id             uuid.UUID           `json:"id"`
+	RelationId     uuid.UUID           `json:"relationId"`     // attribute belongs to this relation
+	RelationshipId pgtype.UUID         `json:"relationshipId"` // ID of target relation
+	IconId         pgtype.UUID         `json:"iconId"`         // default icon
+	Name           string              `json:"name"`           // name, used as table column
+	Content        string              `json:"content"`        // content (integer, varchar, text, real, uuid, files, n:1, ...)
+	ContentUse     string              `json:"contentUse"`     // content use (default, richtext, color, datetime, ...)
+	Length         int                 `json:"length"`         // varchar length or max file size in KB (files attribute)
+	Nullable       bool                `json:"nullable"`       // value is nullable
+	Encrypted      bool                `json:"encrypted"`      // value is encrypted (end-to-end for logins)
+	Def            string              `json:"def"`            // default value
+	OnUpdate       string              `json:"onUpdate"`       // relationship attribute, action on 'UPDATE'
+	OnDelete       string              `json:"onDelete"`       // relationship attribute, action on 'DELETE'
+	Expression     string              `json:"expression"`     // computed attribute, SQL expression referring to attributes of same relation by ID: (ATTRIBUTE_ID)
+	Validation     AttributeValidation `json:"validation"`     // rules to check values against
+	Captions       CaptionMap          `json:"captions"`
+}
+type AttributeValidation struct {
+	Regex      pgtype.Text    `json:"regex"`      // text must match regular expression
+	Min        pgtype.Numeric `json:"min"`        // min. text length, number or duration (seconds)
+	Max        pgtype.Numeric `json:"max"`        // max. text length, number or duration (seconds)
+	Values     []string       `json:"values"`     // allowed texts or numbers, any value if empty
+	Expression pgtype.Text    `json:"expression"` // SQL expression to check record against, attributes referred to by ID: (ATTRIBUTE_ID)
 }
 type Menu struct {
 	Id           uuid.UUID            `json:"id"`
@@ -418,6 +430,22 @@ type FieldList struct {
 	AttributeIdRecord pgtype.UUID `json:"attributeIdRecord"`
 	FormIdOpen        pgtype.UUID `json:"formIdOpen"`
 }
+type FieldMap struct {
+	Id               uuid.UUID            `json:"id"`
+	TabId            pgtype.UUID          `json:"tabId"`
+	IconId           pgtype.UUID          `json:"iconId"`
+	Content          string               `json:"content"`
+	State            string               `json:"state"`
+	OnMobile         bool                 `json:"onMobile"`
+	AttributeIdGeo   uuid.UUID            `json:"attributeIdGeo"` // geo point attribute to place records on map with
+	AttributeIdColor pgtype.UUID          `json:"attributeIdColor"`
+	IndexGeo         int                  `json:"indexGeo"`
+	IndexColor       pgtype.Int4          `json:"indexColor"`
+	Columns          []Column             `json:"columns"`
+	Collections      []CollectionConsumer `json:"collections"` // collections to select values for query filters
+	OpenForm         OpenForm             `json:"openForm"`
+	Query            Query                `json:"query"`
+}
 type FieldTabs struct {
 	Id       uuid.UUID   `json:"id"`
 	TabId    pgtype.UUID `json:"tabId"`
@@ -471,6 +499,7 @@ type Role struct {
 	Id                uuid.UUID         `json:"id"`
 	ModuleId          uuid.UUID         `json:"moduleId"`
 	ChildrenIds       []uuid.UUID       `json:"childrenIds"`
+	ConflictIds       []uuid.UUID       `json:"conflictIds"` // roles that must not be granted to the same login, incl. inherited roles
 	Name              string            `json:"name"`
 	Content           string            `json:"content"`
 	Assignable        bool              `json:"assignable"`
@@ -479,8 +508,16 @@ type Role struct {
 	AccessCollections map[uuid.UUID]int `json:"accessCollections"`
 	AccessMenus       map[uuid.UUID]int `json:"accessMenus"`
 	AccessRelations   map[uuid.UUID]int `json:"accessRelations"`
+	AccessNetworks    []string          `json:"accessNetworks"` // CIDR ranges from which role is granted, empty = any network
+	AccessTimezone    string            `json:"accessTimezone"` // timezone in which access windows are evaluated
+	AccessWindows     []RoleWindow      `json:"accessWindows"`  // time windows during which role is granted, empty = any time
 	Captions          CaptionMap        `json:"captions"`
 }
+type RoleWindow struct {
+	Weekdays []int `json:"weekdays"` // 0 = sunday, 6 = saturday
+	TimeFrom int   `json:"timeFrom"` // seconds after midnight, inclusive
+	TimeTo   int   `json:"timeTo"`   // seconds after midnight, exclusive
+}
 type PgFunction struct {
 	Id             uuid.UUID            `json:"id"`
 	ModuleId       uuid.UUID            `json:"moduleId"`
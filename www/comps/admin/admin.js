@@ -47,6 +47,12 @@ let MyAdmin = {
 						<span>{{ capApp.navigationLoginTemplates }}</span>
 					</router-link>
 					
+					<!-- self-registrations -->
+					<router-link class="entry clickable" tag="div" to="/admin/registrations">
+						<img src="images/person.png" />
+						<span>{{ capApp.navigationRegistrations }}</span>
+					</router-link>
+					
 					<!-- modules -->
 					<router-link class="entry clickable" tag="div" to="/admin/modules">
 						<img src="images/builder.png" />
@@ -101,6 +107,12 @@ let MyAdmin = {
 						<span>{{ capApp.navigationScheduler }}</span>
 					</router-link>
 					
+					<!-- slow queries -->
+					<router-link class="entry clickable" tag="div" to="/admin/slowqueries">
+						<img src="images/speedmeter.png" />
+						<span>{{ capApp.navigationSlowQueries }}</span>
+					</router-link>
+					
 					
 					<!-- REI3 Professional -->
 					<router-link class="entry clickable separator" tag="div" to="/admin/license">
@@ -120,6 +132,12 @@ let MyAdmin = {
 						<span>{{ capApp.navigationLdaps }}</span>
 					</router-link>
 					
+					<!-- SCIM -->
+					<router-link class="entry clickable" tag="div" to="/admin/scim" :class="{ inactive:!activated }">
+						<img src="images/link.png" />
+						<span>{{ capApp.navigationScim }}</span>
+					</router-link>
+					
 					<!-- cluster -->
 					<router-link class="entry clickable" tag="div" to="/admin/cluster" :class="{ inactive:!activated }">
 						<img src="images/cluster.png" />
@@ -184,9 +202,12 @@ let MyAdmin = {
 			if(s.$route.path.includes('mailspooler'))    return s.capApp.navigationMailSpooler;
 			if(s.$route.path.includes('mailtraffic'))    return s.capApp.navigationMailTraffic;
 			if(s.$route.path.includes('modules'))        return s.capApp.navigationModules;
+			if(s.$route.path.includes('registrations'))  return s.capApp.navigationRegistrations;
 			if(s.$route.path.includes('repo'))           return s.capApp.navigationRepo;
 			if(s.$route.path.includes('roles'))          return s.capApp.navigationRoles;
 			if(s.$route.path.includes('scheduler'))      return s.capApp.navigationScheduler;
+			if(s.$route.path.includes('scim'))           return s.capApp.navigationScim;
+			if(s.$route.path.includes('slowqueries'))    return s.capApp.navigationSlowQueries;
 			return '';
 		},
 		licenseTitle:(s) => !s.activated
@@ -1,4 +1,5 @@
 import {getBuildFromVersion} from '../shared/generic.js';
+import {getUnixFormat}       from '../shared/time.js';
 export {MyAdminConfig as default};
 
 let MyAdminConfig = {
@@ -304,6 +305,18 @@ let MyAdminConfig = {
 						<td>{{ capApp.bruteforceAttempts }}</td>
 						<td><input v-model="configInput.bruteforceAttempts" /></td>
 					</tr>
+					<tr>
+						<td>{{ capApp.bruteforceBlockMinutes }}</td>
+						<td><input v-model="configInput.bruteforceBlockMinutes" :placeholder="capApp.bruteforceBlockMinutesHint" /></td>
+					</tr>
+					<tr>
+						<td>{{ capApp.bruteforceIpv6Prefix }}</td>
+						<td><input v-model="configInput.bruteforceIpv6Prefix" /></td>
+					</tr>
+					<tr>
+						<td>{{ capApp.proxiesTrusted }}</td>
+						<td><input v-model="configInput.proxiesTrusted" :placeholder="capApp.proxiesTrustedHint" /></td>
+					</tr>
 					<tr>
 						<td>{{ capApp.bruteforceCountTracked }}</td>
 						<td>{{ bruteforceCountTracked }}</td>
@@ -313,6 +326,173 @@ let MyAdminConfig = {
 						<td>{{ bruteforceCountBlocked }}</td>
 					</tr>
 				</table>
+				
+				<!-- blocked hosts -->
+				<table class="table-default shade" v-if="bruteforceBlocks.length !== 0">
+					<thead>
+						<tr>
+							<th>{{ capApp.bruteforceHost }}</th>
+							<th>{{ capApp.bruteforceDateBlocked }}</th>
+							<th>{{ capApp.bruteforceDateExpiry }}</th>
+							<th>{{ capApp.bruteforceNode }}</th>
+							<th></th>
+						</tr>
+					</thead>
+					<tbody>
+						<tr v-for="b in bruteforceBlocks" :key="b.host">
+							<td>{{ b.host }}</td>
+							<td>{{ displayDate(b.dateBlocked) }}</td>
+							<td>{{ b.dateExpiry !== null ? displayDate(b.dateExpiry) : '-' }}</td>
+							<td>{{ b.nodeName }}</td>
+							<td>
+								<my-button image="delete.png"
+									@trigger="bruteforceUnblock(b.host)"
+									:cancel="true"
+									:caption="capApp.button.bruteforceUnblock"
+								/>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+			</div>
+			
+			<!-- client certificates -->
+			<div class="contentPart">
+				<div class="contentPartHeader">
+					<img class="icon" src="images/key.png" />
+					<h1>{{ capApp.clientCertTitle }}</h1>
+				</div>
+				
+				<table class="default-inputs">
+					<tr>
+						<td>{{ capApp.clientCertAuth }}</td>
+						<td>
+							<my-bool-string-number
+								v-model="configInput.clientCertAuth"
+							/>
+						</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.clientCertLoginMap }}</td>
+						<td>
+							<select v-model="configInput.clientCertLoginMap">
+								<option value="subjectCn">{{ capApp.clientCertLoginMapSubjectCn }}</option>
+								<option value="sanEmail">{{ capApp.clientCertLoginMapSanEmail }}</option>
+								<option value="sanDns">{{ capApp.clientCertLoginMapSanDns }}</option>
+							</select>
+						</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.clientCertCaBundle }}</td>
+						<td>
+							<textarea v-model="configInput.clientCertCaBundle"
+								:placeholder="capApp.clientCertCaBundleHint"
+							></textarea>
+						</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.clientCertRevokedAdd }}</td>
+						<td>
+							<div class="column gap">
+								<input v-model="certRevokedInputSerial"
+									:placeholder="capApp.clientCertRevokedSerialHint"
+								/>
+								<input v-model="certRevokedInputComment"
+									:placeholder="capApp.clientCertRevokedCommentHint"
+								/>
+								<div>
+									<my-button image="add.png"
+										@trigger="certRevokedAdd"
+										:active="certRevokedInputSerial !== ''"
+										:caption="capGen.button.add"
+									/>
+								</div>
+							</div>
+						</td>
+					</tr>
+				</table>
+				
+				<!-- revoked certificates -->
+				<table class="table-default shade" v-if="certsRevoked.length !== 0">
+					<thead>
+						<tr>
+							<th>{{ capApp.clientCertRevokedSerial }}</th>
+							<th>{{ capApp.clientCertRevokedComment }}</th>
+							<th>{{ capApp.clientCertRevokedDate }}</th>
+							<th></th>
+						</tr>
+					</thead>
+					<tbody>
+						<tr v-for="c in certsRevoked" :key="c.serial">
+							<td>{{ c.serial }}</td>
+							<td>{{ c.comment !== null ? c.comment : '-' }}</td>
+							<td>{{ displayDate(c.dateRevoked) }}</td>
+							<td>
+								<my-button image="delete.png"
+									@trigger="certRevokedDel(c.serial)"
+									:cancel="true"
+									:caption="capGen.button.delete"
+								/>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+			</div>
+			
+			<!-- multi-factor authentication -->
+			<div class="contentPart">
+				<div class="contentPartHeader">
+					<img class="icon" src="images/lock.png" />
+					<h1>{{ capApp.mfaTitle }}</h1>
+				</div>
+				
+				<table class="default-inputs">
+					<tr>
+						<td>{{ capApp.mfaRequired }}</td>
+						<td>
+							<my-bool-string-number
+								@update:modelValue="informMfaRequired"
+								v-model="configInput.mfaRequired"
+							/>
+						</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.mfaMail }}</td>
+						<td>
+							<my-bool-string-number
+								v-model="configInput.mfaMail"
+							/>
+						</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.mfaMailAccountId }}</td>
+						<td>
+							<select v-model="configInput.mfaMailAccountId">
+								<option value="0">{{ capApp.mfaMailAccountAny }}</option>
+								<option
+									v-for="a in mailAccounts"
+									:value="String(a.id)"
+								>{{ a.name }}</option>
+							</select>
+						</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.mfaMailCodeMinutes }}</td>
+						<td><input v-model="configInput.mfaMailCodeMinutes" /></td>
+					</tr>
+					<tr>
+						<td>{{ capApp.mfaMailSubject }}</td>
+						<td><input v-model="configInput.mfaMailSubject" /></td>
+					</tr>
+					<tr>
+						<td>{{ capApp.mfaMailBody }}</td>
+						<td>
+							<textarea v-model="configInput.mfaMailBody"
+								:placeholder="capApp.mfaMailBodyHint"
+							></textarea>
+						</td>
+					</tr>
+				</table>
 			</div>
 		</div>
 	</div>`,
@@ -332,8 +512,13 @@ let MyAdminConfig = {
 		return {
 			ready:false,
 			configInput:{},
+			bruteforceBlocks:[],
 			bruteforceCountBlocked:0,
 			bruteforceCountTracked:0,
+			certRevokedInputComment:'',
+			certRevokedInputSerial:'',
+			certsRevoked:[],
+			mailAccounts:[],
 			publicKeyInputName:'',
 			publicKeyInputValue:''
 		};
@@ -370,6 +555,7 @@ let MyAdminConfig = {
 		token:       (s) => s.$store.getters['local/token'],
 		modules:     (s) => s.$store.getters['schema/modules'],
 		config:      (s) => s.$store.getters.config,
+		settings:    (s) => s.$store.getters.settings,
 		license:     (s) => s.$store.getters.license,
 		licenseDays: (s) => s.$store.getters.licenseDays,
 		licenseValid:(s) => s.$store.getters.licenseValid,
@@ -379,6 +565,12 @@ let MyAdminConfig = {
 	methods:{
 		// externals
 		getBuildFromVersion,
+		getUnixFormat,
+		
+		// presentation
+		displayDate(date) {
+			return this.getUnixFormat(date,[this.settings.dateFormat,'H:i:S'].join(' '));
+		},
 		
 		informBuilderMode() {
 			if(this.configInput.builderMode === '0')
@@ -389,6 +581,15 @@ let MyAdminConfig = {
 				captionTop:this.capApp.dialog.pleaseRead
 			});
 		},
+		informMfaRequired() {
+			if(this.configInput.mfaRequired !== '1')
+				return;
+			
+			this.$store.commit('dialog',{
+				captionBody:this.capApp.dialog.mfaRequired,
+				captionTop:this.capApp.dialog.pleaseRead
+			});
+		},
 		informProductionMode() {
 			if(this.configInput.productionMode !== '0')
 				return;
@@ -427,6 +628,41 @@ let MyAdminConfig = {
 				},
 				this.$root.genericError
 			);
+			ws.send('bruteforce','getBlocked',{},true).then(
+				res => this.bruteforceBlocks = res.payload,
+				this.$root.genericError
+			);
+			ws.send('clientCert','getRevoked',{},true).then(
+				res => this.certsRevoked = res.payload,
+				this.$root.genericError
+			);
+			ws.send('mailAccount','get',{},true).then(
+				res => this.mailAccounts = Object.values(res.payload.accounts).filter(v => v.mode === 'smtp'),
+				this.$root.genericError
+			);
+		},
+		bruteforceUnblock(host) {
+			ws.send('bruteforce','del',{host:host},true).then(
+				this.get, this.$root.genericError
+			);
+		},
+		certRevokedAdd() {
+			ws.send('clientCert','setRevoked',{
+				serial:this.certRevokedInputSerial,
+				comment:this.certRevokedInputComment
+			},true).then(
+				() => {
+					this.certRevokedInputComment = '';
+					this.certRevokedInputSerial  = '';
+					this.get();
+				},
+				this.$root.genericError
+			);
+		},
+		certRevokedDel(serial) {
+			ws.send('clientCert','delRevoked',{serial:serial},true).then(
+				this.get, this.$root.genericError
+			);
 		},
 		set() {
 			ws.send('config','set',this.configInput,true).then(
@@ -44,6 +44,10 @@ let MyAdminFiles = {
 					<td>{{ capApp.filesKeepDaysDeleted }}</td>
 					<td><input v-model="configInput.filesKeepDaysDeleted" /></td>
 				</tr>
+				<tr>
+					<td>{{ capApp.recycleBinKeepDays }}</td>
+					<td><input v-model="configInput.recycleBinKeepDays" /></td>
+				</tr>
 			</table>
 			
 			<br />
@@ -105,6 +109,52 @@ let MyAdminFiles = {
 					</template>
 				</tbody>
 			</table>
+			
+			<br />
+			
+			<!-- deleted records of relations with soft delete -->
+			<div class="contentPartHeader">
+				<img class="icon" src="images/delete.png" />
+				<h1>{{ capApp.titleRecycleBin }}</h1>
+			</div>
+			
+			<table class="table-default default-inputs shade">
+				<thead>
+					<tr>
+						<th>{{ capGen.actions }}</th>
+						<th>{{ capApp.relation }}</th>
+						<th>{{ capGen.record }}</th>
+						<th>{{ capApp.recordCount }}</th>
+						<th>{{ capApp.deleteDate }}</th>
+						<th>{{ capApp.deleteLogin }}</th>
+					</tr>
+				</thead>
+				<tbody>
+					<tr v-if="recycled.length === 0">
+						<td colspan="999">{{ capGen.nothingThere }}</td>
+					</tr>
+					<tr v-for="r in recycled">
+						<td class="minimum">
+							<div class="row gap">
+								<my-button image="time.png"
+									@trigger="restoreRecycled(r.id)"
+									:caption="capGen.button.restore"
+								/>
+								<my-button image="delete.png"
+									@trigger="purgeRecycledAsk(r.id)"
+									:cancel="true"
+									:caption="capApp.button.purge"
+								/>
+							</div>
+						</td>
+						<td>{{ displayRelation(r.relationId) }}</td>
+						<td>{{ r.recordId }}</td>
+						<td>{{ r.recordCount }}</td>
+						<td>{{ displayTime(r.dateDeleted) }}</td>
+						<td>{{ r.loginName !== null ? r.loginName : '-' }}</td>
+					</tr>
+				</tbody>
+			</table>
 		</div>
 	</div>`,
 	emits:['hotkeysRegister'],
@@ -115,7 +165,8 @@ let MyAdminFiles = {
 		return {
 			attributeIdMapDeleted:{},
 			attributeIdsShowDeleted:[],
-			configInput:{}
+			configInput:{},
+			recycled:[] // recycle bin entries of deleted records
 		};
 	},
 	mounted() {
@@ -152,6 +203,10 @@ let MyAdminFiles = {
 			let m = this.moduleIdMap[r.moduleId];
 			return `${m.name} -> ${r.name} -> ${a.name} (${cnt})`;
 		},
+		displayRelation(relationId) {
+			let r = this.relationIdMap[relationId];
+			return r === undefined ? '-' : `${this.moduleIdMap[r.moduleId].name} -> ${r.name}`;
+		},
 		displayTime(unixTime) {
 			return unixTime === 0 ? '-' : this.getUnixFormat(unixTime,'Y-m-d H:i:S');
 		},
@@ -161,6 +216,20 @@ let MyAdminFiles = {
 			this.configInput = JSON.parse(JSON.stringify(this.config));
 			this.get();
 		},
+		purgeRecycledAsk(id) {
+			this.$store.commit('dialog',{
+				captionBody:this.capApp.dialog.purge,
+				buttons:[{
+					cancel:true,
+					caption:this.capApp.button.purge,
+					exec:() => this.purgeRecycled(id),
+					image:'delete.png'
+				},{
+					caption:this.capGen.button.cancel,
+					image:'cancel.png'
+				}]
+			});
+		},
 		toggleShow(atrId) {
 			let v = this.attributeIdsShowDeleted;
 			let pos = v.indexOf(atrId);
@@ -170,8 +239,20 @@ let MyAdminFiles = {
 		
 		// backend calls
 		get() {
-			ws.send('file','get',{},true).then(
-				res => this.attributeIdMapDeleted = res.payload.attributeIdMapDeleted,
+			ws.sendMultiple([
+				ws.prepare('file','get',{}),
+				ws.prepare('recycleBin','get',{})
+			],true).then(
+				res => {
+					this.attributeIdMapDeleted = res[0].payload.attributeIdMapDeleted;
+					this.recycled              = res[1].payload;
+				},
+				this.$root.genericError
+			);
+		},
+		purgeRecycled(id) {
+			ws.send('recycleBin','purge',{id:id},true).then(
+				this.get,
 				this.$root.genericError
 			);
 		},
@@ -185,6 +266,12 @@ let MyAdminFiles = {
 				this.$root.genericError
 			);
 		},
+		restoreRecycled(id) {
+			ws.send('recycleBin','restore',{id:id},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
 		set() {
 			ws.send('config','set',this.configInput,true).then(
 				() => {},
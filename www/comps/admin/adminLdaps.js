@@ -1,4 +1,5 @@
 import {hasAnyAssignableRole} from '../shared/access.js';
+import {getUnixFormat}         from '../shared/time.js';
 export {MyAdminLdaps as default};
 
 let MyAdminLdaps = {
@@ -35,7 +36,7 @@ let MyAdminLdaps = {
 							<td>
 								<div class="row gap">
 									<my-button image="download.png"
-										@trigger="runImport(l.id)"
+										@trigger="runImport(l.id,false)"
 										:active="licenseValid"
 										:caption="capApp.button.import"
 									/>
@@ -73,6 +74,12 @@ let MyAdminLdaps = {
 						@trigger="runCheck"
 						:caption="capApp.button.test"
 					/>
+					<my-button image="download.png"
+						v-if="!isNew"
+						@trigger="runImport(idEdit,true)"
+						:active="!hasChanges"
+						:caption="capApp.button.importFull"
+					/>
 					<my-button image="delete.png"
 						v-if="!isNew"
 						@trigger="delAsk"
@@ -110,6 +117,26 @@ let MyAdminLdaps = {
 							<td>{{ capApp.port }}</td>
 							<td><input v-model.number="port" :placeholder="capApp.portHint" /></td>
 						</tr>
+						<tr>
+							<td>{{ capApp.hostsFailover }}</td>
+							<td>
+								<div class="column gap">
+									<div class="row gap" v-for="(h,i) in hostsFailover">
+										<input v-model="hostsFailover[i]" :placeholder="capApp.hostsFailoverHint" />
+										<my-button image="delete.png"
+											@trigger="hostsFailover.splice(i,1)"
+											:cancel="true"
+										/>
+									</div>
+									<div>
+										<my-button image="add.png"
+											@trigger="hostsFailover.push('')"
+											:caption="capGen.button.add"
+										/>
+									</div>
+								</div>
+							</td>
+						</tr>
 						<tr>
 							<td>{{ capApp.bindUserDn }}</td>
 							<td><input v-model="bindUserDn" :placeholder="capApp.bindUserDnHint" /></td>
@@ -169,6 +196,13 @@ let MyAdminLdaps = {
 							<td><span v-html="capApp.assignRoles" /></td>
 							<td><my-bool v-model="assignRoles" /></td>
 						</tr>
+						<tr v-if="assignRoles && !msAdExt">
+							<td>{{ capApp.nestedGroups }}</td>
+							<td>
+								<my-bool v-model="nestedGroups" />
+								<span>{{ capApp.nestedGroupsHint }}</span>
+							</td>
+						</tr>
 						<tr v-if="showExpert && assignRoles">
 							<td>{{ capApp.memberAttribute }}</td>
 							<td>
@@ -177,6 +211,22 @@ let MyAdminLdaps = {
 								/>
 							</td>
 						</tr>
+						<tr>
+							<td>{{ capApp.deltaSync }}</td>
+							<td>
+								<my-bool v-model="deltaSync" />
+								<span>{{ capApp.deltaSyncHint }}</span>
+							</td>
+						</tr>
+						<tr v-if="deltaSync">
+							<td>{{ capApp.fullSyncInterval }}</td>
+							<td>
+								<div class="row gap centered">
+									<input v-model.number="fullSyncInterval" />
+									<span>{{ capApp.fullSyncIntervalHint }}</span>
+								</div>
+							</td>
+						</tr>
 					</tbody>
 				</table>
 				
@@ -230,6 +280,124 @@ let MyAdminLdaps = {
 						</tbody>
 					</table>
 				</template>
+				
+				<template v-if="!isNew && hostStates.length !== 0">
+					<h2 class="roles-title">{{ capApp.titleHosts }}</h2>
+					<table class="table-default shade">
+						<thead>
+							<tr>
+								<th>{{ capApp.host }}</th>
+								<th>{{ capApp.hostHealthy }}</th>
+								<th>{{ capApp.hostFailures }}</th>
+								<th>{{ capApp.hostDateOk }}</th>
+								<th>{{ capApp.hostDateFailed }}</th>
+								<th>{{ capApp.runError }}</th>
+							</tr>
+						</thead>
+						<tbody>
+							<tr v-for="h in hostStates">
+								<td>{{ h.host }}</td>
+								<td><my-bool :modelValue="h.healthy" :readonly="true" /></td>
+								<td>{{ h.failures }}</td>
+								<td>{{ h.dateOk !== 0 ? displayDate(h.dateOk) : '-' }}</td>
+								<td>{{ h.dateFailed !== 0 ? displayDate(h.dateFailed) : '-' }}</td>
+								<td :title="h.lastError">{{ h.lastError !== '' ? h.lastError : '-' }}</td>
+							</tr>
+						</tbody>
+					</table>
+				</template>
+				
+				<template v-if="!isNew">
+					<h2 class="roles-title">{{ capApp.titleRuns }}</h2>
+					<span v-if="runs.length === 0">{{ capApp.runsNone }}</span>
+					<table class="table-default shade" v-if="runs.length !== 0">
+						<thead>
+							<tr>
+								<th>{{ capApp.runDate }}</th>
+								<th>{{ capApp.runType }}</th>
+								<th>{{ capApp.runDuration }}</th>
+								<th>{{ capApp.runCreated }}</th>
+								<th>{{ capApp.runUpdated }}</th>
+								<th>{{ capApp.runDeactivated }}</th>
+								<th>{{ capApp.runFailed }}</th>
+								<th>{{ capApp.runError }}</th>
+							</tr>
+						</thead>
+						<tbody>
+							<tr v-for="r in runs">
+								<td>{{ displayDate(r.dateStart) }}</td>
+								<td>{{ r.fullSync ? capApp.runTypeFull : capApp.runTypeDelta }}</td>
+								<td>{{ (r.durationMs / 1000).toFixed(1) + 's' }}</td>
+								<td>{{ r.countCreated }}</td>
+								<td>{{ r.countUpdated }}</td>
+								<td>{{ r.countDeactivated }}</td>
+								<td>{{ r.countFailed }}</td>
+								<td :title="r.error">{{ r.error !== null ? r.error : '-' }}</td>
+							</tr>
+						</tbody>
+					</table>
+				</template>
+				
+				<h2 class="roles-title">{{ capApp.titleRecord }}</h2>
+				<table class="default-inputs">
+					<tbody>
+						<tr>
+							<td>{{ capApp.loginRecordAttribute }}</td>
+							<td>
+								<select v-model="loginRecordAttributeId">
+									<option :value="null">-</option>
+									<option v-for="lf in loginForms" :value="lf.attributeIdLogin">
+										{{ displayAttribute(lf.attributeIdLogin) }}
+									</option>
+								</select>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+				
+				<template v-if="loginRecordAttributeId !== null">
+					<br />
+					<div>
+						<my-button image="add.png"
+							@trigger="recordAttributeAdd()"
+							:caption="capGen.button.add"
+						/>
+					</div>
+					<br />
+					
+					<table v-if="recordAttributes.length !== 0">
+						<thead>
+							<tr>
+								<th>{{ capApp.ldapAttribute }}</th>
+								<th>{{ capApp.recordAttribute }}</th>
+								<th></th>
+							</tr>
+						</thead>
+						<tbody>
+							<tr v-for="(a,i) in recordAttributes" class="default-inputs">
+								<td>
+									<input v-model="a.ldapAttribute"
+										:placeholder="capApp.ldapAttributeHint"
+									/>
+								</td>
+								<td>
+									<select v-model="a.attributeId">
+										<option :value="null">-</option>
+										<option v-for="atr in recordAttributesAvailable" :value="atr.id">
+											{{ atr.name }}
+										</option>
+									</select>
+								</td>
+								<td>
+									<my-button image="delete.png"
+										@trigger="recordAttributeRemove(i)"
+										:cancel="true"
+									/>
+								</td>
+							</tr>
+						</tbody>
+					</table>
+				</template>
 			</div>
 		</div>
 	</div>`,
@@ -256,15 +424,25 @@ let MyAdminLdaps = {
 			tls:'',
 			tlsVerify:'',
 			roles:'',
+			loginRecordAttributeId:'',
+			recordAttributes:'',
+			deltaSync:'',
+			fullSyncInterval:'',
+			hostsFailover:'',
+			nestedGroups:'',
 			
 			// states
 			idEdit:-1,         // ID of LDAP connection being edited (0 = new)
 			inputKeys:['name','host','port','bindUserDn','bindUserPw',
 				'keyAttribute','loginAttribute','loginTemplateId',
 				'memberAttribute','searchClass','searchDn','assignRoles',
-				'msAdExt','starttls','tls','tlsVerify','roles'],
+				'msAdExt','starttls','tls','tlsVerify','roles',
+				'loginRecordAttributeId','recordAttributes','deltaSync',
+				'fullSyncInterval','hostsFailover','nestedGroups'],
 			inputsOrg:{},      // map of original input values, key = input key
 			ldaps:[],
+			hostStates:[],     // connection states of hosts of LDAP connection being edited
+			runs:[],           // import runs of LDAP connection being edited
 			showExpert:false,
 			templates:[]
 		};
@@ -284,21 +462,55 @@ let MyAdminLdaps = {
 			}
 			return false;
 		},
+		loginForms:(s) => {
+			let out = [];
+			for(let m of s.modules) {
+				for(let lf of m.loginForms) {
+					out.push(lf);
+				}
+			}
+			return out;
+		},
+		recordAttributesAvailable:(s) => {
+			if(s.loginRecordAttributeId === null || typeof s.attributeIdMap[s.loginRecordAttributeId] === 'undefined')
+				return [];
+			
+			let rel = s.relationIdMap[s.attributeIdMap[s.loginRecordAttributeId].relationId];
+			return rel.attributes.filter(v => ['text','varchar'].includes(v.content) && !v.encrypted);
+		},
 		
 		// simple
 		isNew:(s) => s.idEdit === 0,
 		
 		// stores
-		modules:     (s) => s.$store.getters['schema/modules'],
-		roleIdMap:   (s) => s.$store.getters['schema/roleIdMap'],
+		modules:       (s) => s.$store.getters['schema/modules'],
+		moduleIdMap:   (s) => s.$store.getters['schema/moduleIdMap'],
+		attributeIdMap:(s) => s.$store.getters['schema/attributeIdMap'],
+		relationIdMap: (s) => s.$store.getters['schema/relationIdMap'],
+		roleIdMap:     (s) => s.$store.getters['schema/roleIdMap'],
 		capApp:      (s) => s.$store.getters.captions.admin.ldaps,
 		capGen:      (s) => s.$store.getters.captions.generic,
-		licenseValid:(s) => s.$store.getters.licenseValid
+		licenseValid:(s) => s.$store.getters.licenseValid,
+		settings:    (s) => s.$store.getters.settings
 	},
 	methods:{
 		// externals
+		getUnixFormat,
 		hasAnyAssignableRole,
 		
+		// presentation
+		displayDate(date) {
+			return this.getUnixFormat(date,[this.settings.dateFormat,'H:i:S'].join(' '));
+		},
+		displayAttribute(attributeId) {
+			if(typeof this.attributeIdMap[attributeId] === 'undefined')
+				return attributeId;
+			
+			let atr = this.attributeIdMap[attributeId];
+			let rel = this.relationIdMap[atr.relationId];
+			return `${this.moduleIdMap[rel.moduleId].name}: ${rel.name}.${atr.name}`;
+		},
+		
 		// actions
 		close() {
 			this.idEdit = -1;
@@ -321,7 +533,13 @@ let MyAdminLdaps = {
 				starttls:false,
 				tls:true,
 				tlsVerify:true,
-				roles:[]
+				roles:[],
+				loginRecordAttributeId:null,
+				recordAttributes:[],
+				deltaSync:false,
+				fullSyncInterval:24,
+				hostsFailover:[],
+				nestedGroups:false
 			};
 			
 			if(id > 0) {
@@ -341,7 +559,14 @@ let MyAdminLdaps = {
 				this[k]           = JSON.parse(JSON.stringify(ldap[k]));
 				this.inputsOrg[k] = JSON.parse(JSON.stringify(ldap[k]));
 			}
-			this.idEdit = id;
+			this.idEdit     = id;
+			this.hostStates = [];
+			this.runs       = [];
+			
+			if(id > 0) {
+				this.getHostStates();
+				this.getRuns();
+			}
 		},
 		roleAdd() {
 			this.roles.push({
@@ -353,16 +578,32 @@ let MyAdminLdaps = {
 		roleRemove(i) {
 			this.roles.splice(i,1);
 		},
+		recordAttributeAdd() {
+			this.recordAttributes.push({
+				ldapId:this.idEdit,
+				attributeId:null,
+				ldapAttribute:''
+			});
+		},
+		recordAttributeRemove(i) {
+			this.recordAttributes.splice(i,1);
+		},
 		
 		// backend calls
-		runImport(id) {
-			ws.send('ldap','import',{id:id},true).then(
+		runImport(id,fullSync) {
+			ws.send('ldap','import',{id:id,fullSync:fullSync},true).then(
 				() => {
 					this.$store.commit('dialog',{
 						captionBody:this.capApp.dialog.importDone
 					});
+					if(id === this.idEdit)
+						this.getRuns();
 				},
-				this.$root.genericError
+				err => {
+					this.$root.genericError(err);
+					if(id === this.idEdit)
+						this.getRuns();
+				}
 			);
 		},
 		runCheck() {
@@ -371,8 +612,12 @@ let MyAdminLdaps = {
 					this.$store.commit('dialog',{
 						captionBody:this.capApp.dialog.testDone
 					});
+					this.getHostStates();
 				},
-				this.$root.genericError
+				err => {
+					this.$root.genericError(err);
+					this.getHostStates();
+				}
 			);
 		},
 		reloadBackendCache() {
@@ -417,6 +662,18 @@ let MyAdminLdaps = {
 				this.$root.genericError
 			);
 		},
+		getHostStates() {
+			ws.send('ldap','getHostStates',{id:this.idEdit},true).then(
+				res => this.hostStates = res.payload,
+				this.$root.genericError
+			);
+		},
+		getRuns() {
+			ws.send('ldap','getRuns',{id:this.idEdit},true).then(
+				res => this.runs = res.payload,
+				this.$root.genericError
+			);
+		},
 		set() {
 			ws.send('ldap','set',{
 				id:this.idEdit,
@@ -436,7 +693,13 @@ let MyAdminLdaps = {
 				starttls:this.starttls,
 				tls:this.tls,
 				tlsVerify:this.tlsVerify,
-				roles:this.roles
+				roles:this.roles,
+				loginRecordAttributeId:this.loginRecordAttributeId,
+				recordAttributes:this.recordAttributes.filter(v => v.attributeId !== null && v.ldapAttribute !== ''),
+				deltaSync:this.deltaSync,
+				fullSyncInterval:this.fullSyncInterval,
+				hostsFailover:this.hostsFailover.filter(v => v !== ''),
+				nestedGroups:this.nestedGroups
 			},true).then(
 				() => {
 					this.idEdit = -1;
@@ -1,7 +1,9 @@
 import MyForm        from '../form.js';
 import MyTabs        from '../tabs.js';
+import MyInputDate   from '../inputDate.js';
 import MyInputSelect from '../inputSelect.js';
 import srcBase64Icon from '../shared/image.js';
+import {getUnixFormat} from '../shared/time.js';
 import {
 	getCaptionForModule,
 	getValidLanguageCode
@@ -38,6 +40,7 @@ let MyAdminLogin = {
 	components:{
 		MyAdminLoginRole,
 		MyForm,
+		MyInputDate,
 		MyInputSelect,
 		MyTabs
 	},
@@ -111,11 +114,11 @@ let MyAdminLogin = {
 			
 			<my-tabs
 				v-model="tabTarget"
-				:entries="['properties','roles']"
-				:entriesText="[capGen.properties,capApp.roles.replace('{COUNT}',roleTotalNonHidden)]"
+				:entries="['properties','roles','validity']"
+				:entriesText="[capGen.properties,capApp.roles.replace('{COUNT}',roleTotalNonHidden),capApp.validity]"
 			/>
 			
-			<div class="content default-inputs" :class="{ 'no-padding':tabTarget === 'roles' }">
+			<div class="content default-inputs" :class="{ 'no-padding':tabTarget !== 'properties' }">
 				<table class="table-default generic-table-vertical fullWidth" v-if="tabTarget === 'properties'">
 					<tr>
 						<td>
@@ -196,6 +199,26 @@ let MyAdminLogin = {
 						<td><my-bool v-model="noAuth" :readonly="isLdap" /></td>
 						<td>{{ capApp.hint.noAuth }}</td>
 					</tr>
+					<tr>
+						<td>
+							<div class="title-cell">
+								<img src="images/key.png" />
+								<span>{{ capApp.certRequired }}</span>
+							</div>
+						</td>
+						<td><my-bool v-model="certRequired" /></td>
+						<td>{{ capApp.hint.certRequired }}</td>
+					</tr>
+					<tr>
+						<td>
+							<div class="title-cell">
+								<img src="images/mail.png" />
+								<span>{{ capApp.mfaMail }}</span>
+							</div>
+						</td>
+						<td><input v-model="mfaMail" /></td>
+						<td>{{ capApp.hint.mfaMail }}</td>
+					</tr>
 					<tr v-if="isNew">
 						<td>
 							<div class="title-cell">
@@ -236,6 +259,25 @@ let MyAdminLogin = {
 						</td>
 						<td></td>
 					</tr>
+					<tr v-if="!isNew">
+						<td>
+							<div class="title-cell">
+								<img src="images/visible1.png" />
+								<span>{{ capApp.impersonate }}</span>
+							</div>
+						</td>
+						<td>
+							<div class="row gap">
+								<input v-model="impersonationReason" :placeholder="capApp.impersonateReason" />
+								<my-button image="personArrow.png"
+									@trigger="impersonate"
+									:active="active && !hasChanges && impersonationReason !== ''"
+									:caption="capApp.button.impersonate"
+								/>
+							</div>
+						</td>
+						<td>{{ capApp.hint.impersonate }}</td>
+					</tr>
 				</table>
 				
 				<!-- roles -->
@@ -285,6 +327,74 @@ let MyAdminLogin = {
 						</tr>
 					</tbody>
 				</table>
+				
+				<!-- validity of role assignments -->
+				<template v-if="tabTarget === 'validity'">
+					<table class="table-default generic-table-vertical fullWidth">
+						<thead>
+							<tr>
+								<th>{{ capApp.role }}</th>
+								<th>{{ capApp.validFrom }}</th>
+								<th>{{ capApp.validTo }}</th>
+								<th></th>
+							</tr>
+						</thead>
+						<tbody>
+							<tr v-if="roleIdsNonHidden.length === 0">
+								<td colspan="4">{{ capGen.nothingThere }}</td>
+							</tr>
+							<tr v-for="roleId in roleIdsNonHidden" :key="roleId">
+								<td>{{ getRoleCaption(roleId) }}</td>
+								<td>
+									<my-input-date
+										@set-unix-from="setRoleDate(roleId,'dateFrom',$event)"
+										:isDate="true"
+										:isReadonly="isLdapAssignedRoles"
+										:isTime="true"
+										:unixFrom="roleDates[roleId] !== undefined ? roleDates[roleId].dateFrom : null"
+									/>
+								</td>
+								<td>
+									<my-input-date
+										@set-unix-from="setRoleDate(roleId,'dateTo',$event)"
+										:isDate="true"
+										:isReadonly="isLdapAssignedRoles"
+										:isTime="true"
+										:unixFrom="roleDates[roleId] !== undefined ? roleDates[roleId].dateTo : null"
+									/>
+								</td>
+								<td>
+									<my-button image="cancel.png"
+										@trigger="delete roleDates[roleId]"
+										:active="roleDates[roleId] !== undefined && !isLdapAssignedRoles"
+										:captionTitle="capGen.button.reset"
+										:naked="true"
+									/>
+								</td>
+							</tr>
+						</tbody>
+					</table>
+					
+					<!-- audit log of role assignments -->
+					<table class="table-default generic-table-vertical fullWidth" v-if="!isNew">
+						<thead>
+							<tr>
+								<th colspan="4">{{ capApp.roleLog }}</th>
+							</tr>
+						</thead>
+						<tbody>
+							<tr v-if="roleLog.length === 0">
+								<td colspan="4">{{ capGen.nothingThere }}</td>
+							</tr>
+							<tr v-for="l in roleLog">
+								<td>{{ getUnixFormat(l.date,[settings.dateFormat,'H:i:S'].join(' ')) }}</td>
+								<td>{{ capApp.roleLogAction[l.action] }}</td>
+								<td>{{ getRoleCaption(l.roleId) }}</td>
+								<td>{{ l.loginNameActor !== null ? l.loginNameActor : '-' }}</td>
+							</tr>
+						</tbody>
+					</table>
+				</template>
 			</div>
 		</div>
 	</div>`,
@@ -306,17 +416,22 @@ let MyAdminLogin = {
 			admin:false,
 			pass:'',
 			noAuth:false,
+			certRequired:false,
+			mfaMail:'',
 			records:[],
+			roleDates:{},
 			roleIds:[],
 			templateId:null,
+			impersonationReason:'',
 			
 			// states
-			inputKeys:['name','active','admin','pass','noAuth','records','roleIds'],
+			inputKeys:['name','active','admin','pass','noAuth','certRequired','mfaMail','records','roleDates','roleIds'],
 			inputsOrg:{},      // map of original input values, key = input key
 			inputsReady:false, // inputs have been loaded
 			recordInput:'',    // record lookup input
 			recordList:[],     // record lookup dropdown values
 			roleFilter:'',     // filter for role selection
+			roleLog:[],        // audit log of time-limited & delegated role assignments
 			tabTarget:'properties',
 			templates:[],      // login templates
 			
@@ -346,14 +461,8 @@ let MyAdminLogin = {
 			}
 			return false;
 		},
-		roleTotalNonHidden:(s) => {
-			let cnt = 0;
-			for(let roleId of s.roleIds) {
-				if(!s.moduleIdMapOptions[s.roleIdMap[roleId].moduleId].hidden)
-					cnt++
-			}
-			return cnt;
-		},
+		roleIdsNonHidden:(s) => s.roleIds.filter(v => !s.moduleIdMapOptions[s.roleIdMap[v].moduleId].hidden),
+		roleTotalNonHidden:(s) => s.roleIdsNonHidden.length,
 		modulesFiltered:(s) => s.modules.filter(v => !s.moduleIdMapOptions[v.id].hidden &&
 			(s.roleFilter === '' || 	s.getCaptionForModule(v.captions['moduleTitle'],v.name,v).toLowerCase().includes(s.roleFilter.toLowerCase()))),
 		
@@ -369,9 +478,11 @@ let MyAdminLogin = {
 		moduleIdMapOptions:(s) => s.$store.getters['schema/moduleIdMapOptions'],
 		formIdMap:         (s) => s.$store.getters['schema/formIdMap'],
 		roleIdMap:         (s) => s.$store.getters['schema/roleIdMap'],
+		token:             (s) => s.$store.getters['local/token'],
 		capApp:            (s) => s.$store.getters.captions.admin.login,
 		capGen:            (s) => s.$store.getters.captions.generic,
-		config:            (s) => s.$store.getters.config
+		config:            (s) => s.$store.getters.config,
+		settings:          (s) => s.$store.getters.settings
 	},
 	mounted() {
 		window.addEventListener('keydown',this.handleHotkeys);
@@ -394,6 +505,7 @@ let MyAdminLogin = {
 	methods:{
 		// externals
 		getCaptionForModule,
+		getUnixFormat,
 		getValidLanguageCode,
 		srcBase64Icon,
 		
@@ -416,6 +528,13 @@ let MyAdminLogin = {
 			this.inputsReady = true;
 		},
 		
+		// presentation
+		getRoleCaption(roleId) {
+			let r = this.roleIdMap[roleId];
+			let m = this.moduleIdMap[r.moduleId];
+			return `${this.getCaptionForModule(m.captions['moduleTitle'],m.name,m)}: ${this.getCaptionForModule(r.captions['roleTitle'],r.name,m)}`;
+		},
+		
 		// actions
 		openLoginForm(index) {
 			let frm = this.formIdMap[this.loginForms[index].formId];
@@ -432,6 +551,18 @@ let MyAdminLogin = {
 			
 			if(pos === -1)      this.roleIds.push(roleId);
 			else if(pos !== -1) this.roleIds.splice(pos,1);
+			
+			if(pos !== -1)
+				delete this.roleDates[roleId];
+		},
+		setRoleDate(roleId,key,unix) {
+			if(this.roleDates[roleId] === undefined)
+				this.roleDates[roleId] = {dateFrom:null,dateTo:null};
+			
+			this.roleDates[roleId][key] = unix;
+			
+			if(this.roleDates[roleId].dateFrom === null && this.roleDates[roleId].dateTo === null)
+				delete this.roleDates[roleId];
 		},
 		toggleRolesByContent(content) {
 			let roleIdsByContent = [];
@@ -448,6 +579,7 @@ let MyAdminLogin = {
 			if(roleIdsByContent.length === this.roleIds.filter(v => roleIdsByContent.includes(v)).length) {
 				for(let i = 0, j = roleIdsByContent.length; i < j; i++) {
 					this.roleIds.splice(this.roleIds.indexOf(roleIdsByContent[i]),1);
+					delete this.roleDates[roleIdsByContent[i]];
 				}
 				return;
 			}
@@ -501,19 +633,23 @@ let MyAdminLogin = {
 					if(res.payload.logins.length !== 1) return;
 					
 					let login = res.payload.logins[0];
-					this.ldapId  = login.ldapId;
-					this.ldapKey = login.ldapKey;
-					this.name    = login.name;
-					this.active  = login.active;
-					this.admin   = login.admin;
-					this.noAuth  = login.noAuth;
-					this.records = login.records;
-					this.roleIds = login.roleIds;
-					this.pass    = '';
+					this.ldapId       = login.ldapId;
+					this.ldapKey      = login.ldapKey;
+					this.name         = login.name;
+					this.active       = login.active;
+					this.admin        = login.admin;
+					this.noAuth       = login.noAuth;
+					this.certRequired = login.certRequired;
+					this.mfaMail      = login.mfaMail !== null ? login.mfaMail : '';
+					this.records      = login.records;
+					this.roleDates    = login.roleDates;
+					this.roleIds      = login.roleIds;
+					this.pass         = '';
 					this.inputsLoaded();
 				},
 				this.$root.genericError
 			);
+			this.getRoleLog();
 		},
 		getRecords(loginFormIndex) {
 			this.recordList = [];
@@ -534,6 +670,12 @@ let MyAdminLogin = {
 				this.$root.genericError
 			);
 		},
+		getRoleLog() {
+			ws.send('login','getRoleLog',{id:this.id,limit:100},true).then(
+				res => this.roleLog = res.payload,
+				this.$root.genericError
+			);
+		},
 		getTemplates() {
 			ws.send('loginTemplate','get',{byId:0},true).then(
 				res => {
@@ -564,6 +706,9 @@ let MyAdminLogin = {
 				active:this.active,
 				admin:this.admin,
 				noAuth:this.noAuth,
+				certRequired:this.certRequired,
+				mfaMail:this.mfaMail,
+				roleDates:this.roleDates,
 				roleIds:this.roleIds,
 				records:records,
 				templateId:this.templateId
@@ -604,6 +749,20 @@ let MyAdminLogin = {
 			ws.send('login','resetTotp',{id:this.id},true).then(
 				res => {},this.$root.genericError
 			);
+		},
+		impersonate() {
+			ws.send('loginImpersonation','start',{
+				loginId:this.id,
+				reason:this.impersonationReason
+			},true).then(
+				res => {
+					// keep admin token to return to after impersonation ends
+					this.$store.commit('local/tokenImpersonator',this.token);
+					this.$store.commit('local/token',res.payload.token);
+					location.reload();
+				},
+				this.$root.genericError
+			);
 		}
 	}
 };
\ No newline at end of file
@@ -101,6 +101,12 @@ let MyAdminLogins = {
 							:captionTitle="capApp.hint.isLdap"
 							:naked="true"
 						/>
+						<my-button image="lock.png"
+							v-if="l.mfaMissing"
+							:active="false"
+							:captionTitle="capApp.hint.isMfaMissing"
+							:naked="true"
+						/>
 						<my-button image="admin.png"
 							:active="false"
 							:caption="String(l.roleIds.length)"
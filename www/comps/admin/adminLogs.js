@@ -165,7 +165,7 @@ let MyAdminLogs = {
 		return {
 			contextsValid:[
 				'module','api','backup','cache','cluster','csv','imager',
-				'ldap','mail','scheduler','server','transfer','websocket'
+				'ldap','mail','scheduler','scim','server','transfer','websocket'
 			],
 			messageLengthShow:200,
 			
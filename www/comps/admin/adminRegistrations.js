@@ -0,0 +1,315 @@
+import {getUnixFormat}        from '../shared/time.js';
+import {hasAnyAssignableRole} from '../shared/access.js';
+export {MyAdminRegistrations as default};
+
+let MyAdminRegistrations = {
+	name:'my-admin-registrations',
+	template:`<div class="admin-registrations contentBox grow">
+		
+		<div class="top">
+			<div class="area">
+				<img class="icon" src="images/person.png" />
+				<h1>{{ menuTitle }}</h1>
+			</div>
+		</div>
+		<div class="top lower">
+			<div class="area">
+				<my-button image="save.png"
+					@trigger="setConfig"
+					:active="hasConfigChanges"
+					:caption="capGen.button.save"
+				/>
+				<my-button image="refresh.png"
+					@trigger="get"
+					:caption="capGen.button.refresh"
+				/>
+			</div>
+		</div>
+		
+		<div class="content no-padding">
+			<div class="contentPart long">
+				<span v-html="capApp.description"></span>
+				<br /><br />
+				
+				<table class="default-inputs">
+					<tbody>
+						<tr>
+							<td>{{ capApp.active }}</td>
+							<td><my-bool-string-number v-model="configInput.registrationActive" /></td>
+						</tr>
+						<tr>
+							<td>{{ capApp.template }}</td>
+							<td>
+								<select v-model="configInput.registrationLoginTemplateId">
+									<option v-for="t in templates" :title="t.comment" :value="t.name === 'GLOBAL' ? '0' : String(t.id)">
+										{{ t.name }}
+									</option>
+								</select>
+							</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.mailAccountId }}</td>
+							<td>
+								<select v-model="configInput.registrationMailAccountId">
+									<option value="0">{{ capApp.mailAccountAny }}</option>
+									<option v-for="a in mailAccounts" :value="String(a.id)">{{ a.name }}</option>
+								</select>
+							</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.mailSubject }}</td>
+							<td><input v-model="configInput.registrationMailSubject" /></td>
+						</tr>
+						<tr>
+							<td>{{ capApp.mailBody }}</td>
+							<td>
+								<textarea v-model="configInput.registrationMailBody"
+									:placeholder="capApp.mailBodyHint"
+								></textarea>
+							</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.notifyMail }}</td>
+							<td><input v-model="configInput.registrationNotifyMail" :placeholder="capApp.notifyMailHint" /></td>
+						</tr>
+					</tbody>
+				</table>
+			</div>
+			
+			<!-- registrations awaiting verification or approval -->
+			<div class="contentPart long">
+				<div class="contentPartHeader">
+					<img class="icon" src="images/personMultiple.png" />
+					<h1>{{ capApp.titleRegistrations }}</h1>
+				</div>
+				
+				<span v-if="registrations.length === 0">{{ capApp.registrationsNone }}</span>
+				
+				<table class="table-default shade" v-if="registrations.length !== 0">
+					<thead>
+						<tr>
+							<th>{{ capApp.loginName }}</th>
+							<th>{{ capApp.state }}</th>
+							<th>{{ capApp.rule }}</th>
+							<th>{{ capApp.dateCreated }}</th>
+							<th></th>
+						</tr>
+					</thead>
+					<tbody>
+						<tr v-for="r in registrations">
+							<td>{{ r.loginName }}</td>
+							<td>{{ capApp.stateOption[r.state] }}</td>
+							<td>{{ displayRule(r.ruleId) }}</td>
+							<td>{{ getUnixFormat(r.dateCreated,'Y-m-d H:i') }}</td>
+							<td>
+								<div class="row gap">
+									<my-button image="ok.png"
+										@trigger="approve(r.loginId)"
+										:active="r.state === 'pending'"
+										:caption="capApp.button.approve"
+									/>
+									<my-button image="cancel.png"
+										@trigger="reject(r.loginId)"
+										:active="r.state === 'pending'"
+										:cancel="true"
+										:caption="capApp.button.reject"
+									/>
+								</div>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+			</div>
+			
+			<!-- registration rules, allow list of mail domains -->
+			<div class="contentPart long">
+				<div class="contentPartHeader">
+					<img class="icon" src="images/admin.png" />
+					<h1>{{ capApp.titleRules }}</h1>
+				</div>
+				
+				<span v-html="capApp.rulesDescription"></span>
+				<br /><br />
+				
+				<table class="table-default shade">
+					<thead>
+						<tr>
+							<th>{{ capApp.ruleDomain }}</th>
+							<th>{{ capApp.ruleApproveAuto }}</th>
+							<th>{{ capApp.ruleRoles }}</th>
+							<th></th>
+						</tr>
+					</thead>
+					<tbody>
+						<tr v-for="(r,i) in rulesInput" class="default-inputs">
+							<td><input v-model="r.domain" placeholder="example.com" /></td>
+							<td><my-bool v-model="r.approveAuto" /></td>
+							<td>
+								<div class="column gap">
+									<div class="row gap" v-for="(roleId,ri) in r.roleIds">
+										<span>{{ displayRole(roleId) }}</span>
+										<my-button image="cancel.png"
+											@trigger="r.roleIds.splice(ri,1)"
+											:naked="true"
+										/>
+									</div>
+									<select @change="roleAdd(r,$event.target.value);$event.target.value = ''">
+										<option value="">{{ capApp.roleAdd }}</option>
+										<optgroup
+											v-for="m in modules.filter(v => !v.hidden && hasAnyAssignableRole(v.roles))"
+											:label="m.name"
+										>
+											<option
+												v-for="role in m.roles.filter(v => v.assignable && v.name !== 'everyone' && !r.roleIds.includes(v.id))"
+												:value="role.id"
+											>{{ role.name }}</option>
+										</optgroup>
+									</select>
+								</div>
+							</td>
+							<td>
+								<div class="row gap">
+									<my-button image="save.png"
+										@trigger="setRule(r)"
+										:active="r.domain !== '' && (r.id === 0 || JSON.stringify(r) !== JSON.stringify(rules[i]))"
+									/>
+									<my-button image="delete.png"
+										@trigger="delRule(r,i)"
+										:cancel="true"
+									/>
+								</div>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+				<br />
+				<my-button image="add.png"
+					@trigger="ruleAdd"
+					:caption="capGen.button.add"
+				/>
+			</div>
+		</div>
+	</div>`,
+	props:{
+		menuTitle:{ type:String, required:true }
+	},
+	data() {
+		return {
+			configInput:{},
+			mailAccounts:[],
+			registrations:[],
+			rules:[],      // registration rules, as stored
+			rulesInput:[], // registration rules, being edited
+			templates:[]
+		};
+	},
+	mounted() {
+		this.configInput = JSON.parse(JSON.stringify(this.config));
+		this.get();
+		this.$store.commit('pageTitle',this.menuTitle);
+	},
+	computed:{
+		hasConfigChanges:(s) => {
+			for(let k in s.configInput) {
+				if(k.startsWith('registration') && s.configInput[k] !== s.config[k])
+					return true;
+			}
+			return false;
+		},
+		
+		// stores
+		modules:    (s) => s.$store.getters['schema/modules'],
+		moduleIdMap:(s) => s.$store.getters['schema/moduleIdMap'],
+		roleIdMap:  (s) => s.$store.getters['schema/roleIdMap'],
+		capApp:     (s) => s.$store.getters.captions.admin.registrations,
+		capGen:     (s) => s.$store.getters.captions.generic,
+		config:     (s) => s.$store.getters.config
+	},
+	methods:{
+		// externals
+		getUnixFormat,
+		hasAnyAssignableRole,
+		
+		// presentation
+		displayRole(roleId) {
+			if(typeof this.roleIdMap[roleId] === 'undefined')
+				return roleId;
+			
+			let r = this.roleIdMap[roleId];
+			return `${this.moduleIdMap[r.moduleId].name}: ${r.name}`;
+		},
+		displayRule(ruleId) {
+			for(let r of this.rules) {
+				if(r.id === ruleId)
+					return r.domain;
+			}
+			return '-';
+		},
+		
+		// actions
+		roleAdd(rule,roleId) {
+			if(roleId !== '' && !rule.roleIds.includes(roleId))
+				rule.roleIds.push(roleId);
+		},
+		ruleAdd() {
+			this.rulesInput.push({
+				id:0,
+				domain:'',
+				approveAuto:false,
+				roleIds:[]
+			});
+		},
+		
+		// backend calls
+		approve(loginId) {
+			ws.send('registration','approve',{loginId:loginId},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
+		delRule(rule,index) {
+			if(rule.id === 0)
+				return this.rulesInput.splice(index,1);
+			
+			ws.send('registrationRule','del',{id:rule.id},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
+		get() {
+			ws.sendMultiple([
+				ws.prepare('registration','get',{}),
+				ws.prepare('registrationRule','get',{}),
+				ws.prepare('loginTemplate','get',{byId:0}),
+				ws.prepare('mailAccount','get',{})
+			],true).then(
+				res => {
+					this.registrations = res[0].payload;
+					this.rules         = res[1].payload;
+					this.rulesInput    = JSON.parse(JSON.stringify(this.rules));
+					this.templates     = res[2].payload;
+					this.mailAccounts  = Object.values(res[3].payload.accounts).filter(v => v.mode === 'smtp');
+				},
+				this.$root.genericError
+			);
+		},
+		reject(loginId) {
+			ws.send('registration','reject',{loginId:loginId},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
+		setConfig() {
+			ws.send('config','set',this.configInput,true).then(
+				() => {},
+				this.$root.genericError
+			);
+		},
+		setRule(rule) {
+			ws.send('registrationRule','set',rule,true).then(
+				this.get,
+				this.$root.genericError
+			);
+		}
+	}
+};
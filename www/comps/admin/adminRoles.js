@@ -18,6 +18,12 @@ let MyAdminRoleItem = {
 				:image="titleIcon"
 				:naked="true"
 			/>
+			<my-button
+				@trigger="$emit('toggle-mfa')"
+				:caption="capApp.mfaRequired"
+				:naked="true"
+				:image="mfaRequired ? 'checkbox1.png' : 'checkbox0.png'"
+			/>
 		</div>
 		
 		<!-- role description -->
@@ -55,13 +61,14 @@ let MyAdminRoleItem = {
 		</div>
 	</div>`,
 	props:{
-		logins:  { type:Array,   required:true },
-		module:  { type:Object,  required:true },
-		role:    { type:Object,  required:true },
-		showAll: { type:Boolean, required:true },
-		showDesc:{ type:Boolean, required:true }
+		logins:     { type:Array,   required:true },
+		mfaRequired:{ type:Boolean, required:true },
+		module:     { type:Object,  required:true },
+		role:       { type:Object,  required:true },
+		showAll:    { type:Boolean, required:true },
+		showDesc:   { type:Boolean, required:true }
 	},
-	emits:['add','remove-by-index'],
+	emits:['add','remove-by-index','toggle-mfa'],
 	data() {
 		return {
 			loginId:null,
@@ -167,21 +174,48 @@ let MyAdminRoles = {
 					:caption="capApp.button.descriptions"
 					:image="showDesc ? 'visible1.png' : 'visible0.png'"
 				/>
+				<my-button image="warning.png"
+					@trigger="toggleConflicts"
+					:caption="capApp.button.conflicts"
+					:cancel="showConflicts"
+				/>
 			</div>
 		</div>
 		
-		<div class="content" v-if="module === false">
+		<!-- segregation of duties violations of all logins -->
+		<div class="content" v-if="showConflicts">
+			<i v-if="conflicts.length === 0">{{ capApp.conflictsNone }}</i>
+			<table class="generic-table bright" v-if="conflicts.length !== 0">
+				<thead>
+					<tr>
+						<th>{{ capApp.conflictLogin }}</th>
+						<th>{{ capApp.conflictRole }}</th>
+						<th>{{ capApp.conflictRoleOther }}</th>
+					</tr>
+				</thead>
+				<tbody>
+					<tr v-for="c in conflicts">
+						<td :class="{ inactive:!c.loginActive }">{{ c.loginName }}</td>
+						<td>{{ getRoleName(c.roleId) }}</td>
+						<td>{{ getRoleName(c.roleIdConflict) }}</td>
+					</tr>
+				</tbody>
+			</table>
+		</div>
+		
+		<div class="content" v-if="!showConflicts && module === false">
 			<i>{{ capApp.nothingInstalled }}</i>
 		</div>
 		
-		<div class="content no-padding" v-if="module !== false">
+		<div class="content no-padding" v-if="!showConflicts && module !== false">
 			<my-admin-role-item
 				v-for="r in rolesValid"
 				@add="add(r.id,$event)"
 				@remove-by-index="remove(r.id,$event)"
-				@toggle-members=""
+				@toggle-mfa="toggleMfa(r.id)"
 				:key="r.id"
 				:logins="roleIdMapLogins[r.id]"
+				:mfaRequired="roleIdsMfa.includes(r.id)"
 				:module="module"
 				:role="r"
 				:show-all="showAll"
@@ -195,13 +229,17 @@ let MyAdminRoles = {
 	},
 	data() {
 		return {
+			conflicts:[],      // roles that are granted together to logins, despite conflicting
 			loginIdsChanged:[],
 			moduleId:null,
 			roleIdMapLogins:{},
+			roleIdsMfa:[],     // roles requiring multi-factor authentication (across all modules)
 			
 			// states
+			mfaChanged:false,
 			ready:false,
 			showAll:true,
+			showConflicts:false,
 			showDesc:false
 		};
 	},
@@ -225,12 +263,13 @@ let MyAdminRoles = {
 		},
 		
 		// simple
-		hasChanges:(s) => s.loginIdsChanged.length !== 0,
+		hasChanges:(s) => s.loginIdsChanged.length !== 0 || s.mfaChanged,
 		module:(s) => s.moduleId === null ? false : s.moduleIdMap[s.moduleId],
 		
 		// stores
 		modules:    (s) => s.$store.getters['schema/modules'],
 		moduleIdMap:(s) => s.$store.getters['schema/moduleIdMap'],
+		roleIdMap:  (s) => s.$store.getters['schema/roleIdMap'],
 		capApp:     (s) => s.$store.getters.captions.admin.roles,
 		capGen:     (s) => s.$store.getters.captions.generic
 	},
@@ -238,6 +277,12 @@ let MyAdminRoles = {
 		// externals
 		srcBase64Icon,
 		
+		// presentation
+		getRoleName(roleId) {
+			let r = this.roleIdMap[roleId];
+			return `${this.moduleIdMap[r.moduleId].name}.${r.name}`;
+		},
+		
 		// actions
 		add(roleId,login) {
 			let c = JSON.parse(JSON.stringify(this.roleIdMapLogins));
@@ -257,13 +302,26 @@ let MyAdminRoles = {
 			if(!this.loginIdsChanged.includes(login.id))
 				this.loginIdsChanged.push(login.id);
 		},
+		toggleMfa(roleId) {
+			let pos = this.roleIdsMfa.indexOf(roleId);
+			if(pos === -1) this.roleIdsMfa.push(roleId);
+			else           this.roleIdsMfa.splice(pos,1);
+			
+			this.mfaChanged = true;
+		},
+		toggleConflicts() {
+			this.showConflicts = !this.showConflicts;
+			
+			if(this.showConflicts)
+				this.getConflicts();
+		},
 		
 		// backend calls
 		get() {
 			// reset and get logins for all valid roles
 			this.roleIdMapLogins = {};
 			
-			let requests = [];
+			let requests = [ws.prepare('loginMfa','getRoleIds',{})];
 			for(let i = 0, j = this.rolesValid.length; i < j; i++) {
 				this.roleIdMapLogins[this.rolesValid[i].id] = [];
 				
@@ -274,19 +332,28 @@ let MyAdminRoles = {
 			
 			ws.sendMultiple(requests,true).then(
 				res => {
-					for(let i = 0, j = requests.length; i < j; i++) {
+					this.roleIdsMfa = res[0].payload;
+					
+					for(let i = 1, j = requests.length; i < j; i++) {
 						this.roleIdMapLogins[requests[i].payload.roleId] = res[i].payload.logins;
 					}
 					this.loginIdsChanged = [];
+					this.mfaChanged      = false;
 				},
 				this.$root.genericError
 			);
 		},
+		getConflicts() {
+			ws.send('login','getRoleConflicts',{},true).then(
+				res => this.conflicts = res.payload,
+				this.$root.genericError
+			);
+		},
 		set() {
 			if(!this.hasChanges)
 				return;
 			
-			let requests = [];
+			let requests = [ws.prepare('loginMfa','setRoleIds',{roleIds:this.roleIdsMfa})];
 			for(let i = 0, j = this.rolesValid.length; i < j; i++) {
 				
 				let role     = this.rolesValid[i];
@@ -0,0 +1,255 @@
+import {hasAnyAssignableRole} from '../shared/access.js';
+export {MyAdminScim as default};
+
+let MyAdminScim = {
+	name:'my-admin-scim',
+	template:`<div class="admin-scim contentBox grow">
+		
+		<div class="top">
+			<div class="area">
+				<img class="icon" src="images/link.png" />
+				<h1>{{ menuTitle }}</h1>
+			</div>
+		</div>
+		<div class="top lower">
+			<div class="area">
+				<my-button image="refresh.png"
+					@trigger="get"
+					:caption="capGen.button.refresh"
+				/>
+			</div>
+		</div>
+		
+		<div class="content no-padding">
+			<div class="contentPart long">
+				<span v-html="capApp.description"></span>
+				<br /><br />
+				
+				<table class="default-inputs">
+					<tbody>
+						<tr>
+							<td>{{ capApp.url }}</td>
+							<td><input :value="url" readonly /></td>
+						</tr>
+						<tr>
+							<td>{{ capApp.token }}</td>
+							<td>
+								<div class="row gap">
+									<input readonly
+										:placeholder="tokenSet ? capApp.tokenSet : capApp.tokenNotSet"
+										:value="token"
+									/>
+									<my-button image="key.png"
+										@trigger="setTokenAsk"
+										:active="licenseValid"
+										:caption="capApp.button.tokenSet"
+									/>
+									<my-button image="delete.png"
+										@trigger="delToken"
+										:active="tokenSet"
+										:cancel="true"
+										:caption="capApp.button.tokenDel"
+									/>
+								</div>
+							</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.template }}</td>
+							<td>
+								<div class="row gap">
+									<select v-model="configInput.scimLoginTemplateId">
+										<option v-for="t in templates" :title="t.comment" :value="t.name === 'GLOBAL' ? '0' : String(t.id)">
+											{{ t.name }}
+										</option>
+									</select>
+									<my-button image="save.png"
+										@trigger="setConfig"
+										:active="configInput.scimLoginTemplateId !== config.scimLoginTemplateId"
+										:caption="capGen.button.save"
+									/>
+								</div>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+			</div>
+			
+			<div class="contentPart long">
+				<div class="contentPartHeader">
+					<img class="icon" src="images/personMultiple.png" />
+					<h1>{{ capApp.titleGroups }}</h1>
+				</div>
+				
+				<span v-if="groups.length === 0">{{ capApp.groupsNone }}</span>
+				
+				<table class="table-default shade" v-if="groups.length !== 0">
+					<thead>
+						<tr>
+							<th>{{ capApp.groupName }}</th>
+							<th>{{ capApp.groupMembers }}</th>
+							<th>{{ capApp.groupRoles }}</th>
+							<th></th>
+						</tr>
+					</thead>
+					<tbody>
+						<tr v-for="g in groups" class="default-inputs">
+							<td :title="g.externalId">{{ g.name }}</td>
+							<td>{{ g.memberCount }}</td>
+							<td>
+								<div class="column gap">
+									<div class="row gap" v-for="(roleId,i) in groupIdMapRoleIds[g.id]">
+										<span>{{ displayRole(roleId) }}</span>
+										<my-button image="cancel.png"
+											@trigger="groupIdMapRoleIds[g.id].splice(i,1)"
+											:naked="true"
+										/>
+									</div>
+									<select @change="roleAdd(g.id,$event.target.value);$event.target.value = ''">
+										<option value="">{{ capApp.roleAdd }}</option>
+										<optgroup
+											v-for="m in modules.filter(v => !v.hidden && hasAnyAssignableRole(v.roles))"
+											:label="m.name"
+										>
+											<option
+												v-for="r in m.roles.filter(v => v.assignable && v.name !== 'everyone' && !groupIdMapRoleIds[g.id].includes(v.id))"
+												:value="r.id"
+											>{{ r.name }}</option>
+										</optgroup>
+									</select>
+								</div>
+							</td>
+							<td>
+								<my-button image="save.png"
+									@trigger="setGroupRoles(g.id)"
+									:active="JSON.stringify(g.roleIds) !== JSON.stringify(groupIdMapRoleIds[g.id])"
+								/>
+							</td>
+						</tr>
+					</tbody>
+				</table>
+			</div>
+		</div>
+	</div>`,
+	props:{
+		menuTitle:{ type:String, required:true }
+	},
+	data() {
+		return {
+			configInput:{},
+			groups:[],
+			groupIdMapRoleIds:{}, // role assignments being edited, key = SCIM group ID
+			templates:[],
+			token:'',             // newly created token, only shown once
+			tokenSet:false
+		};
+	},
+	mounted() {
+		this.configInput = JSON.parse(JSON.stringify(this.config));
+		this.get();
+		this.$store.commit('pageTitle',this.menuTitle);
+	},
+	computed:{
+		url:(s) => `${location.protocol}//${location.host}/scim/v2`,
+		
+		// stores
+		modules:     (s) => s.$store.getters['schema/modules'],
+		moduleIdMap: (s) => s.$store.getters['schema/moduleIdMap'],
+		roleIdMap:   (s) => s.$store.getters['schema/roleIdMap'],
+		capApp:      (s) => s.$store.getters.captions.admin.scim,
+		capGen:      (s) => s.$store.getters.captions.generic,
+		config:      (s) => s.$store.getters.config,
+		licenseValid:(s) => s.$store.getters.licenseValid
+	},
+	methods:{
+		// externals
+		hasAnyAssignableRole,
+		
+		// presentation
+		displayRole(roleId) {
+			if(typeof this.roleIdMap[roleId] === 'undefined')
+				return roleId;
+			
+			let r = this.roleIdMap[roleId];
+			return `${this.moduleIdMap[r.moduleId].name}: ${r.name}`;
+		},
+		
+		// actions
+		roleAdd(groupId,roleId) {
+			if(roleId !== '' && !this.groupIdMapRoleIds[groupId].includes(roleId))
+				this.groupIdMapRoleIds[groupId].push(roleId);
+		},
+		setTokenAsk() {
+			if(!this.tokenSet)
+				return this.setToken();
+			
+			this.$store.commit('dialog',{
+				captionBody:this.capApp.dialog.tokenSet,
+				buttons:[{
+					cancel:true,
+					caption:this.capApp.button.tokenSet,
+					exec:this.setToken,
+					image:'key.png'
+				},{
+					caption:this.capGen.button.cancel,
+					image:'cancel.png'
+				}]
+			});
+		},
+		
+		// backend calls
+		delToken() {
+			ws.send('scim','delToken',{},true).then(
+				() => {
+					this.token = '';
+					this.get();
+				},
+				this.$root.genericError
+			);
+		},
+		get() {
+			ws.sendMultiple([
+				ws.prepare('scim','get',{}),
+				ws.prepare('loginTemplate','get',{byId:0})
+			],true).then(
+				res => {
+					this.groups    = res[0].payload.groups;
+					this.tokenSet  = res[0].payload.tokenSet;
+					this.templates = res[1].payload;
+					
+					this.groupIdMapRoleIds = {};
+					for(let g of this.groups) {
+						this.groupIdMapRoleIds[g.id] = JSON.parse(JSON.stringify(g.roleIds));
+					}
+				},
+				this.$root.genericError
+			);
+		},
+		setConfig() {
+			ws.send('config','set',this.configInput,true).then(
+				() => {},
+				this.$root.genericError
+			);
+		},
+		setGroupRoles(groupId) {
+			ws.sendMultiple([
+				ws.prepare('scim','setGroupRoles',{
+					id:groupId,
+					roleIds:this.groupIdMapRoleIds[groupId]
+				}),
+				ws.prepare('login','reauthAll',{})
+			],true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
+		setToken() {
+			ws.send('scim','setToken',{},true).then(
+				res => {
+					this.token = res.payload;
+					this.get();
+				},
+				this.$root.genericError
+			);
+		}
+	}
+};
@@ -0,0 +1,340 @@
+import {getUnixFormat} from '../shared/time.js';
+export {MyAdminSlowQueries as default};
+
+let MyAdminSlowQueries = {
+	name:'my-admin-slow-queries',
+	template:`<div class="admin-slow-queries contentBox grow">
+		
+		<div class="top">
+			<div class="area">
+				<img class="icon" src="images/speedmeter.png" />
+				<h1>{{ menuTitle + ' (' + total + ')' }}</h1>
+			</div>
+		</div>
+		<div class="top lower">
+			<div class="area">
+				<my-button image="refresh.png"
+					@trigger="get"
+					:caption="capGen.button.refresh"
+				/>
+				<my-button image="sort.png"
+					@trigger="toggleAggregated"
+					:caption="aggregated ? capApp.button.showEntries : capApp.button.showAggregated"
+				/>
+				<my-button image="delete.png"
+					@trigger="delAsk"
+					:active="total !== 0"
+					:cancel="true"
+					:caption="capGen.button.delete"
+				/>
+			</div>
+			<div class="area default-inputs" v-if="!aggregated && total !== 0">
+				<my-button image="triangleLeft.png"
+					@trigger="offsetSet(false)"
+					:active="offset-limit >= 0"
+					:naked="true"
+				/>
+				
+				<span>{{ String((offset / limit) + 1) + ' / ' + pages  }}</span>
+				
+				<my-button image="triangleRight.png"
+					@trigger="offsetSet(true)"
+					:active="offset+limit < total"
+					:naked="true"
+				/>
+				
+				<select v-model.number="limit" @change="startAtPageFirst">
+					<option>10</option>
+					<option>25</option>
+					<option>50</option>
+					<option>100</option>
+				</select>
+			</div>
+			<div class="area">
+				<my-button image="cancel.png"
+					v-if="!aggregated && querySql !== ''"
+					@trigger="querySql = '';startAtPageFirst()"
+					:caption="capApp.button.filterReset"
+				/>
+				<my-button
+					@trigger="showOptions = !showOptions"
+					:caption="capGen.settings"
+					:image="showOptions ? 'visible1.png' : 'visible0.png'"
+				/>
+			</div>
+		</div>
+		
+		<div class="content default-inputs no-padding">
+			
+			<!-- options -->
+			<div v-if="showOptions" class="admin-slow-queries-settings">
+				<table class="default-inputs">
+					<tr>
+						<td>{{ capApp.thresholdMs }}</td>
+						<td><input class="short" v-model="configInput.slowQueryThresholdMs" /></td>
+						<td>{{ capApp.thresholdMsHint }}</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.explainPercent }}</td>
+						<td><input class="short" v-model="configInput.slowQueryExplainPercent" /></td>
+						<td>{{ capApp.explainPercentHint }}</td>
+					</tr>
+					<tr>
+						<td>{{ capApp.keepDays }}</td>
+						<td><input class="short" v-model="configInput.slowQueryKeepDays" /></td>
+						<td></td>
+					</tr>
+				</table>
+				<div class="row">
+					<my-button image="save.png"
+						@trigger="setConfig"
+						:active="configChanged"
+						:caption="capGen.button.save"
+					/>
+				</div>
+			</div>
+			
+			<!-- aggregated by query -->
+			<table class="table-default shade" v-if="aggregated && aggregates.length !== 0">
+				<thead>
+					<tr>
+						<th>{{ capApp.query }}</th>
+						<th>{{ capApp.relation }}</th>
+						<th>{{ capApp.count }}</th>
+						<th>{{ capApp.durationAvg }}</th>
+						<th>{{ capApp.durationMax }}</th>
+						<th>{{ capApp.dateLast }}</th>
+						<th>{{ capApp.indexSuggestions }}</th>
+						<th></th>
+					</tr>
+				</thead>
+				<tbody>
+					<tr v-for="a in aggregates">
+						<td class="admin-slow-queries-sql" :title="a.querySql">{{ a.querySql }}</td>
+						<td>{{ getRelationName(a.relationId) }}</td>
+						<td>{{ a.count }}</td>
+						<td>{{ a.durationAvgMs + ' ms' }}</td>
+						<td>{{ a.durationMaxMs + ' ms' }}</td>
+						<td>{{ getUnixFormat(a.dateLast,settings.dateFormat+' H:i') }}</td>
+						<td>
+							<div class="column" v-if="a.attributeIdsIndex.length !== 0">
+								<span v-for="atrId in a.attributeIdsIndex">{{ getAttributeName(atrId) }}</span>
+							</div>
+							<span v-else>-</span>
+						</td>
+						<td>
+							<div class="row gap">
+								<my-button image="open.png"
+									@trigger="showEntriesForQuery(a.querySql)"
+									:caption="capApp.button.showEntries"
+								/>
+								<my-button image="speedmeter.png"
+									@trigger="explain(a.querySql)"
+									:active="!a.explainRequested"
+									:caption="a.explainRequested ? capApp.button.explainRequested : capApp.button.explain"
+									:captionTitle="capApp.button.explainHint"
+								/>
+							</div>
+						</td>
+					</tr>
+				</tbody>
+			</table>
+			
+			<!-- single entries -->
+			<table class="table-default shade" v-if="!aggregated && entries.length !== 0">
+				<thead>
+					<tr>
+						<th>{{ capGen.date }}</th>
+						<th>{{ capApp.duration }}</th>
+						<th>{{ capApp.login }}</th>
+						<th>{{ capApp.origin }}</th>
+						<th>{{ capApp.relation }}</th>
+						<th>{{ capApp.args }}</th>
+						<th></th>
+					</tr>
+				</thead>
+				<tbody>
+					<template v-for="e in entries" :key="e.id">
+						<tr>
+							<td>{{ getUnixFormat(e.date,settings.dateFormat+' H:i:S') }}</td>
+							<td>{{ e.durationMs + ' ms' }}</td>
+							<td>{{ e.loginName !== null ? e.loginName : '-' }}</td>
+							<td>{{ getOrigin(e) }}</td>
+							<td>{{ getRelationName(e.relationId) }}</td>
+							<td>{{ e.queryArgs.join(', ') }}</td>
+							<td>
+								<my-button
+									@trigger="toggleEntry(e.id)"
+									:caption="e.explain !== null ? capApp.button.showPlan : capApp.button.showSql"
+									:image="idsShown.includes(e.id) ? 'visible1.png' : 'visible0.png'"
+								/>
+							</td>
+						</tr>
+						<tr v-if="idsShown.includes(e.id)">
+							<td colspan="7">
+								<pre class="admin-slow-queries-pre">{{ e.querySql }}</pre>
+								<pre class="admin-slow-queries-pre" v-if="e.explain !== null">{{ e.explain }}</pre>
+							</td>
+						</tr>
+					</template>
+				</tbody>
+			</table>
+			
+			<span class="admin-slow-queries-empty" v-if="total === 0">
+				<i>{{ config.slowQueryThresholdMs === '0' ? capApp.captureDisabled : capApp.nothingThere }}</i>
+			</span>
+		</div>
+	</div>`,
+	props:{
+		menuTitle:{ type:String, required:true }
+	},
+	data() {
+		return {
+			// inputs
+			aggregated:true,
+			configInput:{},
+			idsShown:[],
+			limit:50,
+			offset:0,
+			querySql:'',
+			showOptions:false,
+			
+			// data
+			aggregates:[],
+			entries:[],
+			total:0
+		};
+	},
+	mounted() {
+		this.$store.commit('pageTitle',this.menuTitle);
+		this.configInput = JSON.parse(JSON.stringify(this.config));
+		this.get();
+	},
+	computed:{
+		configChanged:(s) => s.config.slowQueryThresholdMs !== s.configInput.slowQueryThresholdMs
+			|| s.config.slowQueryExplainPercent !== s.configInput.slowQueryExplainPercent
+			|| s.config.slowQueryKeepDays       !== s.configInput.slowQueryKeepDays,
+		
+		// simple
+		pages:(s) => Math.ceil(s.total / s.limit),
+		
+		// stores
+		apiIdMap:      (s) => s.$store.getters['schema/apiIdMap'],
+		attributeIdMap:(s) => s.$store.getters['schema/attributeIdMap'],
+		formIdMap:     (s) => s.$store.getters['schema/formIdMap'],
+		moduleIdMap:   (s) => s.$store.getters['schema/moduleIdMap'],
+		relationIdMap: (s) => s.$store.getters['schema/relationIdMap'],
+		capApp:        (s) => s.$store.getters.captions.admin.slowQueries,
+		capGen:        (s) => s.$store.getters.captions.generic,
+		config:        (s) => s.$store.getters.config,
+		settings:      (s) => s.$store.getters.settings
+	},
+	methods:{
+		// externals
+		getUnixFormat,
+		
+		// presentation
+		getAttributeName(attributeId) {
+			let a = this.attributeIdMap[attributeId];
+			return a === undefined ? '-' : `${this.getRelationName(a.relationId)}.${a.name}`;
+		},
+		getOrigin(e) {
+			let out = [];
+			if(e.apiId !== null)
+				out.push(this.apiIdMap[e.apiId] === undefined ? 'API' : `API: ${this.apiIdMap[e.apiId].name}`);
+			
+			if(e.formId !== null)
+				out.push(this.formIdMap[e.formId] === undefined ? e.formId : this.formIdMap[e.formId].name);
+			
+			if(e.fieldId !== null)
+				out.push(`${this.capApp.field}: ${e.fieldId}`);
+			
+			return out.length === 0 ? '-' : out.join(', ');
+		},
+		getRelationName(relationId) {
+			let r = this.relationIdMap[relationId];
+			return r === undefined ? '-' : `${this.moduleIdMap[r.moduleId].name}.${r.name}`;
+		},
+		
+		// actions
+		offsetSet(add) {
+			if(add) this.offset += this.limit;
+			else    this.offset -= this.limit;
+			this.get();
+		},
+		showEntriesForQuery(querySql) {
+			this.aggregated = false;
+			this.querySql   = querySql;
+			this.startAtPageFirst();
+		},
+		startAtPageFirst() {
+			this.offset = 0;
+			this.get();
+		},
+		toggleAggregated() {
+			this.aggregated = !this.aggregated;
+			this.querySql   = '';
+			this.startAtPageFirst();
+		},
+		toggleEntry(id) {
+			let pos = this.idsShown.indexOf(id);
+			if(pos === -1) this.idsShown.push(id);
+			else           this.idsShown.splice(pos,1);
+		},
+		
+		// backend calls
+		delAsk() {
+			this.$store.commit('dialog',{
+				captionBody:this.capApp.dialog.delete,
+				buttons:[{
+					cancel:true,
+					caption:this.capGen.button.delete,
+					exec:this.del,
+					image:'delete.png'
+				},{
+					caption:this.capGen.button.cancel,
+					image:'cancel.png'
+				}]
+			});
+		},
+		del() {
+			ws.send('slowQuery','del',{},true).then(
+				this.startAtPageFirst,
+				this.$root.genericError
+			);
+		},
+		explain(querySql) {
+			ws.send('slowQuery','explain',{querySql:querySql},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
+		get() {
+			// total count is always retrieved from single entries
+			ws.send('slowQuery','get',{
+				limit:this.limit,
+				offset:this.offset,
+				querySql:this.querySql
+			},true).then(
+				res => {
+					this.entries = res.payload.entries;
+					this.total   = res.payload.total;
+				},
+				this.$root.genericError
+			);
+			
+			if(this.aggregated) {
+				ws.send('slowQuery','getAggregated',{},true).then(
+					res => this.aggregates = res.payload,
+					this.$root.genericError
+				);
+			}
+		},
+		setConfig() {
+			ws.send('config','set',this.configInput,true).then(
+				() => {},
+				this.$root.genericError
+			);
+		}
+	}
+};
@@ -3,6 +3,7 @@ import MyFeedback            from './feedback.js';
 import MyForm                from './form.js';
 import MyHeader              from './header.js';
 import MyLogin               from './login.js';
+import MySearch              from './search.js';
 import {getStartFormId}      from './shared/access.js';
 import {updateCollections}   from './shared/collection.js';
 import {formOpen}            from './shared/form.js';
@@ -31,7 +32,8 @@ let MyApp = {
 		MyFeedback,
 		MyForm,
 		MyHeader,
-		MyLogin
+		MyLogin,
+		MySearch
 	},
 	template:`<div :class="classes" id="app" :style="styles">
 		
@@ -51,6 +53,17 @@ let MyApp = {
 				:moduleEntries="moduleEntries"
 			/>
 			
+			<!-- impersonation notice -->
+			<div class="app-impersonation row centered gap" v-if="isImpersonated">
+				<img src="images/visible1.png" />
+				<span>{{ capGen.impersonationActive }}</span>
+				<my-button image="cancel.png"
+					@trigger="impersonationEnd"
+					:caption="capGen.button.impersonationEnd"
+					:cancel="true"
+				/>
+			</div>
+			
 			<router-view class="app-content"
 				@logout="sessionInvalid"
 				:bgStyle="bgStyle"
@@ -109,6 +122,11 @@ let MyApp = {
 				<my-feedback v-if="isAtFeedback" />
 			</transition>
 			
+			<!-- global search window -->
+			<transition name="fade">
+				<my-search v-if="isAtSearch" />
+			</transition>
+			
 			<!-- loading input blocker overlay -->
 			<div class="input-block-overlay-bg" :class="{show:blockInput}">
 				<div class="input-block-overlay">
@@ -341,6 +359,8 @@ let MyApp = {
 		isAdmin:          (s) => s.$store.getters.isAdmin,
 		isAtDialog:       (s) => s.$store.getters.isAtDialog,
 		isAtFeedback:     (s) => s.$store.getters.isAtFeedback,
+		isAtSearch:       (s) => s.$store.getters.isAtSearch,
+		isImpersonated:   (s) => s.$store.getters.isImpersonated,
 		isMobile:         (s) => s.$store.getters.isMobile,
 		loginEncryption:  (s) => s.$store.getters.loginEncryption,
 		loginPrivateKey:  (s) => s.$store.getters.loginPrivateKey,
@@ -429,6 +449,27 @@ let MyApp = {
 				case 'files_copied':
 					this.$store.commit('filesCopy',res.payload);
 				break;
+				case 'record_presence':
+					this.$store.commit('recordPresence',res.payload);
+				break;
+				case 'data_changed':
+					this.$store.commit('dataChanged',res.payload);
+				break;
+				case 'role_delegation_changed':
+					if(this.appReady) {
+						ws.send('lookup','get',{name:'access'},true).then(
+							res => {
+								this.$store.commit('access',res.payload);
+								this.updateCollections(false);
+								this.$store.commit('dialog',{
+									captionBody:this.capGen.dialog.roleDelegationChanged,
+									image:'person.png'
+								});
+							},
+							this.genericError
+						);
+					}
+				break;
 				
 				// affects everyone logged in
 				case 'collection_changed':
@@ -483,6 +524,17 @@ let MyApp = {
 		},
 		
 		// session control
+		impersonationEnd() {
+			ws.send('loginImpersonation','end',{},true).then(
+				res => {
+					// return to admin session
+					this.$store.commit('local/token',this.$store.getters['local/tokenImpersonator']);
+					this.$store.commit('local/tokenImpersonator','');
+					location.reload();
+				},
+				this.genericError
+			);
+		},
 		sessionInvalid() {
 			this.$store.commit('local/loginKeyAes',null);
 			this.$store.commit('local/loginKeySalt',null);
@@ -523,10 +575,12 @@ let MyApp = {
 					this.$store.commit('local/companyWelcome',res.payload.companyWelcome);
 					this.$store.commit('local/css',res.payload.css);
 					this.$store.commit('local/schemaTimestamp',res.payload.schemaTimestamp);
+					this.$store.commit('clientCertAuth',res.payload.clientCertAuth);
 					this.$store.commit('clusterNodeName',res.payload.clusterNodeName);
 					this.$store.commit('productionMode',res.payload.productionMode === 1);
 					this.$store.commit('pageTitleRefresh'); // update page title with new app name
 					this.$store.commit('pwaDomainMap',res.payload.pwaDomainMap);
+					this.$store.commit('registration',res.payload.registration);
 					this.$store.commit('searchDictionaries',res.payload.searchDictionaries);
 					this.$store.commit('schema/languageCodes',res.payload.languageCodes);
 					this.publicLoaded = true;
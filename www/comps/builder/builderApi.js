@@ -6,6 +6,8 @@ import {
 	isAttributeDecimal,
 	isAttributeFiles,
 	isAttributeInteger,
+	isAttributeInterval,
+	isAttributeMoney,
 	isAttributeRelationship,
 	isAttributeString,
 	isAttributeUuid,
@@ -238,6 +240,8 @@ let MyBuilderApiPreview = {
 		isAttributeDecimal,
 		isAttributeFiles,
 		isAttributeInteger,
+		isAttributeInterval,
+		isAttributeMoney,
 		isAttributeRelationship,
 		isAttributeString,
 		isAttributeUuid,
@@ -251,6 +255,8 @@ let MyBuilderApiPreview = {
 			if(this.isAttributeRelationship(content)) value = 456;
 			if(this.isAttributeUuid(content))         value = '064fc31d-479d-450d-22cd-71f874df3a50';
 			if(this.isAttributeBoolean(content))      value = true;
+			if(this.isAttributeInterval(content))     value = 3600;
+			if(this.isAttributeMoney(content))        value = { amount:'123.45', currency:'EUR' };
 			if(this.isAttributeFiles(content))
 				value = [{
 	                "changed":1677925664,
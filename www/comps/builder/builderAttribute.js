@@ -11,7 +11,11 @@ import {
 	isAttributeBoolean,
 	isAttributeFiles,
 	isAttributeFloat,
+	isAttributeGeo,
+	isAttributeInterval,
+	isAttributeMoney,
 	isAttributeInteger,
+	isAttributeJson,
 	isAttributeNumeric,
 	isAttributeRegconfig,
 	isAttributeRelationship,
@@ -130,6 +134,9 @@ let MyBuilderAttribute = {
 										<option value="iframe"   :disabled="!isNew && !isString">{{ capApp.option.iframe }}</option>
 										<option value="boolean"  :disabled="!isNew && !isBoolean">{{ capApp.option.boolean }}</option>
 										<option value="files"    :disabled="!isNew && !isFiles">{{ capApp.option.files }}</option>
+										<option value="geo"      :disabled="!isNew && !isGeo">{{ capApp.option.geo }}</option>
+										<option value="money"    :disabled="!isNew && !isMoney">{{ capApp.option.money }}</option>
+										<option value="duration" :disabled="!isNew && !isInterval">{{ capApp.option.duration }}</option>
 									</optgroup>
 									<optgroup :label="capApp.datetimes" :disabled="!isNew && !isInteger">
 										<option value="datetime">{{ capApp.option.datetime }}</option>
@@ -140,10 +147,11 @@ let MyBuilderAttribute = {
 										<option value="relationshipN1">{{ capApp.option.relationshipN1 }}</option>
 										<option value="relationship11">{{ capApp.option.relationship11 }}</option>
 									</optgroup>
-									<optgroup :label="capApp.expert" :disabled="!isNew && !isFloat && !isUuid">
+									<optgroup :label="capApp.expert" :disabled="!isNew && !isFloat && !isUuid && !isRegconfig && !isJson">
 										<option value="float"     :disabled="!isNew && !isFloat">{{ capApp.option.float }}</option>
 										<option value="uuid"      :disabled="!isNew && !isUuid">{{ capApp.option.uuid }}</option>
 										<option value="regconfig" :disabled="!isNew && !isRegconfig">{{ capApp.option.regconfig }}</option>
+										<option value="json"      :disabled="!isNew && !isJson">{{ capApp.option.json }}</option>
 									</optgroup>
 								</select>
 								<my-button
@@ -236,8 +244,21 @@ let MyBuilderAttribute = {
 						<td></td>
 					</tr>
 					
+					<!-- computed -->
+					<tr v-if="canCompute">
+						<td>{{ capApp.expression }}</td>
+						<td>
+							<textarea class="short"
+								v-model="expressionInput"
+								:disabled="readonly || !canComputeEx"
+								:placeholder="capGen.threeDots"
+							></textarea>
+						</td>
+						<td>{{ capApp.expressionHint }}</td>
+					</tr>
+					
 					<!-- encrypted -->
-					<tr v-if="canEncrypt">
+					<tr v-if="canEncrypt && !isComputed">
 						<td>{{ capApp.encrypted }}</td>
 						<td><my-bool v-model="values.encrypted" :readonly="readonly" /></td>
 						<td>{{ capApp.encryptedHint }}</td>
@@ -251,7 +272,7 @@ let MyBuilderAttribute = {
 					</tr>
 					
 					<!-- defaults -->
-					<tr v-if="!isId && !isFiles && !isRelationship">
+					<tr v-if="!isId && !isFiles && !isRelationship && !isComputed">
 						<td>{{ capApp.defaults }}</td>
 						<td>
 							<div class="column centered gap">
@@ -271,6 +292,36 @@ let MyBuilderAttribute = {
 						<td>{{ capApp.defaultsHint }}</td>
 					</tr>
 					
+					<!-- validation -->
+					<template v-if="canValidate">
+						<tr v-if="canValidateRegex">
+							<td>{{ capApp.validationRegex }}</td>
+							<td><input v-model="validationRegex" :disabled="readonly" :placeholder="capGen.threeDots" /></td>
+							<td>{{ capApp.validationRegexHint }}</td>
+						</tr>
+						<tr v-if="canValidateRange">
+							<td>{{ capApp.validationRange }}</td>
+							<td>
+								<div class="row centered gap">
+									<input type="number" v-model="validationMin" :disabled="readonly" />
+									<span>-</span>
+									<input type="number" v-model="validationMax" :disabled="readonly" />
+								</div>
+							</td>
+							<td>{{ isString ? capApp.validationRangeHintText : capApp.validationRangeHint }}</td>
+						</tr>
+						<tr v-if="canValidateValues">
+							<td>{{ capApp.validationValues }}</td>
+							<td><textarea class="short" v-model="validationValues" :disabled="readonly" :placeholder="capGen.threeDots"></textarea></td>
+							<td>{{ capApp.validationValuesHint }}</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.validationExpression }}</td>
+							<td><textarea class="short" v-model="validationExpression" :disabled="readonly" :placeholder="capGen.threeDots"></textarea></td>
+							<td>{{ capApp.validationExpressionHint }}</td>
+						</tr>
+					</template>
+					
 					<!-- expert info -->
 					<tr>
 						<td>{{ capApp.content }}</td>
@@ -307,17 +358,46 @@ let MyBuilderAttribute = {
 			get()  { return this.values.content === 'double precision'; },
 			set(v) { this.values.content = v ? 'double precision' : 'real'; }
 		},
+		expressionInput:{
+			get()  { return this.getExpressionWithNames(this.values.expression); },
+			set(v) { this.values.expression = this.getExpressionWithIds(v); }
+		},
+		validationExpression:{
+			get()  { return this.values.validation.expression === null ? '' : this.getExpressionWithNames(this.values.validation.expression); },
+			set(v) { this.values.validation.expression = v === '' ? null : this.getExpressionWithIds(v); }
+		},
+		validationMax:{
+			get()  { return this.values.validation.max === null ? '' : this.values.validation.max; },
+			set(v) { this.values.validation.max = v === '' ? null : Number(v); }
+		},
+		validationMin:{
+			get()  { return this.values.validation.min === null ? '' : this.values.validation.min; },
+			set(v) { this.values.validation.min = v === '' ? null : Number(v); }
+		},
+		validationRegex:{
+			get()  { return this.values.validation.regex === null ? '' : this.values.validation.regex; },
+			set(v) { this.values.validation.regex = v === '' ? null : v; }
+		},
+		validationValues:{
+			// one allowed value per line
+			get()  { return this.values.validation.values.join('\n'); },
+			set(v) { this.values.validation.values = v === '' ? [] : v.split('\n'); }
+		},
 		usedFor:{
 			get() {
 				if(this.isBoolean)   return 'boolean';
 				if(this.isColor)     return 'color';
 				if(this.isDate)      return 'date';
 				if(this.isDatetime)  return 'datetime';
+				if(this.isInterval)  return 'duration';
 				if(this.isNumber)    return 'number';
 				if(this.isNumeric)   return 'decimal';
 				if(this.isFiles)     return 'files';
 				if(this.isFloat)     return 'float';
+				if(this.isGeo)       return 'geo';
 				if(this.isIframe)    return 'iframe';
+				if(this.isJson)      return 'json';
+				if(this.isMoney)     return 'money';
 				if(this.isRegconfig) return 'regconfig';
 				if(this.isRichtext)  return 'richtext';
 				if(this.isText)      return 'text';
@@ -385,6 +465,24 @@ let MyBuilderAttribute = {
 						this.values.contentUse = 'default';
 					break;
 					
+					// geo point uses
+					case 'geo':
+						this.values.content    = 'point';
+						this.values.contentUse = 'default';
+					break;
+					
+					// money uses
+					case 'money':
+						this.values.content    = 'money';
+						this.values.contentUse = 'default';
+					break;
+					
+					// interval uses
+					case 'duration':
+						this.values.content    = 'interval';
+						this.values.contentUse = 'default';
+					break;
+					
 					// float uses
 					case 'float':
 						this.values.content    = this.isNew ? 'real' : this.values.content;
@@ -398,6 +496,12 @@ let MyBuilderAttribute = {
 						this.values.contentUse = 'default';
 					break;
 					
+					// JSON uses
+					case 'json':
+						this.values.content    = 'jsonb';
+						this.values.contentUse = 'default';
+					break;
+					
 					// regconfig uses
 					case 'regconfig':
 						this.values.content    = 'regconfig';
@@ -426,19 +530,32 @@ let MyBuilderAttribute = {
 		},
 		
 		// simple
+		canCompute:    (s) => !s.isId && !s.isFiles && !s.isRelationship,
+		canComputeEx:  (s) => s.isNew || s.valuesOrg === null || s.valuesOrg.expression !== '', // regular attributes cannot become computed, values would be lost
 		canEncrypt:    (s) => s.relation.encryption && s.values.content === 'text',
 		canSave:       (s) => !s.readonly && s.hasChanges && !s.nameTaken,
 		hasChanges:    (s) => s.values.name !== '' && JSON.stringify(s.values) !== JSON.stringify(s.valuesOrg),
 		hasLength:     (s) => ['files','richtext','text','textarea'].includes(s.usedFor),
+		isComputed:    (s) => s.values.expression !== '',
 		isId:          (s) => !s.isNew && s.values.name === 'id',
 		isNew:         (s) => s.attributeId === null,
 		title:         (s) => s.isNew ? s.capApp.new : s.capApp.edit.replace('{NAME}',s.values.name),
 		
+		// validation
+		canValidate:      (s) => !s.isId && !s.isFiles && !s.values.encrypted,
+		canValidateRange: (s) => s.isString || s.isInteger || s.isNumeric || s.isFloat || s.isInterval,
+		canValidateRegex: (s) => s.isString,
+		canValidateValues:(s) => s.isString || s.isInteger || s.isNumeric || s.isFloat,
+		
 		// content
 		isBoolean:       (s) => s.isAttributeBoolean(s.values.content),
 		isFiles:         (s) => s.isAttributeFiles(s.values.content),
 		isFloat:         (s) => s.isAttributeFloat(s.values.content),
+		isGeo:           (s) => s.isAttributeGeo(s.values.content),
 		isInteger:       (s) => s.isAttributeInteger(s.values.content),
+		isInterval:      (s) => s.isAttributeInterval(s.values.content),
+		isJson:          (s) => s.isAttributeJson(s.values.content),
+		isMoney:         (s) => s.isAttributeMoney(s.values.content),
 		isNumeric:       (s) => s.isAttributeNumeric(s.values.content),
 		isRegconfig:     (s) => s.isAttributeRegconfig(s.values.content),
 		isRelationship:  (s) => s.isAttributeRelationship(s.values.content),
@@ -488,7 +605,11 @@ let MyBuilderAttribute = {
 		isAttributeBoolean,
 		isAttributeFiles,
 		isAttributeFloat,
+		isAttributeGeo,
+		isAttributeInterval,
+		isAttributeMoney,
 		isAttributeInteger,
+		isAttributeJson,
 		isAttributeNumeric,
 		isAttributeRegconfig,
 		isAttributeRelationship,
@@ -497,6 +618,21 @@ let MyBuilderAttribute = {
 		isAttributeString,
 		isAttributeUuid,
 		
+		// presentation
+		getExpressionWithIds(expression) {
+			return expression.replace(/\(([a-z][a-z0-9_]*)\)/g,(match,name) => {
+				const atr = this.relation.attributes.find(a => a.name === name);
+				return typeof atr !== 'undefined' ? `(${atr.id})` : match;
+			});
+		},
+		getExpressionWithNames(expression) {
+			// attributes are stored in expressions as (ATR_ID), shown as (ATR_NAME)
+			return expression.replace(/\(([a-z0-9\-]{36})\)/g,(match,id) => {
+				return typeof this.attributeIdMap[id] !== 'undefined'
+					? `(${this.attributeIdMap[id].name})` : match;
+			});
+		},
+		
 		// actions
 		changedUsedFor() {
 			if(!this.isRelationship && this.values.relationshipId !== null)
@@ -530,6 +666,14 @@ let MyBuilderAttribute = {
 					def:'',
 					onUpdate:'NO ACTION',
 					onDelete:'NO ACTION',
+					expression:'',
+					validation:{
+						regex:null,
+						min:null,
+						max:null,
+						values:[],
+						expression:null
+					},
 					captions:{
 						attributeTitle:{}
 					}
@@ -585,6 +729,7 @@ let MyBuilderAttribute = {
 				res => {
 					const noDependencies =
 						res.payload.apiIds.length         === 0 &&
+						res.payload.attributeIds.length   === 0 &&
 						res.payload.collectionIds.length  === 0 &&
 						res.payload.formIds.length        === 0 &&
 						res.payload.pgIndexIds.length     === 0 &&
@@ -601,6 +746,11 @@ let MyBuilderAttribute = {
 						const url = `#/builder/api/${id}`;
 						dependencies.push(`${this.moduleIdMap[api.moduleId].name}: ${this.capGen.api} <a href="${url}">'${api.name}'</a>`);
 					}
+					for(let id of res.payload.attributeIds) {
+						const atr = this.attributeIdMap[id];
+						const rel = this.relationIdMap[atr.relationId];
+						dependencies.push(`${this.moduleIdMap[rel.moduleId].name}: ${this.capGen.attribute} '${rel.name}.${atr.name}'`);
+					}
 					for(let id of res.payload.collectionIds) {
 						const col = this.collectionIdMap[id];
 						const url = `#/builder/collection/${id}`;
@@ -663,6 +813,25 @@ let MyBuilderAttribute = {
 			if(this.values.encrypted && !this.canEncrypt)
 				this.values.encrypted = false;
 			
+			// computed attributes are generated by the database
+			if(this.isComputed) {
+				this.values.def       = '';
+				this.values.encrypted = false;
+			}
+			if(!this.canCompute)
+				this.values.expression = '';
+			
+			// remove validation rules not applicable to attribute
+			if(!this.canValidate)
+				this.values.validation = { regex:null, min:null, max:null, values:[], expression:null };
+			
+			if(!this.canValidateRegex)  this.values.validation.regex  = null;
+			if(!this.canValidateValues) this.values.validation.values = [];
+			if(!this.canValidateRange) {
+				this.values.validation.min = null;
+				this.values.validation.max = null;
+			}
+			
 			ws.sendMultiple([
 				ws.prepare('attribute','set',this.values),
 				ws.prepare('schema','check',{ moduleId:this.module.id })
@@ -135,7 +135,7 @@ let MyBuilderField = {
 					
 					<!-- action: list data SQL preview -->
 					<img class="action clickable" src="images/code.png"
-						v-if="['calendar','chart','kanban','list'].includes(field.content)"
+						v-if="['calendar','chart','kanban','list','map'].includes(field.content)"
 						@click="getSqlPreview(field)"
 						:title="capApp.sql"
 					/>
@@ -366,6 +366,7 @@ let MyBuilderField = {
 				case 'data':      return s.getItemTitle(s.field.attributeId,s.field.index,s.field.outsideIn,s.field.attributeIdNm); break;
 				case 'kanban':    return s.field.query.relationId === null ? 'Kanban' : `Kanban: ${s.relationIdMap[s.field.query.relationId].name}`; break;
 				case 'list':      return s.field.query.relationId === null ? 'List' : `List: ${s.relationIdMap[s.field.query.relationId].name}`; break;
+				case 'map':       return s.field.query.relationId === null ? 'Map' : `Map: ${s.relationIdMap[s.field.query.relationId].name}`; break;
 			}
 			return '';
 		},
@@ -386,6 +387,8 @@ let MyBuilderField = {
 				if(s.field.attributeIdDate0 === null || s.field.attributeIdDate1 === null)
 					out.push(s.capApp.warning.calendarNoDateFromTo);
 			}
+			if(s.isMap && s.field.attributeIdGeo === null)
+				out.push(s.capApp.warning.mapNoGeo);
 			return out;
 		},
 		
@@ -399,6 +402,7 @@ let MyBuilderField = {
 		isHeader:      (s) => s.field.content === 'header',
 		isKanban:      (s) => s.field.content === 'kanban',
 		isList:        (s) => s.field.content === 'list',
+		isMap:         (s) => s.field.content === 'map',
 		isTabs:        (s) => s.field.content === 'tabs',
 		isSelected:    (s) => s.field.id      === s.fieldIdShow,
 		isRelationship:(s) => !s.isData ? false : s.isAttributeRelationship(s.attribute.content),
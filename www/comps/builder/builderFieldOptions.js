@@ -20,6 +20,7 @@ import {
 	getDetailsFromIndexAttributeId,
 	getIndexAttributeId,
 	isAttributeFiles,
+	isAttributeGeo,
 	isAttributeInteger,
 	isAttributeRegconfig,
 	isAttributeRelationship,
@@ -566,6 +567,53 @@ let MyBuilderFieldOptions = {
 				</template>
 			</template>
 			
+			<template v-if="isMap">
+				<tr>
+					<td>{{ capApp.geo }}</td>
+					<td>
+						<select
+							@input="setIndexAttribute('geo',$event.target.value)"
+							:value="getIndexAttributeId(field.indexGeo,field.attributeIdGeo,false,null)"
+						>
+							<option :value="getIndexAttributeId(null,null,false,null)">-</option>
+							<optgroup
+								v-for="j in field.query.joins"
+								:label="j.index+' '+relationIdMap[j.relationId].name"
+							>
+								<option
+									v-for="a in relationIdMap[j.relationId].attributes.filter(v => isAttributeGeo(v.content))"
+									:value="getIndexAttributeId(j.index,a.id,false,null)"
+								>
+									{{ a.name }}
+								</option>
+							</optgroup>
+						</select>
+					</td>
+				</tr>
+				<tr>
+					<td>{{ capApp.geoColor }}</td>
+					<td>
+						<select
+							@input="setIndexAttribute('color',$event.target.value)"
+							:value="getIndexAttributeId(field.indexColor,field.attributeIdColor,false,null)"
+						>
+							<option :value="getIndexAttributeId(null,null,false,null)">-</option>
+							<optgroup
+								v-for="j in field.query.joins"
+								:label="j.index+' '+relationIdMap[j.relationId].name"
+							>
+								<option
+									v-for="a in relationIdMap[j.relationId].attributes.filter(v => isAttributeString(v.content))"
+									:value="getIndexAttributeId(j.index,a.id,false,null)"
+								>
+									{{ a.name }}
+								</option>
+							</optgroup>
+						</select>
+					</td>
+				</tr>
+			</template>
+			
 			<template v-if="isCalendar">
 				<tr>
 					<td>{{ capApp.date0 }}</td>
@@ -1192,14 +1240,14 @@ let MyBuilderFieldOptions = {
 			</template>
 			
 			<!-- open form & open form bulk -->
-			<tr v-if="isButton || ((isList || isCalendar || isKanban || isRelationship) && field.query.relationId !== null)">
+			<tr v-if="isButton || ((isList || isCalendar || isKanban || isMap || isRelationship) && field.query.relationId !== null)">
 				<td>{{ capApp.openForm }}</td>
 				<td>
 					<my-builder-open-form-input
 						@update:openForm="set('openForm',$event)"
 						:allowAllForms="isButton"
 						:allowNewRecords="true"
-						:allowPopUpInline="isCalendar || isKanban || isList"
+						:allowPopUpInline="isCalendar || isKanban || isList || isMap"
 						:joinsIndexMap="joinsIndexMap"
 						:joinsIndexMapField="joinsIndexMapField"
 						:module="module"
@@ -1225,7 +1273,7 @@ let MyBuilderFieldOptions = {
 			</tr>
 			
 			<!-- consume collection -->
-			<template v-if="isList || isCalendar || isKanban">
+			<template v-if="isList || isCalendar || isKanban || isMap">
 				<tr>
 					<td>
 						<div class="column">
@@ -1323,8 +1371,9 @@ let MyBuilderFieldOptions = {
 		isHeader:        (s) => s.field.content === 'header',
 		isList:          (s) => s.field.content === 'list',
 		isKanban:        (s) => s.field.content === 'kanban',
+		isMap:           (s) => s.field.content === 'map',
 		isOpenForm:      (s) => typeof s.field.openForm !== 'undefined' && s.field.openForm !== null,
-		isQuery:         (s) => s.isCalendar || s.isChart || s.isKanban || s.isList || s.isRelationship,
+		isQuery:         (s) => s.isCalendar || s.isChart || s.isKanban || s.isList || s.isMap || s.isRelationship,
 		isTabs:          (s) => s.field.content === 'tabs',
 		isFiles:         (s) => s.isData && s.isAttributeFiles(s.attribute.content),
 		isInteger:       (s) => s.isData && s.isAttributeInteger(s.attribute.content),
@@ -1356,6 +1405,7 @@ let MyBuilderFieldOptions = {
 		getNilUuid,
 		getRandomInt,
 		isAttributeFiles,
+		isAttributeGeo,
 		isAttributeInteger,
 		isAttributeRegconfig,
 		isAttributeRelationship,
@@ -1432,6 +1482,10 @@ let MyBuilderFieldOptions = {
 					this.set('attributeIdColor',values.attributeId);
 					this.set('indexColor',values.index);
 				break;
+				case 'geo':
+					this.set('attributeIdGeo',values.attributeId);
+					this.set('indexGeo',values.index);
+				break;
 			}
 		},
 		setInt(name,val,allowNull) {
@@ -640,6 +640,7 @@ let MyBuilderForm = {
 				fields.push(this.createFieldCalendar());  // calendar
 				fields.push(this.createFieldGantt());     // Gantt
 				fields.push(this.createFieldKanban());    // Kanban
+				fields.push(this.createFieldMap());       // map
 				fields.push(this.createFieldChart());     // chart
 				fields.push(this.createFieldHeader());    // header
 				fields.push(this.createFieldButton());    // button
@@ -1020,6 +1021,23 @@ let MyBuilderForm = {
 				query:this.getQueryTemplate()
 			};
 		},
+		createFieldMap() {
+			return {
+				id:'template_map',
+				iconId:null,
+				content:'map',
+				state:'default',
+				onMobile:true,
+				attributeIdGeo:null,
+				attributeIdColor:null,
+				indexGeo:null,
+				indexColor:null,
+				columns:[],
+				collections:[],
+				openForm:null,
+				query:this.getQueryTemplate()
+			};
+		},
 		createFieldList() {
 			return {
 				id:'template_list',
@@ -361,6 +361,8 @@ let MyBuilderNew = {
 						encryption:this.inputs.encryption,
 						retentionCount:null,
 						retentionDays:null,
+						softDelete:false,
+						dataChangeNotify:false,
 						policies:[]
 					};
 				break;
@@ -1,3 +1,4 @@
+import {isAttributeGeo} from '../shared/attribute.js';
 export {MyBuilderPgIndex as default};
 
 let MyBuilderPgIndex = {
@@ -46,6 +47,7 @@ let MyBuilderPgIndex = {
 							<select v-model="values.method" :disabled="!isNew || readonly">
 								<option value="BTREE">{{ capApp.method.BTREE }}</option>
 								<option value="GIN">{{ capApp.method.GIN }}</option>
+								<option value="GIST">{{ capApp.method.GIST }}</option>
 							</select>
 						</td>
 						<td>{{ capApp.description[values.method] }}</td>
@@ -56,7 +58,7 @@ let MyBuilderPgIndex = {
 							<div class="column gap">
 								<div class="row gap" v-if="isNew">
 									<select v-model="attributeInput" :disabled="!isNew || readonly">
-										<template v-for="a in relation.attributes.filter(v => !attributeIdsUsed.includes(v.id))">
+										<template v-for="a in attributesValid.filter(v => !attributeIdsUsed.includes(v.id))">
 											<option :value="a.id + '_ASC'">
 												{{ getAttributeCaption(a.id,true) }}
 											</option>
@@ -127,12 +129,17 @@ let MyBuilderPgIndex = {
 			}
 			return ids;
 		},
+		attributesValid:(s) => {
+			// geo points can only be indexed via GIST, GIST is only used for geo points
+			return s.relation.attributes.filter(v => s.isGist === s.isAttributeGeo(v.content));
+		},
 		
 		// simple
 		canSave:   (s) => s.values !== null && s.isNew && !s.isSystem && s.hasChanges && s.values.attributes.length !== 0,
 		hasChanges:(s) => JSON.stringify(s.values) !== JSON.stringify(s.valuesOrg),
 		isBtree:   (s) => s.values.method === 'BTREE',
 		isGin:     (s) => s.values.method === 'GIN',
+		isGist:    (s) => s.values.method === 'GIST',
 		isNew:     (s) => s.pgIndexId === null,
 		isSystem:  (s) => s.values.primaryKey || s.values.autoFki,
 		
@@ -150,6 +157,9 @@ let MyBuilderPgIndex = {
 		window.removeEventListener('keydown',this.handleHotkeys);
 	},
 	methods:{
+		// externals
+		isAttributeGeo,
+		
 		// display
 		getAttributeCaption(attributeId,orderAsc) {
 			let order = this.isBtree ? ` (${orderAsc ? 'ASC' : 'DESC'})` : '';
@@ -282,6 +282,26 @@ let MyBuilderRelation = {
 							</td>
 							<td>{{ capApp.retentionHint }}</td>
 						</tr>
+						<tr>
+							<td>{{ capApp.softDelete }}</td>
+							<td><my-bool v-model="softDelete" :readonly="readonly" /></td>
+							<td>{{ capApp.softDeleteHint }}</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.dataChangeNotify }}</td>
+							<td><my-bool v-model="dataChangeNotify" :readonly="readonly" /></td>
+							<td>{{ capApp.dataChangeNotifyHint }}</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.searchForm }}</td>
+							<td>
+								<select v-model="searchFormId" :disabled="readonly">
+									<option :value="null">[{{ capApp.searchFormNotSet }}]</option>
+									<option v-for="f in formsSearch" :value="f.id">{{ f.name }}</option>
+								</select>
+							</td>
+							<td>{{ capApp.searchFormHint }}</td>
+						</tr>
 					</table>
 					
 					<div class="row">
@@ -605,8 +625,11 @@ let MyBuilderRelation = {
 			indexIdEdit:false,
 			name:'',
 			comment:null,
+			dataChangeNotify:false,
 			retentionCount:null,
 			retentionDays:null,
+			searchFormId:null,
+			softDelete:false,
 			policies:[],
 			
 			// states
@@ -716,11 +739,15 @@ let MyBuilderRelation = {
 			|| s.encryption               !== s.relation.encryption
 			|| s.retentionCount           !== s.relation.retentionCount
 			|| s.retentionDays            !== s.relation.retentionDays
+			|| s.searchFormId             !== s.relation.searchFormId
+			|| s.softDelete               !== s.relation.softDelete
+			|| s.dataChangeNotify         !== s.relation.dataChangeNotify
 			|| JSON.stringify(s.policies) !== JSON.stringify(s.relation.policies),
 		
 		// simple
 		attributesNotFiles:(s) => s.relation === false ? [] : s.relation.attributes.filter(v => !s.isAttributeFiles(v.content)),
 		canSave:           (s) => s.name !== '' && !s.readonly && s.hasChanges,
+		formsSearch:       (s) => s.relation === false ? [] : s.moduleIdMap[s.relation.moduleId].forms.filter(v => v.query.relationId === s.relation.id),
 		relation:          (s) => typeof s.relationIdMap[s.id] === 'undefined' ? false : s.relationIdMap[s.id],
 		
 		// stores
@@ -792,6 +819,9 @@ let MyBuilderRelation = {
 			this.encryption     = this.relation.encryption;
 			this.retentionCount = this.relation.retentionCount;
 			this.retentionDays  = this.relation.retentionDays;
+			this.searchFormId   = this.relation.searchFormId;
+			this.softDelete     = this.relation.softDelete;
+			this.dataChangeNotify = this.relation.dataChangeNotify;
 			this.policies       = JSON.parse(JSON.stringify(this.relation.policies));
 			
 			if(this.showPreview)
@@ -850,6 +880,9 @@ let MyBuilderRelation = {
 				encryption:this.relation.encryption,
 				retentionCount:this.retentionCount === '' ? null : this.retentionCount,
 				retentionDays:this.retentionDays === '' ? null : this.retentionDays,
+				searchFormId:this.searchFormId,
+				softDelete:this.softDelete,
+				dataChangeNotify:this.dataChangeNotify,
 				policies:this.policies
 			},true).then(
 				() => this.$root.schemaReload(this.relation.moduleId),
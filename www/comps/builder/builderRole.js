@@ -467,6 +467,108 @@ let MyBuilderRole = {
 								</select>
 							</td>
 						</tr>
+						<tr>
+							<td>{{ capApp.conflicts }}</td>
+							<td>
+								<my-button image="delete.png"
+									v-for="c in conflictIds"
+									@trigger="conflictRemove(c)"
+									:active="!readonly"
+									:caption="moduleIdMap[roleIdMap[c].moduleId].name + '->' + roleIdMap[c].name"
+									:naked="true"
+								/>
+								
+								<select
+									@change="conflictAdd($event.target.value)"
+									:disabled="readonly"
+									:title="capApp.conflictsHint"
+									:value="null"
+								>
+									<option disabled :value="null">[{{ capGen.button.add }}]</option>
+									<optgroup
+										v-for="mod in getDependentModules(module,modules)"
+										:label="mod.name"
+									>
+										<option
+											v-for="r in mod.roles.filter(v => v.id !== role.id && !conflictIds.includes(v.id) && !childrenIds.includes(v.id) && v.name !== 'everyone')"
+											:value="r.id"
+										>
+											{{ r.name }}
+										</option>
+									</optgroup>
+								</select>
+							</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.accessNetworks }}</td>
+							<td>
+								<div class="column gap">
+									<div class="row gap centered" v-for="(n,i) in accessNetworks">
+										<input v-model="accessNetworks[i]" :disabled="readonly" :placeholder="capApp.accessNetworksHint" />
+										<my-button image="delete.png"
+											@trigger="accessNetworks.splice(i,1)"
+											:active="!readonly"
+											:naked="true"
+										/>
+									</div>
+									<div>
+										<my-button image="add.png"
+											@trigger="accessNetworks.push('')"
+											:active="!readonly"
+											:caption="capGen.button.add"
+										/>
+									</div>
+								</div>
+							</td>
+						</tr>
+						<tr>
+							<td>{{ capApp.accessTimezone }}</td>
+							<td><input v-model="accessTimezone" :disabled="readonly" :placeholder="capApp.accessTimezoneHint" /></td>
+						</tr>
+						<tr>
+							<td>{{ capApp.accessWindows }}</td>
+							<td>
+								<div class="column gap">
+									<div class="column gap" v-for="(w,i) in accessWindows">
+										<div class="row gap">
+											<my-button
+												v-for="d in [1,2,3,4,5,6,0]"
+												@trigger="windowWeekdayToggle(w,d)"
+												:active="!readonly"
+												:caption="capApp.weekdays[d]"
+												:image="w.weekdays.includes(d) ? 'checkbox1.png' : 'checkbox0.png'"
+												:naked="true"
+											/>
+										</div>
+										<div class="row gap centered">
+											<input type="time"
+												@change="w.timeFrom = getSecondsFromTime($event.target.value)"
+												:disabled="readonly"
+												:value="getTimeFromSeconds(w.timeFrom)"
+											/>
+											<span>-</span>
+											<input type="time"
+												@change="w.timeTo = getSecondsFromTime($event.target.value)"
+												:disabled="readonly"
+												:value="getTimeFromSeconds(w.timeTo)"
+											/>
+											<my-button image="delete.png"
+												@trigger="accessWindows.splice(i,1)"
+												:active="!readonly"
+												:naked="true"
+											/>
+										</div>
+									</div>
+									<div>
+										<my-button image="add.png"
+											@trigger="accessWindows.push({weekdays:[1,2,3,4,5],timeFrom:28800,timeTo:64800})"
+											:active="!readonly"
+											:caption="capGen.button.add"
+										/>
+									</div>
+								</div>
+							</td>
+						</tr>
 					</table>
 				</div>
 			</div>
@@ -497,10 +599,14 @@ let MyBuilderRole = {
 			accessAttributes:{},
 			accessCollections:{},
 			accessMenus:{},
+			accessNetworks:[],
 			accessRelations:{},
+			accessTimezone:'UTC',
+			accessWindows:[],
 			assignable:true,
 			captions:{},
 			childrenIds:[],
+			conflictIds:[],
 			content:'user',
 			name:'',
 			
@@ -516,11 +622,15 @@ let MyBuilderRole = {
 			|| s.content    !== s.role.content
 			|| s.assignable !== s.role.assignable
 			|| JSON.stringify(s.childrenIds)       !== JSON.stringify(s.role.childrenIds)
+			|| JSON.stringify(s.conflictIds)       !== JSON.stringify(s.role.conflictIds)
 			|| JSON.stringify(s.accessApis)        !== JSON.stringify(s.role.accessApis)
 			|| JSON.stringify(s.accessAttributes)  !== JSON.stringify(s.role.accessAttributes)
 			|| JSON.stringify(s.accessCollections) !== JSON.stringify(s.role.accessCollections)
 			|| JSON.stringify(s.accessMenus)       !== JSON.stringify(s.role.accessMenus)
 			|| JSON.stringify(s.accessRelations)   !== JSON.stringify(s.role.accessRelations)
+			|| JSON.stringify(s.accessNetworks)    !== JSON.stringify(s.role.accessNetworks)
+			|| JSON.stringify(s.accessWindows)     !== JSON.stringify(s.role.accessWindows)
+			|| s.accessTimezone                    !== s.role.accessTimezone
 			|| JSON.stringify(s.captions)          !== JSON.stringify(s.role.captions),
 		
 		// simple
@@ -558,16 +668,41 @@ let MyBuilderRole = {
 			if(pos !== -1)
 				this.childrenIds.splice(pos,1);
 		},
+		conflictAdd(id) {
+			this.conflictIds.push(id);
+		},
+		conflictRemove(id) {
+			let pos = this.conflictIds.indexOf(id);
+			if(pos !== -1)
+				this.conflictIds.splice(pos,1);
+		},
+		getSecondsFromTime(v) {
+			let parts = v.split(':');
+			return parts.length < 2 ? 0 : parseInt(parts[0]) * 3600 + parseInt(parts[1]) * 60;
+		},
+		getTimeFromSeconds(v) {
+			let pad = (n) => String(n).padStart(2,'0');
+			return `${pad(Math.floor(v / 3600))}:${pad(Math.floor(v % 3600 / 60))}`;
+		},
+		windowWeekdayToggle(w,day) {
+			let pos = w.weekdays.indexOf(day);
+			if(pos === -1) w.weekdays.push(day);
+			else           w.weekdays.splice(pos,1);
+		},
 		reset() {
 			this.name              = this.role.name;
 			this.content           = this.role.content;
 			this.assignable        = this.role.assignable;
 			this.childrenIds       = JSON.parse(JSON.stringify(this.role.childrenIds)),
+			this.conflictIds       = JSON.parse(JSON.stringify(this.role.conflictIds));
 			this.accessApis        = JSON.parse(JSON.stringify(this.role.accessApis));
 			this.accessAttributes  = JSON.parse(JSON.stringify(this.role.accessAttributes));
 			this.accessCollections = JSON.parse(JSON.stringify(this.role.accessCollections));
 			this.accessMenus       = JSON.parse(JSON.stringify(this.role.accessMenus));
 			this.accessRelations   = JSON.parse(JSON.stringify(this.role.accessRelations));
+			this.accessNetworks    = JSON.parse(JSON.stringify(this.role.accessNetworks));
+			this.accessWindows     = JSON.parse(JSON.stringify(this.role.accessWindows));
+			this.accessTimezone    = this.role.accessTimezone;
 			this.captions          = JSON.parse(JSON.stringify(this.role.captions));
 			this.ready = true;
 		},
@@ -610,11 +745,15 @@ let MyBuilderRole = {
 				content:this.content,
 				assignable:this.assignable,
 				childrenIds:this.childrenIds,
+				conflictIds:this.conflictIds,
 				accessApis:this.accessApis,
 				accessAttributes:this.accessAttributes,
 				accessCollections:this.accessCollections,
 				accessMenus:this.accessMenus,
 				accessRelations:this.accessRelations,
+				accessNetworks:this.accessNetworks.filter(v => v !== ''),
+				accessTimezone:this.accessTimezone,
+				accessWindows:this.accessWindows,
 				captions:this.captions
 			},true).then(
 				() => {
@@ -662,7 +662,8 @@ let MyCalendar = {
 					this.attributeIdDate0,this.indexDate0,dateStart,
 					this.attributeIdDate1,this.indexDate1,dateEnd
 				)).concat(this.choiceFilters),
-				orders:orders
+				orders:orders,
+				fieldId:this.fieldId !== '' ? this.fieldId : null
 			},true).then(
 				res => {
 					this.rows = res.payload.rows;
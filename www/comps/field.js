@@ -10,6 +10,7 @@ import MyInputRichtext        from './inputRichtext.js';
 import MyInputSelect          from './inputSelect.js';
 import MyInputUuid            from './inputUuid.js';
 import MyList                 from './list.js';
+import MyMap                  from './map.js';
 import {hasAccessToAttribute} from './shared/access.js';
 import {srcBase64}            from './shared/image.js';
 import {
@@ -30,12 +31,20 @@ import {
 	getQueryColumnsProcessed,
 	getQueryFiltersProcessed
 } from './shared/query.js';
+import {
+	getDurationStringFromSeconds,
+	getSecondsFromDurationString
+} from './shared/time.js';
 import {
 	getIndexAttributeId,
 	isAttributeBoolean,
 	isAttributeDecimal,
 	isAttributeFiles,
+	isAttributeGeo,
 	isAttributeInteger,
+	isAttributeInterval,
+	isAttributeJson,
+	isAttributeMoney,
 	isAttributeRelationship,
 	isAttributeRegconfig,
 	isAttributeString,
@@ -58,7 +67,8 @@ let MyField = {
 		MyInputRichtext,
 		MyInputSelect,
 		MyInputUuid,
-		MyList
+		MyList,
+		MyMap
 	},
 	template:`<div class="field"
 		v-if="isActive"
@@ -113,6 +123,57 @@ let MyField = {
 						:readonly="isReadonly"
 					/>
 					
+					<!-- geo point input -->
+					<input class="input" data-is-input="1" type="text"
+						v-if="isGeo"
+						v-model="valueGeo"
+						@blur="blur"
+						@focus="focus"
+						@click="click"
+						:class="{ invalid:showInvalid }"
+						:disabled="isReadonly"
+						:placeholder="!focused && !isCleanUi ? caption : capApp.geoHint"
+					/>
+					<my-button image="globe.png"
+						v-if="isGeo && !isReadonly"
+						@trigger="setValueGeoCurrent"
+						:captionTitle="capApp.button.geoCurrent"
+						:naked="true"
+					/>
+					
+					<!-- money input -->
+					<div class="money-input" v-if="isMoney">
+						<input class="input" data-is-input="1" type="text"
+							v-model="valueMoneyAmount"
+							@blur="blur"
+							@focus="focus"
+							@click="click"
+							:class="{ invalid:showInvalid }"
+							:disabled="isReadonly"
+							:placeholder="!focused && !isCleanUi ? caption : ''"
+						/>
+						<input class="input currency" type="text" maxlength="3"
+							v-model="valueMoneyCurrency"
+							@blur="blur"
+							@focus="focus"
+							:class="{ invalid:showInvalid }"
+							:disabled="isReadonly"
+							:placeholder="capApp.currency"
+						/>
+					</div>
+					
+					<!-- duration input -->
+					<input class="input" data-is-input="1" type="text"
+						v-if="isInterval"
+						v-model="valueDuration"
+						@blur="blur"
+						@focus="focus"
+						@click="click"
+						:class="{ invalid:showInvalid }"
+						:disabled="isReadonly"
+						:placeholder="!focused && !isCleanUi ? caption : '0:00:00'"
+					/>
+					
 					<!-- UUID input -->
 					<my-input-uuid
 						v-if="isUuid"
@@ -189,6 +250,18 @@ let MyField = {
 						:placeholder="!focused && !isCleanUi ? caption : ''"
 					></textarea>
 					
+					<!-- JSON input -->
+					<textarea class="input textarea" data-is-input="1"
+						v-if="isJson"
+						v-model="valueJson"
+						@blur="blur"
+						@focus="focus"
+						@click="click"
+						:class="{ invalid:showInvalid }"
+						:disabled="isReadonly"
+						:placeholder="!focused && !isCleanUi ? caption : ''"
+					></textarea>
+					
 					<!-- richtext input -->
 					<my-input-richtext
 						v-if="isRichtext"
@@ -480,6 +553,35 @@ let MyField = {
 			:usesPageHistory="isAloneInForm && !formIsPopUp"
 		/>
 		
+		<!-- map -->
+		<my-map
+			v-if="isMap"
+			@clipboard="$emit('clipboard')"
+			@close-inline="closeInline"
+			@open-form="(...args) => openForm(args[0],args[1],args[2],null)"
+			@record-count-change="$emit('set-counter',field.id,$event)"
+			@set-args="(...args) => $emit('set-form-args',...args)"
+			@set-collection-indexes="setCollectionIndexes"
+			:attributeIdColor="field.attributeIdColor"
+			:attributeIdGeo="field.attributeIdGeo"
+			:choices="choicesProcessed"
+			:columns="columnsProcessed"
+			:collections="field.collections"
+			:collectionIdMapIndexes="collectionIdMapIndexes"
+			:fieldId="field.id"
+			:filters="filtersProcessed"
+			:formLoading="formLoading"
+			:hasOpenForm="field.openForm !== null"
+			:iconId="iconId ? iconId : null"
+			:indexColor="field.indexColor"
+			:indexGeo="field.indexGeo"
+			:isHidden="isHidden"
+			:isSingleField="isAloneInForm || isAloneInTab"
+			:popUpFormInline="popUpFormInline"
+			:query="field.query"
+			:usesPageHistory="isAloneInForm && !formIsPopUp"
+		/>
+		
 		<!-- chart -->
 		<my-chart
 			v-if="isChart"
@@ -673,6 +775,65 @@ let MyField = {
 			}
 		},
 		
+		// field value for JSON attribute, shown & edited as JSON text
+		valueJson:{
+			get() {
+				if(this.value === null)            return '';
+				if(typeof this.value === 'string') return this.value;
+				return JSON.stringify(this.value,null,2);
+			},
+			set(val) {
+				this.value = val === '' ? null : val;
+			}
+		},
+		
+		// field value for geo point attribute, shown & edited as 'latitude, longitude' text
+		valueGeo:{
+			get() {
+				if(this.value === null)            return '';
+				if(typeof this.value === 'string') return this.value;
+				return `${this.value.coordinates[1]}, ${this.value.coordinates[0]}`;
+			},
+			set(val) {
+				if(val === '')
+					return this.value = null;
+				
+				// keep text until valid coordinates are given, value is invalid until then
+				const m = /^\s*(-?\d+\.?\d*)\s*[,;\s]\s*(-?\d+\.?\d*)\s*$/.exec(val);
+				if(m === null || Math.abs(m[1]) > 90 || Math.abs(m[2]) > 180)
+					return this.value = val;
+				
+				this.value = { type:'Point', coordinates:[parseFloat(m[2]),parseFloat(m[1])] };
+			}
+		},
+		
+		// field value for money attribute, amount & currency are edited separately
+		valueMoneyAmount:{
+			get()    { return this.value === null ? '' : this.value.amount; },
+			set(val) { this.setValueMoney(val.replace(',','.'),this.valueMoneyCurrency); }
+		},
+		valueMoneyCurrency:{
+			get()    { return this.value === null ? '' : this.value.currency; },
+			set(val) { this.setValueMoney(this.valueMoneyAmount,val.toUpperCase()); }
+		},
+		
+		// field value for interval attribute, shown & edited as '[-]hours:minutes:seconds' text
+		valueDuration:{
+			get() {
+				if(this.value === null)            return '';
+				if(typeof this.value === 'string') return this.value;
+				return this.getDurationStringFromSeconds(this.value);
+			},
+			set(val) {
+				if(val === '')
+					return this.value = null;
+				
+				// keep text until valid duration is given, value is invalid until then
+				const seconds = this.getSecondsFromDurationString(val);
+				this.value = seconds === null ? val : seconds;
+			}
+		},
+		
 		// field value for alternative data attribute
 		valueAlt:{
 			get() {
@@ -867,6 +1028,10 @@ let MyField = {
 			if(s.isData && s.formReadonly && state !== 'hidden')
 				state = 'readonly';
 			
+			// overwrite visible data field to readonly if attribute value is computed
+			if(s.isData && s.attribute.expression !== '' && state !== 'hidden')
+				state = 'readonly';
+			
 			return state;
 		},
 		tabIndexesHidden:(s) => {
@@ -987,8 +1152,12 @@ let MyField = {
 			&& !s.isColor
 			&& !s.isDateInput
 			&& !s.isFiles
+			&& !s.isGeo
 			&& !s.isIframe
+			&& !s.isInterval
+			&& !s.isJson
 			&& !s.isLogin
+			&& !s.isMoney
 			&& !s.isSlider
 			&& !s.isTextarea
 			&& !s.isRegconfig
@@ -1008,10 +1177,41 @@ let MyField = {
 			if(s.isDecimal && !/^-?\d+\.?\d*$/.test(s.value))        return false;
 			if(s.isInteger && !/^-?\d+$/.test(s.value))              return false;
 			
+			if((s.isGeo || s.isInterval) && typeof s.value === 'string')
+				return false;
+			
+			if(s.isMoney && (!/^-?\d+(\.\d+)?$/.test(s.value.amount) || !/^[A-Z]{3}$/.test(s.value.currency)))
+				return false;
+			
+			if(s.isJson && typeof s.value === 'string') {
+				try      { JSON.parse(s.value); }
+				catch(e) { return false; }
+			}
+			
 			if(s.isUuid && !/^[0-9a-f]{8}\-[0-9a-f]{4}\-[0-9a-f]{4}\-[0-9a-f]{4}\-[0-9a-f]{12}$/i.test(s.value))
 				return false;
 			
-			return s.isValidMin && s.isValidMax;
+			return s.isValidMin && s.isValidMax && s.isValidAttribute;
+		},
+		isValidAttribute:(s) => {
+			// attribute validation rules, enforced by backend
+			if(!s.isData || s.value === null || s.isEncrypted || s.isRelationship || s.isFiles) return true;
+			
+			const v = s.attribute.validation;
+			if(s.isString) {
+				if(v.regex !== null && !new RegExp(v.regex).test(s.value)) return false;
+				if(v.min !== null && s.value.length < v.min)               return false;
+				if(v.max !== null && s.value.length > v.max)               return false;
+				if(v.values.length !== 0 && !v.values.includes(s.value))   return false;
+			}
+			if(s.isDecimal || s.isInteger || s.isInterval) {
+				if(v.min !== null && s.value < v.min) return false;
+				if(v.max !== null && s.value > v.max) return false;
+				
+				if(v.values.length !== 0 && !v.values.some(a => Number(a) === Number(s.value)))
+					return false;
+			}
+			return true;
 		},
 		isValidMin:(s) => {
 			if(!s.isData || s.value === null || s.field.min === null) return true;
@@ -1055,6 +1255,7 @@ let MyField = {
 		isHeader:   (s) => s.field.content === 'header',
 		isKanban:   (s) => s.field.content === 'kanban',
 		isList:     (s) => s.field.content === 'list',
+		isMap:      (s) => s.field.content === 'map',
 		isTabs:     (s) => s.field.content === 'tabs',
 		
 		// states
@@ -1083,7 +1284,11 @@ let MyField = {
 		isFiles:         (s) => s.isData && s.isAttributeFiles(s.attribute.content),
 		isIframe:        (s) => s.isData && s.attribute.contentUse === 'iframe',
 		isInteger:       (s) => s.isData && s.isAttributeInteger(s.attribute.content),
-		isQuery:         (s) => s.isCalendar || s.isChart || s.isKanban || s.isList || s.isRelationship,
+		isInterval:      (s) => s.isData && s.isAttributeInterval(s.attribute.content),
+		isJson:          (s) => s.isData && s.isAttributeJson(s.attribute.content),
+		isMoney:         (s) => s.isData && s.isAttributeMoney(s.attribute.content),
+		isGeo:           (s) => s.isData && s.isAttributeGeo(s.attribute.content),
+		isQuery:         (s) => s.isCalendar || s.isChart || s.isKanban || s.isList || s.isMap || s.isRelationship,
 		isRegconfig:     (s) => s.isData && s.isAttributeRegconfig(s.attribute.content),
 		isRichtext:      (s) => s.isData && s.attribute.contentUse === 'richtext',
 		isString:        (s) => s.isData && s.isAttributeString(s.attribute.content),
@@ -1115,6 +1320,7 @@ let MyField = {
 		// externals
 		fieldOptionGet,
 		fieldOptionSet,
+		getDurationStringFromSeconds,
 		getFlexStyle,
 		getFormPopUpConfig,
 		getIndexAttributeId,
@@ -1122,11 +1328,16 @@ let MyField = {
 		getNilUuid,
 		getQueryColumnsProcessed,
 		getQueryFiltersProcessed,
+		getSecondsFromDurationString,
 		hasAccessToAttribute,
 		isAttributeBoolean,
 		isAttributeDecimal,
 		isAttributeFiles,
+		isAttributeGeo,
 		isAttributeInteger,
+		isAttributeInterval,
+		isAttributeJson,
+		isAttributeMoney,
 		isAttributeRelationship,
 		isAttributeRegconfig,
 		isAttributeString,
@@ -1155,6 +1366,7 @@ let MyField = {
 				showsData:  active && oneField && fields[0].content === 'data',
 				showsKanban:active && oneField && fields[0].content === 'kanban',
 				showsList:  active && oneField && fields[0].content === 'list',
+				showsMap:   active && oneField && fields[0].content === 'map',
 				showsTabs:  active && oneField && fields[0].content === 'tabs'
 			};
 		},
@@ -1277,6 +1489,22 @@ let MyField = {
 			if(this.field.jsFunctionId !== null)
 				this.$emit('execute-function',this.field.jsFunctionId);
 		},
+		setValueGeoCurrent() {
+			if(!navigator.geolocation)
+				return;
+			
+			navigator.geolocation.getCurrentPosition(
+				pos => this.value = {
+					type:'Point',
+					coordinates:[pos.coords.longitude,pos.coords.latitude]
+				},
+				err => this.$root.genericError(err.message)
+			);
+		},
+		setValueMoney(amount,currency) {
+			this.value = amount === '' && currency === '' ? null
+				: { amount:amount, currency:currency };
+		},
 		triggerButton(middleClick) {
 			if(this.field.openForm !== null)
 				this.openForm([],[],middleClick,null);
@@ -98,6 +98,15 @@ let MyFilterOperator = {
 				<option value="<@" :title="capApp.option.operator.arrContained">&lt;@</option>
 				<option value="&&" :title="capApp.option.operator.arrOverlap"  >&&</option>
 			</optgroup>
+			
+			<optgroup :label="capApp.operatorsJson">
+				<option value="?" :title="capApp.option.operator.jsonHasKey">?</option>
+			</optgroup>
+			
+			<optgroup :label="capApp.operatorsGeo">
+				<option value="WITHIN RADIUS" :title="capApp.option.operator.geoRadius">WITHIN RADIUS</option>
+				<option value="WITHIN BOX"    :title="capApp.option.operator.geoBox"   >WITHIN BOX</option>
+			</optgroup>
 		</template>
 		
 		<!-- operators in user mode -->
@@ -2,7 +2,10 @@ import MyArticles            from './articles.js';
 import MyField               from './field.js';
 import MyFormLog             from './formLog.js';
 import {hasAccessToRelation} from './shared/access.js';
-import {consoleError}        from './shared/error.js';
+import {
+	consoleError,
+	resolveErrCode
+} from './shared/error.js';
 import {srcBase64}           from './shared/image.js';
 import {generatePdf}         from './shared/pdf.js';
 import {
@@ -116,6 +119,22 @@ let MyForm = {
 				
 				<div class="area">
 					<template v-if="isData && !isBulkUpdate">
+						<my-button image="personMultiple.png"
+							v-if="presenceOthers.length !== 0"
+							:active="false"
+							:caption="String(presenceOthers.length)"
+							:captionTitle="presenceCaption"
+							:naked="true"
+						/>
+						<my-button image="lock.png"
+							v-if="!isNew"
+							@trigger="presenceLockToggle"
+							:active="presenceLockedBy === null && canUpdate"
+							:captionTitle="presenceLockedBy !== null
+								? capApp.presenceLockedBy.replace('{NAME}',presenceLockedBy)
+								: capApp.button.lockHint"
+							:naked="!presenceLock && presenceLockedBy === null"
+						/>
 						<my-button image="refresh.png"
 							v-if="!isMobile"
 							@trigger="get"
@@ -250,6 +269,7 @@ let MyForm = {
 		<my-form-log
 			v-if="showLog"
 			@close-log="showLog = false"
+			@restored="get"
 			:dataFieldMap="fieldIdMapData"
 			:entityIdMapState="entityIdMapState"
 			:form="form"
@@ -289,10 +309,17 @@ let MyForm = {
 			immediate:true
 		});
 		
+		// inform others about whether this login is editing the current record
+		this.$watch('hasChanges',this.presenceSet);
+		
+		// update current record if changed by others
+		this.$watch('dataChanged',this.dataChangedCheck);
+		
 		this.$store.commit('routingGuardAdd',this.routingGuard);
 		window.addEventListener('keydown',this.handleHotkeys);
 	},
 	unmounted() {
+		this.presenceDel();
 		this.$store.commit('routingGuardDel',this.routingGuard);
 		window.removeEventListener('keydown',this.handleHotkeys);
 	},
@@ -309,9 +336,13 @@ let MyForm = {
 			popUp:null,           // configuration for pop-up form (float)
 			popUpFieldIdSrc:null, // ID of field that pop-up form originated from
 			popUpFullscreen:false,// set this pop-up form to fullscreen mode
+			presenceLock:false,   // this login holds an exclusive edit lock on the current record
+			presenceRecord:null,  // record this form announced its presence on, { relationId:X, recordId:Y }
+			presenceTimer:null,   // interval for renewing record presence
 			recordActionFree:false, // set by DEL/SET calls before form functions, which can negate it to block following record actions
 			showHelp:false,       // show form context help
 			showLog:false,        // show data change log
+			subscriptionId:getRandomString(16), // ID for data change subscription of current record
 			titleOverwrite:null,  // custom form title, can be set via frontend function
 			updatingRecord:false, // form is currently attempting to update the current record (saving/deleting)
 			
@@ -323,6 +354,7 @@ let MyForm = {
 			fieldIdMapError:{},   // overwrites for field error messages (custom errors)
 			indexMapRecordId:{},  // record IDs for form, key: relation index
 			indexMapRecordKey:{}, // record en-/decryption keys, key: relation index
+			indexMapRecordVersion:{}, // record versions for detecting concurrent changes, key: relation index
 			indexesNoDel:{},      // relation indexes with no DEL permission (via relation policy)
 			indexesNoSet:{},      // relation indexes with no SET permission (via relation policy)
 			loginIdsEncryptFor:[],        // login IDs for which data keys are encrypted (e2ee), for current form relations/records
@@ -332,6 +364,7 @@ let MyForm = {
 			values:{},            // field values, key: index attribute ID
 			valuesDef:{},         // field value defaults (via field options)
 			valuesOrg:{},         // original field values, used to check for changes
+			valuesMerge:null,     // own field value changes, re-applied after reload to merge with concurrent changes
 			
 			// query data
 			relationId:null,      // source relation ID
@@ -366,7 +399,7 @@ let MyForm = {
 		isBulkUpdate:  (s) => s.isData && s.recordIds.length > 1,
 		isData:        (s) => s.relationId !== null,
 		isNew:         (s) => s.recordIds.length === 0,
-		isSingleField: (s) => s.fields.length === 1 && ['calendar','chart','kanban','list','tabs'].includes(s.fields[0].content),
+		isSingleField: (s) => s.fields.length === 1 && ['calendar','chart','kanban','list','map','tabs'].includes(s.fields[0].content),
 		menuActive:    (s) => typeof s.formIdMapMenu[s.form.id] === 'undefined' ? null : s.formIdMapMenu[s.form.id],
 		noDataActions: (s) => s.form.noDataActions || s.blockInputs,
 		warnUnsaved:   (s) => s.hasChanges && s.settings.warnUnsaved,
@@ -417,6 +450,28 @@ let MyForm = {
 			return '';
 		},
 		
+		// record presence
+		presenceOthers:(s) => {
+			if(s.presenceRecord === null) return [];
+			
+			const key = `${s.presenceRecord.relationId}_${s.presenceRecord.recordId}`;
+			return typeof s.recordPresence[key] === 'undefined'
+				? [] : s.recordPresence[key].filter(p => p.loginId !== s.loginId);
+		},
+		presenceCaption:(s) => {
+			let names = [];
+			for(const p of s.presenceOthers) {
+				if(p.lock)      names.push(`${p.loginName} (${s.capApp.presenceLocked})`);
+				else if(p.edit) names.push(`${p.loginName} (${s.capApp.presenceEditing})`);
+				else            names.push(p.loginName);
+			}
+			return names.join(', ');
+		},
+		presenceLockedBy:(s) => {
+			const p = s.presenceOthers.find(p => p.lock);
+			return typeof p === 'undefined' ? null : p.loginName;
+		},
+		
 		// helpers
 		exposedFunctions:(s) => {
 			return {
@@ -640,6 +695,7 @@ let MyForm = {
 		capApp:             (s) => s.$store.getters.captions.form,
 		capErr:             (s) => s.$store.getters.captions.error,
 		capGen:             (s) => s.$store.getters.captions.generic,
+		dataChanged:        (s) => s.$store.getters.dataChanged,
 		isAdmin:            (s) => s.$store.getters.isAdmin,
 		isMobile:           (s) => s.$store.getters.isMobile,
 		keyLength:          (s) => s.$store.getters.constants.keyLength,
@@ -649,6 +705,7 @@ let MyForm = {
 		loginPrivateKey:    (s) => s.$store.getters.loginPrivateKey,
 		moduleLanguage:     (s) => s.$store.getters.moduleLanguage,
 		patternStyle:       (s) => s.$store.getters.patternStyle,
+		recordPresence:     (s) => s.$store.getters.recordPresence,
 		settings:           (s) => s.$store.getters.settings
 	},
 	methods:{
@@ -681,6 +738,7 @@ let MyForm = {
 		isAttributeRelationshipN1,
 		openLink,
 		pemImport,
+		resolveErrCode,
 		rsaDecrypt,
 		rsaEncrypt,
 		srcBase64,
@@ -930,6 +988,11 @@ let MyForm = {
 					this.indexesNoSet.splice(pos,1);
 			}
 			
+			// update record versions for each relation index
+			for(let index in row.indexRecordVersions) {
+				this.indexMapRecordVersion[index] = row.indexRecordVersions[index];
+			}
+			
 			// update record data keys for each relation index
 			for(let index in row.indexRecordEncKeys) {
 				this.indexMapRecordKey[index] = await this.rsaDecrypt(
@@ -1139,6 +1202,99 @@ let MyForm = {
 				el.scrollIntoView();
 		},
 		
+		// data change subscription
+		dataChangedCheck(changes) {
+			if(this.presenceRecord === null)
+				return;
+			
+			for(const c of changes) {
+				if(c.relationId !== this.presenceRecord.relationId || !c.recordIds.includes(this.presenceRecord.recordId))
+					continue;
+				
+				// reload changed record, unless it is being edited
+				if(this.hasChanges || this.updatingRecord)
+					this.messageSet(this.capApp.message.recordChangedByOther,5000);
+				else
+					this.get();
+				
+				return;
+			}
+		},
+		
+		// record presence
+		presenceDel() {
+			if(this.presenceRecord === null)
+				return;
+			
+			clearInterval(this.presenceTimer);
+			ws.send('presence','del',this.presenceRecord,false).then(
+				() => {},
+				this.$root.genericError
+			);
+			ws.send('subscription','del',{id:this.subscriptionId},false).then(
+				() => {},
+				this.consoleError
+			);
+			this.$store.commit('recordPresence',{...this.presenceRecord,presence:null});
+			this.presenceLock   = false;
+			this.presenceRecord = null;
+			this.presenceTimer  = null;
+		},
+		presenceLockToggle() {
+			this.presenceLock = !this.presenceLock;
+			this.presenceSet();
+		},
+		presenceSet() {
+			if(this.presenceRecord === null)
+				return;
+			
+			const record = this.presenceRecord;
+			ws.send('presence','set',{
+				relationId:record.relationId,
+				recordId:record.recordId,
+				edit:this.hasChanges,
+				lock:this.presenceLock
+			},false).then(
+				res => this.$store.commit('recordPresence',{...record,presence:res.payload}),
+				err => {
+					// lock could not be acquired, renew presence without lock
+					if(this.presenceLock) {
+						this.presenceLock = false;
+						this.presenceSet();
+					}
+					this.$root.genericError(err);
+				}
+			);
+		},
+		presenceUpdate() {
+			const recordId = this.isData && !this.isNew && !this.isBulkUpdate
+				? this.recordIds[0] : null;
+			
+			if(this.presenceRecord !== null
+				&& this.presenceRecord.relationId === this.relationId
+				&& this.presenceRecord.recordId   === recordId) {
+				
+				return;
+			}
+			this.presenceDel();
+			
+			if(recordId === null)
+				return;
+			
+			this.presenceRecord = { relationId:this.relationId, recordId:recordId };
+			this.presenceTimer  = setInterval(this.presenceSet,30000);
+			this.presenceSet();
+			
+			ws.send('subscription','set',{
+				id:this.subscriptionId,
+				relationId:this.relationId,
+				recordIds:[recordId]
+			},false).then(
+				() => {},
+				this.consoleError
+			);
+		},
+		
 		// timer
 		timerClear(name) {
 			if(typeof this.timers[name] !== 'undefined') {
@@ -1302,6 +1458,7 @@ let MyForm = {
 		},
 		get() {
 			this.triggerEventBefore('open');
+			this.presenceUpdate();
 			
 			// no or multiple records defined, no need to load record data
 			if(this.isNew || this.isBulkUpdate) {
@@ -1335,7 +1492,9 @@ let MyForm = {
 				joins:this.relationsJoined,
 				expressions:expressions,
 				filters:filters,
-				getPerm:true
+				getPerm:true,
+				getVersion:true,
+				formId:this.form.id
 			},true).then(
 				res => {
 					// reset states
@@ -1344,13 +1503,23 @@ let MyForm = {
 					this.loading = true;
 					
 					// reset record meta
-					this.indexMapRecordId  = {};
-					this.indexMapRecordKey = {};
-					this.indexesNoDel      = [];
-					this.indexesNoSet      = [];
+					this.indexMapRecordId      = {};
+					this.indexMapRecordKey     = {};
+					this.indexMapRecordVersion = {};
+					this.indexesNoDel          = [];
+					this.indexesNoSet          = [];
 					
 					this.valueSetByRows(res.payload.rows,expressions).then(
-						() => this.triggerEventAfter('open'),
+						() => {
+							// re-apply own changes after reload to merge them with concurrent changes
+							if(this.valuesMerge !== null) {
+								for(let ia in this.valuesMerge) {
+									this.valueSet(ia,this.valuesMerge[ia],false,false);
+								}
+								this.valuesMerge = null;
+							}
+							this.triggerEventAfter('open');
+						},
 						err => {
 							this.badLoad = true;
 							this.consoleError(err);
@@ -1441,7 +1610,9 @@ let MyForm = {
 				joins:joins,
 				expressions:expressions,
 				filters:filters,
-				getPerm:true
+				getPerm:true,
+				getVersion:true,
+				formId:this.form.id
 			},true).then(
 				res => {
 					this.valueSetByRows(res.payload.rows,expressions).then(
@@ -1452,7 +1623,7 @@ let MyForm = {
 				this.$root.genericError
 			);
 		},
-		set:async function(saveAndNew) {
+		set:async function(saveAndNew,overwrite) {
 			if(this.fieldIdsInvalid.length !== 0)
 				return this.badSave = true;
 			
@@ -1527,6 +1698,8 @@ let MyForm = {
 					attributeId:j.attributeId,
 					indexFrom:j.indexFrom,
 					recordId:j.recordId,
+					version:isNew || overwrite === true || typeof this.indexMapRecordVersion[j.index] === 'undefined'
+						? 0 : this.indexMapRecordVersion[j.index],
 					attributes:[],
 					encKeysSet:encLoginKeys
 				};
@@ -1546,6 +1719,10 @@ let MyForm = {
 				if(!isNew && this.valueIsEqual(this.values[k],this.valuesOrg[k]))
 					continue;
 				
+				// ignore computed values, generated by the database
+				if(this.attributeIdMap[d.attributeId].expression !== '')
+					continue;
+				
 				// ignore values if join settings disallow creation/update
 				if(!j.applyCreate && j.recordId === 0) continue;
 				if(!j.applyUpdate && j.recordId !== 0) continue;
@@ -1569,7 +1746,8 @@ let MyForm = {
 					attributeId:d.attributeId,
 					attributeIdNm:d.attributeIdNm,
 					outsideIn:d.outsideIn,
-					value:value
+					value:value,
+					valueOrg:isNew || this.attributeIdMap[d.attributeId].encrypted ? null : this.valuesOrg[k]
 				});
 			}
 			
@@ -1678,11 +1856,46 @@ let MyForm = {
 					// if we knew nothing triggered, we could update our values without reload
 					this.get();
 				},
-				this.$root.genericError
+				err => {
+					// record was changed by others since it was loaded
+					if(typeof err === 'string' && err.startsWith('{ERR_APP_010}'))
+						return this.setConflict(err,saveAndNew);
+					
+					this.$root.genericError(err);
+				}
 			).finally(
 				() => this.updatingRecord = false
 			);
 		},
+		setConflict(err,saveAndNew) {
+			this.$store.commit('dialog',{
+				captionBody:this.resolveErrCode(err),
+				buttons:[{
+					caption:this.capApp.button.merge,
+					exec:this.setMerge,
+					keyEnter:true,
+					image:'refresh.png'
+				},{
+					cancel:true,
+					caption:this.capApp.button.overwrite,
+					exec:() => this.set(saveAndNew,true),
+					image:'save.png'
+				},{
+					caption:this.capGen.button.cancel,
+					keyEscape:true,
+					image:'cancel.png'
+				}]
+			});
+		},
+		setMerge() {
+			// keep own changes, reload record and apply them again on top of concurrent changes
+			this.valuesMerge = {};
+			for(let ia in this.values) {
+				if(!this.valueIsEqual(this.values[ia],this.valuesOrg[ia]))
+					this.valuesMerge[ia] = JSON.parse(JSON.stringify(this.values[ia]));
+			}
+			this.get();
+		},
 		setBulkUpdate() {
 			// bulk update, limitations:
 			// only existing records, only pop-up, no encryption, no joins
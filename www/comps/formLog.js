@@ -44,12 +44,51 @@ let MyFormLog = {
 			<span v-if="logs.length === 0">{{ capGen.nothingThere }}</span>
 			
 			<div class="entry" v-for="(l,i) in logs">
-				<div>
+				<div class="row space-between">
 					<my-button
 						@trigger="toggleLog(i)"
-						:caption="displayTitle(i,l.dateChange,l.loginName)"
+						:caption="displayTitle(i,l.dateChange,l.loginName,l.deleted)"
 						:naked="true"
 					/>
+					<my-button image="time.png"
+						@trigger="restoreGet(i)"
+						:active="!l.deleted && restoreIndex !== i"
+						:captionTitle="capApp.button.restore"
+						:naked="true"
+					/>
+				</div>
+				
+				<!-- restore preview, changes required to restore record to this state -->
+				<div class="log-restore" v-if="restoreIndex === i">
+					<span v-if="restoreChangeCount === 0">{{ capApp.restoreNoChanges }}</span>
+					<table class="log-restore-changes" v-if="restoreChangeCount !== 0">
+						<template v-for="r in restorePreview">
+							<tr v-for="a in r.attributes">
+								<td>{{ displayAttributeCaption(a.attributeId) }}</td>
+								<td>{{ displayRestoreValue(a.attributeId,a.valueCurrent) }}</td>
+								<td>\u2192</td>
+								<td>{{ displayRestoreValue(a.attributeId,a.valueRestored) }}</td>
+							</tr>
+							<tr v-for="f in r.files">
+								<td>{{ displayAttributeCaption(f.attributeId) }}</td>
+								<td>{{ f.action === 'assign' ? '-' : f.nameCurrent + (f.action === 'version' ? ' (v' + f.versionCurrent + ')' : '') }}</td>
+								<td>\u2192</td>
+								<td>{{ f.action === 'delete' ? '-' : f.name + (f.action === 'version' ? ' (v' + f.version + ')' : '') }}</td>
+							</tr>
+						</template>
+					</table>
+					<div class="row gap">
+						<my-button image="ok.png"
+							@trigger="restoreSet"
+							:active="restoreChangeCount !== 0"
+							:caption="capApp.button.restore"
+						/>
+						<my-button image="cancel.png"
+							@trigger="restoreReset"
+							:cancel="true"
+							:caption="capGen.button.cancel"
+						/>
+					</div>
 				</div>
 				
 				<div class="log-fields" v-if="logsShown.includes(i)">
@@ -127,7 +166,7 @@ let MyFormLog = {
 		joinsIndexMap:    { type:Object,  required:true },
 		values:           { type:Object,  required:true }
 	},
-	emits:['close-log'],
+	emits:['close-log','restored'],
 	watch:{
 		formLoading(v) {
 			if(!v) this.get();
@@ -137,7 +176,9 @@ let MyFormLog = {
 		return {
 			loading:false,
 			logs:[],
-			logsShown:[]
+			logsShown:[],
+			restoreIndex:-1,  // index of log group to restore, -1 if none
+			restorePreview:[] // changes required for restore, one per relation of log group
 		};
 	},
 	computed:{
@@ -151,6 +192,13 @@ let MyFormLog = {
 			}
 			return out;
 		},
+		restoreChangeCount:(s) => {
+			let cnt = 0;
+			for(const r of s.restorePreview) {
+				cnt += r.attributes.length + r.files.length;
+			}
+			return cnt;
+		},
 		indexAttributeIdMapField:(s) => {
 			let out = {};
 			for(let k in s.dataFieldMap) {
@@ -188,6 +236,17 @@ let MyFormLog = {
 		isAttributeFiles,
 		
 		// presentation
+		displayAttributeCaption(atrId) {
+			let atr = this.attributeIdMap[atrId];
+			return typeof atr.captions.attributeTitle[this.moduleLanguage] !== 'undefined'
+				? atr.captions.attributeTitle[this.moduleLanguage]
+				: atr.name;
+		},
+		displayRestoreValue(atrId,value) {
+			if(value === null)                      return '-';
+			if(this.attributeIdMap[atrId].encrypted) return this.capApp.restoreEncrypted;
+			return typeof value === 'object' ? JSON.stringify(value) : String(value);
+		},
 		displayFieldCaption(f) {
 			if(typeof f.captions.fieldTitle[this.moduleLanguage] !== 'undefined')
 				return f.captions.fieldTitle[this.moduleLanguage];
@@ -198,11 +257,12 @@ let MyFormLog = {
 				? atr.captions.attributeTitle[this.moduleLanguage]
 				: atr.name;
 		},
-		displayTitle(i,unixTime,name) {
+		displayTitle(i,unixTime,name,deleted) {
 			if(name === '') name = this.capApp.deletedUser;
 			let prefix = this.logsShown.includes(i) ? '\u2BC6' : '\u2BC8';
 			let format = [this.settings.dateFormat,'H:i:S'];
-			return `${prefix} ${this.getUnixFormat(unixTime,format.join(' '))} (${name})`;
+			let suffix = deleted ? ` - ${this.capApp.recordDeleted}` : '';
+			return `${prefix} ${this.getUnixFormat(unixTime,format.join(' '))} (${name})${suffix}`;
 		},
 		isFiles(ia) {
 			let d = this.getDetailsFromIndexAttributeId(ia);
@@ -239,6 +299,11 @@ let MyFormLog = {
 		reset() {
 			this.logs      = [];
 			this.logsShown = [];
+			this.restoreReset();
+		},
+		restoreReset() {
+			this.restoreIndex   = -1;
+			this.restorePreview = [];
 		},
 		
 		// backend calls
@@ -299,10 +364,17 @@ let MyFormLog = {
 							if(typeof logsGrouped[g] === 'undefined')
 								logsGrouped[g] = {
 									dateChange:l.dateChange,
+									deleted:false,
 									loginName:l.loginName,
+									logIds:[],
 									values:{}
 								};
 							
+							logsGrouped[g].logIds.push(l.id);
+							
+							if(l.deleted)
+								logsGrouped[g].deleted = true;
+							
 							for(const a of l.attributes) {
 								let value = JSON.parse(a.value);
 								
@@ -343,6 +415,30 @@ let MyFormLog = {
 				},
 				this.$root.genericError
 			);
+		},
+		restoreGet(i) {
+			ws.sendMultiple(this.logs[i].logIds.map(
+				id => ws.prepare('data','getLogRestore',{logId:id})
+			),true).then(
+				res => {
+					this.restoreIndex   = i;
+					this.restorePreview = res.map(v => v.payload);
+				},
+				this.$root.genericError
+			);
+		},
+		restoreSet() {
+			ws.sendMultiple(this.restorePreview.filter(
+				v => v.attributes.length !== 0 || v.files.length !== 0
+			).map(
+				v => ws.prepare('data','setLogRestore',{logId:v.logId})
+			),true).then(
+				() => {
+					this.restoreReset();
+					this.$emit('restored');
+				},
+				this.$root.genericError
+			);
 		}
 	}
 };
\ No newline at end of file
@@ -856,7 +856,8 @@ let MyGantt = {
 					this.indexDate1,
 					this.getUnixFromDate(this.date1)
 				)).concat(this.choiceFilters),
-				orders:this.query.orders
+				orders:this.query.orders,
+				fieldId:this.fieldId
 			},true).then(
 				res => {
 					// clear existing groups
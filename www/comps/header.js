@@ -155,6 +155,15 @@ let MyHeader = {
 					<img src="images/key_locked.png" />
 				</div>
 				
+				<!-- global search -->
+				<div class="entry no-wrap clickable" tabindex="0"
+					v-if="searchable && !isNoAuth"
+					@click="openSearch"
+					@keyup.enter="openSearch"
+				>
+					<img src="images/search.png" />
+				</div>
+				
 				<!-- feedback -->
 				<div class="entry no-wrap clickable" tabindex="0"
 					v-if="feedback && !isNoAuth"
@@ -258,14 +267,16 @@ let MyHeader = {
 		},
 		
 		// simple
-		pwaSingle:(s) => s.pwaModuleId !== null,
-		styles:   (s) => s.settings.compact ? '' : `max-width:${s.settings.pageLimit}px;`,
+		pwaSingle: (s) => s.pwaModuleId !== null,
+		searchable:(s) => Object.values(s.relationIdMap).some(v => v.searchFormId !== null),
+		styles:    (s) => s.settings.compact ? '' : `max-width:${s.settings.pageLimit}px;`,
 		
 		// stores
 		modules:        (s) => s.$store.getters['schema/modules'],
 		moduleIdMap:    (s) => s.$store.getters['schema/moduleIdMap'],
 		moduleNameMap:  (s) => s.$store.getters['schema/moduleNameMap'],
 		formIdMap:      (s) => s.$store.getters['schema/formIdMap'],
+		relationIdMap:  (s) => s.$store.getters['schema/relationIdMap'],
 		collectionIdMap:(s) => s.$store.getters['schema/collectionIdMap'],
 		builderEnabled: (s) => s.$store.getters.builderEnabled,
 		busyCounter:    (s) => s.$store.getters.busyCounter,
@@ -338,6 +349,7 @@ let MyHeader = {
 				return this.$router.push(`/app/${this.moduleSingle.name}/${this.moduleSingle.name}`);
 		},
 		openFeedback() { this.$store.commit('isAtFeedback',true); },
+		openSearch()   { this.$store.commit('isAtSearch',true); },
 		pagePrev()     { window.history.back(); },
 		pageNext()     { window.history.forward(); }
 	}
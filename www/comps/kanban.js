@@ -741,7 +741,8 @@ let MyKanban = {
 				joins:this.getRelationsJoined(this.joins),
 				expressions:this.expressions,
 				filters:this.filters.concat(this.choiceFilters),
-				orders:this.query.orders
+				orders:this.query.orders,
+				fieldId:this.fieldId
 			},true).then(
 				res => {
 					this.axisEntriesX = this.getAxisEntries(
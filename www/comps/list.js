@@ -12,6 +12,7 @@ import {srcBase64}        from './shared/image.js';
 import {getCaption}       from './shared/language.js';
 import {isAttributeFiles} from './shared/attribute.js';
 import {getColumnTitle}   from './shared/column.js';
+import {getRandomString}  from './shared/crypto.js';
 import {
 	fieldOptionGet,
 	fieldOptionSet
@@ -705,6 +706,7 @@ let MyList = {
 			showFilters:false,          // show UI for user filters
 			showTable:false,            // show regular list table as view or input dropdown
 			smallSize:false,            // limit UI options as list is small
+			subscriptionId:null,        // ID for data change subscriptions, to update list on changes by others
 			
 			// list card layout state
 			cardsOrderByColumnIndex:-1,
@@ -926,6 +928,7 @@ let MyList = {
 		showInputAddLine:(s) => !s.inputAsCategory && (!s.anyInputRows || (s.inputMulti && !s.inputIsReadonly)),
 		showInputAddAll: (s) => s.inputMulti && s.rowsClear.length > 0,
 		showInputHeader: (s) => s.isInput && (s.filterQuick || s.hasChoices || s.showInputAddAll || s.offset !== 0 || s.count > s.limit),
+		subscriptionRelationIds:(s) => [...new Set(s.query.joins.map(j => j.relationId))],
 		
 		// stores
 		relationIdMap: (s) => s.$store.getters['schema/relationIdMap'],
@@ -933,6 +936,7 @@ let MyList = {
 		iconIdMap:     (s) => s.$store.getters['schema/iconIdMap'],
 		capApp:        (s) => s.$store.getters.captions.list,
 		capGen:        (s) => s.$store.getters.captions.generic,
+		dataChanged:   (s) => s.$store.getters.dataChanged,
 		isMobile:      (s) => s.$store.getters.isMobile,
 		moduleLanguage:(s) => s.$store.getters.moduleLanguage,
 		scrollFormId:  (s) => s.$store.getters.constants.scrollFormId,
@@ -960,6 +964,7 @@ let MyList = {
 		this.$watch('isHidden',(val) => {
 			if(!val) this.reloadOutside();
 		});
+		this.$watch('dataChanged',this.dataChangedCheck);
 		this.$watch(() => [this.choices,this.columns,this.filters],(newVals,oldVals) => {
 			for(let i = 0, j = newVals.length; i < j; i++) {
 				if(JSON.stringify(newVals[i]) !== JSON.stringify(oldVals[i]))
@@ -1011,9 +1016,14 @@ let MyList = {
 		this.filtersQuick  = this.fieldOptionGet(this.fieldId,'filtersQuick','');
 		this.filtersUser   = this.fieldOptionGet(this.fieldId,'filtersUser',[]);
 		this.columnBatchIndexMapAggr = this.fieldOptionGet(this.fieldId,'columnBatchIndexMapAggr',{});
+		
+		// subscribe to data changes of all relations shown in list
+		this.subscriptionId = this.getRandomString(16);
+		this.subscriptionSet();
 	},
 	beforeUnmount() {
 		this.setAutoRenewTimer(true);
+		this.subscriptionDel();
 	},
 	unmounted() {
 		if(!this.Input)
@@ -1033,6 +1043,7 @@ let MyList = {
 		getFiltersEncapsulated,
 		getQueryAttributesPkFilter,
 		getQueryExpressions,
+		getRandomString,
 		getRelationsJoined,
 		getRowsDecrypted,
 		isAttributeFiles,
@@ -1067,6 +1078,30 @@ let MyList = {
 				this.isDropdownUpwards(this.$el,dropdownPx,headersPx);
 		},
 		
+		// data change subscriptions
+		dataChangedCheck(changes) {
+			for(const c of changes) {
+				if(this.subscriptionRelationIds.includes(c.relationId))
+					return this.reloadOutside();
+			}
+		},
+		subscriptionDel() {
+			for(const relationId of this.subscriptionRelationIds) {
+				ws.send('subscription','del',{
+					id:`${this.subscriptionId}_${relationId}`
+				},false).then(() => {},this.consoleError);
+			}
+		},
+		subscriptionSet() {
+			for(const relationId of this.subscriptionRelationIds) {
+				ws.send('subscription','set',{
+					id:`${this.subscriptionId}_${relationId}`,
+					relationId:relationId,
+					recordIds:[]
+				},false).then(() => {},this.consoleError);
+			}
+		},
+		
 		// reloads
 		reloadOutside() {
 			// outside state has changed, reload list or list input
@@ -1510,7 +1545,8 @@ let MyList = {
 				filters:this.filtersCombined,
 				orders:this.orders,
 				limit:this.limit,
-				offset:this.offset
+				offset:this.offset,
+				fieldId:this.fieldId
 			},true).then(
 				res => {
 					const count = res.payload.count;
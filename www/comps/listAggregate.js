@@ -1,6 +1,12 @@
 import {getFirstColumnUsableAsAggregator} from './shared/column.js';
+import {getMoneyFormatted}                from './shared/generic.js';
 import {getQueryExpressions}              from './shared/query.js';
 import {
+	isAttributeInterval,
+	isAttributeMoney
+} from './shared/attribute.js';
+import {
+	getDurationStringFromSeconds,
 	getUnixFormat,
 	getUtcTimeStringFromUnix
 } from './shared/time.js';
@@ -37,10 +43,14 @@ let MyListAggregate = {
 	},
 	methods:{
 		// external
+		getDurationStringFromSeconds,
 		getFirstColumnUsableAsAggregator,
+		getMoneyFormatted,
 		getQueryExpressions,
 		getUnixFormat,
 		getUtcTimeStringFromUnix,
+		isAttributeInterval,
+		isAttributeMoney,
 		
 		// calls
 		get() {
@@ -96,7 +106,19 @@ let MyListAggregate = {
 							continue;
 						}
 						
-						switch(this.attributeIdMap[columns[valueIndex].attributeId].contentUse) {
+						const atr = this.attributeIdMap[columns[valueIndex].attributeId];
+						
+						// money aggregations are lists of values (one per currency)
+						if(this.isAttributeMoney(atr.content)) {
+							this.columnBatchIndexMapValue[i] = this.getMoneyFormatted(v);
+							continue;
+						}
+						if(this.isAttributeInterval(atr.content)) {
+							this.columnBatchIndexMapValue[i] = this.getDurationStringFromSeconds(v);
+							continue;
+						}
+						
+						switch(atr.contentUse) {
 							case 'date':     v = this.getUnixFormat(v,this.dateFormat); break;
 							case 'datetime': v = this.getUnixFormat(v,this.dateFormat + ' H:i'); break;
 							case 'time':     v = this.getUtcTimeStringFromUnix(v); break;
@@ -116,10 +116,10 @@ let MyLogin = {
 					</div>
 				</div>
 				
-				<div class="content" :class="{ badAuth:badAuth }">
+				<div class="content" :class="{ badAuth:badAuth }" v-if="!showRegister">
 					
 					<!-- credentials input -->
-					<template v-if="!showMfa">
+					<template v-if="!showMfa && !showMfaSetup">
 						<input autocomplete="username" class="default" type="text" spellcheck="false"
 							@keyup="badAuth = false"
 							@keyup.enter="authenticate"
@@ -140,9 +140,17 @@ let MyLogin = {
 						<span>{{ message.mfa[language] }}</span>
 						<select v-model.number="mfaTokenId">
 							<option v-for="t in mfaTokens" :value="t.id">
-								{{ t.name }}
+								{{ t.context === 'mail' ? message.mfaMail[language].replace('{MAIL}',t.name) : t.name }}
 							</option>
 						</select>
+						<div class="row gap centered" v-if="mfaTokenId === 0">
+							<my-button image="mail.png"
+								@trigger="authenticateMailSend"
+								:active="!loading"
+								:caption="message.mfaMailSend[language]"
+							/>
+							<span v-if="mfaMailSent">{{ message.mfaMailSent[language] }}</span>
+						</div>
 						<input autocomplete="one-time-code" class="default" type="text" maxlength="6"
 							@keyup="badAuth = false"
 							@keyup.enter="authenticate"
@@ -152,6 +160,23 @@ let MyLogin = {
 						/>
 					</template>
 					
+					<!-- MFA setup, if MFA is required but not set up yet -->
+					<template v-if="showMfaSetup">
+						<span>{{ message.mfaSetup[language] }}</span>
+						<div class="login-mfa-qrcode" ref="qrcode"></div>
+						<input class="default" type="text"
+							v-model="mfaSetupName"
+							v-focus
+							:placeholder="message.mfaSetupName[language]"
+						/>
+						<input autocomplete="one-time-code" class="default" type="text" maxlength="6"
+							@keyup="badAuth = false"
+							@keyup.enter="authenticate"
+							v-model="mfaTokenPin"
+							:placeholder="message.mfaHint[language]"
+						/>
+					</template>
+					
 					<div class="actions">
 						<my-button
 							@trigger="tokenKeepInput = !tokenKeepInput"
@@ -159,6 +184,18 @@ let MyLogin = {
 							:image="tokenKeep ? 'checkbox1.png' : 'checkbox0.png'"
 							:naked="true"
 						/>
+						<my-button image="key.png"
+							v-if="clientCertAuth && !httpMode && !showMfa && !showMfaSetup"
+							@trigger="authenticateByCert"
+							:active="!loading"
+							:caption="message.loginCert[language]"
+						/>
+						<my-button image="person.png"
+							v-if="registration && !showMfa && !showMfaSetup"
+							@trigger="showRegister = true"
+							:active="!loading"
+							:caption="message.register[language]"
+						/>
 						<my-button
 							@trigger="authenticate"
 							:active="isValid"
@@ -167,6 +204,59 @@ let MyLogin = {
 						/>
 					</div>
 				</div>
+				
+				<!-- self-registration -->
+				<div class="content" :class="{ badAuth:badAuth }" v-if="showRegister">
+					<span>{{ message.registerSteps[registerStep][language] }}</span>
+					
+					<template v-if="registerStep === 'form'">
+						<input autocomplete="email" class="default" type="text" spellcheck="false"
+							@keyup="badAuth = false"
+							v-model="registerMail"
+							v-focus
+							:placeholder="message.registerMail[language]"
+						/>
+						<input autocomplete="new-password" class="default" type="password"
+							@keyup="badAuth = false"
+							v-model="registerPass0"
+							placeholder="password"
+						/>
+						<input autocomplete="new-password" class="default" type="password"
+							@keyup="badAuth = false"
+							@keyup.enter="register"
+							v-model="registerPass1"
+							placeholder="password"
+						/>
+					</template>
+					
+					<input autocomplete="one-time-code" class="default" type="text" maxlength="8"
+						v-if="registerStep === 'verify'"
+						@keyup="badAuth = false"
+						@keyup.enter="registerVerify"
+						v-model="registerCode"
+						v-focus
+						:placeholder="message.registerCode[language]"
+					/>
+					
+					<div class="actions">
+						<my-button image="cancel.png"
+							@trigger="registerClose"
+							:caption="message.close[language]"
+						/>
+						<my-button image="ok.png"
+							v-if="registerStep === 'form'"
+							@trigger="register"
+							:active="registerValid"
+							:caption="message.register[language]"
+						/>
+						<my-button image="ok.png"
+							v-if="registerStep === 'verify'"
+							@trigger="registerVerify"
+							:active="!loading && registerCode !== ''"
+							:caption="message.registerConfirm[language]"
+						/>
+					</div>
+				</div>
 			</div>
 		</template>
 			
@@ -196,10 +286,17 @@ let MyLogin = {
 	data() {
 		return {
 			// inputs
-			mfaTokens:[],     // list of TOTP tokens to choose from, [{id:12,name:'My Phone'},{...}]
-			mfaTokenId:null,  // selected TOTP token
-			mfaTokenPin:null, // entered TOTP PIN (6 digit code)
+			mfaMailSent:false, // MFA code was sent via mail
+			mfaSetupName:'',   // name of TOTP token to set up
+			mfaSetupSecret:'', // secret of TOTP token to set up, returned if MFA is required but not set up yet
+			mfaTokens:[],      // list of MFA tokens to choose from, [{id:12,name:'My Phone',context:'totp'},{id:0,name:'a***b@c.com',context:'mail'}]
+			mfaTokenId:null,   // selected MFA token, 0 = code via mail
+			mfaTokenPin:null,  // entered TOTP PIN or mail code (6 digit code)
 			password:'',
+			registerCode:'',  // code sent via mail to verify registration
+			registerMail:'',
+			registerPass0:'',
+			registerPass1:'', // repeated password
 			username:'',
 			
 			// states
@@ -207,12 +304,18 @@ let MyLogin = {
 			badAuth:false,       // authentication failed
 			licenseErrCode:null, // error with system license
 			loading:false,
+			registerStep:'form', // self-registration step (form, verify, done)
 			showError:false,
+			showRegister:false,
 			
 			// default messages
 			language:'en_US',
 			languages:['de','en_US'],
 			message:{
+				close:{
+					de:'Schließen',
+					en_US:'Close'
+				},
 				clusterNode:{
 					de:'Verbunden mit: ',
 					en_US:'Connected with: '
@@ -243,6 +346,10 @@ let MyLogin = {
 					de:'Anmelden',
 					en_US:'Login'
 				},
+				loginCert:{
+					de:'Mit Zertifikat anmelden',
+					en_US:'Login with certificate'
+				},
 				maintenanceMode:{
 					de:'Wartungsmodus ist aktiv',
 					en_US:'Maintenance mode is active'
@@ -255,6 +362,56 @@ let MyLogin = {
 					de:'6-stelliger Validierungs-Code',
 					en_US:'6 digit validation code'
 				},
+				mfaMail:{
+					de:'Code per E-Mail an {MAIL}',
+					en_US:'Code via email to {MAIL}'
+				},
+				mfaMailSend:{
+					de:'Code senden',
+					en_US:'Send code'
+				},
+				mfaMailSent:{
+					de:'Code wurde gesendet',
+					en_US:'Code was sent'
+				},
+				mfaSetup:{
+					de:'Multi-Faktor-Authentifizierung ist erforderlich. Bitte den Code mit einer Authenticator-App scannen und den erzeugten Validierungs-Code eingeben.',
+					en_US:'Multi-factor authentication is required. Please scan the code with an authenticator app and enter the generated validation code.'
+				},
+				mfaSetupName:{
+					de:'Name des Geräts, z. B. Mein Telefon',
+					en_US:'Name of device, e. g. My phone'
+				},
+				register:{
+					de:'Registrieren',
+					en_US:'Register'
+				},
+				registerCode:{
+					de:'Code aus E-Mail',
+					en_US:'Code from email'
+				},
+				registerConfirm:{
+					de:'Bestätigen',
+					en_US:'Confirm'
+				},
+				registerMail:{
+					de:'E-Mail-Adresse',
+					en_US:'Email address'
+				},
+				registerSteps:{
+					form:{
+						de:'Mit E-Mail-Adresse und Passwort registrieren',
+						en_US:'Register with email address and password'
+					},
+					verify:{
+						de:'Ein Code wurde an die E-Mail-Adresse gesendet, falls diese registriert werden kann',
+						en_US:'A code was sent to the email address, if it can be registered'
+					},
+					done:{
+						de:'Registrierung bestätigt - nach Freigabe durch einen Administrator ist die Anmeldung möglich',
+						en_US:'Registration confirmed - you can log in once an administrator has approved it'
+					}
+				},
 				stayLoggedIn:{
 					de:'Angemeldet bleiben',
 					en_US:'Stay logged in'
@@ -276,17 +433,24 @@ let MyLogin = {
 		// states
 		bgStyles:(s) => s.activated && s.companyColorLogin !== '' ? `background-color:#${s.companyColorLogin};` : '',
 		isValid: (s) => {
+			if(s.showMfaSetup)
+				return !s.badAuth && s.mfaTokenPin !== '';
+			
 			if(!s.showMfa)
 				return !s.badAuth && s.username !== '' && s.password !== '';
 			
 			return !s.badAuth && s.mfaTokenId !== null && s.mfaTokenPin !== null;
 		},
+		registerValid:(s) => !s.loading && !s.badAuth && s.registerMail.includes('@')
+			&& s.registerPass0 !== '' && s.registerPass0 === s.registerPass1,
 		showCustom:(s) => s.activated && (s.companyName !== '' || s.companyWelcome !== ''),
 		showMfa:   (s) => s.mfaTokens.length !== 0,
+		showMfaSetup:(s) => s.mfaSetupSecret !== '',
 		
 		// stores
 		activated:        (s) => s.$store.getters['local/activated'],
 		appName:          (s) => s.$store.getters['local/appName'],
+		appNameShort:     (s) => s.$store.getters['local/appNameShort'],
 		appVersion:       (s) => s.$store.getters['local/appVersion'],
 		companyColorLogin:(s) => s.$store.getters['local/companyColorLogin'],
 		companyName:      (s) => s.$store.getters['local/companyName'],
@@ -295,9 +459,11 @@ let MyLogin = {
 		customLogoUrl:    (s) => s.$store.getters['local/customLogoUrl'],
 		token:            (s) => s.$store.getters['local/token'],
 		tokenKeep:        (s) => s.$store.getters['local/tokenKeep'],
+		clientCertAuth:   (s) => s.$store.getters.clientCertAuth,
 		clusterNodeName:  (s) => s.$store.getters.clusterNodeName,
 		kdfIterations:    (s) => s.$store.getters.constants.kdfIterations,
-		productionMode:   (s) => s.$store.getters.productionMode
+		productionMode:   (s) => s.$store.getters.productionMode,
+		registration:     (s) => s.$store.getters.registration
 	},
 	watch:{
 		loginReady(v) {
@@ -366,9 +532,11 @@ let MyLogin = {
 			
 			switch(action) {
 				case 'aesExport': break;                      // very unexpected, should not happen
+				case 'authCert':  break;                      // no valid client certificate, regular login still possible
 				case 'authToken': break;                      // token auth failed, to be expected, can expire
 				case 'authUser':  this.badAuth = true; break; // user authorization failed, mark inputs invalid
 				case 'kdfCreate': break;                      // very unexpected, should not happen
+				case 'register':  this.badAuth = true; break; // registration failed, mark inputs invalid
 			}
 			this.loading = false;
 		},
@@ -377,6 +545,17 @@ let MyLogin = {
 			this.loading    = false;
 			this.appInitErr = true;
 		},
+		renderMfaSetupQrCode() {
+			if(typeof this.$refs.qrcode === 'undefined' || this.$refs.qrcode === null)
+				return;
+			
+			let app = encodeURIComponent(this.appNameShort);
+			let usr = encodeURIComponent(this.username);
+			let qr  = qrcode(0,'M');
+			qr.addData(`otpauth://totp/${app}:${usr}?issuer=${app}&secret=${this.mfaSetupSecret}`);
+			qr.make();
+			this.$refs.qrcode.innerHTML = qr.createImgTag(5,20);
+		},
 		
 		// authenticate by username/password or public user
 		authenticate() {
@@ -386,7 +565,9 @@ let MyLogin = {
 				username:this.username,
 				password:this.password,
 				mfaTokenId:this.mfaTokenId,
-				mfaTokenPin:this.mfaTokenPin
+				mfaTokenPin:this.mfaTokenPin,
+				mfaSetupName:this.showMfaSetup ? this.mfaSetupName : null,
+				mfaSetupSecret:this.showMfaSetup ? this.mfaSetupSecret : null
 			},true).then(
 				res => {
 					// MFA token list returned, MFA is required
@@ -398,6 +579,15 @@ let MyLogin = {
 						return;
 					}
 					
+					// TOTP secret returned, MFA is required but must be set up first
+					if(res.payload.mfaSetupSecret !== '') {
+						this.mfaSetupSecret = res.payload.mfaSetupSecret;
+						this.mfaTokenPin    = '';
+						this.loading        = false;
+						this.$nextTick(this.renderMfaSetupQrCode);
+						return;
+					}
+					
 					this.authenticatedByUser(
 						res.payload.loginId,
 						res.payload.loginName,
@@ -409,6 +599,24 @@ let MyLogin = {
 			);
 			this.loading = true;
 		},
+		authenticateMailSend() {
+			ws.send('auth','user',{
+				username:this.username,
+				password:this.password,
+				mfaMailSend:true
+			},true).then(
+				res => {
+					this.mfaTokens   = res.payload.mfaTokens;
+					this.mfaMailSent = true;
+					this.loading     = false;
+				},
+				err => {
+					this.mfaMailSent = false;
+					this.handleError('authUser',err);
+				}
+			);
+			this.loading = true;
+		},
 		authenticatePublic(username) {
 			// keep token as public user is not asked
 			this.$store.commit('local/tokenKeep',true);
@@ -424,6 +632,18 @@ let MyLogin = {
 			);
 			this.loading = true;
 		},
+		authenticateByCert() {
+			ws.send('auth','cert',{},true).then(
+				res => this.authenticatedByUser(
+					res.payload.loginId,
+					res.payload.loginName,
+					res.payload.token,
+					null
+				),
+				err => this.handleError('authCert',err)
+			);
+			this.loading = true;
+		},
 		authenticateByToken() {
 			ws.send('auth','token',{token:this.token},true).then(
 				res => this.appEnable(
@@ -466,10 +686,51 @@ let MyLogin = {
 			);
 		},
 		
+		// self-registration
+		register() {
+			if(!this.registerValid) return;
+			
+			ws.send('registration','register',{
+				mail:this.registerMail,
+				pass:this.registerPass0
+			},true).then(
+				() => {
+					this.registerStep = 'verify';
+					this.loading      = false;
+				},
+				err => this.handleError('register',err)
+			);
+			this.loading = true;
+		},
+		registerClose() {
+			this.badAuth       = false;
+			this.registerCode  = '';
+			this.registerPass0 = '';
+			this.registerPass1 = '';
+			this.registerStep  = 'form';
+			this.showRegister  = false;
+		},
+		registerVerify() {
+			ws.send('registration','verify',{
+				mail:this.registerMail,
+				code:this.registerCode
+			},true).then(
+				res => {
+					if(res.payload) this.registerStep = 'done';
+					else            this.badAuth      = true;
+					
+					this.loading = false;
+				},
+				err => this.handleError('register',err)
+			);
+			this.loading = true;
+		},
+		
 		// authentication successful, prepare application load
 		appEnable(loginId,loginName) {
 			let token = JSON.parse(atob(this.token.split('.')[1]));
 			this.$store.commit('isAdmin',token.admin);
+			this.$store.commit('isImpersonated',typeof token.impersonationId !== 'undefined' && token.impersonationId !== 0);
 			this.$store.commit('isNoAuth',token.noAuth);
 			this.$store.commit('loginId',loginId);
 			this.$store.commit('loginName',loginName);
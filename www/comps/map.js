@@ -0,0 +1,614 @@
+import MyForm             from './form.js';
+import MyInputCollection  from './inputCollection.js';
+import MyValueRich        from './valueRich.js';
+import srcBase64Icon      from './shared/image.js';
+import {getColumnBatches} from './shared/column.js';
+import {getChoiceFilters} from './shared/form.js';
+import {getCaption}       from './shared/language.js';
+import {
+	fieldOptionGet,
+	fieldOptionSet
+} from './shared/field.js';
+import {colorAdjustBg}    from './shared/generic.js';
+import {
+	fillRelationRecordIds,
+	getQueryExpressions,
+	getQueryFiltersGeoBox,
+	getRelationsJoined
+} from './shared/query.js';
+import {
+	routeChangeFieldReload,
+	routeParseParams
+} from './shared/router.js';
+export {MyMap as default};
+
+// map uses Web Mercator projection with tiles from OpenStreetMap
+// geo points are exchanged as GeoJSON point geometries (coordinates: longitude, latitude)
+const tileSize   = 256;
+const tileUrl    = 'https://tile.openstreetmap.org/{z}/{x}/{y}.png';
+const latMax     = 85.0511; // latitude limit of Web Mercator projection
+const zoomMin    = 1;
+const zoomMax    = 18;
+
+let MyMapPopUp = {
+	name:'my-map-pop-up',
+	components:{ MyValueRich },
+	template:`<div class="map-pop-up" :style="style">
+		<div class="map-pop-up-header">
+			<my-button image="open.png"
+				v-if="hasOpenForm"
+				@trigger="$emit('open-form',false)"
+				@trigger-middle="$emit('open-form',true)"
+				:caption="capGen.button.open"
+			/>
+			<div class="empty"></div>
+			<my-button image="cancel.png"
+				@trigger="$emit('close')"
+				:cancel="true"
+			/>
+		</div>
+		<table>
+			<tr v-for="b in columnBatches">
+				<td v-if="b.caption !== null" class="map-pop-up-label">{{ b.caption }}</td>
+				<td>
+					<div class="batch" :class="{ vertical:b.vertical }">
+						<my-value-rich
+							v-for="ind in b.columnIndexes.filter(v => values[v] !== null || columns[v].display === 'gallery')"
+							@clipboard="$emit('clipboard')"
+							:attributeId="columns[ind].attributeId"
+							:basis="columns[ind].basis"
+							:bold="columns[ind].styles.includes('bold')"
+							:clipboard="columns[ind].clipboard"
+							:display="columns[ind].display"
+							:italic="columns[ind].styles.includes('italic')"
+							:key="ind"
+							:length="columns[ind].length"
+							:value="values[ind]"
+							:wrap="columns[ind].wrap"
+						/>
+					</div>
+				</td>
+			</tr>
+		</table>
+	</div>`,
+	props:{
+		columns:      { type:Array,   required:true },
+		columnBatches:{ type:Array,   required:true },
+		hasOpenForm:  { type:Boolean, required:true },
+		x:            { type:Number,  required:true },
+		y:            { type:Number,  required:true },
+		values:       { type:Array,   required:true }
+	},
+	emits:['clipboard','close','open-form'],
+	computed:{
+		style:(s) => `left:${s.x}px;top:${s.y}px;`,
+		
+		// stores
+		capGen:(s) => s.$store.getters.captions.generic
+	}
+};
+
+let MyMap = {
+	name:'my-map',
+	components:{
+		MyInputCollection,
+		MyMapPopUp
+	},
+	template:`<div class="map" :class="{ isSingleField:isSingleField }" v-if="ready">
+		
+		<!-- header -->
+		<div class="top lower">
+			<div class="area nowrap">
+				<my-button image="new.png"
+					v-if="hasCreate"
+					@trigger="$emit('open-form',[],[],false)"
+					@trigger-middle="$emit('open-form',[],[],true)"
+					:caption="capGen.button.new"
+					:captionTitle="capGen.button.newHint"
+				/>
+			</div>
+			<div class="area nowrap">
+				<img class="icon"
+					v-if="iconId !== null"
+					:src="srcBase64Icon(iconId)"
+				/>
+				<my-button image="remove.png"
+					@trigger="zoomSet(zoom-1)"
+					:active="zoom > zoomMin"
+					:naked="true"
+				/>
+				<my-button image="add.png"
+					@trigger="zoomSet(zoom+1)"
+					:active="zoom < zoomMax"
+					:naked="true"
+				/>
+			</div>
+			<div class="area nowrap default-inputs">
+				<my-button image="refresh.png"
+					@trigger="get"
+					:caption="capGen.refresh"
+					:naked="true"
+				/>
+				<my-input-collection class="selector"
+					v-for="c in collections"
+					@update:modelValue="$emit('set-collection-indexes',c.collectionId,$event)"
+					:collectionId="c.collectionId"
+					:columnIdDisplay="c.columnIdDisplay"
+					:key="c.collectionId"
+					:modelValue="collectionIdMapIndexes[c.collectionId]"
+					:multiValue="c.multiValue"
+				/>
+				<select class="selector"
+					v-if="hasChoices"
+					v-model="choiceId"
+					@change="choiceIdSet($event.target.value)"
+				>
+					<option v-for="c in choices" :value="c.id">
+						{{ getCaption(c.captions.queryChoiceTitle,c.name) }}
+					</option>
+				</select>
+				<my-button
+					@trigger="showCaptions = !showCaptions"
+					:caption="capGen.label"
+					:image="showCaptions ? 'visible1.png' : 'visible0.png'"
+				/>
+			</div>
+		</div>
+		
+		<!-- content -->
+		<div class="map-wrap">
+			<div class="map-content" ref="content"
+				@pointerdown="panStart"
+				@pointermove="panMove"
+				@pointerup="panEnd"
+				@pointercancel="panEnd"
+				@wheel.prevent="zoomWheel"
+				:class="{ panning:panActive }"
+			>
+				<!-- tiles -->
+				<img class="map-tile" draggable="false"
+					v-for="t in tiles"
+					:key="t.key"
+					:src="t.src"
+					:style="t.style"
+				/>
+				
+				<!-- markers -->
+				<div class="map-marker"
+					v-for="(m,i) in markers"
+					@click.stop="markerIndexSelected = i"
+					@pointerdown.stop
+					:class="{ active:markerIndexSelected === i }"
+					:key="m.recordId"
+					:style="m.style"
+				></div>
+				
+				<!-- selected marker -->
+				<my-map-pop-up
+					v-if="markerSelected !== null"
+					@clipboard="$emit('clipboard')"
+					@close="markerIndexSelected = null"
+					@open-form="openForm(markerSelected.row,$event)"
+					@pointerdown.stop
+					@wheel.stop
+					:columns="columns"
+					:columnBatches="columnBatches"
+					:hasOpenForm="hasOpenForm"
+					:values="markerSelected.row.values.slice(expressionsOffset)"
+					:x="markerSelected.x"
+					:y="markerSelected.y"
+				/>
+				
+				<div class="map-attribution">© OpenStreetMap contributors</div>
+			</div>
+			
+			<!-- inline form -->
+			<my-form
+				v-if="popUpFormInline !== null"
+				@close="$emit('close-inline')"
+				@record-deleted="get"
+				@record-updated="get"
+				@records-open="popUpFormInline.recordIds = $event"
+				:attributeIdMapDef="popUpFormInline.attributeIdMapDef"
+				:formId="popUpFormInline.formId"
+				:hasHelp="false"
+				:hasLog="false"
+				:isPopUp="true"
+				:isPopUpFloating="false"
+				:moduleId="popUpFormInline.moduleId"
+				:recordIds="popUpFormInline.recordIds"
+				:style="popUpFormInline.style"
+			/>
+		</div>
+	</div>`,
+	props:{
+		attributeIdGeo:     { type:String,  required:true },
+		attributeIdColor:   { required:false, default:null },
+		choices:            { type:Array,   required:false, default:() => [] },
+		columns:            { type:Array,   required:true }, // processed list columns
+		collections:        { type:Array,   required:true },
+		collectionIdMapIndexes:{ type:Object, required:false, default:() => {return {}} },
+		fieldId:            { type:String,  required:true },
+		filters:            { type:Array,   required:true }, // processed query filters
+		formLoading:        { type:Boolean, required:true }, // block GET while form is still loading (avoid redundant GET calls)
+		hasOpenForm:        { type:Boolean, required:true },
+		iconId:             { required:true },
+		indexGeo:           { type:Number,  required:true },
+		indexColor:         { required:false, default:null },
+		isHidden:           { type:Boolean, required:false, default:false },
+		isSingleField:      { type:Boolean, required:false, default:false },
+		popUpFormInline:    { required:false, default:null },
+		query:              { type:Object,  required:true },
+		usesPageHistory:    { type:Boolean, required:true }
+	},
+	emits:['clipboard','close-inline','open-form','record-count-change','set-args','set-collection-indexes'],
+	data() {
+		return {
+			choiceId:null,
+			height:0,                // size of map content in pixels
+			width:0,
+			lat:30,                  // center of map
+			lon:0,
+			markerIndexSelected:null,
+			panActive:false,
+			panPointerX:0,           // pointer position at last pan move
+			panPointerY:0,
+			ready:false,
+			resizeObserver:null,
+			rows:[],
+			showCaptions:false,
+			timerReload:null,
+			zoom:2,
+			zoomMax:zoomMax,
+			zoomMin:zoomMin
+		};
+	},
+	computed:{
+		choiceIdDefault:(s) => s.fieldOptionGet(
+			// default is user field option, fallback is first choice in list
+			s.fieldId,'choiceId',
+			s.choices.length === 0 ? null : s.choices[0].id
+		),
+		expressionsGeo:(s) => {
+			let out = [{
+				attributeId:s.attributeIdGeo,
+				index:s.indexGeo,
+				groupBy:false,
+				aggregator:null
+			}];
+			if(s.attributeIdColor !== null)
+				out.push({
+					attributeId:s.attributeIdColor,
+					index:s.indexColor,
+					groupBy:false,
+					aggregator:null
+				});
+			
+			return out;
+		},
+		markers:(s) => {
+			let out = [];
+			for(const r of s.rows) {
+				const p = r.values[0];
+				if(p === null)
+					continue;
+				
+				const [x,y] = s.getPixelOffset(p.coordinates[0],p.coordinates[1]);
+				const color = s.attributeIdColor !== null && r.values[1] !== null
+					? `background-color:${s.colorAdjustBg(r.values[1])};` : '';
+				
+				out.push({
+					recordId:r.indexRecordIds[0],
+					row:r,
+					style:`left:${x}px;top:${y}px;${color}`,
+					x:x,
+					y:y
+				});
+			}
+			return out;
+		},
+		tiles:(s) => {
+			if(s.width === 0 || s.height === 0)
+				return [];
+			
+			const tileCount = Math.pow(2,s.zoom);
+			const [cx,cy]   = s.getPixelWorld(s.lon,s.lat);
+			const left      = cx - s.width / 2;
+			const top       = cy - s.height / 2;
+			
+			let out = [];
+			for(let ty = Math.floor(top / tileSize); ty <= Math.floor((top + s.height) / tileSize); ty++) {
+				if(ty < 0 || ty >= tileCount)
+					continue;
+				
+				for(let tx = Math.floor(left / tileSize); tx <= Math.floor((left + s.width) / tileSize); tx++) {
+					// wrap tiles horizontally
+					const txWrapped = ((tx % tileCount) + tileCount) % tileCount;
+					out.push({
+						key:`${s.zoom}_${tx}_${ty}`,
+						src:tileUrl.replace('{z}',s.zoom).replace('{x}',txWrapped).replace('{y}',ty),
+						style:`left:${Math.round(tx * tileSize - left)}px;top:${Math.round(ty * tileSize - top)}px;`
+					});
+				}
+			}
+			return out;
+		},
+		
+		// simple
+		choiceFilters:    (s) => s.getChoiceFilters(s.choices,s.choiceId),
+		columnBatches:    (s) => s.getColumnBatches(s.columns,[],s.showCaptions),
+		expressions:      (s) => s.expressionsGeo.concat(s.getQueryExpressions(s.columns)),
+		expressionsOffset:(s) => s.expressionsGeo.length,
+		hasChoices:       (s) => s.choices.length > 1,
+		hasCreate:        (s) => s.query.joins.length === 0 ? false : s.query.joins[0].applyCreate && s.hasOpenForm,
+		joins:            (s) => s.fillRelationRecordIds(s.query.joins),
+		markerSelected:   (s) => s.markerIndexSelected !== null && typeof s.markers[s.markerIndexSelected] !== 'undefined'
+			? s.markers[s.markerIndexSelected] : null,
+		
+		// stores
+		capGen:(s) => s.$store.getters.captions.generic
+	},
+	beforeCreate() {
+		// import at runtime due to circular dependencies
+		this.$options.components.MyForm = MyForm;
+	},
+	mounted() {
+		// setup watchers
+		this.$watch('formLoading',(val) => {
+			if(!val) this.reloadOutside();
+		});
+		this.$watch('isHidden',(val) => {
+			if(!val) this.$nextTick(() => this.get());
+		});
+		this.$watch(() => [this.choices,this.columns,this.filters],(newVals, oldVals) => {
+			for(let i = 0, j = newVals.length; i < j; i++) {
+				if(JSON.stringify(newVals[i]) !== JSON.stringify(oldVals[i]))
+					return this.reloadOutside();
+			}
+		});
+		if(this.usesPageHistory) {
+			this.$watch(() => [this.$route.path,this.$route.query],(newVals,oldVals) => {
+				if(this.routeChangeFieldReload(newVals,oldVals)) {
+					this.paramsUpdated();
+					this.reloadOutside();
+				}
+			});
+		}
+		
+		if(this.usesPageHistory) {
+			// set initial states via route parameters
+			this.paramsUpdated();     // load existing parameters from route query
+			this.paramsUpdate(false); // overwrite parameters (in case defaults are set)
+		} else {
+			this.choiceId = this.choiceIdDefault;
+		}
+		
+		// initial field options
+		this.lat          = this.fieldOptionGet(this.fieldId,'mapLat',this.lat);
+		this.lon          = this.fieldOptionGet(this.fieldId,'mapLon',this.lon);
+		this.showCaptions = this.fieldOptionGet(this.fieldId,'mapShowCaptions',this.showCaptions);
+		this.zoom         = this.fieldOptionGet(this.fieldId,'mapZoom',this.zoom);
+		
+		// setup watchers for presentation changes
+		this.$watch('showCaptions',(val) => {
+			this.fieldOptionSet(this.fieldId,'mapShowCaptions',val);
+		});
+		
+		this.ready = true;
+		this.$nextTick(() => {
+			this.resizeObserver = new ResizeObserver(this.resized);
+			this.resizeObserver.observe(this.$refs.content);
+			this.resized();
+			this.get();
+		});
+	},
+	beforeUnmount() {
+		if(this.resizeObserver !== null)
+			this.resizeObserver.disconnect();
+		
+		clearTimeout(this.timerReload);
+	},
+	methods:{
+		// external
+		colorAdjustBg,
+		fieldOptionGet,
+		fieldOptionSet,
+		fillRelationRecordIds,
+		getCaption,
+		getChoiceFilters,
+		getColumnBatches,
+		getQueryExpressions,
+		getQueryFiltersGeoBox,
+		getRelationsJoined,
+		routeChangeFieldReload,
+		routeParseParams,
+		srcBase64Icon,
+		
+		// projection, pixel positions in world map at current zoom level
+		getPixelWorld(lon,lat) {
+			const size   = tileSize * Math.pow(2,this.zoom);
+			const latRad = Math.max(-latMax,Math.min(latMax,lat)) * Math.PI / 180;
+			return [
+				(lon + 180) / 360 * size,
+				(1 - Math.log(Math.tan(latRad) + 1 / Math.cos(latRad)) / Math.PI) / 2 * size
+			];
+		},
+		getPixelOffset(lon,lat) {
+			// pixel position relative to top left corner of map content
+			const [cx,cy] = this.getPixelWorld(this.lon,this.lat);
+			const [px,py] = this.getPixelWorld(lon,lat);
+			return [
+				Math.round(px - cx + this.width / 2),
+				Math.round(py - cy + this.height / 2)
+			];
+		},
+		getLonLat(x,y) {
+			// reverse of world pixel projection
+			const size = tileSize * Math.pow(2,this.zoom);
+			const n    = Math.PI - 2 * Math.PI * y / size;
+			return [
+				x / size * 360 - 180,
+				180 / Math.PI * Math.atan(Math.sinh(n))
+			];
+		},
+		getBounds() {
+			// visible area as bounding box, full longitude range if map wraps around
+			const [cx,cy] = this.getPixelWorld(this.lon,this.lat);
+			let [lonMin,latBottom] = this.getLonLat(cx - this.width / 2,cy + this.height / 2);
+			let [lonMax,latTop]    = this.getLonLat(cx + this.width / 2,cy - this.height / 2);
+			
+			if(lonMin < -180 || lonMax > 180 || lonMax - lonMin >= 360) {
+				lonMin = -180;
+				lonMax = 180;
+			}
+			return [
+				lonMin,
+				Math.max(-90,latBottom),
+				lonMax,
+				Math.min(90,latTop)
+			];
+		},
+		
+		// actions
+		choiceIdSet(choiceId) {
+			this.fieldOptionSet(this.fieldId,'choiceId',choiceId);
+			this.choiceId = choiceId;
+			this.reloadInside();
+		},
+		openForm(row,middleClick) {
+			this.$emit('open-form',[row],[],middleClick);
+		},
+		panStart(e) {
+			if(e.button !== 0)
+				return;
+			
+			this.panActive   = true;
+			this.panPointerX = e.clientX;
+			this.panPointerY = e.clientY;
+			this.$refs.content.setPointerCapture(e.pointerId);
+		},
+		panMove(e) {
+			if(!this.panActive)
+				return;
+			
+			const [cx,cy] = this.getPixelWorld(this.lon,this.lat);
+			this.setCenter(...this.getLonLat(
+				cx - (e.clientX - this.panPointerX),
+				cy - (e.clientY - this.panPointerY)
+			));
+			this.panPointerX = e.clientX;
+			this.panPointerY = e.clientY;
+		},
+		panEnd(e) {
+			if(!this.panActive)
+				return;
+			
+			this.panActive = false;
+			this.$refs.content.releasePointerCapture(e.pointerId);
+			this.viewChanged();
+		},
+		resized() {
+			this.width  = this.$refs.content.clientWidth;
+			this.height = this.$refs.content.clientHeight;
+		},
+		setCenter(lon,lat) {
+			// wrap longitude, limit latitude to projection
+			this.lon = ((lon + 540) % 360) - 180;
+			this.lat = Math.max(-latMax,Math.min(latMax,lat));
+		},
+		viewChanged() {
+			this.fieldOptionSet(this.fieldId,'mapLat',this.lat);
+			this.fieldOptionSet(this.fieldId,'mapLon',this.lon);
+			this.fieldOptionSet(this.fieldId,'mapZoom',this.zoom);
+			
+			// reload records for visible area, wait for further changes
+			clearTimeout(this.timerReload);
+			this.timerReload = setTimeout(this.get,400);
+		},
+		zoomSet(zoom) {
+			zoom = Math.max(zoomMin,Math.min(zoomMax,zoom));
+			if(zoom === this.zoom)
+				return false;
+			
+			this.zoom = zoom;
+			this.viewChanged();
+			return true;
+		},
+		zoomWheel(e) {
+			if(this.panActive)
+				return;
+			
+			// keep geo position under pointer in place
+			const rect      = this.$refs.content.getBoundingClientRect();
+			const offsetX   = e.clientX - rect.left - this.width / 2;
+			const offsetY   = e.clientY - rect.top - this.height / 2;
+			const [cx,cy]   = this.getPixelWorld(this.lon,this.lat);
+			const [lon,lat] = this.getLonLat(cx + offsetX,cy + offsetY);
+			
+			if(!this.zoomSet(this.zoom + (e.deltaY < 0 ? 1 : -1)))
+				return;
+			
+			const [px,py] = this.getPixelWorld(lon,lat);
+			this.setCenter(...this.getLonLat(px - offsetX,py - offsetY));
+			this.viewChanged();
+		},
+		
+		// reloads
+		reloadOutside() {
+			this.get();
+		},
+		reloadInside() {
+			// reload full page map by updating route parameters
+			// enables browser history for fullpage navigation
+			if(this.usesPageHistory)
+				return this.paramsUpdate(true);
+			
+			this.get();
+		},
+		
+		// page routing
+		paramsUpdate(pushHistory) {
+			let args = [];
+			
+			if(this.choiceId !== null)
+				args.push(`choice=${this.choiceId}`);
+			
+			this.$emit('set-args',args,pushHistory);
+		},
+		paramsUpdated() {
+			let params = {
+				choice:{ parse:'string', value:this.choiceIdDefault }
+			};
+			
+			this.routeParseParams(params);
+			
+			if(this.choiceId !== params['choice'].value)
+				this.choiceId = params['choice'].value;
+		},
+		
+		// backend calls
+		get() {
+			if(this.formLoading || this.isHidden || this.width === 0)
+				return;
+			
+			ws.send('data','get',{
+				relationId:this.query.relationId,
+				joins:this.getRelationsJoined(this.joins),
+				expressions:this.expressions,
+				filters:this.filters.concat(this.choiceFilters).concat(
+					this.getQueryFiltersGeoBox(this.attributeIdGeo,this.indexGeo,...this.getBounds())
+				),
+				orders:this.query.orders,
+				limit:this.query.fixedLimit,
+				fieldId:this.fieldId
+			},true).then(
+				res => {
+					this.markerIndexSelected = null;
+					this.rows = res.payload.rows;
+					this.$emit('record-count-change',res.payload.count);
+				},
+				this.$root.genericError
+			);
+		}
+	}
+};
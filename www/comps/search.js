@@ -0,0 +1,158 @@
+import {getFormRoute}        from './shared/form.js';
+import {getCaptionForModule} from './shared/language.js';
+export {MySearch as default};
+
+let MySearch = {
+	name:'my-search',
+	template:`<div class="app-sub-window" @click.self="close">
+		<div class="search contentBox">
+			<div class="top lower">
+				<div class="area">
+					<img class="icon" src="images/search.png" />
+					<div class="caption">{{ capApp.title }}</div>
+				</div>
+				<div class="area">
+					<my-button image="cancel.png"
+						@trigger="close"
+						:cancel="true"
+					/>
+				</div>
+			</div>
+			
+			<div class="content default-inputs">
+				<div class="row gap">
+					<input class="search-input"
+						v-focus
+						v-model="text"
+						@keyup.enter="get"
+						:placeholder="capApp.textHint"
+					/>
+					<select class="short" v-model="dictionary" v-if="dictionaries.length > 1">
+						<option v-for="d in dictionaries" :value="d">{{ d }}</option>
+					</select>
+					<my-button image="search.png"
+						@trigger="get"
+						:active="text !== ''"
+						:caption="capApp.button.search"
+					/>
+				</div>
+				
+				<span v-if="searched && results.length === 0">{{ capApp.nothingFound }}</span>
+				
+				<div class="search-results">
+					<div class="search-result clickable" tabindex="0"
+						v-for="r in results"
+						@click="open(r,false)"
+						@click.middle="open(r,true)"
+						@keyup.enter="open(r,false)"
+					>
+						<div class="search-result-title">
+							<span>{{ getFormTitle(r.formId) }}</span>
+							<span class="search-result-attribute">{{ getAttributeTitle(r.attributeId) }}</span>
+						</div>
+						<div class="search-result-snippet">
+							<template v-for="(p,i) in getSnippetParts(r.snippet)">
+								<mark v-if="i % 2 === 1">{{ p }}</mark>
+								<span v-else>{{ p }}</span>
+							</template>
+						</div>
+					</div>
+				</div>
+			</div>
+		</div>
+	</div>`,
+	data() {
+		return {
+			dictionary:'simple',
+			results:[],
+			searched:false,
+			text:''
+		};
+	},
+	computed:{
+		dictionaries:(s) => ['simple'].concat(s.settings.searchDictionaries.filter(v => v !== 'simple')),
+		
+		// stores
+		attributeIdMap:(s) => s.$store.getters['schema/attributeIdMap'],
+		formIdMap:     (s) => s.$store.getters['schema/formIdMap'],
+		moduleIdMap:   (s) => s.$store.getters['schema/moduleIdMap'],
+		relationIdMap: (s) => s.$store.getters['schema/relationIdMap'],
+		capApp:        (s) => s.$store.getters.captions.search,
+		settings:      (s) => s.$store.getters.settings
+	},
+	mounted() {
+		if(this.settings.searchDictionaries.length !== 0)
+			this.dictionary = this.settings.searchDictionaries[0];
+		
+		window.addEventListener('keydown',this.handleHotkeys);
+	},
+	unmounted() {
+		window.removeEventListener('keydown',this.handleHotkeys);
+	},
+	methods:{
+		// externals
+		getCaptionForModule,
+		getFormRoute,
+		
+		// presentation
+		getAttributeTitle(attributeId) {
+			let a = this.attributeIdMap[attributeId];
+			if(a === undefined) return '';
+			
+			let m = this.moduleIdMap[this.relationIdMap[a.relationId].moduleId];
+			return this.getCaptionForModule(a.captions.attributeTitle,a.name,m);
+		},
+		getFormTitle(formId) {
+			let f = this.formIdMap[formId];
+			if(f === undefined) return '';
+			
+			return this.getCaptionForModule(f.captions.formTitle,f.name,this.moduleIdMap[f.moduleId]);
+		},
+		getSnippetParts(snippet) {
+			// matches are enclosed in <mark></mark>, odd parts are matches
+			return snippet.split(/<\/?mark>/);
+		},
+		
+		// general
+		handleHotkeys(e) {
+			if(e.key === 'Escape') {
+				this.close();
+				e.preventDefault();
+			}
+		},
+		
+		// actions
+		close() {
+			this.$store.commit('isAtSearch',false);
+		},
+		open(result,middleClick) {
+			if(this.formIdMap[result.formId] === undefined)
+				return;
+			
+			let route = this.getFormRoute(result.formId,result.recordId,false);
+			if(middleClick)
+				return window.open('#'+route,'_blank');
+			
+			this.$router.push(route);
+			this.close();
+		},
+		
+		// backend calls
+		get() {
+			if(this.text === '')
+				return;
+			
+			ws.send('data','search',{
+				dictionary:this.dictionary,
+				limit:50,
+				text:this.text
+			},true).then(
+				res => {
+					this.results  = res.payload;
+					this.searched = true;
+				},
+				this.$root.genericError
+			);
+		}
+	}
+};
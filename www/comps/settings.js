@@ -1,5 +1,8 @@
-import {set as setSetting} from './shared/settings.js';
-import {getUnixFormat}     from './shared/time.js';
+import MyInputDate           from './inputDate.js';
+import MyInputLogin          from './inputLogin.js';
+import {getCaptionForModule} from './shared/language.js';
+import {set as setSetting}   from './shared/settings.js';
+import {getUnixFormat}       from './shared/time.js';
 import {
 	aesGcmDecryptBase64,
 	aesGcmDecryptBase64WithPhrase,
@@ -890,12 +893,297 @@ let MySettingsFixedTokens = {
 	}
 };
 
+let MySettingsImpersonations = {
+	name:'my-settings-impersonations',
+	template:`<div>
+		<span v-if="impersonations.length === 0">{{ capApp.nothingThere }}</span>
+		<table class="default-inputs" v-if="impersonations.length !== 0">
+			<thead>
+				<tr>
+					<th>{{ capApp.titleAdmin }}</th>
+					<th>{{ capApp.titleReason }}</th>
+					<th>{{ capApp.titleDateStart }}</th>
+					<th>{{ capApp.titleDateEnd }}</th>
+					<th>{{ capApp.titleRequests }}</th>
+				</tr>
+			</thead>
+			<tbody>
+				<tr v-for="i in impersonations">
+					<td>{{ i.loginNameAdmin }}</td>
+					<td>{{ i.reason }}</td>
+					<td>{{ getUnixFormat(i.dateStart,'Y-m-d H:i:S') }}</td>
+					<td>{{ getUnixFormat(i.dateEnd,'Y-m-d H:i:S') }}</td>
+					<td>{{ i.requestCount }}</td>
+				</tr>
+			</tbody>
+		</table>
+	</div>`,
+	data() {
+		return {
+			impersonations:[]
+		};
+	},
+	computed:{
+		capApp:(s) => s.$store.getters.captions.settings.impersonations
+	},
+	mounted() {
+		ws.send('login','getImpersonations',{},true).then(
+			res => this.impersonations = res.payload,
+			this.$root.genericError
+		);
+	},
+	methods:{
+		// externals
+		getUnixFormat
+	}
+};
+
+let MySettingsDelegations = {
+	name:'my-settings-delegations',
+	components:{
+		MyInputDate,
+		MyInputLogin
+	},
+	template:`<div class="column gap">
+		<table class="default-inputs">
+			<tbody>
+				<tr>
+					<td>{{ capApp.substitute }}</td>
+					<td>
+						<my-input-login
+							v-model="loginIdSubstitute"
+							:idsExclude="[loginId]"
+						/>
+					</td>
+				</tr>
+				<tr>
+					<td>{{ capApp.roles }}</td>
+					<td>
+						<span v-if="roleIdsDelegable.length === 0">{{ capApp.nothingThere }}</span>
+						<div class="column">
+							<my-button
+								v-for="roleId in roleIdsDelegable"
+								@trigger="toggleRoleId(roleId)"
+								:caption="getRoleCaption(roleId)"
+								:image="roleIds.includes(roleId) ? 'checkbox1.png' : 'checkbox0.png'"
+								:naked="true"
+							/>
+						</div>
+					</td>
+				</tr>
+				<tr>
+					<td>{{ capApp.dateRange }}</td>
+					<td>
+						<my-input-date
+							@set-unix-from="dateFrom = $event"
+							@set-unix-to="dateTo = $event"
+							:isDate="true"
+							:isRange="true"
+							:isTime="true"
+							:unixFrom="dateFrom"
+							:unixTo="dateTo"
+						/>
+					</td>
+				</tr>
+			</tbody>
+		</table>
+		<div class="row">
+			<my-button image="add.png"
+				@trigger="set"
+				:active="canSave"
+				:caption="capApp.button.delegate"
+			/>
+		</div>
+		
+		<span v-if="delegations.length === 0">{{ capApp.nothingThere }}</span>
+		<table class="default-inputs" v-if="delegations.length !== 0">
+			<thead>
+				<tr>
+					<th>{{ capApp.titleFrom }}</th>
+					<th>{{ capApp.titleTo }}</th>
+					<th>{{ capApp.roles }}</th>
+					<th>{{ capApp.titleDateFrom }}</th>
+					<th>{{ capApp.titleDateTo }}</th>
+					<th>{{ capApp.titleState }}</th>
+					<th></th>
+				</tr>
+			</thead>
+			<tbody>
+				<tr v-for="d in delegations">
+					<td>{{ d.loginName }}</td>
+					<td>{{ d.loginNameSubstitute }}</td>
+					<td>{{ getRoleCaption(d.roleId) }}</td>
+					<td>{{ getUnixFormat(d.dateFrom,'Y-m-d H:i') }}</td>
+					<td>{{ getUnixFormat(d.dateTo,'Y-m-d H:i') }}</td>
+					<td>{{ d.revoked ? capApp.stateRevoked : (d.dateEnded !== null ? capApp.stateEnded : capApp.stateActive) }}</td>
+					<td>
+						<my-button image="cancel.png"
+							v-if="d.loginId === loginId && d.dateEnded === null"
+							@trigger="del(d)"
+							:cancel="true"
+							:caption="capApp.button.revoke"
+						/>
+					</td>
+				</tr>
+			</tbody>
+		</table>
+	</div>`,
+	data() {
+		return {
+			delegations:[],
+			loginIdSubstitute:null,
+			roleIds:[],
+			dateFrom:null,
+			dateTo:null
+		};
+	},
+	computed:{
+		// only directly assignable roles can be delegated, assignments are checked by the backend
+		roleIdsDelegable:(s) => s.access.roleIds.filter(v => s.roleIdMap[v] !== undefined && s.roleIdMap[v].assignable),
+		
+		// simple
+		canSave:(s) => s.loginIdSubstitute !== null && s.roleIds.length !== 0 && s.dateFrom !== null && s.dateTo !== null,
+		
+		// stores
+		access:     (s) => s.$store.getters.access,
+		loginId:    (s) => s.$store.getters.loginId,
+		moduleIdMap:(s) => s.$store.getters['schema/moduleIdMap'],
+		roleIdMap:  (s) => s.$store.getters['schema/roleIdMap'],
+		capApp:     (s) => s.$store.getters.captions.settings.delegations
+	},
+	mounted() {
+		this.get();
+	},
+	methods:{
+		// externals
+		getCaptionForModule,
+		getUnixFormat,
+		
+		getRoleCaption(roleId) {
+			let r = this.roleIdMap[roleId];
+			if(r === undefined) return '-';
+			
+			let m = this.moduleIdMap[r.moduleId];
+			return `${this.getCaptionForModule(m.captions['moduleTitle'],m.name,m)}: ${this.getCaptionForModule(r.captions['roleTitle'],r.name,m)}`;
+		},
+		toggleRoleId(roleId) {
+			let pos = this.roleIds.indexOf(roleId);
+			if(pos === -1) this.roleIds.push(roleId);
+			else           this.roleIds.splice(pos,1);
+		},
+		
+		// backend calls
+		del(d) {
+			ws.send('loginRoleDelegation','del',{id:d.id},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		},
+		get() {
+			ws.send('loginRoleDelegation','get',{},true).then(
+				res => this.delegations = res.payload,
+				this.$root.genericError
+			);
+		},
+		set() {
+			ws.send('loginRoleDelegation','set',{
+				loginIdSubstitute:this.loginIdSubstitute,
+				roleIds:this.roleIds,
+				dateFrom:this.dateFrom,
+				dateTo:this.dateTo
+			},true).then(
+				() => {
+					this.loginIdSubstitute = null;
+					this.roleIds           = [];
+					this.dateFrom          = null;
+					this.dateTo            = null;
+					this.get();
+				},
+				this.$root.genericError
+			);
+		}
+	}
+};
+
+let MySettingsRecycleBin = {
+	name:'my-settings-recycle-bin',
+	template:`<div class="column gap">
+		<span v-if="entries.length === 0">{{ capApp.nothingThere }}</span>
+		<table class="default-inputs" v-if="entries.length !== 0">
+			<thead>
+				<tr>
+					<th>{{ capApp.relation }}</th>
+					<th>{{ capApp.recordId }}</th>
+					<th>{{ capApp.recordCount }}</th>
+					<th>{{ capApp.dateDeleted }}</th>
+					<th></th>
+				</tr>
+			</thead>
+			<tbody>
+				<tr v-for="e in entries">
+					<td>{{ getRelationCaption(e.relationId) }}</td>
+					<td>{{ e.recordId }}</td>
+					<td>{{ e.recordCount }}</td>
+					<td>{{ getUnixFormat(e.dateDeleted,'Y-m-d H:i') }}</td>
+					<td>
+						<my-button image="time.png"
+							@trigger="restore(e.id)"
+							:caption="capGen.button.restore"
+						/>
+					</td>
+				</tr>
+			</tbody>
+		</table>
+	</div>`,
+	data() {
+		return {
+			entries:[] // recycle bin entries, deleted by this login
+		};
+	},
+	computed:{
+		// stores
+		moduleIdMap:  (s) => s.$store.getters['schema/moduleIdMap'],
+		relationIdMap:(s) => s.$store.getters['schema/relationIdMap'],
+		capApp:       (s) => s.$store.getters.captions.settings.recycleBin,
+		capGen:       (s) => s.$store.getters.captions.generic
+	},
+	mounted() {
+		this.get();
+	},
+	methods:{
+		// externals
+		getUnixFormat,
+		
+		getRelationCaption(relationId) {
+			let r = this.relationIdMap[relationId];
+			return r === undefined ? '-' : `${this.moduleIdMap[r.moduleId].name}.${r.name}`;
+		},
+		
+		// backend calls
+		get() {
+			ws.send('data','getRecycled',{},true).then(
+				res => this.entries = res.payload,
+				this.$root.genericError
+			);
+		},
+		restore(id) {
+			ws.send('data','restoreRecycled',{id:id},true).then(
+				this.get,
+				this.$root.genericError
+			);
+		}
+	}
+};
+
 let MySettings = {
 	name:'my-settings',
 	components:{
 		MySettingsAccount,
+		MySettingsDelegations,
+		MySettingsRecycleBin,
 		MySettingsEncryption,
 		MySettingsFixedTokens,
+		MySettingsImpersonations
 	},
 	template:`<div class="settings">
 		
@@ -1135,6 +1423,33 @@ let MySettings = {
 						:moduleEntries="moduleEntries"
 					/>
 				</div>
+				
+				<!-- impersonations by admins -->
+				<div class="contentPart short">
+					<div class="contentPartHeader">
+						<img class="icon" src="images/visible1.png" />
+						<h1>{{ capApp.titleImpersonations }}</h1>
+					</div>
+					<my-settings-impersonations />
+				</div>
+				
+				<!-- role delegations to substitutes -->
+				<div class="contentPart short">
+					<div class="contentPartHeader">
+						<img class="icon" src="images/person.png" />
+						<h1>{{ capApp.titleDelegations }}</h1>
+					</div>
+					<my-settings-delegations />
+				</div>
+				
+				<!-- own deleted records, kept in recycle bin -->
+				<div class="contentPart short">
+					<div class="contentPartHeader">
+						<img class="icon" src="images/delete.png" />
+						<h1>{{ capApp.titleRecycleBin }}</h1>
+					</div>
+					<my-settings-recycle-bin />
+				</div>
 			</div>
 		</div>
 	</div>`,
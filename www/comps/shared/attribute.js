@@ -140,6 +140,10 @@ export function getAttributeIcon(attribute,outsideIn,isNm) {
 	if(isAttributeNumeric(attribute.content))   return 'numbers_decimal.png';
 	if(isAttributeFiles(attribute.content))     return 'files.png';
 	if(isAttributeRegconfig(attribute.content)) return 'languages.png';
+	if(isAttributeJson(attribute.content))      return 'code.png';
+	if(isAttributeGeo(attribute.content))       return 'globe.png';
+	if(isAttributeMoney(attribute.content))     return 'numbers_decimal.png';
+	if(isAttributeInterval(attribute.content))  return 'time.png';
 	
 	if(isAttributeRelationship11(attribute.content))
 		return 'link1.png';
@@ -157,7 +161,11 @@ export function isAttributeBoolean(content)   { return content === 'boolean'; };
 export function isAttributeDecimal(content)   { return attributeContentNames.decimal.includes(content); };
 export function isAttributeFiles(content)     { return content === 'files'; };
 export function isAttributeFloat(content)     { return attributeContentNames.float.includes(content); };
+export function isAttributeGeo(content)       { return content === 'point'; };
 export function isAttributeInteger(content)   { return attributeContentNames.integer.includes(content); };
+export function isAttributeInterval(content)  { return content === 'interval'; };
+export function isAttributeJson(content)      { return content === 'jsonb'; };
+export function isAttributeMoney(content)     { return content === 'money'; };
 export function isAttributeNumeric(content)   { return content === 'numeric'; };
 export function isAttributeRegconfig(content) { return content === 'regconfig'; };
 export function isAttributeString(content)    { return attributeContentNames.text.includes(content); };
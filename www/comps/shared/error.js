@@ -155,6 +155,71 @@ export function resolveErrCode(message) {
 				
 				return cap.replace('{VALUE}',matches[1]);
 			break;
+			case '008': // check constraint broken, attribute validation expression
+				matches = message.match(/\[ATR_ID\:(.{36})\]/);
+				if(matches === null || matches.length !== 2)
+					return message;
+				
+				atr = MyStore.getters['schema/attributeIdMap'][matches[1]];
+				if(typeof atr === 'undefined')
+					return message;
+				
+				return cap.replace('{NAME}',typeof atr.captions.attributeTitle[lang] !== 'undefined'
+					? atr.captions.attributeTitle[lang] : atr.name);
+			break;
+		}
+	}
+	if(errContext === 'APP') {
+		let lang = MyStore.getters.moduleLanguage;
+		
+		switch(errNumber) {
+			case '010': // record was changed by others since it was retrieved
+				matches = message.match(/\[ATR_IDS\:([^\]]*)\]/);
+				if(matches === null || matches.length !== 2)
+					return message;
+				
+				let names = [];
+				for(const atrId of matches[1].split(',')) {
+					let atr = MyStore.getters['schema/attributeIdMap'][atrId];
+					if(typeof atr !== 'undefined')
+						names.push(typeof atr.captions.attributeTitle[lang] !== 'undefined'
+							? atr.captions.attributeTitle[lang] : atr.name);
+				}
+				
+				matches = message.match(/\[LOGINS\:([^\]]*)\]/);
+				return cap.replace('{NAMES}',names.join(', ')).replace('{LOGINS}',
+					matches === null || matches[1] === '' ? '-' : matches[1].split(',').join(', '));
+			break;
+			case '011': // record is locked by another login
+				matches = message.match(/\[LOGIN\:([^\]]*)\]/);
+				return matches === null || matches.length !== 2
+					? message
+					: cap.replace('{LOGIN}',matches[1]);
+			break;
+			case '012': // fallthrough, invalid JSON value
+			case '013': // fallthrough, invalid geo point value
+			case '014': // fallthrough, invalid money value
+			case '015': // fallthrough, invalid duration value
+			case '016': // fallthrough, value does not match validation regex
+			case '017': // fallthrough, text shorter than validation min. length
+			case '018': // fallthrough, text longer than validation max. length
+			case '019': // fallthrough, value smaller than validation min. value
+			case '020': // fallthrough, value larger than validation max. value
+			case '021': // value not in validation allowed values
+				matches = message.match(/\[ATR_ID\:([^\]]*)\]/);
+				if(matches === null || matches.length !== 2)
+					return message;
+				
+				let atr = MyStore.getters['schema/attributeIdMap'][matches[1]];
+				if(typeof atr === 'undefined')
+					return message;
+				
+				matches = message.match(/\[LIMIT\:([^\]]*)\]/);
+				
+				return cap.replace('{NAME}',typeof atr.captions.attributeTitle[lang] !== 'undefined'
+					? atr.captions.attributeTitle[lang] : atr.name).replace('{LIMIT}',
+					matches === null || matches.length !== 2 ? '' : matches[1]);
+			break;
 		}
 	}
 	if(errContext === 'SEC') {
@@ -16,6 +16,7 @@ export function getFieldIcon(field) {
 		case 'header':    return 'header.png'; break;
 		case 'kanban':    return 'kanban.png'; break;
 		case 'list':      return 'files_list2.png'; break;
+		case 'map':       return 'globe.png'; break;
 		case 'tabs':      return 'tabs.png'; break;
 	}
 	return 'noPic.png';
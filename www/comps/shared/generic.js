@@ -107,6 +107,20 @@ export function getSizeReadable(size) {
 	return (size / 1073741824).toFixed(2) + ' Tb';
 };
 
+export function getMoneyFormatted(value) {
+	// money values (amount as text, ISO currency), aggregated values are lists (one per currency)
+	if(value === null) return '';
+	if(Array.isArray(value)) return value.map(v => getMoneyFormatted(v)).join(', ');
+	
+	try {
+		return new Intl.NumberFormat(undefined,{ style:'currency', currency:value.currency })
+			.format(parseFloat(value.amount));
+	}
+	catch(e) {
+		return `${value.amount} ${value.currency}`;
+	}
+};
+
 export function getLineBreaksParsedToHtml(input) {
 	return input.replace(/(?:\r\n|\r|\n)/g, '<br />');
 };
@@ -329,6 +329,23 @@ export function getQueryFiltersDateRange(attributeId0,index0,date0,attributeId1,
 	}];
 };
 
+export function getQueryFiltersGeoBox(attributeId,index,lonMin,latMin,lonMax,latMax) {
+	// set query filter for records which geo point attribute value is within bounding box
+	return [{
+		connector:'AND',
+		operator:'WITHIN BOX',
+		side0:{
+			attributeId:attributeId,
+			attributeIndex:index,
+			brackets:0
+		},
+		side1:{
+			brackets:0,
+			value:[lonMin,latMin,lonMax,latMax]
+		}
+	}];
+};
+
 export function getFiltersEncapsulated(filters) {
 	// add brackets to encapsulate a filter set from other filter sets
 	//  some sets: query filters, quick filters, custom user filters
@@ -74,6 +74,27 @@ export function getUtcTimeStringFromUnix(unixTime) {
 		`${getStringFilled(d.getSeconds(),2,'0')}`;
 };
 
+export function getDurationStringFromSeconds(seconds) {
+	// durations as '[-]hours:minutes:seconds', hours are not limited to 24
+	if(seconds === null) return '';
+	
+	let s = Math.abs(seconds);
+	return `${seconds < 0 ? '-' : ''}${Math.floor(s / 3600)}:` +
+		`${getStringFilled(Math.floor(s % 3600 / 60),2,'0')}:` +
+		`${getStringFilled(s % 60,2,'0')}`;
+};
+
+export function getSecondsFromDurationString(input) {
+	// accepts '[-]hours:minutes[:seconds]', returns null if invalid
+	let m = /^\s*(-?)(\d+):([0-5]?\d)(?::([0-5]?\d))?\s*$/.exec(input);
+	if(m === null) return null;
+	
+	let seconds = parseInt(m[2]) * 3600 + parseInt(m[3]) * 60
+		+ (typeof m[4] !== 'undefined' ? parseInt(m[4]) : 0);
+	
+	return m[1] === '-' ? -seconds : seconds;
+};
+
 export function isUnixUtcZero(unixTime) {
 	return unixTime % 86400 === 0;
 };
@@ -3,6 +3,7 @@ import {
 	getAttributeFileVersionHref
 } from './shared/attribute.js';
 import {
+	getDurationStringFromSeconds,
 	getUnixFormat,
 	getUnixShifted,
 	getUtcTimeStringFromUnix
@@ -11,6 +12,7 @@ import {
 	colorAdjustBg,
 	getHtmlStripped,
 	getLinkMeta,
+	getMoneyFormatted,
 	openLink
 } from './shared/generic.js';
 export {MyValueRich as default};
@@ -135,8 +137,10 @@ let MyValueRich = {
 		colorAdjustBg,
 		getAttributeFileThumbHref,
 		getAttributeFileVersionHref,
+		getDurationStringFromSeconds,
 		getHtmlStripped,
 		getLinkMeta,
+		getMoneyFormatted,
 		getUnixFormat,
 		getUnixShifted,
 		getUtcTimeStringFromUnix,
@@ -202,6 +206,34 @@ let MyValueRich = {
 					}
 				break;
 				
+				// JSON
+				case 'jsonb':
+					if(this.value !== null)
+						this.stringValueFull = JSON.stringify(this.value);
+				break;
+				
+				// geo point, shown as 'latitude, longitude'
+				case 'point':
+					if(this.value !== null)
+						this.stringValueFull = `${this.value.coordinates[1]}, ${this.value.coordinates[0]}`;
+				break;
+				
+				// money, aggregated values are lists (one per currency)
+				case 'money':
+					if(this.value !== null && typeof this.value === 'object')
+						this.stringValueFull = this.getMoneyFormatted(this.value);
+					else
+						directValue = true;
+				break;
+				
+				// duration in seconds
+				case 'interval':
+					if(this.value !== null && typeof this.value === 'number')
+						this.stringValueFull = this.getDurationStringFromSeconds(this.value);
+					else
+						directValue = true;
+				break;
+				
 				// others (numbers, UUID)
 				default: directValue = true; break;
 			}
@@ -29,9 +29,12 @@ import MyAdminMailAccounts   from './comps/admin/adminMailAccounts.js';
 import MyAdminMailSpooler    from './comps/admin/adminMailSpooler.js';
 import MyAdminMailTraffic    from './comps/admin/adminMailTraffic.js';
 import MyAdminModules        from './comps/admin/adminModules.js';
+import MyAdminRegistrations  from './comps/admin/adminRegistrations.js';
 import MyAdminRepo           from './comps/admin/adminRepo.js';
 import MyAdminRoles          from './comps/admin/adminRoles.js';
 import MyAdminScheduler      from './comps/admin/adminScheduler.js';
+import MyAdminScim           from './comps/admin/adminScim.js';
+import MyAdminSlowQueries    from './comps/admin/adminSlowQueries.js';
 
 // builder
 import MyBuilder            from './comps/builder/builder.js';
@@ -96,9 +99,12 @@ const MyRouter = VueRouter.createRouter({
 			{ path:'mailspooler',    component:MyAdminMailSpooler },
 			{ path:'mailtraffic',    component:MyAdminMailTraffic },
 			{ path:'modules',        component:MyAdminModules },
+			{ path:'registrations',  component:MyAdminRegistrations },
 			{ path:'repo',           component:MyAdminRepo },
 			{ path:'roles',          component:MyAdminRoles },
-			{ path:'scheduler',      component:MyAdminScheduler }
+			{ path:'scheduler',      component:MyAdminScheduler },
+			{ path:'scim',           component:MyAdminScim },
+			{ path:'slowqueries',    component:MyAdminSlowQueries }
 		]
 	},{
 		path:'/builder',
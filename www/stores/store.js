@@ -13,6 +13,7 @@ const MyStore = Vuex.createStore({
 		builderMode:false,             // builder mode active
 		busyCounter:0,                 // counter of calls making the app busy (WS requests, uploads, etc.)
 		captions:{},                   // all application captions in the user interface language
+		clientCertAuth:false,          // login via client certificate is enabled
 		clusterNodeName:'',            // name of the cluster node that session is connected to
 		collectionIdMap:{},            // map of all collection values, key = collection ID
 		config:{},                     // configuration values (admin only)
@@ -21,6 +22,7 @@ const MyStore = Vuex.createStore({
 			keyLength:64,              // length of new symmetric keys for data encryption
 			scrollFormId:'form-scroll' // ID of form page element (to recover scroll position during routing)
 		},
+		dataChanged:[],       // last received data changes of subscribed relations/records, [{relationId:X,recordIds:[1,2]},...]
 		dialogCaptionTop:'',
 		dialogCaptionBody:'',
 		dialogButtons:[],
@@ -36,6 +38,8 @@ const MyStore = Vuex.createStore({
 		isAtDialog:false,     // app shows generic dialog
 		isAtFeedback:false,   // app shows feedback dialog
 		isAtMenu:false,       // user navigated to menu (only relevant if isMobile)
+		isAtSearch:false,     // app shows global search dialog
+		isImpersonated:false, // admin is impersonating the current login
 		isMobile:false,       // app runs on small screen (probably mobile)
 		isNoAuth:false,       // user logged in without authentication
 		license:{},           // license info (admin only)
@@ -56,7 +60,9 @@ const MyStore = Vuex.createStore({
 		pageTitleFull:'',     // web page title + instance name
 		popUpFormGlobal:null, // configuration of global pop-up form
 		productionMode:false, // system in production mode, false if maintenance
+		registration:false,   // self-registration of new logins is enabled
 		pwaDomainMap:{},      // map of modules per PWA sub domain, key: sub domain, value: module ID
+		recordPresence:{},    // presence of logins on open records, key: relation ID + record ID, value: presence list
 		routingGuards:[],     // functions to call before routing, abort if any returns falls
 		searchDictionaries:[],// dictionaries used for full text search for this login, ['english', 'german', ...]
 		settings:{},          // setting values for logged in user, key: settings name
@@ -98,6 +104,12 @@ const MyStore = Vuex.createStore({
 			state.dialogButtons = payload.buttons;
 			state.isAtDialog    = true;
 		},
+		recordPresence:(state,payload) => {
+			const key = `${payload.relationId}_${payload.recordId}`;
+			
+			if(payload.presence === null) delete state.recordPresence[key];
+			else                          state.recordPresence[key] = payload.presence;
+		},
 		filesCopyReset:(state,payload) => {
 			state.filesCopy = {
 				attributeId:null,
@@ -156,13 +168,17 @@ const MyStore = Vuex.createStore({
 		// simple
 		access:         (state,payload) => state.access          = payload,
 		captions:       (state,payload) => state.captions        = payload,
+		clientCertAuth: (state,payload) => state.clientCertAuth  = payload,
 		clusterNodeName:(state,payload) => state.clusterNodeName = payload,
+		dataChanged:    (state,payload) => state.dataChanged     = payload,
 		feedback:       (state,payload) => state.feedback        = payload,
 		filesCopy:      (state,payload) => state.filesCopy       = payload,
 		isAdmin:        (state,payload) => state.isAdmin         = payload,
 		isAtDialog:     (state,payload) => state.isAtDialog      = payload,
 		isAtFeedback:   (state,payload) => state.isAtFeedback    = payload,
 		isAtMenu:       (state,payload) => state.isAtMenu        = payload,
+		isAtSearch:     (state,payload) => state.isAtSearch      = payload,
+		isImpersonated: (state,payload) => state.isImpersonated  = payload,
 		isNoAuth:       (state,payload) => state.isNoAuth        = payload,
 		isMobile:       (state,payload) => state.isMobile        = payload,
 		loginEncryption:(state,payload) => state.loginEncryption = payload,
@@ -179,6 +195,7 @@ const MyStore = Vuex.createStore({
 		popUpFormGlobal:(state,payload) => state.popUpFormGlobal = payload,
 		productionMode: (state,payload) => state.productionMode  = payload,
 		pwaDomainMap:   (state,payload) => state.pwaDomainMap  = payload,
+		registration:   (state,payload) => state.registration    = payload,
 		searchDictionaries:(state,payload) => state.searchDictionaries = payload,
 		settings:       (state,payload) => state.settings        = payload,
 		system:         (state,payload) => state.system          = payload
@@ -211,10 +228,12 @@ const MyStore = Vuex.createStore({
 		builderEnabled:   (state) => state.builderMode && !state.productionMode,
 		busyCounter:      (state) => state.busyCounter,
 		captions:         (state) => state.captions,
+		clientCertAuth:   (state) => state.clientCertAuth,
 		clusterNodeName:  (state) => state.clusterNodeName,
 		collectionIdMap:  (state) => state.collectionIdMap,
 		config:           (state) => state.config,
 		constants:        (state) => state.constants,
+		dataChanged:      (state) => state.dataChanged,
 		dialogCaptionTop: (state) => state.dialogCaptionTop,
 		dialogCaptionBody:(state) => state.dialogCaptionBody,
 		dialogButtons:    (state) => state.dialogButtons,
@@ -227,7 +246,9 @@ const MyStore = Vuex.createStore({
 		isAtDialog:       (state) => state.isAtDialog,
 		isAtFeedback:     (state) => state.isAtFeedback,
 		isAtMenu:         (state) => state.isAtMenu,
+		isAtSearch:       (state) => state.isAtSearch,
 		isMobile:         (state) => state.isMobile,
+		isImpersonated:   (state) => state.isImpersonated,
 		isNoAuth:         (state) => state.isNoAuth,
 		license:          (state) => state.license,
 		licenseValid:     (state) => state.licenseValid,
@@ -245,7 +266,9 @@ const MyStore = Vuex.createStore({
 		pageTitleFull:    (state) => state.pageTitleFull,
 		popUpFormGlobal:  (state) => state.popUpFormGlobal,
 		productionMode:   (state) => state.productionMode,
+		recordPresence:   (state) => state.recordPresence,
 		pwaDomainMap:     (state) => state.pwaDomainMap,
+		registration:     (state) => state.registration,
 		routingGuards:    (state) => state.routingGuards,
 		searchDictionaries:(state) => state.searchDictionaries,
 		sessionValueStore:(state) => state.sessionValueStore,
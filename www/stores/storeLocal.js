@@ -20,6 +20,7 @@ const MyStoreLocal = {
 		menuIdMapOpen:{},     // map of menu IDs with open state (true/false)
 		schemaTimestamp:-1,   // last known schema timestamp
 		token:'',             // JWT token
+		tokenImpersonator:'', // JWT token of admin, kept while admin impersonates another login
 		tokenKeep:false       // keep JWT token between sessions
 	},
 	mutations:{
@@ -99,6 +100,10 @@ const MyStoreLocal = {
 			state.token = payload;
 			set('token',payload);
 		},
+		tokenImpersonator(state,payload) {
+			state.tokenImpersonator = payload;
+			set('tokenImpersonator',payload);
+		},
 		tokenKeep(state,payload) {
 			state.tokenKeep = payload;
 			set('tokenKeep',payload);
@@ -138,6 +143,7 @@ const MyStoreLocal = {
 		menuIdMapOpen:     (state) => state.menuIdMapOpen,
 		schemaTimestamp:   (state) => state.schemaTimestamp,
 		token:             (state) => state.token,
+		tokenImpersonator: (state) => state.tokenImpersonator,
 		tokenKeep:         (state) => state.tokenKeep
 	}
 };